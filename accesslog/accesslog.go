@@ -0,0 +1,201 @@
+// Package accesslog writes structured records of handled proxy requests to
+// ClickHouse or PostgreSQL in batches, for long-term traffic analysis
+package accesslog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Record is a single handled proxy request, with fields named after the
+// columns of the access log table
+type Record struct {
+	Time        time.Time `json:"time"`         // time the request was received
+	Proxy       string    `json:"proxy"`        // name of the proxy
+	Method      string    `json:"method"`       // request method
+	Path        string    `json:"path"`         // request path
+	Tile        string    `json:"tile"`         // requested tile as z/x/y, empty if none
+	Status      int       `json:"status"`       // response status code
+	CacheStatus string    `json:"cache_status"` // cache status, ex: hit-m, miss
+	LatencyMS   float64   `json:"latency_ms"`   // time taken to handle the request in milliseconds
+	Bytes       int       `json:"bytes"`        // response body size
+	ClientIP    string    `json:"client_ip"`    // IP address of the client
+	UserAgent   string    `json:"user_agent"`   // User-Agent request header
+	Referer     string    `json:"referer"`      // Referer request header
+	Key         string    `json:"key"`          // name of the API key used, empty if none
+	RequestID   string    `json:"request_id"`   // ID of the request, as logged
+}
+
+// Sink inserts batches of records into a database
+type Sink interface {
+	Insert(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// Writer queues records and inserts them into its sink in batches from a
+// single goroutine. Records are dropped rather than blocking requests when the
+// queue is full.
+type Writer struct {
+	config config.AccessLog
+	sink   Sink
+	queue  chan Record
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	writer *Writer
+)
+
+// Init starts a writer if the access log sink is configured, flushing and
+// stopping any writer left over from a previous configuration
+func Init() error {
+	var next *Writer
+	if log := config.Get().Instance.AccessLog; log.Driver != "" {
+		sink, err := newSink(log)
+		if err != nil {
+			return err
+		}
+		next = newWriter(log, sink)
+		go next.run()
+	}
+
+	mu.Lock()
+	prev := writer
+	writer = next
+	mu.Unlock()
+
+	prev.Close()
+	return nil
+}
+
+// Close flushes the records queued by the active writer and stops it
+func Close() {
+	mu.Lock()
+	prev := writer
+	writer = nil
+	mu.Unlock()
+
+	prev.Close()
+}
+
+// Emit queues a record for the sink, if an access log sink is configured
+func Emit(record Record) {
+	mu.RLock()
+	defer mu.RUnlock()
+	writer.Emit(record)
+}
+
+// Enabled returns true if an access log sink is configured
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return writer != nil
+}
+
+// newSink opens the sink of the configured driver
+func newSink(log config.AccessLog) (Sink, error) {
+	if log.Driver == config.AccessLogPostgres {
+		return newPostgres(log)
+	}
+	return newClickHouse(log)
+}
+
+// newWriter builds a writer inserting into the given sink
+func newWriter(log config.AccessLog, sink Sink) *Writer {
+	return &Writer{
+		config: log,
+		sink:   sink,
+		queue:  make(chan Record, log.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Emit queues a record, dropping it if the queue is full
+func (w *Writer) Emit(record Record) {
+	if w == nil {
+		return
+	}
+
+	select {
+	case w.queue <- record:
+		metrics.queued.Inc()
+	default:
+		metrics.records.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Close stops the writer after inserting all queued records, and closes its sink
+func (w *Writer) Close() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+	_ = w.sink.Close()
+}
+
+// run batches queued records, inserting a batch once it is full or the flush
+// interval passes, until stopped
+func (w *Writer) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushIntervalDuration)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, w.config.BufferSize)
+	add := func(record Record) {
+		metrics.queued.Dec()
+		batch = append(batch, record)
+		if len(batch) >= w.config.BufferSize {
+			batch = w.insert(batch)
+		}
+	}
+
+	for {
+		select {
+		case record := <-w.queue:
+			add(record)
+		case <-ticker.C:
+			batch = w.insert(batch)
+		case <-w.stop:
+			for {
+				select {
+				case record := <-w.queue:
+					add(record)
+				default:
+					w.insert(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// insert writes a batch of records to the sink, returning the emptied batch
+// for reuse
+func (w *Writer) insert(batch []Record) []Record {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.FlushIntervalDuration)
+	defer cancel()
+
+	if err := w.sink.Insert(ctx, batch); err != nil {
+		util.Error(str.CMain, str.EAccessLog, len(batch), w.config.Driver, err.Error())
+		metrics.batches.WithLabelValues("failure").Inc()
+		metrics.records.WithLabelValues("failed").Add(float64(len(batch)))
+	} else {
+		metrics.batches.WithLabelValues("success").Inc()
+		metrics.records.WithLabelValues("written").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
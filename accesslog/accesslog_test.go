@@ -0,0 +1,79 @@
+package accesslog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// fakeSink collects inserted batches in memory
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]Record
+	closed  bool
+}
+
+func (f *fakeSink) Insert(_ context.Context, records []Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// the writer reuses its batch, so keep a copy
+	f.batches = append(f.batches, append([]Record(nil), records...))
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.closed = true
+	return nil
+}
+
+// TestWriter will test that queued records are inserted in batches of the
+// configured size, flushed when the writer is closed, and that the sink is
+// closed afterwards
+func TestWriter(t *testing.T) {
+	sink := &fakeSink{}
+	w := newWriter(config.AccessLog{
+		BufferSize:            2,
+		QueueSize:             10,
+		FlushIntervalDuration: time.Hour,
+	}, sink)
+	go w.run()
+
+	for _, name := range []string{"a", "b", "c"} {
+		w.Emit(Record{Proxy: name, Status: 200})
+	}
+	w.Close()
+
+	if len(sink.batches) != 2 || len(sink.batches[0]) != 2 || len(sink.batches[1]) != 1 ||
+		sink.batches[0][0].Proxy != "a" || sink.batches[1][0].Proxy != "c" {
+		t.Errorf(str.TAccessLogBadBatches, sink.batches)
+	}
+	if !sink.closed {
+		t.Error(str.TAccessLogNotClosed)
+	}
+}
+
+// TestWriterDrop will test that records are dropped instead of blocking
+// once the queue is full
+func TestWriterDrop(t *testing.T) {
+	w := newWriter(config.AccessLog{BufferSize: 1, QueueSize: 1}, &fakeSink{})
+
+	done := make(chan struct{})
+	go func() {
+		w.Emit(Record{Proxy: "a"})
+		w.Emit(Record{Proxy: "b"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal(str.TAccessLogBlocked)
+	}
+	if len(w.queue) != 1 {
+		t.Errorf(str.TAccessLogBadQueue, len(w.queue))
+	}
+}
@@ -0,0 +1,37 @@
+package accesslog
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "access_log"
+
+// metrics of the access log sink, which survive its writer being rebuilt on
+// config reloads
+var metrics = struct {
+	records *prometheus.CounterVec
+	batches *prometheus.CounterVec
+	queued  prometheus.Gauge
+}{
+	records: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "records_total",
+		Help:      "The total number of access log records by outcome, written, failed or dropped when the queue is full",
+	}, []string{"result"}),
+	batches: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "batches_total",
+		Help:      "The total number of batches inserted into the sink by result",
+	}, []string{"result"}),
+	queued: promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "queued",
+		Help:      "The number of access log records waiting to be inserted",
+	}),
+}
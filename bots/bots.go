@@ -0,0 +1,110 @@
+// Package bots classifies requests as coming from crawlers and other
+// automated clients or from interactive map users, by user agent and by how
+// many tiles a client requests per minute
+package bots
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Class of a client
+type Class string
+
+const (
+	// Interactive clients are map users panning and zooming
+	Interactive Class = "interactive"
+	// Bot clients are crawlers, scrapers and other automated clients
+	Bot Class = "bot"
+)
+
+// window over which client requests are counted
+const window = time.Minute
+
+// DefaultUserAgents are case-insensitive substrings of the user agents of
+// common crawlers and HTTP libraries
+var DefaultUserAgents = []string{
+	"bot", "crawl", "spider", "slurp", "scrape", "headless",
+	"curl", "wget", "python-requests", "python-urllib", "aiohttp", "go-http-client",
+	"java/", "okhttp", "libwww-perl", "httpclient", "node-fetch", "axios",
+}
+
+// Classifier classifies clients by user agent and request rate
+type Classifier struct {
+	userAgents []string
+	rate       int
+	requests   *Counter
+}
+
+// NewClassifier returns a classifier matching the default and given user
+// agent substrings, also classifying clients making more than rate requests
+// per minute as bots if rate is positive
+func NewClassifier(userAgents []string, rate int) *Classifier {
+	c := &Classifier{rate: rate, requests: NewCounter()}
+	for _, ua := range append(DefaultUserAgents, userAgents...) {
+		c.userAgents = append(c.userAgents, strings.ToLower(ua))
+	}
+	return c
+}
+
+// Classify a request by its user agent and client key at the given time,
+// counting it towards the client's request rate. Requests without a user
+// agent are classified as bots.
+func (c *Classifier) Classify(userAgent, client string, now time.Time) Class {
+	if c.rate > 0 && c.requests.Add(client, now) > c.rate {
+		return Bot
+	}
+
+	if userAgent == "" {
+		return Bot
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	for _, ua := range c.userAgents {
+		if strings.Contains(userAgent, ua) {
+			return Bot
+		}
+	}
+
+	return Interactive
+}
+
+// Counter counts requests per client over fixed one minute windows
+type Counter struct {
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// NewCounter returns an empty counter
+func NewCounter() *Counter {
+	return &Counter{counts: make(map[string]int)}
+}
+
+// Add a request of the client at the given time, returning the client's
+// number of requests in the current window
+func (c *Counter) Add(client string, now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// start a new window, forgetting previous clients
+	if now.Sub(c.start) >= window {
+		c.start = now
+		c.counts = make(map[string]int)
+	}
+
+	c.counts[client]++
+	return c.counts[client]
+}
+
+// Remaining returns the time left in the current window at the given time
+func (c *Counter) Remaining(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if remaining := window - now.Sub(c.start); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
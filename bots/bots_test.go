@@ -0,0 +1,56 @@
+package bots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestClassifyUserAgent will test that crawlers and HTTP libraries are
+// classified as bots and browsers as interactive
+func TestClassifyUserAgent(t *testing.T) {
+	c := NewClassifier([]string{"TileHarvester"}, 0)
+	now := time.Now()
+
+	tests := []struct {
+		userAgent string
+		expected  Class
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0 Safari/537.36", Interactive},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", Bot},
+		{"curl/8.1.2", Bot},
+		{"python-requests/2.31.0", Bot},
+		{"tileharvester/1.0", Bot},
+		{"", Bot},
+	}
+
+	for _, test := range tests {
+		if got := c.Classify(test.userAgent, "client", now); got != test.expected {
+			t.Errorf(str.TBotsBadClass, test.userAgent, got, test.expected)
+		}
+	}
+}
+
+// TestClassifyRate will test that clients requesting more than the allowed
+// rate are classified as bots until the window resets
+func TestClassifyRate(t *testing.T) {
+	c := NewClassifier(nil, 2)
+	browser := "Mozilla/5.0 Firefox/118.0"
+	now := time.Now()
+
+	expected := []Class{Interactive, Interactive, Bot}
+	for i, class := range expected {
+		if got := c.Classify(browser, "10.0.0.1", now); got != class {
+			t.Errorf(str.TBotsBadRateClass, i+1, got, class)
+		}
+	}
+
+	// other clients and later windows are unaffected
+	if got := c.Classify(browser, "10.0.0.2", now); got != Interactive {
+		t.Errorf(str.TBotsBadRateClass, 1, got, Interactive)
+	}
+	if got := c.Classify(browser, "10.0.0.1", now.Add(window)); got != Interactive {
+		t.Errorf(str.TBotsBadRateClass, 1, got, Interactive)
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dechristopher/lod/packet"
+)
+
+// Outcomes of comparing a refetched tile with its previously cached copy
+const (
+	adaptiveChanged   = "changed"
+	adaptiveUnchanged = "unchanged"
+	adaptiveNew       = "new"
+)
+
+// withAdaptiveTTL returns the headers of a tile about to be cached with its
+// adaptive TTL added, unless adaptive TTLs are disabled or the upstream set
+// the tile's lifetime itself. The headers are modified in place.
+func (c *Cache) withAdaptiveTTL(key string, tileData []byte, headers map[string]string) map[string]string {
+	if !c.Proxy.Cache.AdaptiveTTL.Enabled {
+		return headers
+	}
+	if _, ok := headers[packet.HeaderTTL]; ok {
+		return headers
+	}
+
+	ttl, outcome := c.adaptiveTTL(c.previous(key), tileData)
+	c.Metrics.AdaptiveRefreshes.WithLabelValues(outcome).Inc()
+	headers[packet.HeaderAdaptiveTTL] = strconv.FormatInt(int64(ttl/time.Second), 10)
+	return headers
+}
+
+// adaptiveTTL returns the TTL of a refetched tile given its previously cached
+// copy, doubling the previous TTL if the tile is unchanged and halving it if
+// it changed, within the configured bounds. New tiles start at the minimum.
+func (c *Cache) adaptiveTTL(previous *packet.TilePacket, tileData []byte) (time.Duration, string) {
+	bounds := c.Proxy.Cache.AdaptiveTTL
+	if previous == nil {
+		return bounds.MinDuration, adaptiveNew
+	}
+	ttl, ok := previous.AdaptiveTTL()
+	if !ok {
+		return bounds.MinDuration, adaptiveNew
+	}
+
+	if bytes.Equal(previous.TileData(), tileData) {
+		ttl *= 2
+		if ttl > bounds.MaxDuration {
+			ttl = bounds.MaxDuration
+		}
+		return ttl, adaptiveUnchanged
+	}
+
+	ttl /= 2
+	if ttl < bounds.MinDuration {
+		ttl = bounds.MinDuration
+	}
+	return ttl, adaptiveChanged
+}
+
+// previous returns the cached copy of a tile about to be replaced, including
+// copies past their adaptive TTL, nil if neither cache layer holds a valid one
+func (c *Cache) previous(key string) *packet.TilePacket {
+	var data []byte
+	if c.Proxy.Cache.MemEnabled {
+		data, _ = c.lookupMemory(key, nil)
+	}
+	if data == nil && c.Proxy.Cache.RedisEnabled {
+		data, _ = c.lookupRedis(context.Background(), key)
+	}
+	if data == nil {
+		return nil
+	}
+
+	tile, err := packet.FromBytes(data, key)
+	if err != nil {
+		return nil
+	}
+	return tile
+}
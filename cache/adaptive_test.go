@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestAdaptiveTTL will test that tile TTLs double while refetched tiles are
+// unchanged and halve once they change, within the configured bounds
+func TestAdaptiveTTL(t *testing.T) {
+	proxy := config.Proxy{Name: "adaptive", Cache: config.Cache{
+		MemEnabled: true,
+		AdaptiveTTL: config.AdaptiveTTL{
+			Enabled:     true,
+			MinDuration: time.Minute,
+			MaxDuration: 4 * time.Minute,
+		},
+	}}
+	inner := mapEngine{entries: map[string][]byte{}}
+	c := &Cache{Proxy: &proxy, Metrics: initMetrics(proxy, false), internal: inner}
+
+	tests := []struct {
+		data     string
+		expected time.Duration
+	}{
+		{"a", time.Minute},
+		{"a", 2 * time.Minute},
+		{"a", 4 * time.Minute},
+		{"a", 4 * time.Minute},
+		{"b", 2 * time.Minute},
+		{"c", time.Minute},
+		{"c", 2 * time.Minute},
+	}
+
+	for i, test := range tests {
+		headers := c.withAdaptiveTTL("0/0/0", []byte(test.data), map[string]string{
+			packet.HeaderCreated: strconv.FormatInt(time.Now().Unix(), 10),
+		})
+		tile := packet.Encode([]byte(test.data), headers)
+		if got, ok := tile.AdaptiveTTL(); !ok || got != test.expected {
+			t.Errorf(str.TCacheBadAdaptiveTTL, i, test.data, got, test.expected)
+		}
+		_ = inner.Set("0/0/0", tile)
+	}
+
+	// lifetimes set by the upstream are kept as is
+	headers := c.withAdaptiveTTL("0/0/0", []byte("c"), map[string]string{packet.HeaderTTL: "30"})
+	if ttl, ok := headers[packet.HeaderAdaptiveTTL]; ok {
+		t.Errorf(str.TCacheBadAdaptiveTTL, len(tests), "c", ttl, time.Duration(0))
+	}
+}
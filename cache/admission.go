@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// number of hash rows of the admission sketch
+const sketchDepth = 4
+
+// sketch is a count-min sketch estimating how often keys were requested.
+// Counts are halved every sample of as many requests as it has counters per
+// row, so estimates favor recent requests and floods of one-off keys don't
+// add up to admitting them.
+type sketch struct {
+	mu        sync.Mutex
+	rows      [sketchDepth][]uint32
+	mask      uint64
+	additions int
+	sample    int
+}
+
+// newSketch returns a sketch sized for about the given number of keys
+func newSketch(keys int) *sketch {
+	width := 16
+	for width < keys {
+		width <<= 1
+	}
+
+	s := &sketch{mask: uint64(width - 1), sample: width}
+	for i := range s.rows {
+		s.rows[i] = make([]uint32, width)
+	}
+	return s
+}
+
+// increment records a request of the key, returning its new estimated count
+func (s *sketch) increment(key string) uint32 {
+	h1, h2 := sketchHashes(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	estimate := ^uint32(0)
+	for i := range s.rows {
+		counter := &s.rows[i][(h1+uint64(i)*h2)&s.mask]
+		*counter++
+		if *counter < estimate {
+			estimate = *counter
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sample {
+		s.age()
+	}
+	return estimate
+}
+
+// estimate returns the estimated request count of the key
+func (s *sketch) estimate(key string) uint32 {
+	h1, h2 := sketchHashes(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	estimate := ^uint32(0)
+	for i := range s.rows {
+		if counter := s.rows[i][(h1+uint64(i)*h2)&s.mask]; counter < estimate {
+			estimate = counter
+		}
+	}
+	return estimate
+}
+
+// total returns the number of requests recorded, aged along with the counts
+// so estimates can be compared against it as shares of recent requests
+func (s *sketch) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.additions
+}
+
+// age halves every counter, with the lock held
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// sketchHashes derives the two hashes combined into each row's index
+func sketchHashes(key string) (uint64, uint64) {
+	hash := uint64(fnvOffset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= fnvPrime64
+	}
+	return hash, hash>>32 | 1
+}
+
+// admissionEngine wraps a memory engine, only caching tiles requested at
+// least a threshold number of times so one-off requests, such as those of
+// crawlers, don't evict hot tiles
+type admissionEngine struct {
+	memoryEngine
+	requests  *sketch
+	threshold uint32
+	rejects   prometheus.Counter
+}
+
+// withAdmission wraps the engine with the proxy's admission policy,
+// returning the engine as is if admission is disabled
+func withAdmission(engine memoryEngine, proxy config.Proxy, metrics *Metrics) memoryEngine {
+	if proxy.Cache.AdmitAfter <= 1 {
+		return engine
+	}
+
+	// assume tiles of 16KB on average
+	return &admissionEngine{
+		memoryEngine: engine,
+		requests:     newSketch(proxy.Cache.MemCap * 64),
+		threshold:    uint32(proxy.Cache.AdmitAfter),
+		rejects:      metrics.AdmissionRejects,
+	}
+}
+
+// Get a tile by key, counting the request towards its admission
+func (a *admissionEngine) Get(key string) ([]byte, error) {
+	a.requests.increment(key)
+	return a.memoryEngine.Get(key)
+}
+
+// Set a tile by key if it was requested often enough
+func (a *admissionEngine) Set(key string, entry []byte) error {
+	if a.requests.estimate(key) < a.threshold {
+		a.rejects.Inc()
+		return errDropped
+	}
+	return a.memoryEngine.Set(key, entry)
+}
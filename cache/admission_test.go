@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestSketch will test that request counts are estimated without
+// undercounting and fade out as counts are aged
+func TestSketch(t *testing.T) {
+	s := newSketch(1024)
+
+	for i := 0; i < 5; i++ {
+		s.increment("14/8192/5461")
+	}
+	if got := s.estimate("14/8192/5461"); got < 5 {
+		t.Errorf(str.TCacheBadSketch, "14/8192/5461", got, 5)
+	}
+	if got := s.estimate("0/0/0"); got > 1 {
+		t.Errorf(str.TCacheBadSketch, "0/0/0", got, 0)
+	}
+
+	// a crawl of one-off tiles ages the hot tile's count
+	for i := 0; i < s.sample; i++ {
+		s.increment(strconv.Itoa(i))
+	}
+	if got := s.estimate("14/8192/5461"); got > 3 {
+		t.Errorf(str.TCacheBadSketch, "14/8192/5461", got, 2)
+	}
+
+	// one-off tiles of the crawl rarely look requested twice
+	admitted := 0
+	for i := 0; i < 100; i++ {
+		if s.increment("crawl/"+strconv.Itoa(i)) >= 2 {
+			admitted++
+		}
+	}
+	if admitted > 10 {
+		t.Errorf(str.TCacheBadSketchAdmits, admitted, 100)
+	}
+}
@@ -0,0 +1,255 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Budget windows upstream requests are counted in
+const (
+	BudgetHourly = "hourly"
+	BudgetDaily  = "daily"
+)
+
+// budgetTracker counts upstream requests per budget window, in process when
+// Redis isn't available or must not be written to, and remembers until when
+// the budget is spent
+type budgetTracker struct {
+	mu        sync.Mutex
+	local     map[string]int64 // counts by window key, without redis or in read-only mode
+	exhausted time.Time        // end of the window whose budget is spent, zero if none
+}
+
+// BudgetWindow is the state of one window of a proxy's upstream request budget
+type BudgetWindow struct {
+	Window string    `json:"window"` // "hourly" or "daily"
+	Limit  int       `json:"limit"`  // upstream requests allowed in the window
+	Used   int64     `json:"used"`   // upstream requests counted in the window so far
+	Resets time.Time `json:"resets"` // time the window ends
+}
+
+// budgetWindows returns the configured budget windows containing the time
+func (c *Cache) budgetWindows(now time.Time) []BudgetWindow {
+	now = now.UTC()
+	var windows []BudgetWindow
+	if c.Proxy.Budget.Hourly > 0 {
+		windows = append(windows, BudgetWindow{
+			Window: BudgetHourly,
+			Limit:  c.Proxy.Budget.Hourly,
+			Resets: now.Truncate(time.Hour).Add(time.Hour),
+		})
+	}
+	if c.Proxy.Budget.Daily > 0 {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		windows = append(windows, BudgetWindow{
+			Window: BudgetDaily,
+			Limit:  c.Proxy.Budget.Daily,
+			Resets: day.AddDate(0, 0, 1),
+		})
+	}
+	return windows
+}
+
+// budgetKey returns the key counting upstream requests of a budget window
+func (c *Cache) budgetKey(window BudgetWindow) string {
+	return fmt.Sprintf("%s:budget:%s:%s:%d", config.Namespace, c.Proxy.Name,
+		window.Window, window.Resets.Unix())
+}
+
+// SpendBudget counts an upstream request against the proxy's budget, returning
+// false along with how long until the budget resets if it is already spent.
+// Requests are let through if the budget can't be counted.
+func (c *Cache) SpendBudget(ctx context.Context) (bool, time.Duration) {
+	if !c.Proxy.Budget.Enabled() {
+		return true, 0
+	}
+
+	now := time.Now()
+	if until, spent := c.BudgetExhausted(now); spent {
+		return false, until
+	}
+
+	windows := c.budgetWindows(now)
+	counts, err := c.countBudget(ctx, windows)
+	if err != nil {
+		util.Error(str.CCache, str.ECacheBudget, c.Proxy.Name, err.Error())
+		return true, 0
+	}
+
+	for i, window := range windows {
+		if counts[i] > int64(window.Limit) {
+			c.exhaustBudget(window)
+			return false, window.Resets.Sub(now)
+		}
+	}
+	return true, 0
+}
+
+// Budgeted wraps an upstream fetch, counting it against the proxy's budget and
+// failing with ErrBudgetSpent instead of fetching once the budget is spent
+func (c *Cache) Budgeted(fetch func() (interface{}, error)) func() (interface{}, error) {
+	if !c.Proxy.Budget.Enabled() {
+		return fetch
+	}
+	return func() (interface{}, error) {
+		if ok, retryAfter := c.SpendBudget(context.Background()); !ok {
+			return nil, ErrBudgetSpent{ProxyName: c.Proxy.Name, RetryAfter: retryAfter}
+		}
+		return fetch()
+	}
+}
+
+// countBudget increments the counts of the budget windows, returning them.
+// Read-only instances never write to Redis, counting their own requests in
+// process on top of those counted in Redis by the instances writing to it.
+func (c *Cache) countBudget(ctx context.Context, windows []BudgetWindow) ([]int64, error) {
+	if !c.Proxy.Cache.RedisEnabled {
+		return c.countBudgetLocal(windows), nil
+	}
+
+	if config.IsReadOnly() {
+		shared, err := c.sharedBudget(ctx, windows)
+		if err != nil {
+			return nil, err
+		}
+		counts := c.countBudgetLocal(windows)
+		for i := range counts {
+			counts[i] += shared[i]
+		}
+		return counts, nil
+	}
+
+	counts := make([]int64, len(windows))
+	pipe := c.external.Pipeline()
+	incrs := make([]*redis.IntCmd, len(windows))
+	for i, window := range windows {
+		key := c.budgetKey(window)
+		incrs[i] = pipe.Incr(ctx, key)
+		pipe.ExpireAt(ctx, key, window.Resets.Add(time.Hour))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	for i, incr := range incrs {
+		counts[i] = incr.Val()
+	}
+	return counts, nil
+}
+
+// countBudgetLocal increments the in-process counts of the budget windows,
+// returning them
+func (c *Cache) countBudgetLocal(windows []BudgetWindow) []int64 {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	if c.budget.local == nil {
+		c.budget.local = make(map[string]int64)
+	}
+	counts := make([]int64, len(windows))
+	current := make(map[string]int64, len(windows))
+	for i, window := range windows {
+		key := c.budgetKey(window)
+		c.budget.local[key]++
+		counts[i] = c.budget.local[key]
+		current[key] = counts[i]
+	}
+	// drop the counts of windows that have passed
+	c.budget.local = current
+	return counts
+}
+
+// localBudget returns the in-process counts of the budget windows
+func (c *Cache) localBudget(windows []BudgetWindow) []int64 {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	counts := make([]int64, len(windows))
+	for i, window := range windows {
+		counts[i] = c.budget.local[c.budgetKey(window)]
+	}
+	return counts
+}
+
+// sharedBudget reads the counts of the budget windows kept in Redis, without
+// writing to it
+func (c *Cache) sharedBudget(ctx context.Context, windows []BudgetWindow) ([]int64, error) {
+	counts := make([]int64, len(windows))
+	if len(windows) == 0 {
+		return counts, nil
+	}
+
+	keys := make([]string, len(windows))
+	for i, window := range windows {
+		keys[i] = c.budgetKey(window)
+	}
+	values, err := c.external.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if used, ok := value.(string); ok {
+			counts[i], _ = strconv.ParseInt(used, 10, 64)
+		}
+	}
+	return counts, nil
+}
+
+// exhaustBudget marks the budget spent until the end of the window, alerting
+// once per window
+func (c *Cache) exhaustBudget(window BudgetWindow) {
+	c.budget.mu.Lock()
+	alert := window.Resets.After(c.budget.exhausted)
+	if alert {
+		c.budget.exhausted = window.Resets
+	}
+	c.budget.mu.Unlock()
+
+	if alert {
+		util.Error(str.CCache, str.EBudgetExhausted, c.Proxy.Name, window.Window,
+			window.Limit, window.Resets.Format(time.RFC3339))
+		c.Metrics.BudgetExhaustions.WithLabelValues(window.Window).Inc()
+	}
+}
+
+// BudgetExhausted returns true along with how long until the budget resets if
+// the proxy's upstream request budget is spent
+func (c *Cache) BudgetExhausted(now time.Time) (time.Duration, bool) {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	if c.budget.exhausted.IsZero() || !now.Before(c.budget.exhausted) {
+		return 0, false
+	}
+	return c.budget.exhausted.Sub(now), true
+}
+
+// BudgetStatus returns the state of each configured budget window
+func (c *Cache) BudgetStatus(ctx context.Context) ([]BudgetWindow, error) {
+	windows := c.budgetWindows(time.Now())
+
+	// only read-only instances count in process alongside redis
+	used := c.localBudget(windows)
+	if c.Proxy.Cache.RedisEnabled {
+		shared, err := c.sharedBudget(ctx, windows)
+		if err != nil {
+			return nil, err
+		}
+		for i := range used {
+			used[i] += shared[i]
+		}
+	}
+
+	for i := range windows {
+		windows[i].Used = used[i]
+	}
+	return windows, nil
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestBudget will test that upstream fetches are refused once the hourly
+// budget is spent, and that the budget stays spent until the window resets
+func TestBudget(t *testing.T) {
+	proxy := config.Proxy{Name: "budget", Budget: config.Budget{Hourly: 2, Daily: 10}}
+	c := &Cache{Proxy: &proxy, Metrics: initMetrics(proxy, false)}
+
+	fetches := 0
+	fetch := c.Budgeted(func() (interface{}, error) {
+		fetches++
+		return nil, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		_, err := fetch()
+		var budgetErr ErrBudgetSpent
+		if errors.As(err, &budgetErr) != (i == 2) {
+			t.Errorf(str.TCacheBadBudget, i, err)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf(str.TCacheBadBudgetFetches, fetches, 2)
+	}
+
+	until, spent := c.BudgetExhausted(time.Now())
+	if !spent || until <= 0 || until > time.Hour {
+		t.Errorf(str.TCacheBadBudgetReset, until, spent)
+	}
+}
+
+// TestBudgetWindows will test that budget windows reset at the end of the
+// current UTC hour and day
+func TestBudgetWindows(t *testing.T) {
+	proxy := config.Proxy{Name: "budget", Budget: config.Budget{Hourly: 1, Daily: 1}}
+	c := &Cache{Proxy: &proxy}
+
+	now := time.Date(2024, 6, 1, 13, 45, 0, 0, time.UTC)
+	windows := c.budgetWindows(now)
+	if len(windows) != 2 ||
+		!windows[0].Resets.Equal(time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)) ||
+		!windows[1].Resets.Equal(time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf(str.TCacheBadBudgetWindows, windows)
+	}
+}
+
+// TestBudgetReadOnly will test that read-only instances count their upstream
+// fetches on top of the counts in Redis without ever writing to it
+func TestBudgetReadOnly(t *testing.T) {
+	t.Setenv("READ_ONLY", "true")
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{
+		Name:   "budget",
+		Cache:  config.Cache{RedisEnabled: true},
+		Budget: config.Budget{Hourly: 3},
+	}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+
+	// a request counted by an instance writing to redis
+	key := c.budgetKey(c.budgetWindows(time.Now())[0])
+	if err := server.Set(key, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	fetches := 0
+	fetch := c.Budgeted(func() (interface{}, error) {
+		fetches++
+		return nil, nil
+	})
+	for i := 0; i < 3; i++ {
+		_, err := fetch()
+		if errors.As(err, &ErrBudgetSpent{}) != (i == 2) {
+			t.Errorf(str.TCacheBadBudget, i, err)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf(str.TCacheBadBudgetFetches, fetches, 2)
+	}
+
+	if used, _ := server.Get(key); used != "1" || len(server.Keys()) != 1 {
+		t.Errorf(str.TCacheBadBudgetWrite, server.Keys(), used)
+	}
+
+	windows, err := c.BudgetStatus(context.Background())
+	if err != nil || len(windows) != 1 || windows[0].Used != 4 {
+		t.Errorf(str.TCacheBadBudgetWindows, windows)
+	}
+}
@@ -3,15 +3,19 @@ package cache
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"os"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/allegro/bigcache/v3"
 	"github.com/go-redis/redis/v8"
 	"github.com/gofiber/fiber/v2"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/dechristopher/lod/cdn"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/env"
 	"github.com/dechristopher/lod/packet"
@@ -21,150 +25,257 @@ import (
 
 var Subsystem = "cache"
 
-// CachesMap is an alias type for the map of proxy name to its cache
-type CachesMap map[string]*Cache
-
-// Caches configured for this instance
-var Caches = make(CachesMap)
-
 // Cache is a wrapper struct that operates a dual cache against the in-memory
 // cache and Redis as a backing cache
 type Cache struct {
-	internal *bigcache.BigCache // pointer to internal cache instance
-	external *redis.Client      // pointer to external Redis cache
-	Proxy    *config.Proxy      // a reference to the proxy's configuration
-	Metrics  *Metrics           // metrics container instance
+	internal memoryEngine  // internal cache instance
+	external *redis.Client // pointer to external Redis cache
+	Proxy    *config.Proxy // a reference to the proxy's configuration
+	Metrics  *Metrics      // metrics container instance
+	CDN      cdn.Purger    // downstream CDN purger, nil if no CDN configured
+
+	maintenance atomic.Bool      // whether the proxy is currently in maintenance mode
+	savings     savingsTracker   // per-minute counters of upstream requests avoided
+	memBytes    *atomic.Int64    // approximate bytes held by the in-memory cache
+	warm        atomic.Bool      // whether warm-up has completed, latched once true
+	warmList    atomic.Bool      // whether the warm-up tile list has been fetched
+	generation  atomic.Value     // active cache generation, when generations are enabled
+	coverage    atomic.Value     // served zoom levels and bounds, once discovered from the upstream
+	sparse      *sparseTree      // subtrees known to be empty upstream, nil unless enabled
+	budget      budgetTracker    // upstream requests counted against the proxy's budget
+	fetchTime   atomic.Int64     // moving average of upstream fetch times in nanoseconds
+	shards      *shardHasher     // per-shard operation counts, nil unless stats are enabled
+	writes      *writeBehind     // batched redis writes, nil unless write-behind is enabled
+	epoch       atomic.Value     // namespace of redis keys since the last scheduled flush
+	flushes     *scheduledFlush  // scheduled flushes of cache tiers, nil unless scheduled
+	generations *generationWatch // generation switches published by other instances, nil unless followed
+	jobs        jobs             // latest background jobs run against the cache, by kind
+
+	redisLookups singleflight.Group // in-flight redis lookups by key, shared by concurrent lookups
 }
 
 // Metrics for the cache instance
 type Metrics struct {
-	CacheHits   prometheus.Counter     // cache hits
+	CacheHits   *prometheus.CounterVec // cache hits, by layer ("memory" or "redis")
 	CacheMisses prometheus.Counter     // cache misses
-	HitRate     prometheus.CounterFunc // cache hit rate
+	// tile request latency, by cache status
+	RequestDuration *prometheus.HistogramVec
+	// invalid vector tiles received from the upstream, by action taken
+	InvalidTiles *prometheus.CounterVec
+	// refetched tiles given adaptive TTLs, by outcome ("changed", "unchanged" or "new")
+	AdaptiveRefreshes *prometheus.CounterVec
+	// tile bytes served to clients, by source ("cache" or "upstream")
+	BytesServed *prometheus.CounterVec
+	// tile bytes received from the upstream
+	BytesUpstream prometheus.Counter
+	// requests aborted by their client before completion, by phase
+	ClientAborts *prometheus.CounterVec
+	// in-memory hits each eviction policy would have served, when simulated
+	PolicyHits *prometheus.CounterVec
+	// in-memory lookups seen by the simulated eviction policies
+	PolicyLookups prometheus.Counter
+	// tiles kept out of the in-memory cache by the admission policy
+	AdmissionRejects prometheus.Counter
+	// tiles pinned in memory as hot keys
+	PinnedTiles prometheus.Gauge
+	// requests by client class ("bot" or "interactive"), when classified
+	ClientClasses *prometheus.CounterVec
+	// requests by client country, when a GeoIP database is configured
+	ClientCountries *prometheus.CounterVec
+	// requests answered without the upstream from known empty subtrees
+	SparseSkips prometheus.Counter
+	// cache misses of cache-only zoom levels, never fetched from the upstream
+	CacheOnlyMisses prometheus.Counter
+	// upstream request budgets spent, by window ("hourly" or "daily")
+	BudgetExhaustions *prometheus.CounterVec
+	// cache misses answered without the upstream while the budget is spent
+	BudgetRejections prometheus.Counter
+	// redis operations retried after transient errors, by operation
+	RedisRetries *prometheus.CounterVec
+	// redis operations still failing with transient errors after all retries
+	RedisRetryFailures *prometheus.CounterVec
+	// panics recovered from while handling requests
+	Panics prometheus.Counter
+	// redis writes sent per write-behind pipeline
+	RedisWriteBatches prometheus.Histogram
+	// redis lookups sharing a GET with concurrent lookups of the same key
+	RedisSharedLookups prometheus.Counter
 }
 
+// Cache layers a hit can be served from
+const (
+	LayerMemory = "memory"
+	LayerRedis  = "redis"
+)
+
+// Sources of tile bytes served to clients
+const (
+	SourceCache    = "cache"
+	SourceUpstream = "upstream"
+)
+
+// Phases a client can abort a request in
+const (
+	AbortQueue    = "queue"    // waiting for a fair queue slot to the upstream
+	AbortUpstream = "upstream" // waiting for the upstream response
+	AbortStream   = "stream"   // receiving a streamed tile
+)
+
 // OneMB represents one megabyte worth of bytes
 const OneMB = 1024 * 1024
 
-// Init cache instances for all configured proxies
-func Init() error {
-	// build out initial proxy instances
-	for _, proxy := range config.Get().Proxies {
-		if Caches[proxy.Name] == nil {
-			err := BuildInstance(proxy.Name)
-			if err != nil {
-				return ErrBuildInstance{
-					Name: proxy.Name,
-					Err:  err,
-				}
+// New builds a cache instance for the given prepared proxy and instance
+// configuration, without registering it with a Manager
+func New(proxy config.Proxy, instance config.Instance) (*Cache, error) {
+	var internal memoryEngine
+	var external *redis.Client
+	var shards *shardHasher
+	var err error
+	memBytes := &atomic.Int64{}
+
+	if proxy.Cache.MemEnabled {
+		internal, shards, err = initInternal(proxy, memBytes)
+		if err != nil {
+			return nil, ErrInitInternalCache{
+				Name: proxy.Name,
+				Err:  err,
 			}
 		}
 	}
 
-	// ensure old proxies under different names aren't kept around
-	WipeOldCaches()
-	return nil
-}
+	if proxy.Cache.RedisEnabled {
+		external, err = initExternal(proxy)
+		if err != nil {
+			return nil, ErrInitExternalCache{
+				Name: proxy.Name,
+				Err:  err,
+			}
+		}
+	}
 
-// Get a cache instance by name
-func Get(name string) *Cache {
-	return Caches[name]
-}
+	// initialize metrics for this cache instance
+	metrics := initMetrics(proxy, instance.LegacyMetrics)
+
+	if internal != nil {
+		internal = withHotKeys(withAdmission(withEviction(internal, proxy, metrics), proxy, metrics), proxy, metrics)
+	}
 
-// WipeOldCaches deletes old cache instances from the Caches map
-// Usually called after a config read/reload
-func WipeOldCaches() {
-	proxies := config.Get().Proxies
+	util.DebugFlag("cache", str.CCache, str.DCacheUp, proxy.Name)
 
-	for cacheName := range Caches {
-		present := false
+	c := &Cache{
+		internal: internal,
+		external: external,
+		Proxy:    &proxy,
+		Metrics:  metrics,
+		CDN:      cdn.New(proxy.CDN),
+		memBytes: memBytes,
+		shards:   shards,
+	}
 
-		// ensure proxy is present in current config
-		for _, proxy := range proxies {
-			if cacheName == proxy.Name {
-				present = true
-				break
+	// apply initial maintenance mode state from configuration
+	c.SetMaintenance(proxy.Maintenance.Enabled)
+
+	// follow generation switches made on any instance sharing redis,
+	// subscribing before restoring so no switch in between is missed, as
+	// switches persist the generation before publishing it
+	if proxy.Generations.Enabled && proxy.Cache.RedisEnabled {
+		if c.generations, err = newGenerationWatch(c); err != nil {
+			return nil, ErrInitExternalCache{
+				Name: proxy.Name,
+				Err:  err,
 			}
 		}
+	}
 
-		// if present, don't delete
-		if present {
-			continue
+	// restore the active cache generation from a previous switch
+	if err = c.initGeneration(); err != nil {
+		c.generations.close()
+		return nil, ErrInitExternalCache{
+			Name: proxy.Name,
+			Err:  err,
 		}
-
-		// delete old cache if not present in current config
-		util.Info(str.CCache, str.MOldCacheDeleted, cacheName)
-		delete(Caches, cacheName)
 	}
-}
-
-// BuildInstance will build a cache instance by name
-func BuildInstance(name string) error {
-	// find and populate a new cache instance for the given name
-	for _, proxy := range config.Get().Proxies {
-		if proxy.Name == name {
-			var internal *bigcache.BigCache
-			var external *redis.Client
-			var err error
-
-			if proxy.Cache.MemEnabled {
-				internal, err = initInternal(proxy)
-				if err != nil {
-					return ErrInitInternalCache{
-						Name: proxy.Name,
-						Err:  err,
-					}
-				}
-			}
-
-			if proxy.Cache.RedisEnabled {
-				external, err = initExternal(proxy)
-				if err != nil {
-					return ErrInitExternalCache{
-						Name: proxy.Name,
-						Err:  err,
-					}
-				}
-			}
 
-			// initialize metrics for this cache instance
-			metrics := initMetrics(proxy)
+	// restore the subtrees known to be empty upstream
+	if err = c.initSparse(); err != nil {
+		c.generations.close()
+		return nil, ErrInitExternalCache{
+			Name: proxy.Name,
+			Err:  err,
+		}
+	}
 
-			util.DebugFlag("cache", str.CCache, str.DCacheUp, name)
+	// restore the redis key namespace of the last scheduled flush
+	if err = c.initFlushEpoch(); err != nil {
+		c.generations.close()
+		return nil, ErrInitExternalCache{
+			Name: proxy.Name,
+			Err:  err,
+		}
+	}
 
-			Caches[name] = &Cache{
-				internal: internal,
-				external: external,
-				Proxy:    &proxy,
-				Metrics:  metrics,
-			}
+	// batch redis writes into pipelines if configured
+	if proxy.Cache.RedisEnabled && proxy.Cache.WriteBehind.Enabled {
+		c.writes = newWriteBehind(c)
+	}
 
-			return nil
-		}
+	// flush cache tiers on their schedule if configured
+	if proxy.ScheduledFlush.Enabled() {
+		c.flushes = newScheduledFlush(c)
 	}
 
-	return nil
+	return c, nil
 }
 
-// initInternal initializes an in-memory cache instance from proxy configuration
-func initInternal(proxy config.Proxy) (*bigcache.BigCache, error) {
-	maxEntrySize := 4
+// initInternal initializes an in-memory cache instance of the configured
+// engine, along with its shard counting hasher if bigcache stats are enabled
+func initInternal(proxy config.Proxy, memBytes *atomic.Int64) (memoryEngine, *shardHasher, error) {
+	if proxy.Cache.MemEngine == config.MemEngineRistretto {
+		internal, err := newRistretto(proxy, memBytes)
+		return internal, nil, err
+	}
+
+	tuning := proxy.Cache.Bigcache
+	maxEntrySize := 4 * OneMB
 
-	// allow override of MaxEntrySize via env var
+	// allow override of MaxEntrySize via env var, or per proxy
 	if max, present := os.LookupEnv("MAX_ENTRY_SIZE"); present {
 		if maxInt, err := strconv.Atoi(max); err == nil {
-			maxEntrySize = maxInt
+			maxEntrySize = maxInt * OneMB
 		} else {
 			util.Error(str.CCache, str.ECacheEntry, max)
 		}
 	}
+	if tuning.MaxEntrySize > 0 {
+		maxEntrySize = tuning.MaxEntrySize * 1024
+	}
 
 	conf := bigcache.DefaultConfig(proxy.Cache.MemTTLDuration)
-	conf.StatsEnabled = !env.IsProd()
-	conf.MaxEntrySize = OneMB * maxEntrySize
+	if tuning.Shards > 0 {
+		conf.Shards = tuning.Shards
+	}
+	if tuning.CleanWindow != "" {
+		conf.CleanWindow = tuning.CleanWindowDuration
+	}
+	if tuning.MaxEntriesInWindow > 0 {
+		conf.MaxEntriesInWindow = tuning.MaxEntriesInWindow
+	}
+	conf.StatsEnabled = tuning.Stats || !env.IsProd()
+	conf.Verbose = tuning.Verbose
+	conf.MaxEntrySize = maxEntrySize
 	conf.HardMaxCacheSize = proxy.Cache.MemCap
 
-	return bigcache.New(context.TODO(), conf)
+	var shards *shardHasher
+	if conf.StatsEnabled {
+		shards = newShardHasher(conf.Shards)
+		conf.Hasher = shards
+	}
+
+	internal, err := newBigcache(conf, memBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return internal, shards, nil
 }
 
 // initExternal initializes an external cache instance from proxy configuration
@@ -181,166 +292,469 @@ func initExternal(proxy config.Proxy) (*redis.Client, error) {
 	// ping Redis to verify connectivity
 	_, err := external.Ping(context.Background()).Result()
 
+	// inject Redis failures after connecting, so boot isn't affected
+	if proxy.Chaos.RedisErrorRate > 0 {
+		external.AddHook(chaosHook{name: proxy.Name, rate: proxy.Chaos.RedisErrorRate})
+	}
+
 	return external, err
 }
 
-// initMetrics for the given proxy configuration
-func initMetrics(proxy config.Proxy) *Metrics {
-	cacheHits := promauto.NewCounter(prometheus.CounterOpts{
+// initMetrics for the given proxy configuration, also exporting deprecated
+// metrics if legacy is set
+func initMetrics(proxy config.Proxy, legacy bool) *Metrics {
+	cacheHits := register(prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace: config.Namespace,
 		Subsystem: Subsystem,
-		Name:      "hit_total",
+		Name:      "hits_total",
 		ConstLabels: map[string]string{
 			"proxy": proxy.Name,
 		},
-		Help: "The total number of cache hits",
-	})
+		Help: "The total number of cache hits by the layer that served them",
+	}, []string{"layer"}))
+
+	// initialize both layers so rate() has a series to work with from boot
+	cacheHits.WithLabelValues(LayerMemory)
+	cacheHits.WithLabelValues(LayerRedis)
 
-	cacheMisses := promauto.NewCounter(prometheus.CounterOpts{
+	cacheMisses := register(prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: config.Namespace,
 		Subsystem: Subsystem,
-		Name:      "miss_total",
+		Name:      "misses_total",
 		ConstLabels: map[string]string{
 			"proxy": proxy.Name,
 		},
 		Help: "The total number of cache misses",
-	})
+	}))
 
-	hitRate := promauto.NewCounterFunc(prometheus.CounterOpts{
+	requestDuration := register(prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: config.Namespace,
-		Subsystem: Subsystem,
-		Name:      "hit_rate",
+		Subsystem: "proxy",
+		Name:      "request_duration_seconds",
 		ConstLabels: map[string]string{
 			"proxy": proxy.Name,
 		},
-		Help: "The rate of hits to misses",
-	}, func() float64 {
-		hits := util.GetMetricValue(cacheHits)
-		misses := util.GetMetricValue(cacheMisses)
-		return hits / (hits + misses)
-	})
+		Help:    "Tile request latency by cache status",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 15),
+	}, []string{"status"}))
 
-	return &Metrics{
-		CacheHits:   cacheHits,
-		CacheMisses: cacheMisses,
-		HitRate:     hitRate,
+	if legacy {
+		initLegacyMetrics(proxy, cacheHits, cacheMisses)
 	}
-}
 
-// Fetch will attempt to grab a tile by key from any of the cache layers,
-// populating higher layers of the cache if found.
-func (c *Cache) Fetch(key string, ctx *fiber.Ctx) *packet.TilePacket {
-	var cachedTile []byte
-	var err error
-	var hit string
+	invalidTiles := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "invalid_tiles_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of invalid vector tiles received from the upstream",
+	}, []string{"action"}))
 
-	// fetch from in-memory cache if enabled
-	if c.Proxy.Cache.MemEnabled {
-		cachedTile, err = c.internal.Get(key)
-		if err != nil {
-			if err == bigcache.ErrEntryNotFound {
-				util.DebugFlag("cache", str.CCache, str.DCacheMiss, key)
-			} else {
-				util.Error(str.CCache, str.ECacheFetch, key, err.Error())
-				return nil
-			}
-		}
+	adaptiveRefreshes := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "adaptive_refreshes_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of refetched tiles given adaptive TTLs, by whether they changed",
+	}, []string{"outcome"}))
 
-		hit = ":hit-i"
+	bytesServed := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "served_bytes_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of tile bytes served to clients by source",
+	}, []string{"source"}))
+
+	bytesUpstream := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "upstream_bytes_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of tile bytes received from the upstream",
+	}))
+
+	clientAborts := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: "proxy",
+		Name:      "client_aborts_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of requests aborted by the client before completion, by phase",
+	}, []string{"phase"}))
+
+	for _, phase := range []string{AbortQueue, AbortUpstream, AbortStream} {
+		clientAborts.WithLabelValues(phase)
 	}
 
-	// try fetching from redis if not present in internal cache
-	if cachedTile == nil && c.Proxy.Cache.RedisEnabled {
-		var redisTile *redis.StringCmd
+	policyHits := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "policy_hits_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of in-memory hits each simulated eviction policy would have served",
+	}, []string{"policy"}))
 
-		if c.Proxy.Cache.RedisTTLDuration > 0 {
-			// if TTL set, extend Redis TTL when we fetch a tile to prevent
-			// key expiry for tiles that are fetched periodically
-			redisTile = c.external.GetEx(ctx.Context(), key, c.Proxy.Cache.RedisTTLDuration)
-		} else {
-			// get and persist the key, meaning no expiry
-			redisTile = c.external.GetEx(ctx.Context(), key, 0)
-		}
+	for _, policy := range config.EvictionPolicies {
+		policyHits.WithLabelValues(policy)
+	}
+
+	policyLookups := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "policy_lookups_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of in-memory lookups seen by the simulated eviction policies",
+	}))
+
+	admissionRejects := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "admission_rejects_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of tiles kept out of the in-memory cache by the admission policy",
+	}))
 
-		if redisTile.Err() != nil {
-			if redisTile.Err() == redis.Nil {
-				// exit early if we don't have anything cached at any level
-				c.Metrics.CacheMisses.Inc()
-				util.DebugFlag("cache", str.CCache, str.DCacheMissExt, key)
-				return nil
+	pinnedTiles := register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "pinned_tiles",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The number of tiles pinned in memory as hot keys",
+	}))
+
+	clientClasses := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: "proxy",
+		Name:      "client_class_requests_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of requests by client class, bot or interactive",
+	}, []string{"class"}))
+
+	clientCountries := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: "proxy",
+		Name:      "client_country_requests_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of requests by client country, ISO 3166-1 alpha-2 code or unknown",
+	}, []string{"country"}))
+
+	sparseSkips := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "sparse_skips_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of requests answered as empty from subtrees known to be empty upstream",
+	}))
+
+	cacheOnlyMisses := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "only_misses_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of cache misses of cache-only zoom levels answered without the upstream",
+	}))
+
+	budgetExhaustions := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "budget_exhaustions_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of times the upstream request budget was spent, by window",
+	}, []string{"window"}))
+
+	budgetRejections := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "budget_rejections_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of cache misses answered without the upstream while the budget was spent",
+	}))
+
+	redisRetries := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "redis_retries_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of redis operations retried after transient errors, by operation",
+	}, []string{"op"}))
+
+	redisRetryFailures := register(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "redis_retry_failures_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of retried redis operations still failing after all retries, by operation",
+	}, []string{"op"}))
+
+	panics := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: "proxy",
+		Name:      "panics_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of panics recovered from while handling requests",
+	}))
+
+	redisWriteBatches := register(prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "redis_write_batch_size",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help:    "The number of redis writes sent per write-behind pipeline",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+	}))
+
+	redisSharedLookups := register(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "redis_shared_lookups_total",
+		ConstLabels: map[string]string{
+			"proxy": proxy.Name,
+		},
+		Help: "The total number of redis lookups sharing a GET with concurrent lookups of the same key",
+	}))
+
+	return &Metrics{
+		CacheHits:          cacheHits,
+		CacheMisses:        cacheMisses,
+		RequestDuration:    requestDuration,
+		InvalidTiles:       invalidTiles,
+		AdaptiveRefreshes:  adaptiveRefreshes,
+		BytesServed:        bytesServed,
+		BytesUpstream:      bytesUpstream,
+		ClientAborts:       clientAborts,
+		PolicyHits:         policyHits,
+		PolicyLookups:      policyLookups,
+		AdmissionRejects:   admissionRejects,
+		PinnedTiles:        pinnedTiles,
+		ClientClasses:      clientClasses,
+		ClientCountries:    clientCountries,
+		SparseSkips:        sparseSkips,
+		CacheOnlyMisses:    cacheOnlyMisses,
+		BudgetExhaustions:  budgetExhaustions,
+		BudgetRejections:   budgetRejections,
+		RedisRetries:       redisRetries,
+		RedisRetryFailures: redisRetryFailures,
+		Panics:             panics,
+		RedisWriteBatches:  redisWriteBatches,
+		RedisSharedLookups: redisSharedLookups,
+	}
+}
+
+// register registers a per-proxy metric, returning the one already registered
+// under the same name and labels instead when a proxy's cache is rebuilt
+func register[T prometheus.Collector](collector T) T {
+	if err := prometheus.Register(collector); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(T); ok {
+				return existing
 			}
-			util.Error(str.CCache, str.ECacheFetch, key, err.Error())
-			return nil
 		}
+		panic(err)
+	}
+	return collector
+}
 
-		// squeeze out the bytes from the redis response
-		cachedTile, err = redisTile.Bytes()
-		if err != nil {
-			util.Error(str.CCache, str.ECacheFetch, key, err.Error())
-			return nil
-		}
+// Fetch will attempt to grab a tile by key from any of the cache layers,
+// populating higher layers of the cache if found.
+func (c *Cache) Fetch(key string, ctx *fiber.Ctx) *packet.TilePacket {
+	log := util.Log(ctx)
 
-		hit = ":hit-e"
+	cachedTile, layer, err := c.lookup(key, ctx, log)
+	if err != nil {
+		log.Error(str.CCache, str.ECacheFetch, key, err.Error())
+		return nil
 	}
 
+	tile := c.accept(key, cachedTile, layer, ctx, log)
+	if tile == nil {
+		return nil
+	}
+
+	if layer == LayerRedis {
+		ctx.Locals(str.LocalCacheStatus, ":hit-e")
+	} else {
+		ctx.Locals(str.LocalCacheStatus, ":hit-i")
+	}
+
+	return tile
+}
+
+// accept validates tile data looked up in the given layer, returning it as a
+// TilePacket unless it is missing, corrupted or expired, and counts the hit
+// or miss
+func (c *Cache) accept(key string, cachedTile []byte, layer string, ctx *fiber.Ctx, log *util.Logger) *packet.TilePacket {
 	if cachedTile == nil {
 		// exit if we don't have anything cached at any level
 		c.Metrics.CacheMisses.Inc()
-		util.DebugFlag("cache", str.CCache, str.DCacheMissExt, key)
+		log.DebugFlag("cache", str.CCache, str.DCacheMissExt, key)
 		return nil
 	}
 
-	ctx.Locals(str.LocalCacheStatus, hit)
-	c.Metrics.CacheHits.Inc()
-
 	// wrap bytes in TilePacket container
 	tile, err := packet.FromBytes(cachedTile, key)
 	if err != nil {
 		// exit early and wipe cache if we cached a bad value
-		util.Error(str.CCache, str.ECacheFetch, key, err.Error())
+		log.Error(str.CCache, str.ECacheFetch, key, err.Error())
 		err = c.Invalidate(key, ctx.Context())
 		if err != nil {
-			util.Error(str.CCache, str.ECacheDelete, key, err.Error())
+			log.Error(str.CCache, str.ECacheDelete, key, err.Error())
 		}
 		return nil
 	}
 
-	util.DebugFlag("cache", str.CCache, str.DCacheHit, key, tile.TileDataSize())
+	// serve stale tiles rather than refetching them while the upstream request
+	// budget is spent
+	if _, spent := c.BudgetExhausted(time.Now()); !spent {
+		if miss, reason := c.expired(*tile); miss {
+			c.Metrics.CacheMisses.Inc()
+			log.DebugFlag("cache", str.CCache, reason, key)
+			return nil
+		}
+	}
+
+	c.Metrics.CacheHits.WithLabelValues(layer).Inc()
+
+	log.DebugFlag("cache", str.CCache, str.DCacheHit, key, tile.TileDataSize())
 
 	// extend internal cache TTL (keeping entry alive) by resetting the entry
-	// this also sets internal cache entries if we find a tile in redis but not internally
+	// this also sets internal cache entries if we find a tile in redis but not internally,
+	// unless the request's tiles are kept out of memory
 	// TODO investigate alternative methods of preventing entry death
-	go c.Set(key, *tile, true)
+	if ctx.Locals(str.LocalSkipMemory) != true {
+		go c.Set(key, *tile, true)
+	}
 
 	return tile
 }
 
+// expired returns true along with the debug message to log if a cached tile
+// should be refetched: if it is older than the configured max staleness,
+// including tiles cached before their age was recorded, is refetched early, or
+// is past the lifetime its upstream set for it or adapted to its changes
+func (c *Cache) expired(tile packet.TilePacket) (bool, string) {
+	if c.Proxy.Cache.MaxStaleDuration > 0 {
+		if created, ok := tile.Created(); !ok || time.Since(created) > c.Proxy.Cache.MaxStaleDuration {
+			return true, str.DCacheStale
+		} else if c.Proxy.Cache.XFetch && c.expiresEarly(time.Since(created)) {
+			return true, str.DCacheEarly
+		}
+	}
+
+	if expires, ok := tile.Expires(); ok && !time.Now().Before(expires) {
+		return true, str.DCacheExpired
+	}
+	if expires, ok := tile.AdaptiveExpires(); ok && !time.Now().Before(expires) {
+		return true, str.DCacheAdaptiveExpired
+	}
+	return false, ""
+}
+
 // EncodeSet will encode tile data into a TilePacket and then set the cache
-// entry to the specified key
-func (c *Cache) EncodeSet(key string, tileData []byte, headers map[string]string) {
-	tilePacket := packet.Encode(tileData, headers)
-	c.Set(key, tilePacket)
+// entry to the specified key, only in Redis if skipMemory is set
+func (c *Cache) EncodeSet(key string, tileData []byte, headers map[string]string, skipMemory ...bool) {
+	// stamp the fetch time so tile age can be reported and bounded, copying
+	// since the caller may still be reading its headers
+	stamped := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		stamped[k] = v
+	}
+	stamped[packet.HeaderCreated] = strconv.FormatInt(time.Now().Unix(), 10)
+	stamped = c.withAdaptiveTTL(key, tileData, stamped)
+
+	tilePacket := packet.Encode(tileData, stamped)
+	c.set(key, tilePacket, len(skipMemory) == 0 || !skipMemory[0], true)
 }
 
 // Set the tile in all cache levels with the configured TTLs
 func (c *Cache) Set(key string, tile packet.TilePacket, internalOnly ...bool) {
+	c.set(key, tile, true, len(internalOnly) == 0 || !internalOnly[0])
+}
+
+// set the tile in the in-memory and external caches, as requested and enabled
+func (c *Cache) set(key string, tile packet.TilePacket, memory, external bool) {
+	// tiles whose upstream lifetime has passed aren't cached at all
+	ttl, alive := c.writeTTL(tile)
+	if !alive {
+		util.DebugFlag("cache", str.CCache, str.DCacheExpired, key)
+		return
+	}
+
 	util.DebugFlag("cache", str.CCache, str.DCacheSet, key, len(tile))
 
-	// set in external cache if enabled and allowed
-	if (len(internalOnly) == 0 || !internalOnly[0]) && c.Proxy.Cache.RedisEnabled {
-		go func() {
-			status := c.external.Set(context.Background(), key,
-				tile.Raw(), c.Proxy.Cache.RedisTTLDuration)
-			if status.Err() != nil {
-				util.Error(str.CCache, str.ECacheSet, key, status.Err())
-			}
-		}()
+	// set in external cache if enabled and allowed, never in read-only mode,
+	// batching the write if write-behind is enabled
+	if external && c.Proxy.Cache.RedisEnabled && !config.IsReadOnly() {
+		write := pendingWrite{key: c.redisKey(key), value: tile.Raw(), ttl: ttl}
+		if c.indexed(key) {
+			write.index, write.orig = c.indexKey(write.key), key
+		}
+
+		if !c.writes.enqueue(write) {
+			go func() {
+				ctx := context.Background()
+				err := c.withRetry(ctx, RedisOpSet, func() error {
+					if write.index == "" {
+						return c.external.Set(ctx, write.key, write.value, ttl).Err()
+					}
+					_, err := c.external.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+						pipe.Set(ctx, write.key, write.value, ttl)
+						pipe.Set(ctx, write.index, write.orig, ttl)
+						return nil
+					})
+					return err
+				})
+				if err != nil {
+					util.Error(str.CCache, str.ECacheSet, key, err)
+				}
+			}()
+		}
 	}
 
 	// set in the in-memory cache if enabled
-	if c.Proxy.Cache.MemEnabled {
+	if memory && c.Proxy.Cache.MemEnabled {
 		err := c.internal.Set(key, tile)
-		if err != nil {
+		if errors.Is(err, errDropped) {
+			util.DebugFlag("cache", str.CCache, str.DCacheDropped, key)
+		} else if err != nil {
 			util.Error(str.CCache, str.ECacheSet, key, err.Error())
 		}
 	}
@@ -356,10 +770,21 @@ func (c *Cache) Invalidate(key string, ctx context.Context) error {
 		}
 	}
 
-	if c.Proxy.Cache.RedisEnabled {
-		status := c.external.Del(ctx, key)
-		if status.Err() != nil {
-			return status.Err()
+	// leave redis untouched in read-only mode
+	if c.Proxy.Cache.RedisEnabled && !config.IsReadOnly() {
+		// send batched writes of the tile first, or they would restore it
+		c.writes.sync()
+
+		keys := []string{c.redisKey(key)}
+		if c.indexed(key) {
+			keys = append(keys, c.indexKey(keys[0]))
+		}
+
+		err := c.withRetry(ctx, RedisOpDelete, func() error {
+			return c.external.Del(ctx, keys...).Err()
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -374,10 +799,12 @@ func (c *Cache) FlushInternal() error {
 	return nil
 }
 
-// StatsInternal returns stats about the internal bigcache instance
-func (c *Cache) StatsInternal() bigcache.Stats {
-	if c.Proxy.Cache.MemEnabled {
-		return c.internal.Stats()
-	}
-	return bigcache.Stats{}
+// SetMaintenance toggles maintenance mode for the proxy this cache serves
+func (c *Cache) SetMaintenance(enabled bool) {
+	c.maintenance.Store(enabled)
+}
+
+// InMaintenance returns true if the proxy this cache serves is in maintenance mode
+func (c *Cache) InMaintenance() bool {
+	return c.maintenance.Load()
 }
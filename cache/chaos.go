@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// chaosHook fails Redis commands at a proxy's configured error rate before
+// they are sent
+type chaosHook struct {
+	name string
+	rate float64
+}
+
+// fail returns an injected error at the configured rate
+func (h chaosHook) fail() error {
+	if rand.Float64() < h.rate {
+		return ErrChaosRedis{ProxyName: h.name}
+	}
+	return nil
+}
+
+// BeforeProcess fails single commands at the configured rate
+func (h chaosHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, h.fail()
+}
+
+// AfterProcess is a no-op
+func (h chaosHook) AfterProcess(_ context.Context, _ redis.Cmder) error {
+	return nil
+}
+
+// BeforeProcessPipeline fails whole pipelines at the configured rate
+func (h chaosHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, h.fail()
+}
+
+// AfterProcessPipeline is a no-op
+func (h chaosHook) AfterProcessPipeline(_ context.Context, _ []redis.Cmder) error {
+	return nil
+}
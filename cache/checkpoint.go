@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+const (
+	// checkpointTTL bounds how long the progress of an interrupted seed job
+	// is kept for it to resume
+	checkpointTTL = 7 * 24 * time.Hour
+	// checkpointInterval bounds how often the progress of a seed job is saved
+	checkpointInterval = time.Second
+)
+
+// Checkpoint is the persisted progress of a seed job, counting the tiles
+// completed in order at each zoom level so an interrupted job can resume
+// after them rather than starting over
+type Checkpoint struct {
+	ID      string      `json:"id"`      // ID of the seed job
+	Job     string      `json:"job"`     // description of the tiles seeded, ex: the admin request path
+	Total   int         `json:"total"`   // number of tiles seeded by the job
+	Done    map[int]int `json:"done"`    // number of tiles completed in order, by zoom level
+	Percent float64     `json:"percent"` // share of tiles completed, 0-100
+	Updated time.Time   `json:"updated"` // time progress was last saved
+}
+
+// Completed returns the number of tiles completed in order across all zooms
+func (cp Checkpoint) Completed() int {
+	completed := 0
+	for _, done := range cp.Done {
+		completed += done
+	}
+	return completed
+}
+
+// checkpointKey returns the Redis key of the hash persisting a seed job's progress
+func (c *Cache) checkpointKey(id string) string {
+	return fmt.Sprintf("%s:seed:%s:%s", config.Namespace, c.Proxy.Name, id)
+}
+
+// checkpointsKey returns the Redis key of the set tracking the proxy's seed
+// jobs with persisted progress
+func (c *Cache) checkpointsKey() string {
+	return fmt.Sprintf("%s:seeds:%s", config.Namespace, c.Proxy.Name)
+}
+
+// LoadCheckpoint returns the persisted progress of the seed job with the
+// given ID, nil if there is none or the redis cache is disabled
+func (c *Cache) LoadCheckpoint(ctx context.Context, id string) (*Checkpoint, error) {
+	if !c.Proxy.Cache.RedisEnabled {
+		return nil, nil
+	}
+
+	fields, err := c.external.HGetAll(ctx, c.checkpointKey(id)).Result()
+	if err != nil || len(fields) == 0 {
+		return nil, err
+	}
+
+	cp := &Checkpoint{ID: id, Job: fields["job"], Done: make(map[int]int)}
+	cp.Total, _ = strconv.Atoi(fields["total"])
+	if updated, errUpdated := strconv.ParseInt(fields["updated"], 10, 64); errUpdated == nil {
+		cp.Updated = time.Unix(updated, 0)
+	}
+	for field, value := range fields {
+		if strings.HasPrefix(field, "z:") {
+			z, errZoom := strconv.Atoi(strings.TrimPrefix(field, "z:"))
+			done, errDone := strconv.Atoi(value)
+			if errZoom == nil && errDone == nil {
+				cp.Done[z] = done
+			}
+		}
+	}
+	if cp.Total > 0 {
+		cp.Percent = float64(cp.Completed()) / float64(cp.Total) * 100
+	}
+	return cp, nil
+}
+
+// ResumeCheckpoint returns the persisted progress of the seed job with the
+// given ID to resume it from, describing the job's tiles and total, or fresh
+// progress if there is none. Persisted progress is dropped instead if asked
+// to restart the job. Returns nil if the redis cache is disabled.
+func (c *Cache) ResumeCheckpoint(ctx context.Context, id, job string, total int, restart bool) (*Checkpoint, error) {
+	if !c.Proxy.Cache.RedisEnabled {
+		return nil, nil
+	}
+
+	var cp *Checkpoint
+	var err error
+	if restart {
+		err = c.DropCheckpoint(ctx, id)
+	} else {
+		cp, err = c.LoadCheckpoint(ctx, id)
+	}
+
+	if cp == nil {
+		cp = &Checkpoint{ID: id, Done: make(map[int]int)}
+	}
+	cp.Job = job
+	cp.Total = total
+	return cp, err
+}
+
+// SaveCheckpoint persists the progress of a seed job, unless the redis cache
+// is disabled or the instance is read-only
+func (c *Cache) SaveCheckpoint(ctx context.Context, cp Checkpoint) error {
+	if !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return nil
+	}
+
+	fields := []interface{}{"job", cp.Job, "total", cp.Total, "updated", time.Now().Unix()}
+	for zoom, done := range cp.Done {
+		fields = append(fields, "z:"+strconv.Itoa(zoom), done)
+	}
+
+	pipe := c.external.TxPipeline()
+	pipe.HSet(ctx, c.checkpointKey(cp.ID), fields...)
+	pipe.Expire(ctx, c.checkpointKey(cp.ID), checkpointTTL)
+	pipe.SAdd(ctx, c.checkpointsKey(), cp.ID)
+	pipe.Expire(ctx, c.checkpointsKey(), checkpointTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DropCheckpoint removes the persisted progress of a seed job, once it
+// completed or to start it over
+func (c *Cache) DropCheckpoint(ctx context.Context, id string) error {
+	if !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return nil
+	}
+
+	pipe := c.external.TxPipeline()
+	pipe.Del(ctx, c.checkpointKey(id))
+	pipe.SRem(ctx, c.checkpointsKey(), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Checkpoints returns the persisted progress of all seed jobs of the proxy
+// that are running or were interrupted, most recently updated first
+func (c *Cache) Checkpoints(ctx context.Context) ([]Checkpoint, error) {
+	checkpoints := make([]Checkpoint, 0)
+	if !c.Proxy.Cache.RedisEnabled {
+		return checkpoints, nil
+	}
+
+	ids, err := c.external.SMembers(ctx, c.checkpointsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		cp, errLoad := c.LoadCheckpoint(ctx, id)
+		if errLoad != nil {
+			return nil, errLoad
+		}
+		if cp != nil {
+			checkpoints = append(checkpoints, *cp)
+		}
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Updated.After(checkpoints[j].Updated)
+	})
+	return checkpoints, nil
+}
+
+// CheckpointProgress tracks the tiles completed by a seed job, advancing the
+// checkpoint of each zoom level past tiles completed in order and saving it
+// at most every checkpointInterval. A nil CheckpointProgress tracks nothing.
+type CheckpointProgress struct {
+	mu        sync.Mutex
+	cache     *Cache
+	cp        Checkpoint
+	completed map[int]map[int]bool // tiles completed out of order, by zoom and index
+	saved     time.Time
+}
+
+// NewCheckpointProgress returns progress tracking resuming from a copy of the
+// given checkpoint, nil if there is none
+func NewCheckpointProgress(c *Cache, cp *Checkpoint) *CheckpointProgress {
+	if cp == nil {
+		return nil
+	}
+
+	tracked := *cp
+	tracked.Done = make(map[int]int, len(cp.Done))
+	for zoom, done := range cp.Done {
+		tracked.Done[zoom] = done
+	}
+
+	return &CheckpointProgress{
+		cache:     c,
+		cp:        tracked,
+		completed: make(map[int]map[int]bool),
+		saved:     time.Now(),
+	}
+}
+
+// Complete records the completed tile at the given position among the tiles
+// of its zoom level, saving the checkpoint if it is due
+func (p *CheckpointProgress) Complete(zoom, index int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.completed[zoom] == nil {
+		p.completed[zoom] = make(map[int]bool)
+	}
+	p.completed[zoom][index] = true
+	for p.completed[zoom][p.cp.Done[zoom]] {
+		delete(p.completed[zoom], p.cp.Done[zoom])
+		p.cp.Done[zoom]++
+	}
+
+	if time.Since(p.saved) >= checkpointInterval {
+		p.save()
+	}
+}
+
+// Finish drops the checkpoint if every tile was completed, otherwise saves it
+// so the job resumes after the tiles completed in order
+func (p *CheckpointProgress) Finish(succeeded bool) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if succeeded {
+		if err := p.cache.DropCheckpoint(context.Background(), p.cp.ID); err != nil {
+			util.Error(str.CCache, str.ECacheCheckpoint, p.cache.Proxy.Name, p.cp.ID, err.Error())
+		}
+		return
+	}
+	p.save()
+}
+
+// save persists the checkpoint, must be called with the lock held
+func (p *CheckpointProgress) save() {
+	p.saved = time.Now()
+	if err := p.cache.SaveCheckpoint(context.Background(), p.cp); err != nil {
+		util.Error(str.CCache, str.ECacheCheckpoint, p.cache.Proxy.Name, p.cp.ID, err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestCheckpoint will test that seed job progress is persisted, loaded with
+// its percent complete, listed and dropped
+func TestCheckpoint(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{
+		Name:  "seed",
+		Cache: config.Cache{RedisEnabled: true},
+	}
+	c := &Cache{
+		Proxy:    &proxy,
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+	ctx := context.Background()
+
+	cp, err := c.LoadCheckpoint(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp != nil {
+		t.Errorf(str.TCacheBadCheckpoint, cp, nil)
+	}
+
+	saved := Checkpoint{ID: "a", Job: "/prime/deep/0/0/0/2", Total: 21, Done: map[int]int{0: 1, 1: 4, 2: 2}}
+	if err = c.SaveCheckpoint(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.SaveCheckpoint(ctx, Checkpoint{ID: "b", Total: 5, Done: map[int]int{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err = c.LoadCheckpoint(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp == nil || cp.Job != saved.Job || cp.Total != saved.Total ||
+		!reflect.DeepEqual(cp.Done, saved.Done) || cp.Percent != float64(7)/21*100 {
+		t.Errorf(str.TCacheBadCheckpoint, cp, saved)
+	}
+
+	checkpoints, err := c.Checkpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 2 {
+		t.Errorf(str.TCacheBadCheckpoint, checkpoints, "2 checkpoints")
+	}
+
+	if err = c.DropCheckpoint(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	checkpoints, err = c.Checkpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].ID != "b" || checkpoints[0].Percent != 0 {
+		t.Errorf(str.TCacheBadCheckpoint, checkpoints, "checkpoint b")
+	}
+}
+
+// TestCheckpointProgress will test that tiles completed out of order only
+// advance the checkpoint once every tile before them completed, and that
+// finished progress is kept only for jobs that didn't complete every tile
+func TestCheckpointProgress(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{
+		Name:  "seed",
+		Cache: config.Cache{RedisEnabled: true},
+	}
+	c := &Cache{
+		Proxy:    &proxy,
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+	ctx := context.Background()
+
+	cp, err := c.ResumeCheckpoint(ctx, "a", "/prime/deep/0/0/0/1", 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := NewCheckpointProgress(c, cp)
+	progress.Complete(0, 0)
+	progress.Complete(1, 1)
+	progress.Complete(1, 3)
+	progress.Finish(false)
+
+	cp, err = c.ResumeCheckpoint(ctx, "a", "/prime/deep/0/0/0/1", 5, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[int]int{0: 1}; cp == nil || !reflect.DeepEqual(cp.Done, expected) {
+		t.Errorf(str.TCacheBadCheckpoint, cp, expected)
+	}
+
+	progress = NewCheckpointProgress(c, cp)
+	for i := 0; i < 4; i++ {
+		progress.Complete(1, i)
+	}
+	progress.Finish(true)
+
+	if cp, err = c.LoadCheckpoint(ctx, "a"); err != nil || cp != nil {
+		t.Errorf(str.TCacheBadCheckpoint, cp, nil)
+	}
+
+	// restarting drops progress, and nothing is tracked without redis
+	if err = c.SaveCheckpoint(ctx, Checkpoint{ID: "b", Total: 5, Done: map[int]int{0: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err = c.ResumeCheckpoint(ctx, "b", "", 5, true); err != nil || cp.Completed() != 0 {
+		t.Errorf(str.TCacheBadCheckpoint, cp, "no progress")
+	}
+	proxy.Cache.RedisEnabled = false
+	if cp, _ = c.ResumeCheckpoint(ctx, "b", "", 5, false); cp != nil || NewCheckpointProgress(c, cp) != nil {
+		t.Errorf(str.TCacheBadCheckpoint, cp, nil)
+	}
+}
@@ -0,0 +1,17 @@
+package cache
+
+import "github.com/dechristopher/lod/config"
+
+// Coverage returns the zoom levels and bounds the proxy serves tiles for,
+// as configured or as discovered from the upstream since
+func (c *Cache) Coverage() config.Coverage {
+	if coverage, ok := c.coverage.Load().(config.Coverage); ok {
+		return coverage
+	}
+	return c.Proxy.Coverage
+}
+
+// SetCoverage replaces the zoom levels and bounds the proxy serves tiles for
+func (c *Cache) SetCoverage(coverage config.Coverage) {
+	c.coverage.Store(coverage)
+}
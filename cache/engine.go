@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/cespare/xxhash/v2"
+	"github.com/dgraph-io/ristretto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// memoryEngine stores tiles in memory. Engines report misses with
+// bigcache.ErrEntryNotFound and keep the cache's byte count of the entries
+// they hold, net of overwritten, deleted and evicted ones, keeping the cache
+// engine agnostic.
+type memoryEngine interface {
+	Get(key string) ([]byte, error)
+	Set(key string, entry []byte) error
+	Delete(key string) error
+	Reset() error
+	Len() int
+	Stats() bigcache.Stats
+}
+
+// errDropped is returned by engines that dropped a write under contention
+// or by their admission policy
+var errDropped = errors.New("entry dropped by memory engine")
+
+// sizeStripes is the number of independently locked stripes the bigcache
+// engine tracks entry sizes in
+const sizeStripes = 64
+
+// bigcacheEngine stores tiles in bigcache, tracking the size held under each
+// key so overwritten entries are subtracted from the byte count, which
+// bigcache only reports deleted and evicted entries for
+type bigcacheEngine struct {
+	*bigcache.BigCache
+	memBytes *atomic.Int64
+	stripes  [sizeStripes]sizeStripe
+}
+
+// sizeStripe holds the sizes of entries by the hash of their key
+type sizeStripe struct {
+	mu    sync.Mutex
+	sizes map[uint64]int
+}
+
+// newBigcache builds a bigcache engine from bigcache configuration
+func newBigcache(conf bigcache.Config, memBytes *atomic.Int64) (*bigcacheEngine, error) {
+	b := &bigcacheEngine{memBytes: memBytes}
+	for i := range b.stripes {
+		b.stripes[i].sizes = make(map[uint64]int)
+	}
+
+	// called with the shard locked, so stripes must never be locked around
+	// calls into bigcache
+	conf.OnRemove = func(key string, _ []byte) {
+		b.track(key, 0)
+	}
+
+	cache, err := bigcache.New(context.TODO(), conf)
+	if err != nil {
+		return nil, err
+	}
+	b.BigCache = cache
+	return b, nil
+}
+
+// Set a tile by key, replacing the size of any entry it overwrites
+func (b *bigcacheEngine) Set(key string, entry []byte) error {
+	if err := b.BigCache.Set(key, entry); err != nil {
+		return err
+	}
+	b.track(key, len(entry))
+	return nil
+}
+
+// Reset removes all tiles, which bigcache reports no removals for
+func (b *bigcacheEngine) Reset() error {
+	err := b.BigCache.Reset()
+	for i := range b.stripes {
+		s := &b.stripes[i]
+		s.mu.Lock()
+		for _, size := range s.sizes {
+			b.memBytes.Add(-int64(size))
+		}
+		s.sizes = make(map[uint64]int)
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// track records the size held under a key, zero once removed, adding the
+// difference to the byte count
+func (b *bigcacheEngine) track(key string, size int) {
+	hash := xxhash.Sum64String(key)
+	s := &b.stripes[hash%sizeStripes]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b.memBytes.Add(int64(size - s.sizes[hash]))
+	if size == 0 {
+		delete(s.sizes, hash)
+	} else {
+		s.sizes[hash] = size
+	}
+}
+
+// ristrettoEngine stores tiles in ristretto, admitting them by access
+// frequency and evicting by cost within the configured capacity
+type ristrettoEngine struct {
+	cache    *ristretto.Cache
+	ttl      time.Duration
+	memBytes *atomic.Int64
+}
+
+// newRistretto builds a ristretto engine from proxy configuration
+func newRistretto(proxy config.Proxy, memBytes *atomic.Int64) (*ristrettoEngine, error) {
+	cache, err := ristretto.NewCache(&ristretto.Config{
+		NumCounters: proxy.Cache.Ristretto.Counters,
+		MaxCost:     int64(proxy.Cache.MemCap) * OneMB,
+		BufferItems: 64,
+		Metrics:     true,
+		OnExit: func(val interface{}) {
+			if entry, ok := val.([]byte); ok {
+				memBytes.Add(-int64(len(entry)))
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ristrettoEngine{cache: cache, ttl: proxy.Cache.MemTTLDuration, memBytes: memBytes}, nil
+}
+
+// Get a tile by key
+func (r *ristrettoEngine) Get(key string) ([]byte, error) {
+	if val, ok := r.cache.Get(key); ok {
+		if entry, ok := val.([]byte); ok {
+			return entry, nil
+		}
+	}
+	return nil, bigcache.ErrEntryNotFound
+}
+
+// Set a tile by key, costing its size. Writes are applied asynchronously and
+// may still be rejected by the admission policy after returning, which
+// subtracts them again along with overwritten entries.
+func (r *ristrettoEngine) Set(key string, entry []byte) error {
+	if !r.cache.SetWithTTL(key, entry, int64(len(entry)), r.ttl) {
+		return errDropped
+	}
+	r.memBytes.Add(int64(len(entry)))
+	return nil
+}
+
+// Delete a tile by key
+func (r *ristrettoEngine) Delete(key string) error {
+	r.cache.Del(key)
+	return nil
+}
+
+// Reset removes all tiles
+func (r *ristrettoEngine) Reset() error {
+	r.cache.Clear()
+	return nil
+}
+
+// Len returns the approximate number of cached tiles
+func (r *ristrettoEngine) Len() int {
+	m := r.cache.Metrics
+	return int(m.KeysAdded() - m.KeysEvicted())
+}
+
+// Stats returns hit and miss counts, ristretto resolves key collisions with a
+// second hash so none are reported
+func (r *ristrettoEngine) Stats() bigcache.Stats {
+	return bigcache.Stats{
+		Hits:   int64(r.cache.Metrics.Hits()),
+		Misses: int64(r.cache.Metrics.Misses()),
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+const (
+	benchTiles    = 20000    // distinct tiles requested
+	benchTileSize = 2 * 1024 // bytes per tile
+)
+
+// BenchmarkEngineHitRate compares the hit rates of the in-memory engines and
+// eviction policies on a skewed tile workload, with around a tenth of the
+// tiles fitting in memory
+func BenchmarkEngineHitRate(b *testing.B) {
+	for _, engine := range []string{config.MemEngineBigcache, config.MemEngineRistretto} {
+		for _, eviction := range append([]string{""}, config.EvictionPolicies...) {
+			benchmarkHitRate(b, engine, eviction)
+		}
+	}
+}
+
+// benchmarkHitRate runs the hit rate benchmark for an engine and eviction
+// policy, the engine's own if empty
+func benchmarkHitRate(b *testing.B, engine, eviction string) {
+	name := engine
+	if eviction != "" {
+		name += "/" + eviction
+	}
+
+	b.Run(name, func(b *testing.B) {
+		proxy := config.Proxy{Cache: config.Cache{
+			MemEnabled:     true,
+			MemCap:         4,
+			MemTTLDuration: time.Hour,
+			MemEngine:      engine,
+			Eviction:       eviction,
+			Bigcache:       config.Bigcache{Shards: 64, MaxEntrySize: 4},
+			Ristretto:      config.Ristretto{Counters: benchTiles},
+		}}
+
+		internal, _, err := initInternal(proxy, &atomic.Int64{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		internal = withEviction(internal, proxy, nil)
+
+		zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, benchTiles-1)
+		tile := make([]byte, benchTileSize)
+		hits := 0
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			key := strconv.FormatUint(zipf.Uint64(), 10)
+			if _, err = internal.Get(key); err == nil {
+				hits++
+				continue
+			}
+			_ = internal.Set(key, tile)
+		}
+
+		b.ReportMetric(float64(hits)/float64(b.N)*100, "hit%")
+	})
+}
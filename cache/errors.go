@@ -1,6 +1,9 @@
 package cache
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ErrBuildInstance is an error struct for errors
 // encountered during a proxy's cache initialization
@@ -37,3 +40,82 @@ type ErrInitExternalCache struct {
 func (e ErrInitExternalCache) Error() string {
 	return fmt.Sprintf("cache: failed to init external cache for '%s', got error %s", e.Name, e.Err.Error())
 }
+
+// ErrGenerationsDisabled is an error struct for generation switches against
+// a proxy without cache generations enabled
+type ErrGenerationsDisabled struct {
+	Name string
+}
+
+// Error returns the string representation of ErrGenerationsDisabled
+func (e ErrGenerationsDisabled) Error() string {
+	return fmt.Sprintf("cache: generations are not enabled for proxy '%s'", e.Name)
+}
+
+// ErrInvalidGeneration is an error struct for switches to a generation other
+// than blue or green
+type ErrInvalidGeneration struct {
+	Name       string
+	Generation string
+}
+
+// Error returns the string representation of ErrInvalidGeneration
+func (e ErrInvalidGeneration) Error() string {
+	return fmt.Sprintf("cache: invalid generation '%s' for proxy '%s'", e.Generation, e.Name)
+}
+
+// ErrKeyIndexDisabled is an error struct for lookups of hashed keys against
+// a proxy without the key hashing debug index enabled
+type ErrKeyIndexDisabled struct {
+	Name string
+}
+
+// Error returns the string representation of ErrKeyIndexDisabled
+func (e ErrKeyIndexDisabled) Error() string {
+	return fmt.Sprintf("cache: key hashing debug index is not enabled for proxy '%s'", e.Name)
+}
+
+// ErrChaosRedis is an error struct for Redis commands deliberately failed by
+// a proxy's fault injection
+type ErrChaosRedis struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrChaosRedis
+func (e ErrChaosRedis) Error() string {
+	return fmt.Sprintf("chaos: injected redis failure for proxy '%s'", e.ProxyName)
+}
+
+// ErrBudgetSpent is an error struct for upstream requests not made because
+// the proxy's upstream request budget is spent
+type ErrBudgetSpent struct {
+	ProxyName  string
+	RetryAfter time.Duration
+}
+
+// Error returns the string representation of ErrBudgetSpent
+func (e ErrBudgetSpent) Error() string {
+	return fmt.Sprintf("cache: upstream request budget of proxy '%s' spent for %s", e.ProxyName, e.RetryAfter)
+}
+
+// ErrClosed is an error struct for background jobs started against a cache
+// instance that has already been closed, ex: dropped on a config reload
+type ErrClosed struct {
+	Name string
+}
+
+// Error returns the string representation of ErrClosed
+func (e ErrClosed) Error() string {
+	return fmt.Sprintf("cache: instance of proxy '%s' is closed", e.Name)
+}
+
+// ErrReadOnly is an error struct for operations that must write to Redis
+// against a proxy while the instance is in read-only mode
+type ErrReadOnly struct {
+	Name string
+}
+
+// Error returns the string representation of ErrReadOnly
+func (e ErrReadOnly) Error() string {
+	return fmt.Sprintf("cache: proxy '%s' can not be modified in read-only mode", e.Name)
+}
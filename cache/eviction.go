@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// share of mem_cap managed by an eviction policy, leaving headroom so the
+// engine's own eviction doesn't kick in first
+const evictionHeadroom = 0.8
+
+// evictionIndex tracks the keys and sizes of cached tiles, choosing which to
+// evict to stay within capacity according to an eviction policy
+type evictionIndex struct {
+	mu       sync.Mutex
+	policy   string
+	capacity int64
+	used     int64
+	seq      uint64
+	entries  map[string]*indexEntry
+	order    *list.List // LRU and FIFO victims, next victim first
+	lfu      lfuHeap    // LFU victims, next victim first
+}
+
+type indexEntry struct {
+	key   string
+	size  int64
+	freq  uint64
+	seq   uint64
+	elem  *list.Element
+	index int
+}
+
+// newEvictionIndex returns an empty index of the given policy and capacity
+// in bytes
+func newEvictionIndex(policy string, capacity int64) *evictionIndex {
+	return &evictionIndex{
+		policy:   policy,
+		capacity: capacity,
+		entries:  make(map[string]*indexEntry),
+		order:    list.New(),
+	}
+}
+
+// access records a lookup of the key, returning true if it's indexed
+func (x *evictionIndex) access(key string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entry, ok := x.entries[key]
+	if !ok {
+		return false
+	}
+
+	switch x.policy {
+	case config.EvictionLRU:
+		x.order.MoveToBack(entry.elem)
+	case config.EvictionLFU:
+		entry.freq++
+		x.seq++
+		entry.seq = x.seq
+		heap.Fix(&x.lfu, entry.index)
+	}
+	return true
+}
+
+// insert indexes the key, or updates its size if indexed already, returning
+// the keys evicted to stay within capacity
+func (x *evictionIndex) insert(key string, size int64) []string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if entry, ok := x.entries[key]; ok {
+		x.used += size - entry.size
+		entry.size = size
+	} else {
+		x.seq++
+		entry = &indexEntry{key: key, size: size, freq: 1, seq: x.seq}
+		if x.policy == config.EvictionLFU {
+			heap.Push(&x.lfu, entry)
+		} else {
+			entry.elem = x.order.PushBack(entry)
+		}
+		x.entries[key] = entry
+		x.used += size
+	}
+
+	var evicted []string
+	for x.used > x.capacity && len(x.entries) > 1 {
+		evicted = append(evicted, x.evict())
+	}
+	return evicted
+}
+
+// evict removes the next victim, returning its key
+func (x *evictionIndex) evict() string {
+	var victim *indexEntry
+	if x.policy == config.EvictionLFU {
+		victim = x.lfu[0]
+	} else {
+		victim = x.order.Front().Value.(*indexEntry)
+	}
+	x.unlink(victim)
+	return victim.key
+}
+
+// remove the key from the index
+func (x *evictionIndex) remove(key string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if entry, ok := x.entries[key]; ok {
+		x.unlink(entry)
+	}
+}
+
+// unlink an entry from the index, with the lock held
+func (x *evictionIndex) unlink(entry *indexEntry) {
+	if x.policy == config.EvictionLFU {
+		heap.Remove(&x.lfu, entry.index)
+	} else {
+		x.order.Remove(entry.elem)
+	}
+	delete(x.entries, entry.key)
+	x.used -= entry.size
+}
+
+// reset empties the index
+func (x *evictionIndex) reset() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.entries = make(map[string]*indexEntry)
+	x.order.Init()
+	x.lfu = nil
+	x.used = 0
+}
+
+// lfuHeap orders entries by access count, then least recent access
+type lfuHeap []*indexEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*indexEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// policyEngine wraps a memory engine, evicting tiles by the configured policy
+// and simulating every policy to report the hit rate each would achieve
+type policyEngine struct {
+	memoryEngine
+	index   *evictionIndex            // enforced policy, nil to leave eviction to the engine
+	shadows map[string]*evictionIndex // simulated policies, empty unless enabled
+	hits    *prometheus.CounterVec    // simulated hits by policy
+	lookups prometheus.Counter        // lookups seen by the simulated policies
+}
+
+// withEviction wraps the engine with the proxy's eviction policy and policy
+// simulation, returning the engine as is if neither is configured
+func withEviction(engine memoryEngine, proxy config.Proxy, metrics *Metrics) memoryEngine {
+	if proxy.Cache.Eviction == "" && !proxy.Cache.EvictionShadow {
+		return engine
+	}
+
+	capacity := int64(float64(proxy.Cache.MemCap*OneMB) * evictionHeadroom)
+	p := &policyEngine{
+		memoryEngine: engine,
+		shadows:      make(map[string]*evictionIndex),
+	}
+	if proxy.Cache.Eviction != "" {
+		p.index = newEvictionIndex(proxy.Cache.Eviction, capacity)
+	}
+	if proxy.Cache.EvictionShadow {
+		p.hits, p.lookups = metrics.PolicyHits, metrics.PolicyLookups
+		for _, policy := range config.EvictionPolicies {
+			p.shadows[policy] = newEvictionIndex(policy, capacity)
+		}
+	}
+	return p
+}
+
+// Get a tile by key, untracking tiles the engine no longer holds
+func (p *policyEngine) Get(key string) ([]byte, error) {
+	if len(p.shadows) > 0 {
+		p.lookups.Inc()
+		for policy, shadow := range p.shadows {
+			if shadow.access(key) {
+				p.hits.WithLabelValues(policy).Inc()
+			}
+		}
+	}
+
+	entry, err := p.memoryEngine.Get(key)
+	if p.index != nil {
+		if err == nil {
+			p.index.access(key)
+		} else {
+			p.index.remove(key)
+		}
+	}
+	return entry, err
+}
+
+// Set a tile by key, evicting tiles chosen by the policy to make room
+func (p *policyEngine) Set(key string, entry []byte) error {
+	for _, shadow := range p.shadows {
+		shadow.insert(key, int64(len(entry)))
+	}
+
+	if err := p.memoryEngine.Set(key, entry); err != nil {
+		return err
+	}
+	if p.index != nil {
+		for _, victim := range p.index.insert(key, int64(len(entry))) {
+			_ = p.memoryEngine.Delete(victim)
+		}
+	}
+	return nil
+}
+
+// Delete a tile by key
+func (p *policyEngine) Delete(key string) error {
+	for _, shadow := range p.shadows {
+		shadow.remove(key)
+	}
+	if p.index != nil {
+		p.index.remove(key)
+	}
+	return p.memoryEngine.Delete(key)
+}
+
+// Reset removes all tiles
+func (p *policyEngine) Reset() error {
+	for _, shadow := range p.shadows {
+		shadow.reset()
+	}
+	if p.index != nil {
+		p.index.reset()
+	}
+	return p.memoryEngine.Reset()
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestEvictionIndex will test that each eviction policy picks the expected
+// victim once over capacity
+func TestEvictionIndex(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected string
+	}{
+		// a was accessed most recently, b is least recently used
+		{config.EvictionLRU, "b"},
+		// a was cached first regardless of accesses
+		{config.EvictionFIFO, "a"},
+		// a was accessed twice and c once, b is least frequently used
+		{config.EvictionLFU, "b"},
+	}
+
+	for _, test := range tests {
+		x := newEvictionIndex(test.policy, 30)
+		x.insert("a", 10)
+		x.insert("b", 10)
+		x.insert("c", 10)
+		x.access("a")
+		x.access("c")
+		x.access("a")
+
+		evicted := x.insert("d", 10)
+		if len(evicted) != 1 || evicted[0] != test.expected {
+			t.Errorf(str.TCacheBadEviction, test.policy, evicted, test.expected)
+		}
+		if x.used != 30 || len(x.entries) != 3 {
+			t.Errorf(str.TCacheBadEvictionUsage, test.policy, x.used, len(x.entries))
+		}
+	}
+}
+
+// TestEvictionIndexResize will test that updating a tile's size evicts others
+// to stay within capacity, and removals release their size
+func TestEvictionIndexResize(t *testing.T) {
+	x := newEvictionIndex(config.EvictionLRU, 30)
+	x.insert("a", 10)
+	x.insert("b", 10)
+
+	if evicted := x.insert("b", 25); len(evicted) != 1 || evicted[0] != "a" {
+		t.Errorf(str.TCacheBadEviction, config.EvictionLRU, evicted, "a")
+	}
+
+	x.remove("b")
+	if x.used != 0 || len(x.entries) != 0 {
+		t.Errorf(str.TCacheBadEvictionUsage, config.EvictionLRU, x.used, len(x.entries))
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// flushEpochKey returns the Redis key persisting the proxy's flush epoch
+func (c *Cache) flushEpochKey() string {
+	return fmt.Sprintf("%s:flush:%s", config.Namespace, c.Proxy.Name)
+}
+
+// initFlushEpoch sets the flush epoch from Redis if one was persisted by a
+// previous scheduled flush of the redis cache
+func (c *Cache) initFlushEpoch() error {
+	flush := c.Proxy.ScheduledFlush
+	if !flush.Enabled() || !flush.Redis {
+		return nil
+	}
+
+	epoch, err := c.external.Get(context.Background(), c.flushEpochKey()).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	c.epoch.Store(epoch)
+	return nil
+}
+
+// flushEpoch returns the namespace of redis keys written since the last
+// scheduled flush, empty if the redis cache was never flushed
+func (c *Cache) flushEpoch() string {
+	epoch, _ := c.epoch.Load().(string)
+	return epoch
+}
+
+// FlushExternal flushes the redis cache by moving to the key namespace of the
+// given flush minute, persisting it so restarts and new instances use it too.
+// Instances flushing for the same minute move to the same namespace.
+func (c *Cache) FlushExternal(ctx context.Context, minute time.Time) error {
+	if !c.Proxy.Cache.RedisEnabled {
+		return nil
+	}
+
+	epoch := strconv.FormatInt(minute.Truncate(time.Minute).Unix(), 36)
+	if !config.IsReadOnly() {
+		if err := c.external.Set(ctx, c.flushEpochKey(), epoch, 0).Err(); err != nil {
+			return err
+		}
+	}
+
+	c.epoch.Store(epoch)
+	return nil
+}
+
+// scheduledFlush flushes the configured cache tiers at every minute matching
+// the proxy's flush schedule, until the cache is closed
+type scheduledFlush struct {
+	c    *Cache
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newScheduledFlush builds and starts flushing the cache on its schedule
+func newScheduledFlush(c *Cache) *scheduledFlush {
+	f := &scheduledFlush{
+		c:    c,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go f.run()
+	return f
+}
+
+// run waits for each next flush minute and flushes the cache
+func (f *scheduledFlush) run() {
+	defer close(f.done)
+
+	window := f.c.Proxy.ScheduledFlush.Window
+	for {
+		// start of the next minute, so no flush minute runs twice
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		at, ok := window.Next(next)
+		if !ok {
+			// nothing scheduled within the week, check again in a day
+			at = next.Add(24 * time.Hour)
+		}
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-timer.C:
+			if ok {
+				f.flush(at)
+			}
+		case <-f.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// flush the configured cache tiers for the given flush minute
+func (f *scheduledFlush) flush(minute time.Time) {
+	c := f.c
+	flush := c.Proxy.ScheduledFlush
+
+	if flush.Redis {
+		if err := c.FlushExternal(context.Background(), minute); err != nil {
+			util.Error(str.CCache, str.ECacheFlush, c.Proxy.Name, err.Error())
+			return
+		}
+	}
+
+	if flush.Memory {
+		if err := c.FlushInternal(); err != nil {
+			util.Error(str.CCache, str.ECacheFlush, c.Proxy.Name, err.Error())
+			return
+		}
+	}
+
+	util.Info(str.CCache, str.MScheduledFlush, c.Proxy.Name, flush.Memory, flush.Redis)
+}
+
+// close stops flushing the cache
+func (f *scheduledFlush) close() {
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		close(f.stop)
+	})
+	<-f.done
+}
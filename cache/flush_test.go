@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestFlushExternal will test that flushing the redis cache hides tiles cached
+// before the flush, and that caches built later use the persisted namespace
+// of the last flush
+func TestFlushExternal(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{
+		Name:           "flush",
+		Cache:          config.Cache{RedisEnabled: true},
+		ScheduledFlush: config.ScheduledFlush{Cron: []string{"0 3 * * *"}, Redis: true},
+	}
+	build := func() *Cache {
+		c := &Cache{
+			Proxy:    &proxy,
+			Metrics:  initMetrics(proxy, false),
+			external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		}
+		if err := c.initFlushEpoch(); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	lookup := func(c *Cache) string {
+		data, err := c.lookupRedis(context.Background(), "1/2/3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	c := build()
+	if err := c.external.Set(context.Background(), "1/2/3", "old", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	minute := time.Date(2024, 6, 1, 3, 0, 0, 0, time.UTC)
+	if err := c.FlushExternal(context.Background(), minute); err != nil {
+		t.Fatal(err)
+	}
+	if data := lookup(c); data != "" {
+		t.Errorf(str.TCacheBadFlush, "1/2/3", data, "")
+	}
+
+	tile := packet.Encode([]byte("new"), nil)
+	c.set("1/2/3", tile, false, true)
+	deadline := time.Now().Add(time.Second)
+	for lookup(c) == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if data := lookup(c); data != string(tile.Raw()) {
+		t.Errorf(str.TCacheBadFlush, "1/2/3", data, tile.Raw())
+	}
+
+	// a cache built later, ex: by another instance, reads the same namespace
+	if data := lookup(build()); data != string(tile.Raw()) {
+		t.Errorf(str.TCacheBadFlush, "1/2/3", data, tile.Raw())
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// generationSubscribeTimeout bounds subscribing to generation switches
+const generationSubscribeTimeout = 5 * time.Second
+
+// generationKey returns the Redis key persisting the proxy's active generation
+func (c *Cache) generationKey() string {
+	return fmt.Sprintf("%s:generation:%s", config.Namespace, c.Proxy.Name)
+}
+
+// initGeneration sets the active generation from Redis if one was persisted
+// by a previous switch, falling back to the configured generation
+func (c *Cache) initGeneration() error {
+	active := c.Proxy.Generations.Active
+
+	if c.Proxy.Generations.Enabled && c.Proxy.Cache.RedisEnabled {
+		persisted, err := c.external.Get(context.Background(), c.generationKey()).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if persisted == config.GenerationBlue || persisted == config.GenerationGreen {
+			active = persisted
+		}
+	}
+
+	c.generation.Store(active)
+	return nil
+}
+
+// Generation returns the cache generation currently being served, empty if
+// generations are disabled for this proxy
+func (c *Cache) Generation() string {
+	if !c.Proxy.Generations.Enabled {
+		return ""
+	}
+	active, _ := c.generation.Load().(string)
+	return active
+}
+
+// InactiveGeneration returns the cache generation not currently being served
+func (c *Cache) InactiveGeneration() string {
+	return otherGeneration(c.Generation())
+}
+
+// SwitchGeneration atomically switches serving to the given generation,
+// persisting it to Redis so restarts and new instances serve it too, and
+// publishing it so running instances sharing Redis switch along
+func (c *Cache) SwitchGeneration(ctx context.Context, generation string) error {
+	if !c.Proxy.Generations.Enabled {
+		return ErrGenerationsDisabled{Name: c.Proxy.Name}
+	}
+	if generation != config.GenerationBlue && generation != config.GenerationGreen {
+		return ErrInvalidGeneration{Name: c.Proxy.Name, Generation: generation}
+	}
+
+	if c.Proxy.Cache.RedisEnabled && !config.IsReadOnly() {
+		if err := c.external.Set(ctx, c.generationKey(), generation, 0).Err(); err != nil {
+			return err
+		}
+		if err := c.external.Publish(ctx, c.generationKey(), generation).Err(); err != nil {
+			return err
+		}
+	}
+
+	c.generation.Store(generation)
+	return nil
+}
+
+// generationWatch switches the cache to the generations published by
+// switches made on any instance sharing its Redis, until the cache is closed
+type generationWatch struct {
+	c      *Cache
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// newGenerationWatch subscribes to the proxy's generation switches and starts
+// following them
+func newGenerationWatch(c *Cache) (*generationWatch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), generationSubscribeTimeout)
+	defer cancel()
+
+	pubsub := c.external.Subscribe(ctx, c.generationKey())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	w := &generationWatch{
+		c:      c,
+		pubsub: pubsub,
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// run switches to each published generation
+func (w *generationWatch) run() {
+	defer close(w.done)
+
+	for msg := range w.pubsub.Channel() {
+		if msg.Payload != config.GenerationBlue && msg.Payload != config.GenerationGreen {
+			continue
+		}
+		if w.c.generation.Swap(msg.Payload) != msg.Payload {
+			util.Info(str.CCache, str.MGenerationSwitch, w.c.Proxy.Name, msg.Payload)
+		}
+	}
+}
+
+// close stops watching for generation switches
+func (w *generationWatch) close() {
+	if w == nil {
+		return
+	}
+	_ = w.pubsub.Close()
+	<-w.done
+}
+
+// otherGeneration returns the opposite of the given generation
+func otherGeneration(generation string) string {
+	if generation == config.GenerationGreen {
+		return config.GenerationBlue
+	}
+	return config.GenerationGreen
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestSwitchGeneration will test that switching the generation on one
+// instance switches every instance sharing its redis, and that switching to
+// an explicit generation twice leaves it active
+func TestSwitchGeneration(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{
+		Name:        "generations",
+		Cache:       config.Cache{RedisEnabled: true},
+		Generations: config.Generations{Enabled: true, Active: config.GenerationBlue},
+	}
+	build := func() *Cache {
+		c := &Cache{
+			Proxy:    &proxy,
+			external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		}
+		var err error
+		if c.generations, err = newGenerationWatch(c); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.initGeneration(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(c.Close)
+		return c
+	}
+
+	instances := []*Cache{build(), build()}
+	for _, generation := range []string{config.GenerationGreen, config.GenerationGreen, config.GenerationBlue} {
+		if err := instances[0].SwitchGeneration(context.Background(), generation); err != nil {
+			t.Fatal(err)
+		}
+
+		// switches reach other instances asynchronously
+		deadline := time.Now().Add(5 * time.Second)
+		for instances[1].Generation() != generation {
+			if time.Now().After(deadline) {
+				t.Fatalf(str.TCacheBadGeneration, 1, instances[1].Generation(), generation)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := instances[0].Generation(); got != generation {
+			t.Errorf(str.TCacheBadGeneration, 0, got, generation)
+		}
+	}
+
+	// instances started later restore the last switch
+	if got := build().Generation(); got != config.GenerationBlue {
+		t.Errorf(str.TCacheBadGeneration, 2, got, config.GenerationBlue)
+	}
+
+	if err := instances[0].SwitchGeneration(context.Background(), "purple"); err == nil {
+		t.Errorf(str.TCacheBadGeneration, 0, "purple", "error")
+	}
+}
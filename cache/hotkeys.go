@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// minHotLookups is the fewest recent lookups of a tile before it can be
+// pinned, so the first lookups after startup don't all look hot
+const minHotLookups = 16
+
+// pinnedTile is a tile pinned in memory until it expires
+type pinnedTile struct {
+	data    []byte
+	expires time.Time
+}
+
+// hotKeyEngine wraps a memory engine, pinning tiles that receive at least a
+// share of recent lookups in a small store beside it for their in-memory TTL,
+// so the very hottest tiles are never lost to eviction. When full, the
+// coldest pinned tile makes way for a hotter one.
+type hotKeyEngine struct {
+	memoryEngine
+	lookups *sketch
+	share   float64
+	maxKeys int
+	ttl     time.Duration
+	gauge   prometheus.Gauge
+
+	mu     sync.Mutex
+	pinned map[string]pinnedTile
+}
+
+// withHotKeys wraps the engine with the proxy's hot key pinning, returning
+// the engine as is if pinning is disabled
+func withHotKeys(engine memoryEngine, proxy config.Proxy, metrics *Metrics) memoryEngine {
+	if !proxy.Cache.HotKeys.Enabled {
+		return engine
+	}
+
+	// assume tiles of 16KB on average
+	return &hotKeyEngine{
+		memoryEngine: engine,
+		lookups:      newSketch(proxy.Cache.MemCap * 64),
+		share:        proxy.Cache.HotKeys.Share,
+		maxKeys:      proxy.Cache.HotKeys.MaxKeys,
+		ttl:          proxy.Cache.MemTTLDuration,
+		gauge:        metrics.PinnedTiles,
+		pinned:       make(map[string]pinnedTile),
+	}
+}
+
+// Get a tile by key from the pinned tiles, falling back to the wrapped
+// engine and pinning the tile if it has become hot
+func (h *hotKeyEngine) Get(key string) ([]byte, error) {
+	lookups := h.lookups.increment(key)
+
+	h.mu.Lock()
+	if tile, ok := h.pinned[key]; ok {
+		if time.Now().Before(tile.expires) {
+			h.mu.Unlock()
+			return tile.data, nil
+		}
+		delete(h.pinned, key)
+		h.gauge.Set(float64(len(h.pinned)))
+	}
+	h.mu.Unlock()
+
+	data, err := h.memoryEngine.Get(key)
+	if err == nil && h.hot(lookups) {
+		h.pin(key, data, lookups)
+	}
+	return data, err
+}
+
+// Set a tile by key, replacing its pinned copy or pinning it if it is hot,
+// such as when refetched after being evicted from the wrapped engine
+func (h *hotKeyEngine) Set(key string, entry []byte) error {
+	if lookups := h.lookups.estimate(key); h.isPinned(key) || h.hot(lookups) {
+		h.pin(key, entry, lookups)
+	}
+	return h.memoryEngine.Set(key, entry)
+}
+
+// Delete a tile by key, unpinning it
+func (h *hotKeyEngine) Delete(key string) error {
+	h.mu.Lock()
+	delete(h.pinned, key)
+	h.gauge.Set(float64(len(h.pinned)))
+	h.mu.Unlock()
+	return h.memoryEngine.Delete(key)
+}
+
+// Reset removes all tiles, unpinning them
+func (h *hotKeyEngine) Reset() error {
+	h.mu.Lock()
+	h.pinned = make(map[string]pinnedTile)
+	h.gauge.Set(0)
+	h.mu.Unlock()
+	return h.memoryEngine.Reset()
+}
+
+// hot returns whether a tile with the given number of recent lookups receives
+// enough of all recent lookups to be pinned
+func (h *hotKeyEngine) hot(lookups uint32) bool {
+	return lookups >= minHotLookups && float64(lookups) >= h.share*float64(h.lookups.total())
+}
+
+// isPinned returns whether a tile is pinned
+func (h *hotKeyEngine) isPinned(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.pinned[key]
+	return ok
+}
+
+// pin pins a copy of a tile for the in-memory TTL, replacing the coldest
+// pinned tile if the store is full and it is colder than this one
+func (h *hotKeyEngine) pin(key string, data []byte, lookups uint32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.pinned[key]; !ok && len(h.pinned) >= h.maxKeys {
+		coldest, coldestLookups := "", lookups
+		for pinnedKey := range h.pinned {
+			if estimate := h.lookups.estimate(pinnedKey); estimate < coldestLookups {
+				coldest, coldestLookups = pinnedKey, estimate
+			}
+		}
+		if coldest == "" {
+			return
+		}
+		delete(h.pinned, coldest)
+	}
+
+	h.pinned[key] = pinnedTile{
+		data:    append([]byte(nil), data...),
+		expires: time.Now().Add(h.ttl),
+	}
+	h.gauge.Set(float64(len(h.pinned)))
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// mapEngine is an in-memory engine storing tiles in a map
+type mapEngine struct {
+	memoryEngine
+	entries map[string][]byte
+}
+
+func (m mapEngine) Get(key string) ([]byte, error) {
+	if entry, ok := m.entries[key]; ok {
+		return entry, nil
+	}
+	return nil, bigcache.ErrEntryNotFound
+}
+
+func (m mapEngine) Set(key string, entry []byte) error {
+	m.entries[key] = entry
+	return nil
+}
+
+func (m mapEngine) Delete(key string) error {
+	delete(m.entries, key)
+	return nil
+}
+
+// TestHotKeys will test that tiles receiving enough of the lookups are pinned
+// and survive eviction from the wrapped engine, while others aren't
+func TestHotKeys(t *testing.T) {
+	proxy := config.Proxy{Name: "hot", Cache: config.Cache{
+		MemCap:         1,
+		MemTTLDuration: time.Hour,
+		HotKeys:        config.HotKeys{Enabled: true, MaxKeys: 1, Share: 0.5},
+	}}
+	inner := mapEngine{entries: map[string][]byte{"hot": []byte("hot"), "warm": []byte("warm")}}
+	engine := withHotKeys(inner, proxy, initMetrics(proxy, false))
+
+	for i := 0; i < minHotLookups; i++ {
+		_, _ = engine.Get("hot")
+	}
+	for i := 0; i < minHotLookups; i++ {
+		_, _ = engine.Get("warm")
+	}
+
+	// evict both tiles from the wrapped engine
+	delete(inner.entries, "hot")
+	delete(inner.entries, "warm")
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"hot", "hot"},
+		// looked up as often, but only after the hot tile claimed the one pin
+		{"warm", ""},
+	}
+	for _, test := range tests {
+		data, _ := engine.Get(test.key)
+		if string(data) != test.expected {
+			t.Errorf(str.TCacheBadHotKey, test.key, data, test.expected)
+		}
+	}
+
+	// invalidated tiles are unpinned
+	_ = engine.Delete("hot")
+	if data, err := engine.Get("hot"); err != bigcache.ErrEntryNotFound {
+		t.Errorf(str.TCacheBadHotKey, "hot", data, "")
+	}
+}
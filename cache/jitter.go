@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/dechristopher/lod/packet"
+)
+
+// redisTTL returns the TTL of a redis write, shortened by the proxy's TTL
+// jitter so tiles cached together don't expire together
+func (c *Cache) redisTTL() time.Duration {
+	return jitter(c.Proxy.Cache.RedisTTLDuration, c.Proxy.Cache.TTLJitter, rand.Float64())
+}
+
+// writeTTL returns the TTL of a redis write of the tile, bounded by the
+// lifetime the upstream set for it if any, and false if the tile has already
+// expired and shouldn't be cached at all
+func (c *Cache) writeTTL(tile packet.TilePacket) (time.Duration, bool) {
+	ttl := c.redisTTL()
+	expires, ok := tile.Expires()
+	if !ok {
+		return ttl, true
+	}
+	return boundTTL(ttl, time.Until(expires))
+}
+
+// boundTTL bounds a TTL by the remaining lifetime of a tile, where TTLs of
+// zero mean no expiry, and returns false if no lifetime remains
+func boundTTL(ttl, remaining time.Duration) (time.Duration, bool) {
+	if remaining <= 0 {
+		return 0, false
+	}
+	if ttl <= 0 || remaining < ttl {
+		return remaining, true
+	}
+	return ttl, true
+}
+
+// jitter shortens a TTL by up to the given percentage, scaled by a uniform
+// random number in [0, 1). TTLs of zero mean no expiry and are kept as is.
+func jitter(ttl time.Duration, percent, random float64) time.Duration {
+	if ttl <= 0 || percent <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(float64(ttl)*percent/100*random)
+}
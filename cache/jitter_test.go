@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestJitter will test that jittered TTLs stay within the configured
+// percentage below the original TTL
+func TestJitter(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		percent  float64
+		random   float64
+		expected time.Duration
+	}{
+		{time.Hour, 0, 0.5, time.Hour},
+		{time.Hour, 10, 0, time.Hour},
+		{time.Hour, 10, 0.5, 57 * time.Minute},
+		{time.Hour, 50, 0.5, 45 * time.Minute},
+		{time.Hour, 100, 0.5, 30 * time.Minute},
+		// no expiry stays no expiry
+		{0, 10, 0.5, 0},
+	}
+
+	for _, test := range tests {
+		if got := jitter(test.ttl, test.percent, test.random); got != test.expected {
+			t.Errorf(str.TCacheBadJitter, test.ttl, test.percent, test.random, got, test.expected)
+		}
+	}
+}
+
+// TestBoundTTL will test that redis TTLs are bounded by the remaining
+// lifetime of tiles the upstream set one for
+func TestBoundTTL(t *testing.T) {
+	tests := []struct {
+		ttl       time.Duration
+		remaining time.Duration
+		expected  time.Duration
+		alive     bool
+	}{
+		{time.Hour, time.Minute, time.Minute, true},
+		{time.Minute, time.Hour, time.Minute, true},
+		// no expiry takes the tile's lifetime
+		{0, time.Hour, time.Hour, true},
+		// expired tiles aren't cached
+		{time.Hour, 0, 0, false},
+		{time.Hour, -time.Second, 0, false},
+	}
+
+	for _, test := range tests {
+		if got, alive := boundTTL(test.ttl, test.remaining); got != test.expected || alive != test.alive {
+			t.Errorf(str.TCacheBadBoundTTL, test.ttl, test.remaining, got, alive, test.expected, test.alive)
+		}
+	}
+}
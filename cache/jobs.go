@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Job is a background job run against a cache instance, such as a seed job,
+// cancelled when the instance is closed
+type Job interface {
+	Running() bool // whether the job is still running
+	Cancel() bool  // stops the job, returning whether it was running
+	Wait()         // blocks until the job has stopped running
+}
+
+// jobs holds the latest background job of each kind run against a cache
+// instance, refusing new ones once the instance is closed
+type jobs struct {
+	mu     sync.Mutex
+	latest map[string]Job
+	closed bool
+}
+
+// AddJob records a background job of the given kind as the latest run
+// against the cache, unless the latest job of the kind is still running, in
+// which case that job is returned instead and the given one must not be
+// started. Returns ErrClosed once the cache has been closed.
+func (c *Cache) AddJob(kind string, job Job) (Job, error) {
+	c.jobs.mu.Lock()
+	defer c.jobs.mu.Unlock()
+
+	if c.jobs.closed {
+		return nil, ErrClosed{Name: c.Proxy.Name}
+	}
+
+	if latest := c.jobs.latest[kind]; latest != nil && latest.Running() {
+		return latest, nil
+	}
+
+	if c.jobs.latest == nil {
+		c.jobs.latest = make(map[string]Job)
+	}
+	c.jobs.latest[kind] = job
+	return job, nil
+}
+
+// Job returns the latest background job of the given kind run against the
+// cache, running or not, nil if none was added
+func (c *Cache) Job(kind string) Job {
+	c.jobs.mu.Lock()
+	defer c.jobs.mu.Unlock()
+	return c.jobs.latest[kind]
+}
+
+// closeJobs cancels the background jobs running against the cache and waits
+// for them to stop, refusing new ones
+func (c *Cache) closeJobs() {
+	c.jobs.mu.Lock()
+	c.jobs.closed = true
+	latest := make([]Job, 0, len(c.jobs.latest))
+	for _, job := range c.jobs.latest {
+		latest = append(latest, job)
+	}
+	c.jobs.mu.Unlock()
+
+	for _, job := range latest {
+		job.Cancel()
+		job.Wait()
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// redisKey returns the key a tile is stored under in redis, namespaced by the
+// last scheduled flush, and a digest of that behind the configured prefix if
+// key hashing is enabled
+func (c *Cache) redisKey(key string) string {
+	key = c.namespaced(key)
+	if !c.hashed(key) {
+		return key
+	}
+
+	hash := c.Proxy.Cache.KeyHash
+
+	switch hash.Algorithm {
+	case config.KeyHashXXHash:
+		return hash.Prefix + strconv.FormatUint(xxhash.Sum64String(key), 16)
+	case config.KeyHashSHA256:
+		sum := sha256.Sum256([]byte(key))
+		return hash.Prefix + hex.EncodeToString(sum[:])
+	}
+	return key
+}
+
+// namespaced returns the cache key namespaced by the last scheduled flush of
+// the redis cache, if any
+func (c *Cache) namespaced(key string) string {
+	if epoch := c.flushEpoch(); epoch != "" {
+		return epoch + ":" + key
+	}
+	return key
+}
+
+// hashed returns true if the given namespaced key is stored under its digest
+func (c *Cache) hashed(key string) bool {
+	hash := c.Proxy.Cache.KeyHash
+	return hash.Algorithm != "" && len(key) > hash.MinLength
+}
+
+// redisKeys returns the redis keys of the given cache keys
+func (c *Cache) redisKeys(keys []string) []string {
+	if c.Proxy.Cache.KeyHash.Algorithm == "" && c.flushEpoch() == "" {
+		return keys
+	}
+
+	hashed := make([]string, len(keys))
+	for i, key := range keys {
+		hashed[i] = c.redisKey(key)
+	}
+	return hashed
+}
+
+// indexKey returns the Redis key of the debug index entry holding the cache
+// key of a hashed redis key
+func (c *Cache) indexKey(hashed string) string {
+	return fmt.Sprintf("%s:keyhash:%s:%s", config.Namespace, c.Proxy.Name, hashed)
+}
+
+// indexed returns true if the given cache key is stored in the debug index
+func (c *Cache) indexed(key string) bool {
+	return c.Proxy.Cache.KeyHash.DebugIndex && c.hashed(c.namespaced(key))
+}
+
+// KeyOf returns the cache key the given redis key was hashed from, empty if
+// the debug index doesn't hold it
+func (c *Cache) KeyOf(ctx context.Context, hashed string) (string, error) {
+	if !c.Proxy.Cache.KeyHash.DebugIndex {
+		return "", ErrKeyIndexDisabled{Name: c.Proxy.Name}
+	}
+
+	key, err := c.external.Get(ctx, c.indexKey(hashed)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return key, err
+}
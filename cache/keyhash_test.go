@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestKeyHash will test that long cache keys are stored in redis under their
+// digest, short keys as they are, and that the debug index maps digests back
+// to their cache keys until the tile is invalidated
+func TestKeyHash(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{Name: "keyhash", Cache: config.Cache{
+		RedisEnabled: true,
+		WriteBehind: config.WriteBehind{
+			Enabled:          true,
+			IntervalDuration: time.Hour,
+			BatchSize:        100,
+			QueueSize:        10,
+		},
+		KeyHash: config.KeyHash{
+			Algorithm:  config.KeyHashXXHash,
+			Prefix:     "keyhash:",
+			MinLength:  8,
+			DebugIndex: true,
+		},
+	}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+	c.writes = newWriteBehind(c)
+
+	short := "1/2/3"
+	long := "14/8192/5461?layers=roads,water&style=night"
+
+	tile := packet.Encode([]byte("tile"), nil)
+	c.set(short, tile, false, true)
+	c.set(long, tile, false, true)
+	c.Close()
+
+	if hashed := c.redisKey(short); hashed != short {
+		t.Errorf(str.TCacheBadKeyHash, short, hashed, short)
+	}
+	hashed := c.redisKey(long)
+	if !strings.HasPrefix(hashed, "keyhash:") || len(hashed) > len("keyhash:")+16 {
+		t.Errorf(str.TCacheBadKeyHash, long, hashed, "keyhash:<xxhash>")
+	}
+	if !server.Exists(short) || !server.Exists(hashed) || server.Exists(long) {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+
+	ctx := context.Background()
+	if data, err := c.lookupRedis(ctx, long); err != nil || data == nil {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+
+	if key, err := c.KeyOf(ctx, hashed); err != nil || key != long {
+		t.Errorf(str.TCacheBadKeyIndex, hashed, key, long)
+	}
+	if key, _ := c.KeyOf(ctx, short); key != "" {
+		t.Errorf(str.TCacheBadKeyIndex, short, key, "")
+	}
+
+	if err := c.Invalidate(long, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := c.KeyOf(ctx, hashed); server.Exists(hashed) || key != "" {
+		t.Errorf(str.TCacheBadKeyIndex, hashed, key, "")
+	}
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/util"
+)
+
+// initLegacyMetrics registers the deprecated hit_total, miss_total and
+// hit_rate metrics as aliases of their replacements so existing dashboards
+// keep working while they're migrated. Enabled by instance.legacy_metrics.
+func initLegacyMetrics(proxy config.Proxy, hits *prometheus.CounterVec, misses prometheus.Counter) {
+	labels := map[string]string{
+		"proxy": proxy.Name,
+	}
+
+	register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   Subsystem,
+		Name:        "hit_total",
+		ConstLabels: labels,
+		Help:        "DEPRECATED: use lod_cache_hits_total. The total number of cache hits",
+	}, func() float64 {
+		return util.SumMetricValues(hits)
+	}))
+
+	register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   Subsystem,
+		Name:        "miss_total",
+		ConstLabels: labels,
+		Help:        "DEPRECATED: use lod_cache_misses_total. The total number of cache misses",
+	}, func() float64 {
+		return util.GetMetricValue(misses)
+	}))
+
+	register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   config.Namespace,
+		Subsystem:   Subsystem,
+		Name:        "hit_rate",
+		ConstLabels: labels,
+		Help:        "DEPRECATED: compute rate(lod_cache_hits_total) / (rate(lod_cache_hits_total) + rate(lod_cache_misses_total)). The rate of hits to misses",
+	}, func() float64 {
+		h := util.SumMetricValues(hits)
+		m := util.GetMetricValue(misses)
+		return h / (h + m)
+	}))
+}
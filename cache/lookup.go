@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/allegro/bigcache/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/timing"
+	"github.com/dechristopher/lod/util"
+)
+
+// lookupResult is the outcome of looking a tile up in a single cache layer
+type lookupResult struct {
+	data  []byte // tile packet bytes, nil on a miss
+	layer string
+	err   error
+	took  time.Duration
+}
+
+// lookup finds a tile in the enabled cache layers, returning nil data if it
+// missed all of them, along with the layer it was found in
+func (c *Cache) lookup(key string, ctx *fiber.Ctx, log *util.Logger) ([]byte, string, error) {
+	if c.Proxy.Cache.ParallelLookup && c.Proxy.Cache.MemEnabled && c.Proxy.Cache.RedisEnabled {
+		return c.lookupParallel(key, ctx, log)
+	}
+
+	// fetch from in-memory cache if enabled
+	if c.Proxy.Cache.MemEnabled {
+		stopCache := timing.Track(ctx, timing.Cache)
+		data, err := c.lookupMemory(key, log)
+		stopCache()
+		if err != nil || data != nil {
+			return data, LayerMemory, err
+		}
+	}
+
+	// try fetching from redis if not present in internal cache
+	if c.Proxy.Cache.RedisEnabled {
+		stopRedis := timing.Track(ctx, timing.Redis)
+		data, err := c.lookupRedis(ctx.Context(), key)
+		stopRedis()
+		return data, LayerRedis, err
+	}
+
+	return nil, "", nil
+}
+
+// lookupParallel looks a tile up in both cache layers at once, using whichever
+// layer finds it first and cancelling the redis lookup if the in-memory cache
+// wins. Misses and errors of one layer fall back to the other's result.
+func (c *Cache) lookupParallel(key string, ctx *fiber.Ctx, log *util.Logger) ([]byte, string, error) {
+	// a cancelled redis lookup may outlive the request, so it can't use the
+	// request's context
+	redisCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan lookupResult, 2)
+	start := time.Now()
+	go func() {
+		data, err := c.lookupMemory(key, log)
+		results <- lookupResult{data: data, layer: LayerMemory, err: err, took: time.Since(start)}
+	}()
+	go func() {
+		data, err := c.lookupRedis(redisCtx, key)
+		results <- lookupResult{data: data, layer: LayerRedis, err: err, took: time.Since(start)}
+	}()
+
+	var result lookupResult
+	for i := 0; i < 2; i++ {
+		result = <-results
+		if result.layer == LayerMemory {
+			timing.Add(ctx, timing.Cache, result.took)
+		} else {
+			timing.Add(ctx, timing.Redis, result.took)
+		}
+
+		if result.err == nil && result.data != nil {
+			return result.data, result.layer, nil
+		}
+		if i == 0 && result.err != nil {
+			log.Error(str.CCache, str.ECacheFetch, key, result.err.Error())
+		}
+	}
+
+	// both layers missed, report the last layer's error if any
+	return nil, result.layer, result.err
+}
+
+// FetchMany grabs tiles by key from the cache layers like Fetch, looking all
+// keys missed in memory up in redis in a single round trip. Tiles are returned
+// in the order of their keys, nil if not cached.
+func (c *Cache) FetchMany(keys []string, ctx *fiber.Ctx) []*packet.TilePacket {
+	log := util.Log(ctx)
+	found := make([][]byte, len(keys))
+	layers := make([]string, len(keys))
+
+	// look every key up in memory first, falling back to redis on errors
+	if c.Proxy.Cache.MemEnabled {
+		stopCache := timing.Track(ctx, timing.Cache)
+		for i, key := range keys {
+			data, err := c.lookupMemory(key, log)
+			if err != nil {
+				log.Error(str.CCache, str.ECacheFetch, key, err.Error())
+			}
+			found[i], layers[i] = data, LayerMemory
+		}
+		stopCache()
+	}
+
+	// then look up all keys missed in memory at once
+	if c.Proxy.Cache.RedisEnabled {
+		var missed []string
+		var indexes []int
+		for i, key := range keys {
+			if found[i] == nil {
+				missed = append(missed, key)
+				indexes = append(indexes, i)
+			}
+		}
+
+		if len(missed) > 0 {
+			stopRedis := timing.Track(ctx, timing.Redis)
+			data, err := c.lookupRedisMany(ctx.Context(), missed)
+			stopRedis()
+			if err != nil {
+				log.Error(str.CCache, str.ECacheFetchMany, len(missed), err.Error())
+			} else {
+				for j, i := range indexes {
+					found[i], layers[i] = data[j], LayerRedis
+				}
+			}
+		}
+	}
+
+	tiles := make([]*packet.TilePacket, len(keys))
+	for i, key := range keys {
+		tiles[i] = c.accept(key, found[i], layers[i], ctx, log)
+	}
+	return tiles
+}
+
+// lookupMemory looks a tile up in the in-memory cache, returning nil data on
+// a miss
+func (c *Cache) lookupMemory(key string, log *util.Logger) ([]byte, error) {
+	data, err := c.internal.Get(key)
+	if err == bigcache.ErrEntryNotFound {
+		log.DebugFlag("cache", str.CCache, str.DCacheMiss, key)
+		return nil, nil
+	}
+	return data, err
+}
+
+// lookupRedis looks a tile up in redis, returning nil data on a miss.
+// Concurrent lookups of the same key share a single GET, so hot tiles missed
+// in memory all at once, ex: after a flush, cost redis one round trip.
+func (c *Cache) lookupRedis(ctx context.Context, key string) ([]byte, error) {
+	key = c.redisKey(key)
+
+	// the shared GET outlives any one lookup, so it can't use their contexts
+	results := c.redisLookups.DoChan(key, func() (interface{}, error) {
+		return c.getRedis(context.Background(), key)
+	})
+
+	select {
+	case result := <-results:
+		if result.Shared {
+			c.Metrics.RedisSharedLookups.Inc()
+		}
+		data, _ := result.Val.([]byte)
+		return data, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// getRedis gets a tile from redis by its redis key, returning nil data on a
+// miss
+func (c *Cache) getRedis(ctx context.Context, key string) ([]byte, error) {
+	var redisTile *redis.StringCmd
+
+	// retry transient errors rather than treating them as misses
+	err := c.withRetry(ctx, RedisOpGet, func() error {
+		if config.IsReadOnly() {
+			// plain get without touching key expiry when in read-only mode
+			redisTile = c.external.Get(ctx, key)
+		} else if c.Proxy.Cache.RedisTTLDuration > 0 {
+			// if TTL set, extend Redis TTL when we fetch a tile to prevent
+			// key expiry for tiles that are fetched periodically
+			redisTile = c.external.GetEx(ctx, key, c.redisTTL())
+		} else {
+			// get and persist the key, meaning no expiry
+			redisTile = c.external.GetEx(ctx, key, 0)
+		}
+		return redisTile.Err()
+	})
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// squeeze out the bytes from the redis response
+	return redisTile.Bytes()
+}
+
+// lookupRedisMany looks tiles up in redis in a single round trip, with MGET
+// in read-only mode and pipelined GETEX extending their TTLs otherwise,
+// returning nil data for misses
+func (c *Cache) lookupRedisMany(ctx context.Context, keys []string) ([][]byte, error) {
+	found := make([][]byte, len(keys))
+	keys = c.redisKeys(keys)
+
+	// retry transient errors rather than treating them as misses
+	err := c.withRetry(ctx, RedisOpGet, func() error {
+		if config.IsReadOnly() {
+			// plain gets without touching key expiry when in read-only mode
+			values, err := c.external.MGet(ctx, keys...).Result()
+			if err != nil {
+				return err
+			}
+			for i, value := range values {
+				found[i] = nil
+				if data, ok := value.(string); ok {
+					found[i] = []byte(data)
+				}
+			}
+			return nil
+		}
+
+		cmds := make([]*redis.StringCmd, len(keys))
+		// errors are checked per command, since misses fail the pipeline too
+		_, _ = c.external.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				var ttl time.Duration
+				if c.Proxy.Cache.RedisTTLDuration > 0 {
+					ttl = c.redisTTL()
+				}
+				cmds[i] = pipe.GetEx(ctx, key, ttl)
+			}
+			return nil
+		})
+
+		for i, cmd := range cmds {
+			data, err := cmd.Bytes()
+			if err == redis.Nil {
+				found[i] = nil
+				continue
+			} else if err != nil {
+				return err
+			}
+			found[i] = data
+		}
+		return nil
+	})
+
+	return found, err
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/allegro/bigcache/v3"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+)
+
+// slowEngine is an in-memory engine answering lookups after a delay
+type slowEngine struct {
+	memoryEngine
+	entries map[string][]byte
+	delay   time.Duration
+}
+
+func (s slowEngine) Get(key string) ([]byte, error) {
+	time.Sleep(s.delay)
+	if entry, ok := s.entries[key]; ok {
+		return entry, nil
+	}
+	return nil, bigcache.ErrEntryNotFound
+}
+
+// TestLookupParallel will test that parallel lookups use the first layer to
+// find a tile, and fall back to the other layer on misses
+func TestLookupParallel(t *testing.T) {
+	server := miniredis.RunT(t)
+	_ = server.Set("both", "redis")
+	_ = server.Set("redis", "redis")
+
+	proxy := config.Proxy{Name: "parallel", Cache: config.Cache{
+		MemEnabled:     true,
+		RedisEnabled:   true,
+		ParallelLookup: true,
+	}}
+	metrics := initMetrics(proxy, false)
+	external := redis.NewClient(&redis.Options{Addr: server.Addr()})
+
+	app := fiber.New()
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	tests := []struct {
+		key   string
+		delay time.Duration
+		data  string
+		layer string
+	}{
+		{"both", 0, "memory", LayerMemory},
+		{"both", 200 * time.Millisecond, "redis", LayerRedis},
+		{"memory", 50 * time.Millisecond, "memory", LayerMemory},
+		{"redis", 0, "redis", LayerRedis},
+		{"none", 0, "", LayerRedis},
+	}
+
+	for _, test := range tests {
+		c := &Cache{
+			Proxy:    &proxy,
+			Metrics:  metrics,
+			external: external,
+			internal: slowEngine{
+				entries: map[string][]byte{"both": []byte("memory"), "memory": []byte("memory")},
+				delay:   test.delay,
+			},
+		}
+
+		data, layer, err := c.lookup(test.key, ctx, nil)
+		if err != nil || string(data) != test.data || (data != nil && layer != test.layer) {
+			t.Errorf(str.TCacheBadLookup, test.key, test.delay, data, layer, err, test.data, test.layer)
+		}
+	}
+}
+
+// TestFetchMany will test that tiles missed in memory are looked up in redis
+// together, and that results keep the order of the keys
+func TestFetchMany(t *testing.T) {
+	server := miniredis.RunT(t)
+	_ = server.Set("redis", string(packet.Encode([]byte("redis"), nil)))
+	_ = server.Set("both", string(packet.Encode([]byte("stale"), nil)))
+
+	proxy := config.Proxy{Name: "many", Cache: config.Cache{MemEnabled: true, RedisEnabled: true}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		internal: slowEngine{entries: map[string][]byte{
+			"both":   packet.Encode([]byte("memory"), nil),
+			"memory": packet.Encode([]byte("memory"), nil),
+		}},
+	}
+
+	app := fiber.New()
+	// initialized request contexts can be used as contexts of redis commands
+	requestCtx := &fasthttp.RequestCtx{}
+	requestCtx.Init(&fasthttp.Request{}, nil, nil)
+	ctx := app.AcquireCtx(requestCtx)
+	defer app.ReleaseCtx(ctx)
+	// keep hits from being written back to the stub engine
+	ctx.Locals(str.LocalSkipMemory, true)
+
+	keys := []string{"none", "both", "redis", "memory"}
+	expected := []string{"", "memory", "redis", "memory"}
+
+	tiles := c.FetchMany(keys, ctx)
+	for i, key := range keys {
+		var got string
+		if tiles[i] != nil {
+			got = string(tiles[i].TileData())
+		}
+		if got != expected[i] {
+			t.Errorf(str.TCacheBadFetchMany, key, got, expected[i])
+		}
+	}
+}
+
+// countingHook counts the redis commands processed, delaying each of them
+type countingHook struct {
+	commands atomic.Int32
+	delay    time.Duration
+}
+
+func (h *countingHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	h.commands.Add(1)
+	time.Sleep(h.delay)
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h *countingHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *countingHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
+
+// TestLookupRedisShared will test that concurrent redis lookups of the same
+// key share a single GET, and that lookups stop waiting once cancelled
+func TestLookupRedisShared(t *testing.T) {
+	server := miniredis.RunT(t)
+	_ = server.Set("hot", "tile")
+
+	hook := &countingHook{delay: 100 * time.Millisecond}
+	external := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	external.AddHook(hook)
+
+	proxy := config.Proxy{Name: "shared", Cache: config.Cache{RedisEnabled: true}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: external,
+	}
+
+	const lookups = 10
+	var wg sync.WaitGroup
+	results := make([]string, lookups)
+	for i := 0; i < lookups; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, err := c.lookupRedis(context.Background(), "hot")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = string(data)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != "tile" {
+			t.Errorf(str.TCacheBadSharedLookup, result, hook.commands.Load(), "tile", 1)
+		}
+	}
+	if hook.commands.Load() != 1 {
+		t.Errorf(str.TCacheBadSharedLookup, results[0], hook.commands.Load(), "tile", 1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if data, err := c.lookupRedis(ctx, "hot"); err != context.Canceled {
+		t.Errorf(str.TCacheBadSharedLookup, data, hook.commands.Load(), "", 2)
+	}
+}
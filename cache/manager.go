@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Manager holds the cache instances of an instance's proxies by name, and is
+// passed to the handlers serving them
+type Manager struct {
+	mu     sync.RWMutex
+	caches map[string]*Cache
+}
+
+// NewManager returns an empty cache manager
+func NewManager() *Manager {
+	return &Manager{caches: make(map[string]*Cache)}
+}
+
+// Init builds cache instances for all proxies of the given configuration that
+// don't have one yet, and drops those of proxies no longer configured. Usually
+// called after a config read/reload.
+func (m *Manager) Init(capabilities *config.Capabilities) error {
+	var dropped []*Cache
+	defer func() {
+		// send pending writes of dropped caches without holding the lock
+		for _, c := range dropped {
+			c.Close()
+		}
+	}()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// build out initial proxy instances
+	present := make(map[string]bool, len(capabilities.Proxies))
+	for _, proxy := range capabilities.Proxies {
+		present[proxy.Name] = true
+		if m.caches[proxy.Name] != nil {
+			continue
+		}
+
+		c, err := New(proxy, capabilities.Instance)
+		if err != nil {
+			return ErrBuildInstance{
+				Name: proxy.Name,
+				Err:  err,
+			}
+		}
+		m.caches[proxy.Name] = c
+	}
+
+	// ensure old proxies under different names aren't kept around
+	for name := range m.caches {
+		if !present[name] {
+			util.Info(str.CCache, str.MOldCacheDeleted, name)
+			dropped = append(dropped, m.caches[name])
+			delete(m.caches, name)
+		}
+	}
+
+	return nil
+}
+
+// Drop the cache instance of the given name, so that the next Init builds it
+// anew from the proxy's current configuration
+func (m *Manager) Drop(name string) {
+	m.mu.Lock()
+	c := m.caches[name]
+	delete(m.caches, name)
+	m.mu.Unlock()
+
+	if c != nil {
+		c.Close()
+	}
+}
+
+// Close sends the pending writes of all cache instances, for shutdown
+func (m *Manager) Close() {
+	for _, c := range m.All() {
+		c.Close()
+	}
+}
+
+// Get a cache instance by name, nil if none is managed under the name
+func (m *Manager) Get(name string) *Cache {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.caches[name]
+}
+
+// All returns a snapshot of the managed cache instances by name
+func (m *Manager) All() map[string]*Cache {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	caches := make(map[string]*Cache, len(m.caches))
+	for name, c := range m.caches {
+		caches[name] = c
+	}
+	return caches
+}
+
+// FromCtx returns the cache instance of the proxy a request is handled for,
+// nil if the request isn't handled for a proxy
+func FromCtx(ctx *fiber.Ctx) *Cache {
+	c, _ := ctx.Locals(str.LocalCache).(*Cache)
+	return c
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestManagerInit will test that re-initializing a manager keeps the caches
+// of proxies still configured and drops those of removed proxies
+func TestManagerInit(t *testing.T) {
+	m := NewManager()
+
+	if err := m.Init(&config.Capabilities{Proxies: []config.Proxy{{Name: "a"}, {Name: "b"}}}); err != nil {
+		t.Fatalf(str.TCacheBadManager, err.Error())
+	}
+
+	a := m.Get("a")
+	if a == nil || m.Get("b") == nil {
+		t.Fatalf(str.TCacheBadManaged, m.All())
+	}
+
+	if err := m.Init(&config.Capabilities{Proxies: []config.Proxy{{Name: "a"}}}); err != nil {
+		t.Fatalf(str.TCacheBadManager, err.Error())
+	}
+
+	if m.Get("a") != a || m.Get("b") != nil || len(m.All()) != 1 {
+		t.Errorf(str.TCacheBadManaged, m.All())
+	}
+}
+
+// testJob is a background job running until cancelled
+type testJob struct {
+	done chan struct{}
+}
+
+func (j *testJob) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (j *testJob) Cancel() bool {
+	if !j.Running() {
+		return false
+	}
+	close(j.done)
+	return true
+}
+
+func (j *testJob) Wait() {
+	<-j.done
+}
+
+// TestManagerDropJobs will test that only one job of a kind runs against a
+// cache at a time, and that dropping the cache cancels it and refuses more
+func TestManagerDropJobs(t *testing.T) {
+	m := NewManager()
+	if err := m.Init(&config.Capabilities{Proxies: []config.Proxy{{Name: "a"}}}); err != nil {
+		t.Fatalf(str.TCacheBadManager, err.Error())
+	}
+	c := m.Get("a")
+
+	job := &testJob{done: make(chan struct{})}
+	if latest, err := c.AddJob("test", job); err != nil || latest != Job(job) {
+		t.Fatalf(str.TCacheBadJob, latest, job)
+	}
+
+	// a second job of the kind is refused while the first is running
+	second := &testJob{done: make(chan struct{})}
+	if latest, err := c.AddJob("test", second); err != nil || latest != Job(job) {
+		t.Fatalf(str.TCacheBadJob, latest, job)
+	}
+
+	m.Drop("a")
+	if job.Running() || c.Job("test") != Job(job) {
+		t.Fatalf(str.TCacheBadJob, c.Job("test"), job)
+	}
+
+	if _, err := c.AddJob("test", second); err == nil {
+		t.Fatalf(str.TCacheBadJob, err, ErrClosed{Name: "a"})
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis operations retried on transient errors
+const (
+	RedisOpGet    = "get"
+	RedisOpSet    = "set"
+	RedisOpDelete = "delete"
+)
+
+// transientPrefixes of redis error replies worth retrying, sent while a
+// server loads its data set, fails over or redirects to another node
+var transientPrefixes = []string{
+	"LOADING ", "READONLY ", "MASTERDOWN ", "CLUSTERDOWN ", "TRYAGAIN ",
+	"MOVED ", "ASK ", "ERR max number of clients reached",
+}
+
+// withRetry runs a redis operation, retrying it with exponential backoff and
+// full jitter while it fails with transient errors, up to the configured
+// number of retries or until the context is done
+func (c *Cache) withRetry(ctx context.Context, op string, fn func() error) error {
+	retry := c.Proxy.Cache.RedisRetry
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retry.MaxRetries || !transient(err) {
+			if err != nil && attempt > 0 && transient(err) {
+				c.Metrics.RedisRetryFailures.WithLabelValues(op).Inc()
+			}
+			return err
+		}
+
+		c.Metrics.RedisRetries.WithLabelValues(op).Inc()
+		timer := time.NewTimer(backoff(attempt, retry.MinBackoffDuration, retry.MaxBackoffDuration, rand.Float64()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the given retry, counted from zero: a
+// uniform random share of the minimum backoff doubled once per retry, bounded
+// by the maximum backoff
+func backoff(attempt int, min, max time.Duration, random float64) time.Duration {
+	ceiling := max
+	if attempt < 32 && min<<uint(attempt) < max && min<<uint(attempt) > 0 {
+		ceiling = min << uint(attempt)
+	}
+	return time.Duration(float64(ceiling) * random)
+}
+
+// transient returns true if a redis error is likely to go away on retry
+func transient(err error) bool {
+	if errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var chaosErr ErrChaosRedis
+	if errors.As(err, &chaosErr) {
+		return true
+	}
+
+	msg := err.Error()
+	if msg == "redis: connection pool timeout" {
+		return true
+	}
+	for _, prefix := range transientPrefixes {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestBackoff will test that backoffs double per retry up to the maximum,
+// scaled by the jitter
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		random   float64
+		expected time.Duration
+	}{
+		{0, 1, 10 * time.Millisecond},
+		{1, 1, 20 * time.Millisecond},
+		{2, 0.5, 20 * time.Millisecond},
+		{5, 1, 250 * time.Millisecond},
+		{64, 1, 250 * time.Millisecond},
+		{3, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := backoff(test.attempt, 10*time.Millisecond, 250*time.Millisecond, test.random); got != test.expected {
+			t.Errorf(str.TCacheBadBackoff, test.attempt, test.random, got, test.expected)
+		}
+	}
+}
+
+// TestWithRetry will test that transient errors are retried up to the
+// configured number of retries, and other errors and misses aren't
+func TestWithRetry(t *testing.T) {
+	proxy := config.Proxy{Name: "retry", Cache: config.Cache{RedisRetry: config.RedisRetry{
+		MaxRetries:         2,
+		MinBackoffDuration: time.Microsecond,
+		MaxBackoffDuration: time.Microsecond,
+	}}}
+	c := &Cache{Proxy: &proxy, Metrics: initMetrics(proxy, false)}
+
+	tests := []struct {
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{[]error{io.EOF, nil}, 2, nil},
+		{[]error{io.EOF, io.EOF, io.EOF, nil}, 3, io.EOF},
+		{[]error{errors.New("LOADING Redis is loading the dataset in memory"), nil}, 2, nil},
+		{[]error{redis.Nil}, 1, redis.Nil},
+		{[]error{errors.New("WRONGTYPE Operation against a key")}, 1, nil},
+	}
+
+	for i, test := range tests {
+		attempts := 0
+		err := c.withRetry(context.Background(), RedisOpGet, func() error {
+			err := test.errs[attempts]
+			attempts++
+			return err
+		})
+		if attempts != test.attempts || (test.err != nil && err != test.err) {
+			t.Errorf(str.TCacheBadRetry, i, attempts, err, test.attempts, test.err)
+		}
+	}
+}
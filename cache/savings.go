@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// savingsBuckets is the number of one-minute buckets kept by the savings
+// tracker, bounding report windows to seven days
+const savingsBuckets = 7 * 24 * 60
+
+// MaxSavingsWindow is the longest window a savings report can cover
+const MaxSavingsWindow = savingsBuckets * time.Minute
+
+// Savings is a summary of upstream requests and bytes avoided by the cache
+// over a window of time
+type Savings struct {
+	CacheRequests    uint64 `json:"cache_requests"`    // requests served from cache, each an upstream request avoided
+	CacheBytes       uint64 `json:"cache_bytes"`       // bytes served from cache, each an upstream byte avoided
+	UpstreamRequests uint64 `json:"upstream_requests"` // requests made to the upstream
+	UpstreamBytes    uint64 `json:"upstream_bytes"`    // bytes received from the upstream
+}
+
+// savingsBucket accumulates savings for a single minute
+type savingsBucket struct {
+	minute int64 // unix minute the bucket holds, stale buckets are reset
+	Savings
+}
+
+// savingsTracker keeps per-minute savings counters in a ring buffer
+type savingsTracker struct {
+	mu      sync.Mutex
+	buckets [savingsBuckets]savingsBucket
+}
+
+// bucket returns the bucket for the given minute, resetting it if stale.
+// Must be called with the lock held.
+func (s *savingsTracker) bucket(minute int64) *savingsBucket {
+	b := &s.buckets[minute%savingsBuckets]
+	if b.minute != minute {
+		*b = savingsBucket{minute: minute}
+	}
+	return b
+}
+
+// recordCache records a request served from cache
+func (s *savingsTracker) recordCache(bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.bucket(time.Now().Unix() / 60)
+	b.CacheRequests++
+	b.CacheBytes += uint64(bytes)
+}
+
+// recordUpstream records a response received from the upstream
+func (s *savingsTracker) recordUpstream(bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.bucket(time.Now().Unix() / 60)
+	b.UpstreamRequests++
+	b.UpstreamBytes += uint64(bytes)
+}
+
+// sum totals the savings over the given window ending now
+func (s *savingsTracker) sum(window time.Duration) Savings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().Unix() / 60
+	minutes := int64(window / time.Minute)
+	if minutes > savingsBuckets {
+		minutes = savingsBuckets
+	}
+
+	var total Savings
+	for minute := now - minutes + 1; minute <= now; minute++ {
+		b := &s.buckets[minute%savingsBuckets]
+		if b.minute != minute {
+			continue
+		}
+		total.CacheRequests += b.CacheRequests
+		total.CacheBytes += b.CacheBytes
+		total.UpstreamRequests += b.UpstreamRequests
+		total.UpstreamBytes += b.UpstreamBytes
+	}
+
+	return total
+}
+
+// RecordServed records tile bytes served to a client from the given source
+func (c *Cache) RecordServed(source string, bytes int) {
+	c.Metrics.BytesServed.WithLabelValues(source).Add(float64(bytes))
+	if source == SourceCache {
+		c.savings.recordCache(bytes)
+	}
+}
+
+// RecordUpstream records tile bytes received from the upstream
+func (c *Cache) RecordUpstream(bytes int) {
+	c.Metrics.BytesUpstream.Add(float64(bytes))
+	c.savings.recordUpstream(bytes)
+}
+
+// Savings returns the upstream requests and bytes avoided by the cache over
+// the given window, up to MaxSavingsWindow
+func (c *Cache) Savings(window time.Duration) Savings {
+	return c.savings.sum(window)
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+func TestSavingsWindow(t *testing.T) {
+	s := &savingsTracker{}
+	now := time.Now().Unix() / 60
+
+	// an hour old bucket outside of a 30 minute window
+	old := s.bucket(now - 60)
+	old.CacheRequests = 10
+	old.CacheBytes = 1000
+
+	s.recordCache(100)
+	s.recordCache(50)
+	s.recordUpstream(25)
+
+	recent := s.sum(30 * time.Minute)
+	if recent.CacheRequests != 2 || recent.CacheBytes != 150 ||
+		recent.UpstreamRequests != 1 || recent.UpstreamBytes != 25 {
+		t.Fatalf(str.TCacheBadSavings, recent)
+	}
+
+	all := s.sum(2 * time.Hour)
+	if all.CacheRequests != 12 || all.CacheBytes != 1150 {
+		t.Fatalf(str.TCacheBadSavings, all)
+	}
+}
+
+func TestSavingsStaleBucket(t *testing.T) {
+	s := &savingsTracker{}
+	now := time.Now().Unix() / 60
+
+	// a bucket from a full ring ago shares the slot of the current minute
+	stale := s.bucket(now - savingsBuckets)
+	stale.CacheRequests = 10
+
+	if total := s.sum(MaxSavingsWindow); total.CacheRequests != 0 {
+		t.Fatalf(str.TCacheBadSavings, total)
+	}
+
+	s.recordCache(1)
+	if total := s.sum(time.Minute); total.CacheRequests != 1 {
+		t.Fatalf(str.TCacheBadSavings, total)
+	}
+}
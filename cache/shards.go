@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/allegro/bigcache/v3"
+)
+
+// FNV-1a parameters, matching bigcache's default hasher
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// shardHasher hashes keys like bigcache's default hasher while counting the
+// operations that land on each shard, exposing hot shards
+type shardHasher struct {
+	mask uint64
+	ops  []atomic.Int64
+}
+
+// newShardHasher returns a counting hasher for the given power of two number
+// of shards
+func newShardHasher(shards int) *shardHasher {
+	return &shardHasher{
+		mask: uint64(shards - 1),
+		ops:  make([]atomic.Int64, shards),
+	}
+}
+
+// Sum64 returns the FNV-1a hash of the key, counting an operation against
+// the shard it maps to
+func (h *shardHasher) Sum64(key string) uint64 {
+	hash := uint64(fnvOffset64)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= fnvPrime64
+	}
+	h.ops[hash&h.mask].Add(1)
+	return hash
+}
+
+// ShardLoad of operations on a single shard
+type ShardLoad struct {
+	Shard int   `json:"shard"`
+	Ops   int64 `json:"ops"`
+}
+
+// MemoryStats of the in-memory cache. Collisions are only counted by
+// bigcache across all shards, while operations are counted per shard.
+type MemoryStats struct {
+	bigcache.Stats
+	Entries   int         `json:"entries"`    // number of cached entries
+	Shards    int         `json:"shards"`     // number of shards
+	OpsMin    int64       `json:"ops_min"`    // operations on the least loaded shard
+	OpsMax    int64       `json:"ops_max"`    // operations on the most loaded shard
+	OpsMean   float64     `json:"ops_mean"`   // mean operations per shard
+	HotShards []ShardLoad `json:"hot_shards"` // most loaded shards, most first
+}
+
+// number of hot shards reported in memory stats
+const hotShards = 8
+
+// MemoryStats returns stats of the in-memory cache, nil if disabled. Shard
+// load is only reported when stats collection is enabled.
+func (c *Cache) MemoryStats() *MemoryStats {
+	if !c.Proxy.Cache.MemEnabled {
+		return nil
+	}
+
+	stats := &MemoryStats{
+		Stats:   c.internal.Stats(),
+		Entries: c.internal.Len(),
+		Shards:  c.Proxy.Cache.Bigcache.Shards,
+	}
+	if c.shards != nil {
+		stats.Shards = len(c.shards.ops)
+		stats.OpsMin, stats.OpsMax, stats.OpsMean, stats.HotShards = shardLoad(c.shards.ops, hotShards)
+	}
+	return stats
+}
+
+// shardLoad summarizes per-shard operation counts, returning the minimum,
+// maximum and mean along with the top most loaded shards
+func shardLoad(ops []atomic.Int64, top int) (min, max int64, mean float64, hot []ShardLoad) {
+	if len(ops) == 0 {
+		return 0, 0, 0, nil
+	}
+
+	loads := make([]ShardLoad, len(ops))
+	var total int64
+	for i := range ops {
+		n := ops[i].Load()
+		loads[i] = ShardLoad{Shard: i, Ops: n}
+		total += n
+		if i == 0 || n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+
+	sort.SliceStable(loads, func(i, j int) bool {
+		return loads[i].Ops > loads[j].Ops
+	})
+	if top > len(loads) {
+		top = len(loads)
+	}
+
+	return min, max, float64(total) / float64(len(ops)), loads[:top]
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"hash/fnv"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestShardHasher will test that keys hash like bigcache's default FNV-1a
+// hasher and are counted against the shard they map to
+func TestShardHasher(t *testing.T) {
+	h := newShardHasher(16)
+
+	for _, key := range []string{"", "0/0/0", "14/8192/5461", "osm:v2:3/4/5"} {
+		expected := fnv.New64a()
+		_, _ = expected.Write([]byte(key))
+
+		got := h.Sum64(key)
+		if got != expected.Sum64() {
+			t.Errorf(str.TCacheBadShardHash, key, got, expected.Sum64())
+		}
+		if h.ops[got&15].Load() == 0 {
+			t.Errorf(str.TCacheBadShardLoad, key)
+		}
+	}
+}
+
+// TestShardLoad will test that per-shard operation counts are summarized
+// with the most loaded shards first
+func TestShardLoad(t *testing.T) {
+	h := newShardHasher(4)
+	for shard, n := range []int64{3, 9, 0, 4} {
+		h.ops[shard].Store(n)
+	}
+
+	min, max, mean, hot := shardLoad(h.ops, 2)
+	if min != 0 || max != 9 || mean != 4 {
+		t.Errorf(str.TCacheBadShardSummary, min, max, mean)
+	}
+	if len(hot) != 2 || hot[0].Shard != 1 || hot[1].Shard != 3 {
+		t.Errorf(str.TCacheBadHotShards, hot)
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+// sparseTree tracks the tile subtrees known to be empty upstream by the
+// quadkeys of their roots. A tile is known empty if its own quadkey or that of
+// any of its ancestors is a root.
+type sparseTree struct {
+	mu    sync.RWMutex
+	roots map[string]struct{}
+}
+
+// newSparseTree returns an empty tree holding the given roots
+func newSparseTree(roots []string) *sparseTree {
+	t := &sparseTree{roots: make(map[string]struct{}, len(roots))}
+	for _, root := range roots {
+		t.roots[root] = struct{}{}
+	}
+	return t
+}
+
+// contains returns true if the quadkey or any of its prefixes is a root
+func (t *sparseTree) contains(quadkey string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for i := 0; i <= len(quadkey); i++ {
+		if _, ok := t.roots[quadkey[:i]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// add makes the quadkey a root, returning false if it was already covered
+func (t *sparseTree) add(quadkey string) bool {
+	if t.contains(quadkey) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.roots[quadkey] = struct{}{}
+	return true
+}
+
+// remove drops all roots covering the quadkey or covered by it, returning them
+func (t *sparseTree) remove(quadkey string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var removed []string
+	for root := range t.roots {
+		if strings.HasPrefix(quadkey, root) || strings.HasPrefix(root, quadkey) {
+			removed = append(removed, root)
+			delete(t.roots, root)
+		}
+	}
+	return removed
+}
+
+// reset drops all roots
+func (t *sparseTree) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.roots = make(map[string]struct{})
+}
+
+// size returns the number of roots
+func (t *sparseTree) size() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.roots)
+}
+
+// sparseKey returns the Redis key persisting the proxy's known empty subtrees
+func (c *Cache) sparseKey() string {
+	return fmt.Sprintf("%s:sparse:%s", config.Namespace, c.Proxy.Name)
+}
+
+// initSparse loads the known empty subtrees persisted to Redis, if enabled
+func (c *Cache) initSparse() error {
+	if !c.Proxy.Sparse.Enabled {
+		return nil
+	}
+
+	var roots []string
+	if c.Proxy.Cache.RedisEnabled {
+		var err error
+		if roots, err = c.external.SMembers(context.Background(), c.sparseKey()).Result(); err != nil {
+			return err
+		}
+	}
+
+	c.sparse = newSparseTree(roots)
+	return nil
+}
+
+// KnownEmpty returns true if the tile lies in a subtree known to be empty
+// upstream, so it can be answered without contacting the upstream
+func (c *Cache) KnownEmpty(t tile.Tile) bool {
+	if c.sparse == nil || !c.sparse.contains(t.Quadkey()) {
+		return false
+	}
+	c.Metrics.SparseSkips.Inc()
+	return true
+}
+
+// MarkEmpty records the tile's subtree as empty upstream after the upstream
+// responded without data for it. Tiles shallower than the configured minimum
+// zoom level don't mark their subtrees.
+func (c *Cache) MarkEmpty(t tile.Tile) {
+	if c.sparse == nil || t.Zoom < c.Proxy.Sparse.MinZoom {
+		return
+	}
+
+	quadkey := t.Quadkey()
+	if !c.sparse.add(quadkey) || !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return
+	}
+
+	if err := c.external.SAdd(context.Background(), c.sparseKey(), quadkey).Err(); err != nil {
+		util.Error(str.CCache, str.ECacheSparse, c.Proxy.Name, err.Error())
+	}
+}
+
+// ForgetEmpty invalidates any known empty subtrees containing the tile or
+// contained in its subtree, so they are fetched from the upstream again
+func (c *Cache) ForgetEmpty(ctx context.Context, t tile.Tile) error {
+	if c.sparse == nil {
+		return nil
+	}
+
+	removed := c.sparse.remove(t.Quadkey())
+	if len(removed) == 0 || !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return nil
+	}
+
+	members := make([]interface{}, len(removed))
+	for i, root := range removed {
+		members[i] = root
+	}
+	return c.external.SRem(ctx, c.sparseKey(), members...).Err()
+}
+
+// ResetEmpty invalidates all known empty subtrees of the proxy
+func (c *Cache) ResetEmpty(ctx context.Context) error {
+	if c.sparse == nil {
+		return nil
+	}
+
+	c.sparse.reset()
+	if !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return nil
+	}
+	return c.external.Del(ctx, c.sparseKey()).Err()
+}
+
+// EmptySubtrees returns the number of subtrees known to be empty upstream
+func (c *Cache) EmptySubtrees() int {
+	if c.sparse == nil {
+		return 0
+	}
+	return c.sparse.size()
+}
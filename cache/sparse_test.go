@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestSparseTree will test that tiles below known empty roots are covered,
+// and that removing a tile drops the roots above and below it
+func TestSparseTree(t *testing.T) {
+	tree := newSparseTree([]string{"12", "3001"})
+
+	tests := map[string]bool{
+		"12":     true,
+		"1203":   true,
+		"1":      false,
+		"13":     false,
+		"30013":  true,
+		"300":    false,
+		"022222": false,
+	}
+	for quadkey, expected := range tests {
+		if got := tree.contains(quadkey); got != expected {
+			t.Errorf(str.TCacheBadSparse, quadkey, got, expected)
+		}
+	}
+
+	if tree.add("1201") {
+		t.Errorf(str.TCacheBadSparse, "1201", true, false)
+	}
+	if !tree.add("2") {
+		t.Errorf(str.TCacheBadSparse, "2", false, true)
+	}
+
+	removed := tree.remove("30")
+	sort.Strings(removed)
+	if !reflect.DeepEqual(removed, []string{"3001"}) {
+		t.Errorf(str.TCacheBadSparseRemove, removed, []string{"3001"})
+	}
+
+	removed = tree.remove("1203")
+	if !reflect.DeepEqual(removed, []string{"12"}) {
+		t.Errorf(str.TCacheBadSparseRemove, removed, []string{"12"})
+	}
+
+	if tree.size() != 1 || !tree.contains("2013") {
+		t.Errorf(str.TCacheBadSparse, "2013", tree.contains("2013"), true)
+	}
+}
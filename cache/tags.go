@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// tagKey returns the Redis key of the set tracking all tiles with the given tag
+func (c *Cache) tagKey(tag string) string {
+	return fmt.Sprintf("%s:tags:%s:%s", config.Namespace, c.Proxy.Name, tag)
+}
+
+// Tag records the tile at the given cache key as a member of each given tag
+func (c *Cache) Tag(ctx context.Context, key string, tags []string) {
+	if len(tags) == 0 || !c.Proxy.Cache.RedisEnabled || config.IsReadOnly() {
+		return
+	}
+
+	pipe := c.external.Pipeline()
+	for _, tag := range tags {
+		pipe.SAdd(ctx, c.tagKey(tag), key)
+
+		// keep tag sets alive for as long as the tiles they track
+		if c.Proxy.Cache.RedisTTLDuration > 0 {
+			pipe.Expire(ctx, c.tagKey(tag), c.Proxy.Cache.RedisTTLDuration)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Error(str.CCache, str.ECacheTag, key, err.Error())
+	}
+}
+
+// PurgeTag invalidates all tiles with the given tag from all cache levels and
+// removes the tag, returning the number of tiles purged. Tags can't be purged
+// in read-only mode, as tagged tiles would remain in Redis
+func (c *Cache) PurgeTag(ctx context.Context, tag string) (int, error) {
+	if !c.Proxy.Cache.RedisEnabled {
+		return 0, nil
+	}
+
+	if config.IsReadOnly() {
+		return 0, ErrReadOnly{Name: c.Proxy.Name}
+	}
+
+	keys, err := c.external.SMembers(ctx, c.tagKey(tag)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, key := range keys {
+		if err = c.Invalidate(key, ctx); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, c.external.Del(ctx, c.tagKey(tag)).Err()
+}
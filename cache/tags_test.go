@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestPurgeTagReadOnly will test that read-only instances refuse to purge
+// tags rather than reporting tiles purged while they remain in Redis
+func TestPurgeTagReadOnly(t *testing.T) {
+	t.Setenv("READ_ONLY", "true")
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{Name: "tags", Cache: config.Cache{RedisEnabled: true}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+
+	// tiles tagged by an instance writing to redis
+	if _, err := server.SetAdd(c.tagKey("roads"), "1/0/0", "1/0/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := c.PurgeTag(context.Background(), "roads")
+	if purged != 0 || !errors.As(err, &ErrReadOnly{}) || !server.Exists(c.tagKey("roads")) {
+		t.Errorf(str.TCacheBadPurgeTag, purged, err, server.Keys())
+	}
+}
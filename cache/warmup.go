@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"github.com/dechristopher/lod/util"
+)
+
+// WarmupState reports a cache's progress towards its warm-up conditions
+type WarmupState struct {
+	Ready       bool    `json:"ready"`        // whether the cache is considered warm
+	FillPercent float64 `json:"fill_percent"` // approximate in-memory cache fill percentage
+	TileList    bool    `json:"tile_list"`    // whether the warm-up tile list has been fetched
+}
+
+// FillPercent returns the approximate percentage of the in-memory cache's
+// capacity currently holding tiles
+func (c *Cache) FillPercent() float64 {
+	if !c.Proxy.Cache.MemEnabled || c.Proxy.Cache.MemCap <= 0 {
+		return 0
+	}
+
+	fill := float64(c.memBytes.Load()) / float64(c.Proxy.Cache.MemCap*OneMB) * 100
+	if fill < 0 {
+		return 0
+	}
+	if fill > 100 {
+		return 100
+	}
+	return fill
+}
+
+// SetWarmupListDone marks the warm-up tile list as fetched
+func (c *Cache) SetWarmupListDone() {
+	c.warmList.Store(true)
+}
+
+// Warm returns true once the cache has met its warm-up conditions or the
+// warm-up timeout has elapsed. Once warm, a cache stays warm so that normal
+// eviction never flaps instance readiness.
+func (c *Cache) Warm() bool {
+	if c.warm.Load() {
+		return true
+	}
+
+	w := c.Proxy.Warmup
+	if !w.Enabled() || util.TimeSinceBoot() >= w.TimeoutDuration {
+		c.warm.Store(true)
+		return true
+	}
+
+	if w.TileList != "" && !c.warmList.Load() {
+		return false
+	}
+
+	if w.FillPercent > 0 && c.FillPercent() < w.FillPercent {
+		return false
+	}
+
+	c.warm.Store(true)
+	return true
+}
+
+// WarmupState returns the cache's current warm-up progress
+func (c *Cache) WarmupState() WarmupState {
+	return WarmupState{
+		Ready:       c.Warm(),
+		FillPercent: c.FillPercent(),
+		TileList:    c.Proxy.Warmup.TileList == "" || c.warmList.Load(),
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+func TestWarmFillPercent(t *testing.T) {
+	util.BootTime = time.Now()
+	c := &Cache{
+		Proxy: &config.Proxy{
+			Cache:  config.Cache{MemEnabled: true, MemCap: 10},
+			Warmup: config.Warmup{FillPercent: 50, TimeoutDuration: time.Hour},
+		},
+		memBytes: &atomic.Int64{},
+	}
+
+	c.memBytes.Store(4 * OneMB)
+	if c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, true, false)
+	}
+
+	c.memBytes.Store(5 * OneMB)
+	if !c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, false, true)
+	}
+
+	// warm state is latched against later eviction
+	c.memBytes.Store(0)
+	if !c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, false, true)
+	}
+}
+
+func TestWarmTileList(t *testing.T) {
+	util.BootTime = time.Now()
+	c := &Cache{
+		Proxy: &config.Proxy{
+			Warmup: config.Warmup{TileList: "tiles.txt", TimeoutDuration: time.Hour},
+		},
+		memBytes: &atomic.Int64{},
+	}
+
+	if c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, true, false)
+	}
+
+	c.SetWarmupListDone()
+	if !c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, false, true)
+	}
+}
+
+func TestWarmTimeout(t *testing.T) {
+	c := &Cache{
+		Proxy: &config.Proxy{
+			Warmup: config.Warmup{TileList: "tiles.txt", TimeoutDuration: time.Nanosecond},
+		},
+		memBytes: &atomic.Int64{},
+	}
+
+	if !c.Warm() {
+		t.Fatalf(str.TCacheBadWarm, false, true)
+	}
+}
+
+// TestWarmFillNet will test that the in-memory byte count behind the fill
+// percentage holds the bytes of live entries only, so repeatedly setting the
+// same key, as every cache hit does, doesn't grow it
+func TestWarmFillNet(t *testing.T) {
+	for _, engine := range []string{config.MemEngineBigcache, config.MemEngineRistretto} {
+		for _, eviction := range []string{"", config.EvictionPolicies[0]} {
+			proxy := config.Proxy{Cache: config.Cache{
+				MemEnabled:     true,
+				MemCap:         4,
+				MemTTLDuration: time.Hour,
+				MemEngine:      engine,
+				Eviction:       eviction,
+				Bigcache:       config.Bigcache{Shards: 16, MaxEntrySize: 4},
+				Ristretto:      config.Ristretto{Counters: 1000},
+			}}
+
+			memBytes := &atomic.Int64{}
+			internal, _, err := initInternal(proxy, memBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Cache{internal: withEviction(internal, proxy, nil), Proxy: &proxy, memBytes: memBytes}
+
+			// ristretto applies writes asynchronously
+			settle := func() {
+				if r, ok := internal.(*ristrettoEngine); ok {
+					r.cache.Wait()
+				}
+			}
+
+			tile := packet.Encode(make([]byte, 1024), nil)
+			c.Set("0/0/0", tile, true)
+			settle()
+			expected := memBytes.Load()
+			if expected != int64(len(tile)) {
+				t.Fatalf(str.TCacheBadFill, engine, "set", expected, len(tile))
+			}
+
+			for i := 0; i < 100; i++ {
+				c.Set("0/0/0", tile, true)
+			}
+			settle()
+			if got := memBytes.Load(); got != expected {
+				t.Errorf(str.TCacheBadFill, engine, "repeated sets", got, expected)
+			}
+
+			if err = c.Invalidate("0/0/0", context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			settle()
+			if got := memBytes.Load(); got != 0 {
+				t.Errorf(str.TCacheBadFill, engine, "invalidate", got, 0)
+			}
+		}
+	}
+}
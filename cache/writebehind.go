@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// pendingWrite is a redis write waiting for its batch to be sent
+type pendingWrite struct {
+	key   string
+	value []byte
+	ttl   time.Duration
+	index string // debug index entry key holding the cache key, if any
+	orig  string // cache key the redis key was hashed from
+}
+
+// writeBehind queues redis writes and sends them as pipelines from a single
+// goroutine, once a batch fills or the flush interval passes
+type writeBehind struct {
+	c      *Cache
+	mu     sync.RWMutex // guards closed against writes queued while closing
+	closed bool
+	queue  chan pendingWrite
+	syncs  chan chan struct{} // requests to send all pending writes, answered once sent
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// newWriteBehind builds and starts batching redis writes of the cache
+func newWriteBehind(c *Cache) *writeBehind {
+	w := &writeBehind{
+		c:     c,
+		queue: make(chan pendingWrite, c.Proxy.Cache.WriteBehind.QueueSize),
+		syncs: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues a write for the next batch, returning false if it must be
+// sent on its own because batching is disabled, closed or the queue is full
+func (w *writeBehind) enqueue(write pendingWrite) bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return false
+	}
+
+	select {
+	case w.queue <- write:
+		return true
+	default:
+		return false
+	}
+}
+
+// sync sends all writes queued so far and waits for them, so that deleting
+// their keys afterwards can't be undone by a write still pending
+func (w *writeBehind) sync() {
+	if w == nil {
+		return
+	}
+
+	sent := make(chan struct{})
+	select {
+	case w.syncs <- sent:
+		<-sent
+	case <-w.done:
+		// pending writes were sent on close
+	}
+}
+
+// close sends all pending writes and stops batching
+func (w *writeBehind) close() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	close(w.stop)
+	<-w.done
+}
+
+// run batches queued writes, sending a batch once it is full or the flush
+// interval passes, until closed
+func (w *writeBehind) run() {
+	defer close(w.done)
+
+	settings := w.c.Proxy.Cache.WriteBehind
+	ticker := time.NewTicker(settings.IntervalDuration)
+	defer ticker.Stop()
+
+	batch := make([]pendingWrite, 0, settings.BatchSize)
+	add := func(write pendingWrite) {
+		batch = append(batch, write)
+		if len(batch) >= settings.BatchSize {
+			batch = w.flush(batch)
+		}
+	}
+
+	// drain moves all queued writes into the batch
+	drain := func() {
+		for {
+			select {
+			case write := <-w.queue:
+				add(write)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case write := <-w.queue:
+			add(write)
+		case <-ticker.C:
+			batch = w.flush(batch)
+		case sent := <-w.syncs:
+			drain()
+			batch = w.flush(batch)
+			close(sent)
+		case <-w.stop:
+			// nothing is queued once closed, so drain what's left
+			drain()
+			w.flush(batch)
+			return
+		}
+	}
+}
+
+// flush sends a batch of writes as a single pipeline, returning the emptied
+// batch for reuse
+func (w *writeBehind) flush(batch []pendingWrite) []pendingWrite {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	w.c.Metrics.RedisWriteBatches.Observe(float64(len(batch)))
+
+	// retry transient errors, rewriting the whole batch since sets are idempotent
+	ctx := context.Background()
+	err := w.c.withRetry(ctx, RedisOpSet, func() error {
+		_, err := w.c.external.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, write := range batch {
+				pipe.Set(ctx, write.key, write.value, write.ttl)
+				if write.index != "" {
+					pipe.Set(ctx, write.index, write.orig, write.ttl)
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		util.Error(str.CCache, str.ECacheSetMany, len(batch), err.Error())
+	}
+
+	return batch[:0]
+}
+
+// Close cancels background jobs running against the cache and waits for
+// them, stops scheduled flushes and following generation switches, and sends
+// the redis writes still pending, if write-behind batching is enabled. Later
+// writes are sent on their own.
+func (c *Cache) Close() {
+	c.closeJobs()
+	c.flushes.close()
+	c.generations.close()
+	c.writes.close()
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestWriteBehind will test that redis writes are sent once their batch
+// fills, that pending writes are flushed on close, and that writes made after
+// closing are still sent on their own
+func TestWriteBehind(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{Name: "writebehind", Cache: config.Cache{
+		RedisEnabled: true,
+		WriteBehind: config.WriteBehind{
+			Enabled:          true,
+			IntervalDuration: time.Hour,
+			BatchSize:        2,
+			QueueSize:        10,
+		},
+	}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+	c.writes = newWriteBehind(c)
+
+	tile := packet.Encode([]byte("tile"), nil)
+	for _, key := range []string{"a", "b", "c"} {
+		c.set(key, tile, false, true)
+	}
+
+	// the first batch is sent once full, the last write waits for the flush
+	deadline := time.Now().Add(time.Second)
+	for !server.Exists("b") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !server.Exists("a") || !server.Exists("b") || server.Exists("c") {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+
+	c.Close()
+	if !server.Exists("c") {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+
+	c.set("d", tile, false, true)
+	deadline = time.Now().Add(time.Second)
+	for !server.Exists("d") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !server.Exists("d") {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+}
+
+// TestWriteBehindInvalidate will test that invalidating a tile whose write is
+// still pending sends the write first, so the tile stays invalidated
+func TestWriteBehindInvalidate(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	proxy := config.Proxy{Name: "writebehind", Cache: config.Cache{
+		RedisEnabled: true,
+		WriteBehind: config.WriteBehind{
+			Enabled:          true,
+			IntervalDuration: time.Hour,
+			BatchSize:        10,
+			QueueSize:        10,
+		},
+	}}
+	c := &Cache{
+		Proxy:    &proxy,
+		Metrics:  initMetrics(proxy, false),
+		external: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+	c.writes = newWriteBehind(c)
+
+	tile := packet.Encode([]byte("tile"), nil)
+	c.set("a", tile, false, true)
+	c.set("b", tile, false, true)
+	if err := c.Invalidate("a", context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+	if server.Exists("a") || !server.Exists("b") {
+		t.Errorf(str.TCacheBadWriteBehind, server.Keys())
+	}
+}
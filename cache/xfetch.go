@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// assumed upstream fetch time until one has been observed
+const defaultFetchTime = 100 * time.Millisecond
+
+// ObserveFetch records the time an upstream fetch took, used to estimate how
+// early tiles should be refetched ahead of expiry
+func (c *Cache) ObserveFetch(d time.Duration) {
+	// exponentially weighted moving average, approximate under concurrent use
+	avg := c.fetchTime.Load()
+	if avg == 0 {
+		c.fetchTime.Store(int64(d))
+		return
+	}
+	c.fetchTime.Store(avg + (int64(d)-avg)/10)
+}
+
+// expiresEarly returns true if a tile of the given age should be treated as
+// expired ahead of the proxy's max staleness, per probabilistic early
+// expiration
+func (c *Cache) expiresEarly(age time.Duration) bool {
+	delta := time.Duration(c.fetchTime.Load())
+	if delta == 0 {
+		delta = defaultFetchTime
+	}
+	return xfetch(age, c.Proxy.Cache.MaxStaleDuration, delta, c.Proxy.Cache.XFetchBeta, rand.Float64())
+}
+
+// xfetch decides whether to recompute a value of the given age and lifetime
+// early, given the time a recompute takes, the eagerness beta, and a uniform
+// random number in [0, 1). The chance grows exponentially as expiry nears, so
+// concurrent readers of a hot value rarely refetch it at the same time.
+func xfetch(age, lifetime, delta time.Duration, beta, random float64) bool {
+	if random <= 0 {
+		return true
+	}
+	early := time.Duration(float64(delta) * beta * -math.Log(random))
+	return age+early >= lifetime
+}
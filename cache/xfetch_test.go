@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestXFetch will test that tiles are only expired early close to their max
+// staleness, with a chance growing as they approach it
+func TestXFetch(t *testing.T) {
+	lifetime := time.Hour
+	delta := time.Second
+
+	tests := []struct {
+		age      time.Duration
+		random   float64
+		expected bool
+	}{
+		// fresh tiles are kept even with unlikely random draws
+		{time.Minute, 0.001, false},
+		// tiles close to expiry are refetched with likely draws only
+		{lifetime - 2*time.Second, 0.01, true},
+		{lifetime - 2*time.Second, 0.9, false},
+		// expired tiles are always refetched
+		{lifetime, 0.999, true},
+		{time.Minute, 0, true},
+	}
+
+	for _, test := range tests {
+		if got := xfetch(test.age, lifetime, delta, 1, test.random); got != test.expected {
+			t.Errorf(str.TCacheBadXFetch, test.age, test.random, got)
+		}
+	}
+
+	// higher beta refetches earlier
+	if !xfetch(lifetime-time.Minute, lifetime, delta, 100, 0.5) {
+		t.Errorf(str.TCacheBadXFetch, lifetime-time.Minute, 0.5, false)
+	}
+}
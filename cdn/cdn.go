@@ -0,0 +1,47 @@
+package cdn
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Purger purges cached objects from a downstream CDN
+type Purger interface {
+	// PurgeURLs purges the given public URLs from the CDN
+	PurgeURLs(urls []string) error
+	// PurgeTags purges all objects with the given surrogate key tags from the
+	// CDN, if supported by the provider
+	PurgeTags(tags []string) error
+	// TagHeader returns the response header name and value the CDN reads the
+	// given surrogate key tags from, or empty strings if tag purging is not supported
+	TagHeader(tags []string) (string, string)
+}
+
+// client used for all CDN API requests
+var client = &http.Client{Timeout: time.Second * 30}
+
+// New builds the Purger for the given proxy's CDN configuration, returning
+// nil if no CDN is configured
+func New(conf config.CDN) Purger {
+	switch conf.Provider {
+	case config.CDNFastly:
+		return &fastly{conf: conf}
+	case config.CDNCloudflare:
+		return &cloudflare{conf: conf}
+	case config.CDNCloudFront:
+		return &cloudFront{conf: conf}
+	}
+
+	return nil
+}
+
+// batch splits the given values into batches of at most size values
+func batch(values []string, size int) [][]string {
+	var batches [][]string
+	for size < len(values) {
+		values, batches = values[size:], append(batches, values[:size])
+	}
+	return append(batches, values)
+}
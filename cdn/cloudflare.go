@@ -0,0 +1,76 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// cloudflareAPI is the base URL of the Cloudflare API
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareBatchSize is the maximum number of files or tags per purge request
+const cloudflareBatchSize = 30
+
+// cloudflare purges objects through the Cloudflare cache purge API
+type cloudflare struct {
+	conf config.CDN
+}
+
+// PurgeURLs purges the given URLs in batches
+func (c *cloudflare) PurgeURLs(urls []string) error {
+	for _, files := range batch(urls, cloudflareBatchSize) {
+		if err := c.purge(map[string][]string{"files": files}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeTags purges the given cache tags in batches
+func (c *cloudflare) PurgeTags(tags []string) error {
+	for _, batchTags := range batch(tags, cloudflareBatchSize) {
+		if err := c.purge(map[string][]string{"tags": batchTags}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagHeader returns the comma separated header Cloudflare reads cache tags from
+func (c *cloudflare) TagHeader(tags []string) (string, string) {
+	return "Cache-Tag", strings.Join(tags, ",")
+}
+
+// purge makes an authenticated purge request to the Cloudflare API
+func (c *cloudflare) purge(body map[string][]string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/zones/%s/purge_cache", cloudflareAPI, c.conf.ZoneID), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.conf.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrPurge{Provider: c.conf.Provider, Status: resp.StatusCode}
+	}
+
+	return nil
+}
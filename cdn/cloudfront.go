@@ -0,0 +1,141 @@
+package cdn
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// CloudFront API endpoint and request signing constants
+const (
+	cloudFrontHost      = "cloudfront.amazonaws.com"
+	cloudFrontRegion    = "us-east-1"
+	cloudFrontService   = "cloudfront"
+	cloudFrontBatchSize = 3000
+	amzDateFormat       = "20060102T150405Z"
+)
+
+// cloudFront purges objects by creating CloudFront invalidations
+type cloudFront struct {
+	conf config.CDN
+}
+
+// invalidationBatch is the XML request body of a CloudFront invalidation
+type invalidationBatch struct {
+	XMLName         xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Items           []string `xml:"Paths>Items>Path"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// PurgeURLs invalidates the paths of the given URLs in batches
+func (c *cloudFront) PurgeURLs(urls []string) error {
+	paths := make([]string, 0, len(urls))
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, parsed.RequestURI())
+	}
+
+	for i, batchPaths := range batch(paths, cloudFrontBatchSize) {
+		if err := c.invalidate(batchPaths, i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeTags is not supported by CloudFront
+func (c *cloudFront) PurgeTags(_ []string) error {
+	return nil
+}
+
+// TagHeader returns no header since CloudFront does not support tag purging
+func (c *cloudFront) TagHeader(_ []string) (string, string) {
+	return "", ""
+}
+
+// invalidate creates a single signed CloudFront invalidation for the given paths
+func (c *cloudFront) invalidate(paths []string, num int) error {
+	body, err := xml.Marshal(invalidationBatch{
+		Quantity:        len(paths),
+		Items:           paths,
+		CallerReference: "lod-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.Itoa(num),
+	})
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/2020-05-31/distribution/%s/invalidation", c.conf.DistributionID)
+	req, err := http.NewRequest(http.MethodPost, "https://"+cloudFrontHost+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	c.sign(req, path, body, time.Now().UTC())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return ErrPurge{Provider: c.conf.Provider, Status: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// sign the request using AWS Signature Version 4
+func (c *cloudFront) sign(req *http.Request, path string, body []byte, now time.Time) {
+	amzDate := now.Format(amzDateFormat)
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("Host", cloudFrontHost)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := fmt.Sprintf("%s\n%s\n\ncontent-type:text/xml\nhost:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n\n%s\n%s",
+		http.MethodPost, path, cloudFrontHost, payloadHash, amzDate, signedHeaders, payloadHash)
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, cloudFrontRegion, cloudFrontService)
+	stringToSign := fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s\n%s", amzDate, scope, sha256Hex([]byte(canonicalRequest)))
+
+	key := hmacSHA256([]byte("AWS4"+c.conf.SecretAccessKey), date)
+	key = hmacSHA256(key, cloudFrontRegion)
+	key = hmacSHA256(key, cloudFrontService)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.conf.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// sha256Hex returns the hex encoded SHA-256 hash of the given data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the given data with the given key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,14 @@
+package cdn
+
+import "fmt"
+
+// ErrPurge is an error struct for failed CDN purge requests
+type ErrPurge struct {
+	Provider string
+	Status   int
+}
+
+// Error returns the string representation of ErrPurge
+func (e ErrPurge) Error() string {
+	return fmt.Sprintf("cdn: %s purge request failed with status %d", e.Provider, e.Status)
+}
@@ -0,0 +1,71 @@
+package cdn
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// fastlyAPI is the base URL of the Fastly API
+const fastlyAPI = "https://api.fastly.com"
+
+// fastly purges objects through the Fastly purge API
+type fastly struct {
+	conf config.CDN
+}
+
+// PurgeURLs purges each of the given URLs individually
+func (f *fastly) PurgeURLs(urls []string) error {
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return err
+		}
+
+		if err = f.do(fmt.Sprintf("%s/purge/%s%s", fastlyAPI, parsed.Host, parsed.RequestURI()), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeTags purges the given surrogate keys in a single request
+func (f *fastly) PurgeTags(tags []string) error {
+	header := http.Header{}
+	header.Set("Surrogate-Key", strings.Join(tags, " "))
+	return f.do(fmt.Sprintf("%s/service/%s/purge", fastlyAPI, f.conf.ServiceID), header)
+}
+
+// TagHeader returns the space separated header Fastly reads surrogate keys from
+func (f *fastly) TagHeader(tags []string) (string, string) {
+	return "Surrogate-Key", strings.Join(tags, " ")
+}
+
+// do makes an authenticated purge request to the Fastly API
+func (f *fastly) do(endpoint string, header http.Header) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+	req.Header.Set("Fastly-Key", f.conf.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrPurge{Provider: f.conf.Provider, Status: resp.StatusCode}
+	}
+
+	return nil
+}
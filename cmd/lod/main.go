@@ -9,11 +9,23 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"github.com/dechristopher/lod/accesslog"
 	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/corsdebug"
+	"github.com/dechristopher/lod/dnscache"
 	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/fleet"
+	"github.com/dechristopher/lod/geoip"
+	"github.com/dechristopher/lod/heatmap"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/probe"
+	"github.com/dechristopher/lod/slo"
+	"github.com/dechristopher/lod/statsd"
 	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/upstream"
 	"github.com/dechristopher/lod/util"
+	"github.com/dechristopher/lod/webhook"
 	"github.com/dechristopher/lod/www"
 )
 
@@ -45,14 +57,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	// print message if in read-only mode
+	if config.IsReadOnly() {
+		util.Info(str.CMain, str.MReadOnly)
+	}
+
 	// initialize cache instances
-	if err := cache.Init(); err != nil {
+	caches := cache.NewManager()
+	if err := caches.Init(config.Get()); err != nil {
+		util.Error(str.CMain, str.EConfig, err.Error())
+		os.Exit(1)
+	}
+
+	// resolve upstream hostnames through the DNS cache, if enabled
+	dnscache.Init()
+
+	// initialize upstream address pools
+	upstream.Init()
+
+	// refuse proxies whose upstream leads back to this instance
+	if err := upstream.CheckLoops(config.Get()); err != nil {
 		util.Error(str.CMain, str.EConfig, err.Error())
 		os.Exit(1)
 	}
 
+	// discover unset proxy coverage from upstream metadata in the background
+	helpers.DiscoverAll(caches)
+
+	// open the GeoIP database, if configured
+	if err := geoip.Init(); err != nil {
+		util.Error(str.CMain, str.EConfig, err.Error())
+		os.Exit(1)
+	}
+
+	// track service level objectives
+	slo.Init()
+
+	// aggregate tile request heatmaps
+	heatmap.Init()
+
+	// capture sampled CORS decisions
+	corsdebug.Init()
+
+	// post request summaries to the webhook collector
+	webhook.Init()
+
+	// write request records to the access log sink, if configured
+	if err := accesslog.Init(); err != nil {
+		util.Error(str.CMain, str.EConfig, err.Error())
+		os.Exit(1)
+	}
+
+	// emit metrics to the statsd agent, if configured
+	if err := statsd.Init(); err != nil {
+		util.Error(str.CMain, str.EConfig, err.Error())
+		os.Exit(1)
+	}
+
+	// register the instance in the fleet registry, if configured
+	fleet.Init()
+
+	// start synthetic probes
+	probe.Init()
+
 	// serve LOD endpoints
-	www.Serve()
+	www.Serve(caches)
 }
 
 // parseFlags parses and processes command line flags
@@ -61,6 +130,7 @@ func parseFlags() {
 	// parse command line flags
 	config.File = flag.String(str.FConfigFile, "config.toml", str.FConfigFileUsage)
 	env.IsDevFlag = flag.Bool(str.FDevMode, false, str.FDevModeUsage)
+	config.ReadOnlyFlag = flag.Bool(str.FReadOnly, false, str.FReadOnlyUsage)
 	util.DebugFlagPtr = flag.String(str.FDebugFlags, "", str.FDebugFlagsUsage)
 	help := flag.Bool(str.FHelp, false, str.FHelpUsage)
 	flag.Parse()
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build details of the running LOD binary
+type Build struct {
+	Version   string `json:"version"`    // LOD version
+	Commit    string `json:"commit"`     // VCS revision built, "unknown" if not stamped
+	BuildDate string `json:"build_date"` // time of the build or of its commit, "unknown" if not stamped
+	GoVersion string `json:"go_version"` // version of Go the binary was built with
+	Modified  bool   `json:"modified"`   // whether the tree had uncommitted changes when built
+}
+
+// GetBuild returns the build details of the running binary
+func GetBuild() Build {
+	build := Build{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if build.Commit == "" {
+					build.Commit = setting.Value
+				}
+			case "vcs.time":
+				if build.BuildDate == "" {
+					build.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				build.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if build.Commit == "" {
+		build.Commit = "unknown"
+	}
+	if build.BuildDate == "" {
+		build.BuildDate = "unknown"
+	}
+	return build
+}
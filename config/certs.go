@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// certCheckInterval is the minimum time between checks of client certificate
+// files for rotation
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves an upstream client certificate, reloading it from disk
+// whenever the certificate or key file changes so rotated certificates are
+// picked up without restarting LOD
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// newCertReloader loads the given client certificate and key pair, returning
+// an error if the initial load fails
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetClientCertificate returns the current client certificate, reloading it
+// first if the files on disk have changed. Reload failures are logged and the
+// previously loaded certificate continues to be used.
+func (r *certReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= certCheckInterval {
+		r.checked = time.Now()
+		if r.latestModTime().After(r.modTime) {
+			if err := r.load(); err != nil {
+				util.Error(str.CProxy, str.ECertReload, r.certFile, err.Error())
+			} else {
+				util.Info(str.CProxy, str.MCertReload, r.certFile)
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+// load reads the certificate and key pair from disk
+func (r *certReloader) load() error {
+	modTime := r.latestModTime()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	r.checked = time.Now()
+
+	return nil
+}
+
+// latestModTime returns the most recent modification time of the certificate
+// and key files
+func (r *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff is the structural difference between the running configuration and a
+// candidate configuration, listing config file keys rather than values so
+// secrets aren't revealed
+type Diff struct {
+	Instance []string    `json:"instance"` // keys of instance settings that differ
+	Added    []string    `json:"added"`    // names of proxies only in the candidate
+	Removed  []string    `json:"removed"`  // names of proxies only in the running configuration
+	Changed  []ProxyDiff `json:"changed"`  // proxies in both whose settings differ
+}
+
+// ProxyDiff lists the settings of a proxy that differ between configurations
+type ProxyDiff struct {
+	Name   string   `json:"name"`   // name of the proxy
+	Fields []string `json:"fields"` // keys of the settings that differ, ex: cache.mem_ttl
+	// caches are kept across reloads, so changes to the cache settings of a
+	// proxy take effect only once its cache is rebuilt, ex: on restart
+	CacheRebuild bool `json:"cache_rebuild"` // whether cache settings differ
+}
+
+// Parse decodes and prepares capabilities from the given raw config of the
+// given format, along with any dynamic proxies persisted by its store,
+// without loading them
+func Parse(configData []byte, format string) (Capabilities, error) {
+	dynamicMu.Lock()
+	defer dynamicMu.Unlock()
+
+	return build(configData, format, nil)
+}
+
+// DiffCapabilities returns the structural difference between the running and
+// candidate configurations, matching proxies by name
+func DiffCapabilities(running, candidate *Capabilities) Diff {
+	diff := Diff{
+		Instance: diffFields(reflect.ValueOf(running.Instance), reflect.ValueOf(candidate.Instance), ""),
+		Added:    []string{},
+		Removed:  []string{},
+		Changed:  []ProxyDiff{},
+	}
+
+	proxies := make(map[string]Proxy, len(running.Proxies))
+	for _, p := range running.Proxies {
+		proxies[p.Name] = p
+	}
+
+	for _, next := range candidate.Proxies {
+		prev, ok := proxies[next.Name]
+		if !ok {
+			diff.Added = append(diff.Added, next.Name)
+			continue
+		}
+		delete(proxies, next.Name)
+
+		fields := diffFields(reflect.ValueOf(prev), reflect.ValueOf(next), "")
+		if len(fields) == 0 {
+			continue
+		}
+
+		changed := ProxyDiff{Name: next.Name, Fields: fields}
+		for _, field := range fields {
+			if field == "cache" || strings.HasPrefix(field, "cache.") {
+				changed.CacheRebuild = true
+			}
+		}
+		diff.Changed = append(diff.Changed, changed)
+	}
+
+	for name := range proxies {
+		diff.Removed = append(diff.Removed, name)
+	}
+	sort.Strings(diff.Removed)
+
+	return diff
+}
+
+// diffFields returns the config file keys, under the given prefix, of the
+// fields differing between two configuration structs. Fields derived from
+// others aren't part of the config file and are skipped, and lists and
+// tables within lists are compared as a whole.
+func diffFields(prev, next reflect.Value, prefix string) []string {
+	fields := []string{}
+	typ := prev.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, diffFields(prev.Field(i), next.Field(i), prefix+name+".")...)
+			continue
+		}
+
+		if !reflect.DeepEqual(prev.Field(i).Interface(), next.Field(i).Interface()) {
+			fields = append(fields, prefix+name)
+		}
+	}
+
+	return fields
+}
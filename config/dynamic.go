@@ -0,0 +1,301 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Dynamic configures proxies registered at runtime through the admin API,
+// persisted to a state file or Redis and served alongside those defined in
+// the config file
+type Dynamic struct {
+	Enabled   bool   `json:"enabled" toml:"enabled"`       // whether proxies can be registered at runtime
+	StateFile string `json:"state_file" toml:"state_file"` // path of the JSON file persisting registered proxies
+	RedisURL  string `json:"-" toml:"redis_url"`           // redis connection URL persisting registered proxies, SENSITIVE
+	RedisKey  string `json:"redis_key" toml:"redis_key"`   // redis key holding registered proxies, defaults to lod:proxies
+}
+
+const (
+	defaultDynamicRedisKey = "lod:proxies"
+	dynamicRedisTimeout    = 5 * time.Second
+)
+
+var (
+	// dynamicMu serializes config loads and dynamic proxy changes
+	dynamicMu sync.Mutex
+
+	// staticData and staticFormat hold the last loaded raw config, rebuilt
+	// with the registered proxies whenever they change
+	staticData   []byte
+	staticFormat string
+)
+
+// PutProxy registers the dynamic proxy of the given name from a JSON document
+// using the config file's proxy keys, replacing any registered under the same
+// name. The proxy is validated and persisted before it is added to the
+// instance capabilities, and created is true if it wasn't registered before.
+func PutProxy(name string, doc []byte) (created bool, err error) {
+	dynamicMu.Lock()
+	defer dynamicMu.Unlock()
+
+	dynamic := capabilities.Instance.Dynamic
+	if !dynamic.Enabled {
+		return false, ErrDynamicDisabled{}
+	}
+
+	if p := findProxy(name); p != nil && !p.Dynamic {
+		return false, ErrDynamicProxyStatic{ProxyName: name}
+	}
+
+	// the URL decides the name of the proxy, overriding any in the document
+	var fields map[string]interface{}
+	if err = json.Unmarshal(doc, &fields); err != nil {
+		return false, err
+	}
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["name"] = name
+	if doc, err = json.Marshal(fields); err != nil {
+		return false, err
+	}
+
+	docs, err := readDynamic(dynamic)
+	if err != nil {
+		return false, err
+	}
+
+	created = true
+	for i := range docs {
+		if docName(docs[i]) == name {
+			docs[i] = doc
+			created = false
+		}
+	}
+	if created {
+		docs = append(docs, doc)
+	}
+
+	return created, commitDynamic(dynamic, docs)
+}
+
+// DeleteProxy removes the dynamic proxy of the given name
+func DeleteProxy(name string) error {
+	dynamicMu.Lock()
+	defer dynamicMu.Unlock()
+
+	dynamic := capabilities.Instance.Dynamic
+	if !dynamic.Enabled {
+		return ErrDynamicDisabled{}
+	}
+
+	p := findProxy(name)
+	if p != nil && !p.Dynamic {
+		return ErrDynamicProxyStatic{ProxyName: name}
+	}
+
+	docs, err := readDynamic(dynamic)
+	if err != nil {
+		return err
+	}
+
+	kept := make([]json.RawMessage, 0, len(docs))
+	for _, doc := range docs {
+		if docName(doc) != name {
+			kept = append(kept, doc)
+		}
+	}
+	if len(kept) == len(docs) {
+		return ErrDynamicProxyNotFound{ProxyName: name}
+	}
+
+	return commitDynamic(dynamic, kept)
+}
+
+// commitDynamic validates the capabilities resulting from the given dynamic
+// proxy documents, then persists the documents and serves the capabilities
+func commitDynamic(dynamic Dynamic, docs []json.RawMessage) error {
+	newCapabilities, err := build(staticData, staticFormat, docs)
+	if err != nil {
+		return err
+	}
+
+	if err = writeDynamic(dynamic, docs); err != nil {
+		return err
+	}
+
+	capabilities = newCapabilities
+	return nil
+}
+
+// findProxy returns the configured proxy of the given name, nil if none
+func findProxy(name string) *Proxy {
+	for i := range capabilities.Proxies {
+		if capabilities.Proxies[i].Name == name {
+			return &capabilities.Proxies[i]
+		}
+	}
+	return nil
+}
+
+// addDynamic decodes the given dynamic proxy documents, or those read from
+// the configured store if nil, into the given capabilities. Proxies defined
+// in the config file take precedence over dynamic proxies of the same name.
+func addDynamic(c *Capabilities, docs []json.RawMessage) error {
+	if err := validateDynamic(c.Instance.Dynamic); err != nil {
+		return err
+	}
+
+	if docs == nil {
+		var err error
+		if docs, err = readDynamic(c.Instance.Dynamic); err != nil {
+			return err
+		}
+	}
+
+	static := make(map[string]bool, len(c.Proxies))
+	for _, p := range c.Proxies {
+		static[p.Name] = true
+	}
+
+	for _, doc := range docs {
+		// decode through the config file schema, so the document shares its
+		// key names and sensitive fields aren't dropped
+		var wrapper Capabilities
+		data, err := json.Marshal(map[string]interface{}{"proxies": []json.RawMessage{doc}})
+		if err != nil {
+			return err
+		}
+		if err = decode(data, FormatYAML, &wrapper); err != nil {
+			return err
+		}
+		if len(wrapper.Proxies) != 1 {
+			continue
+		}
+
+		p := wrapper.Proxies[0]
+		if static[p.Name] {
+			util.Error(str.CMain, str.EDynamicShadowed, p.Name)
+			continue
+		}
+
+		p.Dynamic = true
+		c.Proxies = append(c.Proxies, p)
+	}
+
+	return nil
+}
+
+// validateDynamic ensures dynamic proxies have exactly one store configured
+func validateDynamic(dynamic Dynamic) error {
+	if dynamic.Enabled && (dynamic.StateFile == "") == (dynamic.RedisURL == "") {
+		return ErrDynamicStore{}
+	}
+	return nil
+}
+
+// docName returns the name of a dynamic proxy document
+func docName(doc json.RawMessage) string {
+	var named struct {
+		Name string `json:"name"`
+	}
+	_ = json.Unmarshal(doc, &named)
+	return named.Name
+}
+
+// readDynamic reads the dynamic proxy documents persisted by the configured
+// store, never returning nil without an error
+func readDynamic(dynamic Dynamic) ([]json.RawMessage, error) {
+	var data []byte
+	var err error
+
+	if dynamic.RedisURL != "" {
+		data, err = withDynamicRedis(dynamic, func(ctx context.Context, client *redis.Client, key string) ([]byte, error) {
+			return client.Get(ctx, key).Bytes()
+		})
+		if errors.Is(err, redis.Nil) {
+			err = nil
+		}
+	} else {
+		data, err = os.ReadFile(dynamic.StateFile)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+
+	docs := make([]json.RawMessage, 0)
+	if err != nil || len(data) == 0 {
+		return docs, err
+	}
+
+	return docs, json.Unmarshal(data, &docs)
+}
+
+// writeDynamic persists the given dynamic proxy documents to the configured
+// store, replacing state files atomically
+func writeDynamic(dynamic Dynamic, docs []json.RawMessage) error {
+	data, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dynamic.RedisURL != "" {
+		if IsReadOnly() {
+			return ErrDynamicReadOnly{}
+		}
+
+		_, err = withDynamicRedis(dynamic, func(ctx context.Context, client *redis.Client, key string) ([]byte, error) {
+			return nil, client.Set(ctx, key, data, 0).Err()
+		})
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dynamic.StateFile), filepath.Base(dynamic.StateFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dynamic.StateFile)
+}
+
+// withDynamicRedis runs fn against a short-lived connection to the dynamic
+// proxy store
+func withDynamicRedis(dynamic Dynamic,
+	fn func(ctx context.Context, client *redis.Client, key string) ([]byte, error)) ([]byte, error) {
+	opts, err := redis.ParseURL(dynamic.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	defer func() { _ = client.Close() }()
+
+	key := dynamic.RedisKey
+	if key == "" {
+		key = defaultDynamicRedisKey
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dynamicRedisTimeout)
+	defer cancel()
+
+	return fn(ctx, client, key)
+}
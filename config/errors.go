@@ -1,6 +1,10 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/dechristopher/lod/tile"
+)
 
 // ErrConfigGetHTTP is an error struct resulting from a bad
 // HTTP request for the configuration file
@@ -78,6 +82,59 @@ func (e ErrMissingTileURLTemplate) Error() string {
 		e.ProxyName, e.TileURL, e.Parameter)
 }
 
+// ErrInvalidMissingTile is an error struct for an unknown missing tile
+// behavior, caught during the proxy validation phase
+type ErrInvalidMissingTile struct {
+	ProxyName   string
+	MissingTile string
+}
+
+// Error returns the string representation of ErrInvalidMissingTile
+func (e ErrInvalidMissingTile) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid missing_tile '%s', valid values are \"%s\", \"%s\", and \"%s\"",
+		e.ProxyName, e.MissingTile, MissingTileNotFound, MissingTileNoContent, MissingTileEmpty)
+}
+
+// ErrInvalidEmptyTileFormat is an error struct for an unknown empty tile
+// format, caught during the proxy validation phase
+type ErrInvalidEmptyTileFormat struct {
+	ProxyName string
+	Format    string
+}
+
+// Error returns the string representation of ErrInvalidEmptyTileFormat
+func (e ErrInvalidEmptyTileFormat) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid empty_tile_format '%s', valid formats are \"%s\" and \"%s\"",
+		e.ProxyName, e.Format, EmptyTileMVT, EmptyTilePNG)
+}
+
+// ErrInvalidErrorTile is an error struct for an error tile with an invalid
+// status or an unreadable file, caught during the proxy validation phase
+type ErrInvalidErrorTile struct {
+	ProxyName string
+	Status    int
+	Err       error
+}
+
+// Error returns the string representation of ErrInvalidErrorTile
+func (e ErrInvalidErrorTile) Error() string {
+	return fmt.Sprintf("config:proxy(%s):error_tiles invalid tile for status %d: %s",
+		e.ProxyName, e.Status, e.Err.Error())
+}
+
+// ErrInvalidMVTValidation is an error struct for an unknown vector tile
+// validation mode, caught during the proxy validation phase
+type ErrInvalidMVTValidation struct {
+	ProxyName string
+	Mode      string
+}
+
+// Error returns the string representation of ErrInvalidMVTValidation
+func (e ErrInvalidMVTValidation) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid mvt_validation '%s', valid modes are \"%s\" and \"%s\"",
+		e.ProxyName, e.Mode, MVTValidationReject, MVTValidationRepair)
+}
+
 // ErrNoCacheEnabled is an error struct thrown when neither
 // the internal nor external cache are enabled
 type ErrNoCacheEnabled struct {
@@ -123,6 +180,97 @@ type ErrInvalidRedisTTL struct {
 	TTL       string
 }
 
+// ErrInvalidMaxStale is an error struct for an invalid maximum tile
+// staleness, caught during the proxy cache validation phase
+type ErrInvalidMaxStale struct {
+	ProxyName string
+	MaxStale  string
+}
+
+// Error returns the string representation of ErrInvalidMaxStale
+func (e ErrInvalidMaxStale) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache invalid max_stale of '%s', must be a positive duration",
+		e.ProxyName, e.MaxStale)
+}
+
+// ErrInvalidMaxUpstreamTTL is an error struct for an invalid upper bound of
+// per-tile TTLs, caught during the proxy cache validation phase
+type ErrInvalidMaxUpstreamTTL struct {
+	ProxyName string
+	MaxTTL    string
+}
+
+// Error returns the string representation of ErrInvalidMaxUpstreamTTL
+func (e ErrInvalidMaxUpstreamTTL) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache invalid max_upstream_ttl of '%s', must be a positive duration",
+		e.ProxyName, e.MaxTTL)
+}
+
+// ErrInvalidRedisRetry is an error struct for redis command retries
+// configured with an invalid value, caught during the proxy cache validation phase
+type ErrInvalidRedisRetry struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidRedisRetry
+func (e ErrInvalidRedisRetry) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache.redis_retry invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidAdaptiveTTL is an error struct for adaptive tile TTLs configured
+// with invalid bounds, caught during the proxy cache validation phase
+type ErrInvalidAdaptiveTTL struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidAdaptiveTTL
+func (e ErrInvalidAdaptiveTTL) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache.adaptive_ttl invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidHotKeys is an error struct for hot tile pinning configured
+// with an invalid value, caught during the proxy cache validation phase
+type ErrInvalidHotKeys struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidHotKeys
+func (e ErrInvalidHotKeys) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache.hot_keys invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidWriteBehind is an error struct for redis write batching
+// configured with an invalid value, caught during the proxy cache validation phase
+type ErrInvalidWriteBehind struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidWriteBehind
+func (e ErrInvalidWriteBehind) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache.write_behind invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidKeyHash is an error struct for redis key hashing
+// configured with an invalid value, caught during the proxy cache validation phase
+type ErrInvalidKeyHash struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidKeyHash
+func (e ErrInvalidKeyHash) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache.key_hash invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
 // ErrInvalidRedisURL is an error struct for invalid redis
 // cache URL, caught during the proxy cache validation phase
 type ErrInvalidRedisURL struct {
@@ -183,3 +331,925 @@ func (e ErrParamNameDuplicate) Error() string {
 	return fmt.Sprintf("config:proxy(%s):params duplicate parameter with name '%s'",
 		e.ProxyName, e.Parameter.Name)
 }
+
+// ErrInvalidParamValue is an error struct for an empty or duplicate value of
+// a proxy parameter, caught during the proxy param validation phase
+type ErrInvalidParamValue struct {
+	ProxyName string
+	Parameter Param
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidParamValue
+func (e ErrInvalidParamValue) Error() string {
+	return fmt.Sprintf("config:proxy(%s):params invalid value '%s' for parameter '%s', values must be non-empty and unique",
+		e.ProxyName, e.Value, e.Parameter.Name)
+}
+
+// ErrParamMatrixTooLarge is an error struct for parameter values with too
+// many combinations, caught during the proxy param validation phase
+type ErrParamMatrixTooLarge struct {
+	ProxyName string
+	Max       int
+}
+
+// Error returns the string representation of ErrParamMatrixTooLarge
+func (e ErrParamMatrixTooLarge) Error() string {
+	return fmt.Sprintf("config:proxy(%s):params values have more than %d combinations",
+		e.ProxyName, e.Max)
+}
+
+// ErrInvalidMaintenanceMode is an error struct for an unknown maintenance
+// mode, caught during the proxy maintenance validation phase
+type ErrInvalidMaintenanceMode struct {
+	ProxyName string
+	Mode      string
+}
+
+// Error returns the string representation of ErrInvalidMaintenanceMode
+func (e ErrInvalidMaintenanceMode) Error() string {
+	return fmt.Sprintf("config:proxy(%s):maintenance invalid mode '%s', valid modes are \"%s\" and \"%s\"",
+		e.ProxyName, e.Mode, MaintenanceCacheOnly, MaintenanceUnavailable)
+}
+
+// ErrInvalidRetryAfter is an error struct for an invalid maintenance
+// Retry-After duration, caught during the proxy maintenance validation phase
+type ErrInvalidRetryAfter struct {
+	ProxyName  string
+	RetryAfter string
+}
+
+// Error returns the string representation of ErrInvalidRetryAfter
+func (e ErrInvalidRetryAfter) Error() string {
+	return fmt.Sprintf("config:proxy(%s):maintenance invalid retry_after of '%s', "+
+		"valid time units are \"ns\", \"us\" (or \"µs\"), \"ms\", \"s\", \"m\", \"h\"",
+		e.ProxyName, e.RetryAfter)
+}
+
+// ErrTagsNoRedis is an error struct for a proxy with tagging configured
+// without the Redis cache enabled, caught during the proxy tags validation phase
+type ErrTagsNoRedis struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrTagsNoRedis
+func (e ErrTagsNoRedis) Error() string {
+	return fmt.Sprintf("config:proxy(%s):tags tagging requires the redis cache to be enabled", e.ProxyName)
+}
+
+// ErrInvalidZoomBands is an error struct for zoom bands that are negative or
+// not strictly ascending, caught during the proxy tags validation phase
+type ErrInvalidZoomBands struct {
+	ProxyName string
+	ZoomBands []int
+}
+
+// Error returns the string representation of ErrInvalidZoomBands
+func (e ErrInvalidZoomBands) Error() string {
+	return fmt.Sprintf("config:proxy(%s):tags zoom bands %v must be non-negative and strictly ascending",
+		e.ProxyName, e.ZoomBands)
+}
+
+// ErrInvalidCDNProvider is an error struct for an unknown CDN
+// provider, caught during the proxy CDN validation phase
+type ErrInvalidCDNProvider struct {
+	ProxyName string
+	Provider  string
+}
+
+// Error returns the string representation of ErrInvalidCDNProvider
+func (e ErrInvalidCDNProvider) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cdn invalid provider '%s', valid providers are \"%s\", \"%s\", and \"%s\"",
+		e.ProxyName, e.Provider, CDNFastly, CDNCloudflare, CDNCloudFront)
+}
+
+// ErrMissingCDNField is an error struct for a CDN configuration missing a
+// field required by its provider, caught during the proxy CDN validation phase
+type ErrMissingCDNField struct {
+	ProxyName string
+	Provider  string
+	Field     string
+}
+
+// Error returns the string representation of ErrMissingCDNField
+func (e ErrMissingCDNField) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cdn provider '%s' requires %s to be set",
+		e.ProxyName, e.Provider, e.Field)
+}
+
+// ErrInvalidCDNPublicURL is an error struct for an invalid CDN public
+// URL template, caught during the proxy CDN validation phase
+type ErrInvalidCDNPublicURL struct {
+	ProxyName string
+	URL       string
+}
+
+// Error returns the string representation of ErrInvalidCDNPublicURL
+func (e ErrInvalidCDNPublicURL) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cdn invalid public URL '%s'", e.ProxyName, e.URL)
+}
+
+// ErrInvalidCacheHeaderBand is an error struct for a cache header zoom band
+// with an invalid zoom range, caught during the proxy validation phase
+type ErrInvalidCacheHeaderBand struct {
+	ProxyName string
+	Number    int
+}
+
+// Error returns the string representation of ErrInvalidCacheHeaderBand
+func (e ErrInvalidCacheHeaderBand) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache_headers zoom band #%d must have 0 <= min_zoom <= max_zoom",
+		e.ProxyName, e.Number)
+}
+
+// ErrInvalidCacheOnlyBand is an error struct for a cache-only zoom band with
+// an invalid zoom range, caught during the proxy validation phase
+type ErrInvalidCacheOnlyBand struct {
+	ProxyName string
+	Number    int
+}
+
+// Error returns the string representation of ErrInvalidCacheOnlyBand
+func (e ErrInvalidCacheOnlyBand) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache_only band #%d must have 0 <= min_zoom <= max_zoom <= %d",
+		e.ProxyName, e.Number, tile.MaxZoom)
+}
+
+// ErrInvalidTier is an error struct for an unsupported proxy deployment tier,
+// caught during the proxy validation phase
+type ErrInvalidTier struct {
+	ProxyName string
+	Tier      string
+}
+
+// Error returns the string representation of ErrInvalidTier
+func (e ErrInvalidTier) Error() string {
+	return fmt.Sprintf("config:proxy(%s):tier '%s' must be one of 'origin' or 'edge'",
+		e.ProxyName, e.Tier)
+}
+
+// ErrInvalidUpstreamType is an error struct for an unsupported upstream type
+// or debug tile format, caught during the proxy validation phase
+type ErrInvalidUpstreamType struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidUpstreamType
+func (e ErrInvalidUpstreamType) Error() string {
+	return fmt.Sprintf("config:proxy(%s):unsupported upstream %s '%s'",
+		e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidUpstreamProxy is an error struct for an unparseable or unsupported
+// outbound proxy URL, caught during the proxy validation phase
+type ErrInvalidUpstreamProxy struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrInvalidUpstreamProxy
+func (e ErrInvalidUpstreamProxy) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream proxy_url must be a valid http:// or socks5:// URL",
+		e.ProxyName)
+}
+
+// ErrUpstreamTLS is an error struct for upstream TLS settings that could not
+// be loaded, caught during the proxy validation phase
+type ErrUpstreamTLS struct {
+	ProxyName string
+	Err       error
+}
+
+// Error returns the string representation of ErrUpstreamTLS
+func (e ErrUpstreamTLS) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream failed to load TLS settings: %s",
+		e.ProxyName, e.Err.Error())
+}
+
+// ErrInvalidResolveInterval is an error struct for an unparseable upstream
+// re-resolution interval, caught during the proxy validation phase
+type ErrInvalidResolveInterval struct {
+	ProxyName string
+	Interval  string
+}
+
+// Error returns the string representation of ErrInvalidResolveInterval
+func (e ErrInvalidResolveInterval) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream invalid resolve_interval '%s'",
+		e.ProxyName, e.Interval)
+}
+
+// ErrResolveWithProxy is an error struct for upstream re-resolution configured
+// alongside an outbound proxy, caught during the proxy validation phase
+type ErrResolveWithProxy struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrResolveWithProxy
+func (e ErrResolveWithProxy) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream re-resolution cannot be used with proxy_url",
+		e.ProxyName)
+}
+
+// ErrInvalidStrategy is an error struct for an unsupported upstream balancing
+// strategy, caught during the proxy validation phase
+type ErrInvalidStrategy struct {
+	ProxyName string
+	Strategy  string
+}
+
+// Error returns the string representation of ErrInvalidStrategy
+func (e ErrInvalidStrategy) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream strategy '%s' must be one of "+
+		"'round_robin', 'least_inflight', 'weighted', or 'ewma'", e.ProxyName, e.Strategy)
+}
+
+// ErrInvalidServer is an error struct for an upstream server with an invalid
+// address or weight, caught during the proxy validation phase
+type ErrInvalidServer struct {
+	ProxyName string
+	Number    int
+}
+
+// Error returns the string representation of ErrInvalidServer
+func (e ErrInvalidServer) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream server #%d must have a host:port addr and a non-negative weight",
+		e.ProxyName, e.Number)
+}
+
+// ErrServersConflict is an error struct for static upstream servers configured
+// alongside an outbound proxy or re-resolution, caught during the proxy validation phase
+type ErrServersConflict struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrServersConflict
+func (e ErrServersConflict) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream servers cannot be used with proxy_url, srv, or resolve_interval",
+		e.ProxyName)
+}
+
+// ErrInvalidHealthCheck is an error struct for an invalid upstream health probe
+// setting, caught during the proxy validation phase
+type ErrInvalidHealthCheck struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidHealthCheck
+func (e ErrInvalidHealthCheck) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream health has an invalid or missing %s",
+		e.ProxyName, e.Field)
+}
+
+// ErrInvalidSlowStart is an error struct for an unparseable upstream slow-start
+// window, caught during the proxy validation phase
+type ErrInvalidSlowStart struct {
+	ProxyName string
+	SlowStart string
+}
+
+// Error returns the string representation of ErrInvalidSlowStart
+func (e ErrInvalidSlowStart) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream invalid slow_start '%s'",
+		e.ProxyName, e.SlowStart)
+}
+
+// ErrInvalidFairness is an error struct for an invalid upstream fair queuing
+// setting, caught during the proxy validation phase
+type ErrInvalidFairness struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidFairness
+func (e ErrInvalidFairness) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream fairness has an invalid %s",
+		e.ProxyName, e.Field)
+}
+
+// ErrInvalidConnPool is an error struct for an invalid upstream connection
+// pool setting, caught during the proxy validation phase
+type ErrInvalidConnPool struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidConnPool
+func (e ErrInvalidConnPool) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream pool has an invalid %s",
+		e.ProxyName, e.Field)
+}
+
+// ErrInvalidDialing is an error struct for an invalid upstream address
+// family preference or fallback delay, caught during the proxy validation phase
+type ErrInvalidDialing struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidDialing
+func (e ErrInvalidDialing) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream invalid %s '%s'",
+		e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidUserAgent is an error struct for an upstream User-Agent
+// containing line breaks, caught during the proxy validation phase
+type ErrInvalidUserAgent struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrInvalidUserAgent
+func (e ErrInvalidUserAgent) Error() string {
+	return fmt.Sprintf("config:proxy(%s):upstream user_agent must not contain line breaks",
+		e.ProxyName)
+}
+
+// ErrInvalidHints is an error struct for an invalid preload hint setting,
+// caught during the proxy validation phase
+type ErrInvalidHints struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidHints
+func (e ErrInvalidHints) Error() string {
+	return fmt.Sprintf("config:proxy(%s):hints has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidWarmup is an error struct for an invalid cache warm-up setting,
+// caught during the proxy validation phase
+type ErrInvalidWarmup struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidWarmup
+func (e ErrInvalidWarmup) Error() string {
+	return fmt.Sprintf("config:proxy(%s):warmup has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidBackground is an error struct for an invalid background job
+// limit, caught during the proxy validation phase
+type ErrInvalidBackground struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidBackground
+func (e ErrInvalidBackground) Error() string {
+	return fmt.Sprintf("config:proxy(%s):background has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidScheduledFlush is an error struct for an invalid scheduled cache
+// flush setting, caught during the proxy validation phase
+type ErrInvalidScheduledFlush struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidScheduledFlush
+func (e ErrInvalidScheduledFlush) Error() string {
+	return fmt.Sprintf("config:proxy(%s):scheduled_flush invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidSLO is an error struct for an invalid service level objective
+// setting, caught during the proxy validation phase
+type ErrInvalidSLO struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidSLO
+func (e ErrInvalidSLO) Error() string {
+	return fmt.Sprintf("config:proxy(%s):slo has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidProbe is an error struct for an invalid synthetic probe setting,
+// caught during the proxy validation phase
+type ErrInvalidProbe struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidProbe
+func (e ErrInvalidProbe) Error() string {
+	return fmt.Sprintf("config:proxy(%s):probes has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidChaos is an error struct for an invalid fault injection setting,
+// caught during the proxy validation phase
+type ErrInvalidChaos struct {
+	ProxyName string
+	Field     string
+}
+
+// Error returns the string representation of ErrInvalidChaos
+func (e ErrInvalidChaos) Error() string {
+	return fmt.Sprintf("config:proxy(%s):chaos has an invalid %s", e.ProxyName, e.Field)
+}
+
+// ErrInvalidGeneration is an error struct for an unknown active cache
+// generation, caught during the proxy validation phase
+type ErrInvalidGeneration struct {
+	ProxyName  string
+	Generation string
+}
+
+// Error returns the string representation of ErrInvalidGeneration
+func (e ErrInvalidGeneration) Error() string {
+	return fmt.Sprintf("config:proxy(%s):generations has invalid active generation '%s', "+
+		"expected blue or green", e.ProxyName, e.Generation)
+}
+
+// ErrInvalidPath is an error struct for an invalid custom tile route, caught
+// during the proxy validation phase
+type ErrInvalidPath struct {
+	ProxyName string
+	Path      string
+	Reason    string
+}
+
+// Error returns the string representation of ErrInvalidPath
+func (e ErrInvalidPath) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid path '%s': %s", e.ProxyName, e.Path, e.Reason)
+}
+
+// ErrInvalidRoute is an error struct for an invalid additional proxy route,
+// caught during the proxy validation phase
+type ErrInvalidRoute struct {
+	ProxyName string
+	Route     string
+	Reason    string
+}
+
+// Error returns the string representation of ErrInvalidRoute
+func (e ErrInvalidRoute) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid route '%s': %s", e.ProxyName, e.Route, e.Reason)
+}
+
+// ErrInvalidHeaderPattern is an error struct for an invalid pull_headers or
+// del_headers pattern, caught during the proxy validation phase
+type ErrInvalidHeaderPattern struct {
+	ProxyName string
+	Err       error
+}
+
+// Error returns the string representation of ErrInvalidHeaderPattern
+func (e ErrInvalidHeaderPattern) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid header pattern: %s", e.ProxyName, e.Err.Error())
+}
+
+// ErrInvalidMethod is an error struct for an unsupported client or upstream
+// request method, caught during the proxy validation phase
+type ErrInvalidMethod struct {
+	ProxyName string
+	Method    string
+}
+
+// Error returns the string representation of ErrInvalidMethod
+func (e ErrInvalidMethod) Error() string {
+	return fmt.Sprintf("config:proxy(%s) invalid method '%s', bodies can only be sent with POST, PUT or PATCH",
+		e.ProxyName, e.Method)
+}
+
+// ErrInvalidStreaming is an error struct for a negative streaming cache size
+// cutoff, caught during the proxy validation phase
+type ErrInvalidStreaming struct {
+	ProxyName    string
+	MaxCacheSize int
+}
+
+// Error returns the string representation of ErrInvalidStreaming
+func (e ErrInvalidStreaming) Error() string {
+	return fmt.Sprintf("config:proxy(%s):streaming invalid max_cache_size of %d, must not be negative",
+		e.ProxyName, e.MaxCacheSize)
+}
+
+// ErrInvalidVersionPin is an error struct for an empty, duplicate or
+// mismatched data version pin, caught during the proxy validation phase
+type ErrInvalidVersionPin struct {
+	ProxyName string
+	Version   string
+}
+
+// Error returns the string representation of ErrInvalidVersionPin
+func (e ErrInvalidVersionPin) Error() string {
+	return fmt.Sprintf("config:proxy(%s):versions has an invalid pin for version '%s'",
+		e.ProxyName, e.Version)
+}
+
+// ErrHTTP3MissingCert is an error struct for an HTTP/3 listener configured
+// without a TLS certificate, caught during the instance validation phase
+type ErrHTTP3MissingCert struct{}
+
+// Error returns the string representation of ErrHTTP3MissingCert
+func (e ErrHTTP3MissingCert) Error() string {
+	return "config:instance:http3 requires cert_file and key_file"
+}
+
+// ErrInvalidFormat is an error struct for a config file
+// in an unsupported format
+type ErrInvalidFormat struct {
+	Format string
+}
+
+// Error returns the string representation of ErrInvalidFormat
+func (e ErrInvalidFormat) Error() string {
+	return fmt.Sprintf("config:unsupported config format '%s'", e.Format)
+}
+
+// ErrDuplicateProxyName is an error struct for two proxies
+// configured under the same name
+type ErrDuplicateProxyName struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrDuplicateProxyName
+func (e ErrDuplicateProxyName) Error() string {
+	return fmt.Sprintf("config:proxy(%s) is configured more than once", e.ProxyName)
+}
+
+// ErrDynamicStore is an error struct for dynamic proxy registration
+// configured without exactly one state file or Redis store
+type ErrDynamicStore struct{}
+
+// Error returns the string representation of ErrDynamicStore
+func (e ErrDynamicStore) Error() string {
+	return "config:instance:dynamic must configure exactly one of state_file or redis_url"
+}
+
+// ErrDynamicDisabled is an error struct for a dynamic proxy change
+// requested while dynamic proxy registration is disabled
+type ErrDynamicDisabled struct{}
+
+// Error returns the string representation of ErrDynamicDisabled
+func (e ErrDynamicDisabled) Error() string {
+	return "config:dynamic proxy registration is disabled"
+}
+
+// ErrDynamicReadOnly is an error struct for a dynamic proxy change
+// that would write to Redis while the instance is in read-only mode
+type ErrDynamicReadOnly struct{}
+
+// Error returns the string representation of ErrDynamicReadOnly
+func (e ErrDynamicReadOnly) Error() string {
+	return "config:dynamic proxies cannot be changed in read-only mode"
+}
+
+// ErrDynamicProxyStatic is an error struct for a dynamic proxy change
+// targeting a proxy defined in the static config
+type ErrDynamicProxyStatic struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrDynamicProxyStatic
+func (e ErrDynamicProxyStatic) Error() string {
+	return fmt.Sprintf("config:proxy(%s) is defined in the config file and can't be changed at runtime",
+		e.ProxyName)
+}
+
+// ErrDynamicProxyNotFound is an error struct for a dynamic proxy
+// change targeting a proxy that isn't registered
+type ErrDynamicProxyNotFound struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrDynamicProxyNotFound
+func (e ErrDynamicProxyNotFound) Error() string {
+	return fmt.Sprintf("config:proxy(%s) is not a registered dynamic proxy", e.ProxyName)
+}
+
+// ErrInvalidKey is an error struct for a proxy API key
+// with an invalid configuration
+type ErrInvalidKey struct {
+	ProxyName string
+	Key       string
+	Reason    string
+}
+
+// Error returns the string representation of ErrInvalidKey
+func (e ErrInvalidKey) Error() string {
+	return fmt.Sprintf("config:proxy(%s):keys invalid key '%s': %s", e.ProxyName, e.Key, e.Reason)
+}
+
+// ErrInvalidSchedule is an error struct for the serving windows
+// of a proxy or API key configured with an invalid value
+type ErrInvalidSchedule struct {
+	ProxyName string
+	Key       string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidSchedule
+func (e ErrInvalidSchedule) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("config:proxy(%s):keys(%s):schedule invalid %s '%s'",
+			e.ProxyName, e.Key, e.Field, e.Value)
+	}
+	return fmt.Sprintf("config:proxy(%s):schedule invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidMetadata is an error struct for a proxy's
+// metadata endpoint configured with an invalid value
+type ErrInvalidMetadata struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidMetadata
+func (e ErrInvalidMetadata) Error() string {
+	return fmt.Sprintf("config:proxy(%s):metadata invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidCoverage is an error struct for a proxy's served
+// zoom levels and bounds configured with invalid values
+type ErrInvalidCoverage struct {
+	ProxyName string
+	Reason    string
+}
+
+// Error returns the string representation of ErrInvalidCoverage
+func (e ErrInvalidCoverage) Error() string {
+	return fmt.Sprintf("config:proxy(%s):coverage %s", e.ProxyName, e.Reason)
+}
+
+// ErrInvalidSparse is an error struct for empty subtree
+// tracking configured with a zoom level outside the tile pyramid
+type ErrInvalidSparse struct {
+	ProxyName string
+	MinZoom   int
+}
+
+// Error returns the string representation of ErrInvalidSparse
+func (e ErrInvalidSparse) Error() string {
+	return fmt.Sprintf("config:proxy(%s):sparse invalid min_zoom %d", e.ProxyName, e.MinZoom)
+}
+
+// ErrInvalidHeatmap is an error struct for a proxy's
+// request heatmap configured with an invalid value
+type ErrInvalidHeatmap struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidHeatmap
+func (e ErrInvalidHeatmap) Error() string {
+	return fmt.Sprintf("config:proxy(%s):heatmap invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidBudget is an error struct for a proxy's
+// upstream request budget configured with an invalid value
+type ErrInvalidBudget struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidBudget
+func (e ErrInvalidBudget) Error() string {
+	return fmt.Sprintf("config:proxy(%s):budget invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidSlowRequests is an error struct for a proxy's
+// slow request logging threshold configured with an invalid duration
+type ErrInvalidSlowRequests struct {
+	ProxyName string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidSlowRequests
+func (e ErrInvalidSlowRequests) Error() string {
+	return fmt.Sprintf("config:proxy(%s):slow_requests invalid threshold '%s'", e.ProxyName, e.Value)
+}
+
+// ErrInvalidQueryLimits is an error struct for a proxy's
+// query string limits configured with an invalid value
+type ErrInvalidQueryLimits struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidQueryLimits
+func (e ErrInvalidQueryLimits) Error() string {
+	return fmt.Sprintf("config:proxy(%s):query invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidCORSDebug is an error struct for a proxy's
+// CORS decision capture configured with an invalid value
+type ErrInvalidCORSDebug struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidCORSDebug
+func (e ErrInvalidCORSDebug) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cors_debug invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidWebhook is an error struct for the request
+// summary webhook configured with an invalid value
+type ErrInvalidWebhook struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidWebhook
+func (e ErrInvalidWebhook) Error() string {
+	return fmt.Sprintf("config:instance:webhook invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidAccessLog is an error struct for the access
+// log sink configured with an invalid value
+type ErrInvalidAccessLog struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidAccessLog
+func (e ErrInvalidAccessLog) Error() string {
+	return fmt.Sprintf("config:instance:access_log invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidStatsD is an error struct for the statsd
+// emitter configured with an invalid value
+type ErrInvalidStatsD struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidStatsD
+func (e ErrInvalidStatsD) Error() string {
+	return fmt.Sprintf("config:instance:statsd invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidDNS is an error struct for the upstream
+// DNS cache configured with an invalid value
+type ErrInvalidDNS struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidDNS
+func (e ErrInvalidDNS) Error() string {
+	return fmt.Sprintf("config:instance:dns invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidFleet is an error struct for the fleet
+// registry configured with an invalid value
+type ErrInvalidFleet struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidFleet
+func (e ErrInvalidFleet) Error() string {
+	return fmt.Sprintf("config:instance:fleet invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidDrain is an error struct for instance
+// draining configured with an invalid value
+type ErrInvalidDrain struct {
+	Field string
+	Value string
+}
+
+// Error returns the string representation of ErrInvalidDrain
+func (e ErrInvalidDrain) Error() string {
+	return fmt.Sprintf("config:instance:drain invalid %s '%s'", e.Field, e.Value)
+}
+
+// ErrInvalidJWT is an error struct for a proxy's bearer
+// token validation configured with an invalid value
+type ErrInvalidJWT struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidJWT
+func (e ErrInvalidJWT) Error() string {
+	return fmt.Sprintf("config:proxy(%s):jwt invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidMemEngine is an error struct for an unknown in-memory
+// cache engine or invalid engine tuning
+type ErrInvalidMemEngine struct {
+	ProxyName string
+	Engine    string
+}
+
+// Error returns the string representation of ErrInvalidMemEngine
+func (e ErrInvalidMemEngine) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache invalid mem_engine '%s' or engine tuning, must be one of bigcache, ristretto",
+		e.ProxyName, e.Engine)
+}
+
+// ErrInvalidEviction is an error struct for an unknown
+// in-memory eviction policy
+type ErrInvalidEviction struct {
+	ProxyName string
+	Eviction  string
+}
+
+// Error returns the string representation of ErrInvalidEviction
+func (e ErrInvalidEviction) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache invalid eviction '%s', must be one of lru, lfu, fifo",
+		e.ProxyName, e.Eviction)
+}
+
+// ErrInvalidAdmitAfter is an error struct for a negative
+// in-memory cache admission threshold
+type ErrInvalidAdmitAfter struct {
+	ProxyName  string
+	AdmitAfter int
+}
+
+// Error returns the string representation of ErrInvalidAdmitAfter
+func (e ErrInvalidAdmitAfter) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache admit_after cannot be negative, got %d",
+		e.ProxyName, e.AdmitAfter)
+}
+
+// ErrInvalidBigcache is an error struct for in-memory
+// cache tuning configured with an invalid value
+type ErrInvalidBigcache struct {
+	ProxyName string
+	Field     string
+	Value     string
+}
+
+// Error returns the string representation of ErrInvalidBigcache
+func (e ErrInvalidBigcache) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache:bigcache invalid %s '%s'", e.ProxyName, e.Field, e.Value)
+}
+
+// ErrInvalidBots is an error struct for negative bot
+// classification or rate limiting rates
+type ErrInvalidBots struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrInvalidBots
+func (e ErrInvalidBots) Error() string {
+	return fmt.Sprintf("config:proxy(%s):bots behavior_rate and rate_limit cannot be negative", e.ProxyName)
+}
+
+// ErrInvalidCountry is an error struct for a country in a
+// proxy's country lists that isn't an ISO 3166-1 alpha-2 code
+type ErrInvalidCountry struct {
+	ProxyName string
+	Country   string
+}
+
+// Error returns the string representation of ErrInvalidCountry
+func (e ErrInvalidCountry) Error() string {
+	return fmt.Sprintf("config:proxy(%s):geo invalid country '%s', must be an ISO 3166-1 alpha-2 code",
+		e.ProxyName, e.Country)
+}
+
+// ErrGeoIPRequired is an error struct for country lists
+// configured without an instance GeoIP database
+type ErrGeoIPRequired struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrGeoIPRequired
+func (e ErrGeoIPRequired) Error() string {
+	return fmt.Sprintf("config:proxy(%s):geo country lists require instance.geoip.database", e.ProxyName)
+}
+
+// ErrInvalidXFetch is an error struct for probabilistic early
+// expiration enabled without max_stale or with a negative beta
+type ErrInvalidXFetch struct {
+	ProxyName string
+	Beta      float64
+}
+
+// Error returns the string representation of ErrInvalidXFetch
+func (e ErrInvalidXFetch) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache xfetch requires max_stale and a non-negative xfetch_beta, got beta %g",
+		e.ProxyName, e.Beta)
+}
+
+// ErrInvalidTTLJitter is an error struct for TTL jitter percentages outside
+// of 0 to 100
+type ErrInvalidTTLJitter struct {
+	ProxyName string
+	Jitter    float64
+}
+
+// Error returns the string representation of ErrInvalidTTLJitter
+func (e ErrInvalidTTLJitter) Error() string {
+	return fmt.Sprintf("config:proxy(%s):cache ttl_jitter must be a percentage between 0 and 100, got %g",
+		e.ProxyName, e.Jitter)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"gopkg.in/yaml.v3"
+
+	"github.com/dechristopher/lod/util"
+)
+
+// Config file formats supported by instances, sharing the TOML schema
+const (
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+	FormatHCL  = "hcl"
+)
+
+// FormatOf detects the format of a config file path or URL by its extension,
+// defaulting to TOML
+func FormatOf(file string) string {
+	if util.IsUrl(file) {
+		if fileUrl, err := url.Parse(file); err == nil {
+			file = fileUrl.Path
+		}
+	}
+
+	switch strings.ToLower(path.Ext(file)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".hcl":
+		return FormatHCL
+	}
+
+	return FormatTOML
+}
+
+// decode raw config data of the given format into capabilities. YAML and HCL
+// are decoded generically and translated to TOML, so all formats share the
+// schema and key names of the TOML config.
+func decode(configData []byte, format string, capabilities *Capabilities) error {
+	var generic map[string]interface{}
+
+	switch format {
+	case FormatTOML:
+		_, err := toml.Decode(string(configData), capabilities)
+		return err
+	case FormatYAML:
+		if err := yaml.Unmarshal(configData, &generic); err != nil {
+			return err
+		}
+	case FormatHCL:
+		if err := hcl.Unmarshal(configData, &generic); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidFormat{Format: format}
+	}
+
+	normalized, _ := normalize(generic, reflect.TypeOf(*capabilities)).(map[string]interface{})
+
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(normalized); err != nil {
+		return err
+	}
+
+	_, err := toml.Decode(buf.String(), capabilities)
+	return err
+}
+
+// normalize a generically decoded config value against the type it decodes
+// into. HCL decodes every block as a list of objects, so single blocks are
+// unwrapped where the schema expects an object rather than a list.
+func normalize(value interface{}, typ reflect.Type) interface{} {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if list := asList(value); len(list) == 1 {
+			value = list[0]
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+
+		for key, val := range object {
+			if field, found := tomlField(typ, key); found {
+				object[key] = normalize(val, field.Type)
+			}
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		list := asList(value)
+		if list == nil {
+			return value
+		}
+
+		for i, val := range list {
+			list[i] = normalize(val, typ.Elem())
+		}
+		return list
+	case reflect.Map:
+		if list := asList(value); len(list) == 1 {
+			value = list[0]
+		}
+
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+
+		for key, val := range object {
+			object[key] = normalize(val, typ.Elem())
+		}
+		return object
+	}
+
+	return value
+}
+
+// asList returns the elements of a generically decoded list, or nil if the
+// value isn't one
+func asList(value interface{}) []interface{} {
+	switch list := value.(type) {
+	case []interface{}:
+		return list
+	case []map[string]interface{}:
+		elements := make([]interface{}, len(list))
+		for i, element := range list {
+			elements[i] = element
+		}
+		return elements
+	}
+
+	return nil
+}
+
+// tomlField finds the struct field a TOML key decodes into, matching names
+// case-insensitively like the TOML decoder
+func tomlField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
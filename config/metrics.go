@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// buildInfo is a constant 1 gauge labeled with the running build's details
+var buildInfo = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: Namespace,
+	Name:      "build_info",
+	ConstLabels: func() prometheus.Labels {
+		build := GetBuild()
+		return prometheus.Labels{
+			"version":    build.Version,
+			"commit":     build.Commit,
+			"build_date": build.BuildDate,
+			"goversion":  build.GoVersion,
+		}
+	}(),
+	Help: "A metric with a constant '1' value labeled by the LOD version, commit, build date and Go version it was built with",
+}, func() float64 { return 1 })
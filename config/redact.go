@@ -0,0 +1,161 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// redactedMask replaces the values of secrets in redacted configuration
+const redactedMask = "********"
+
+// sensitiveNames are fragments of header and query parameter names whose
+// values are treated as secrets
+var sensitiveNames = []string{"auth", "token", "key", "secret", "password", "cookie", "signature"}
+
+// headerType is the type of configured upstream request headers, whose values
+// are masked by name
+var headerType = reflect.TypeOf(Header{})
+
+// Redacted returns the effective configuration keyed like the config file,
+// with defaults and parsed values applied and secrets masked. Secrets are the
+// fields hidden from the capabilities endpoint, values of sensitive headers,
+// and passwords and sensitive query parameters of URLs.
+func (c *Capabilities) Redacted() map[string]interface{} {
+	redacted, _ := redact(reflect.ValueOf(*c)).(map[string]interface{})
+	return redacted
+}
+
+// redact converts a configuration value into its redacted generic form
+func redact(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return redact(value.Elem())
+	case reflect.Struct:
+		return redactStruct(value)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return []interface{}{}
+		}
+		list := make([]interface{}, value.Len())
+		for i := range list {
+			list[i] = redact(value.Index(i))
+		}
+		return list
+	case reflect.Map:
+		object := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			object[iter.Key().String()] = redact(iter.Value())
+		}
+		return object
+	case reflect.String:
+		return redactURL(value.String())
+	case reflect.Func, reflect.Chan:
+		return nil
+	}
+
+	return value.Interface()
+}
+
+// redactStruct converts a configuration struct into an object keyed by its
+// config file keys, masking secrets
+func redactStruct(value reflect.Value) map[string]interface{} {
+	typ := value.Type()
+	object := make(map[string]interface{}, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tomlName := strings.Split(field.Tag.Get("toml"), ",")[0]
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		// internal fields derived from others aren't part of the config file
+		if tomlName == "-" {
+			continue
+		}
+
+		// fields only shown by the capabilities endpoint keep their JSON name
+		name := tomlName
+		if name == "" {
+			if jsonName == "" || jsonName == "-" {
+				continue
+			}
+			name = jsonName
+		}
+
+		// fields in the config file hidden from the capabilities endpoint
+		// are secrets
+		if jsonName == "-" {
+			object[name] = mask(value.Field(i))
+			continue
+		}
+
+		object[name] = redact(value.Field(i))
+	}
+
+	if typ == headerType && sensitiveName(value.FieldByName("Name").String()) {
+		object["value"] = mask(value.FieldByName("Value"))
+	}
+
+	return object
+}
+
+// mask returns the mask for set secrets, and the empty value for unset ones
+// so operators can tell whether a secret was configured
+func mask(value reflect.Value) interface{} {
+	if value.IsZero() {
+		return ""
+	}
+	return redactedMask
+}
+
+// redactURL masks the password and sensitive query parameter values of URLs,
+// leaving other strings and the rest of the URL untouched
+func redactURL(raw string) string {
+	if !strings.Contains(raw, "://") {
+		return raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	if password, ok := parsed.User.Password(); ok && password != "" {
+		userInfo := parsed.User.String() + "@"
+		raw = strings.Replace(raw, userInfo, parsed.User.Username()+":"+redactedMask+"@", 1)
+	}
+
+	base, query, found := strings.Cut(raw, "?")
+	if !found {
+		return raw
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		if key, _, hasValue := strings.Cut(param, "="); hasValue && sensitiveName(key) {
+			params[i] = key + "=" + redactedMask
+		}
+	}
+
+	return base + "?" + strings.Join(params, "&")
+}
+
+// sensitiveName returns true if a header or query parameter name suggests its
+// value is a secret
+func sensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range sensitiveNames {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// Package corsdebug captures a sample of the cross-origin requests and
+// preflights each proxy receives along with the CORS decision made for them,
+// for debugging origin mismatches without digging through logs
+package corsdebug
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// RecordersMap is an alias type for the map of proxy name to its recorder
+type RecordersMap map[string]*Recorder
+
+// Recorders of proxies with CORS decision capture enabled
+var Recorders = make(RecordersMap)
+
+// Capture is a cross-origin request or preflight and the CORS decision made
+type Capture struct {
+	Time           time.Time         `json:"time"`                      // time the request was received
+	Method         string            `json:"method"`                    // request method
+	Path           string            `json:"path"`                      // request path
+	Origin         string            `json:"origin"`                    // Origin request header
+	Preflight      bool              `json:"preflight"`                 // whether the request was a CORS preflight
+	RequestMethod  string            `json:"request_method,omitempty"`  // Access-Control-Request-Method of preflights
+	RequestHeaders string            `json:"request_headers,omitempty"` // Access-Control-Request-Headers of preflights
+	Key            string            `json:"key,omitempty"`             // name of the API key used, if any
+	Policy         string            `json:"policy"`                    // origins checked, "proxy", "key" or "none" if CORS isn't configured
+	Rule           string            `json:"rule,omitempty"`            // allowed origin rule matching the origin, empty if none did
+	Allowed        bool              `json:"allowed"`                   // whether the origin was allowed
+	Status         int               `json:"status"`                    // response status code
+	Headers        map[string]string `json:"headers"`                   // Access-Control-* response headers emitted
+}
+
+// Recorder keeps the most recent sampled captures of a proxy in a ring
+type Recorder struct {
+	mu       sync.Mutex
+	config   config.CORSDebug
+	captures []Capture // ring of captures, oldest overwritten first
+	next     int       // index the next capture is written to
+	seen     uint64    // cross-origin requests considered for capture
+	recorded uint64    // captures recorded, including overwritten ones
+}
+
+// Init builds recorders for all proxies with CORS decision capture enabled,
+// keeping the captures of proxies that already had one across config reloads
+func Init() {
+	recorders := make(RecordersMap)
+	for _, proxy := range config.Get().Proxies {
+		if !proxy.CORSDebug.Enabled {
+			continue
+		}
+
+		if recorder := Recorders[proxy.Name]; recorder != nil && recorder.config.Capacity == proxy.CORSDebug.Capacity {
+			recorder.mu.Lock()
+			recorder.config = proxy.CORSDebug
+			recorder.mu.Unlock()
+			recorders[proxy.Name] = recorder
+		} else {
+			recorders[proxy.Name] = newRecorder(proxy.CORSDebug)
+		}
+	}
+	Recorders = recorders
+}
+
+// Get a recorder by proxy name, nil if the proxy has no CORS decision capture
+func Get(name string) *Recorder {
+	return Recorders[name]
+}
+
+// newRecorder builds an empty recorder
+func newRecorder(debug config.CORSDebug) *Recorder {
+	return &Recorder{
+		config:   debug,
+		captures: make([]Capture, 0, debug.Capacity),
+	}
+}
+
+// Sample returns true if the next cross-origin request should be captured
+func (r *Recorder) Sample() bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen++
+	return r.config.SampleRate >= 1 || rand.Float64() < r.config.SampleRate
+}
+
+// Record keeps a capture, unless only mismatches are captured and its origin
+// was allowed
+func (r *Recorder) Record(capture Capture) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.config.MismatchesOnly && capture.Allowed {
+		return
+	}
+
+	r.recorded++
+	if len(r.captures) < cap(r.captures) {
+		r.captures = append(r.captures, capture)
+		return
+	}
+	r.captures[r.next] = capture
+	r.next = (r.next + 1) % len(r.captures)
+}
+
+// Captures returns the kept captures newest first, optionally only those of
+// the given origin or of disallowed origins, along with the number of
+// cross-origin requests considered and captures recorded
+func (r *Recorder) Captures(origin string, mismatches bool) (captures []Capture, seen, recorded uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	captures = make([]Capture, 0, len(r.captures))
+	for i := len(r.captures) - 1; i >= 0; i-- {
+		capture := r.captures[(r.next+i)%len(r.captures)]
+		if origin != "" && !strings.EqualFold(capture.Origin, origin) {
+			continue
+		}
+		if mismatches && capture.Allowed {
+			continue
+		}
+		captures = append(captures, capture)
+	}
+	return captures, r.seen, r.recorded
+}
+
+// Reset drops all kept captures and counts
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.captures = r.captures[:0]
+	r.next = 0
+	r.seen = 0
+	r.recorded = 0
+}
+
+// MatchRule returns the first of the allowed origin rules matching the
+// origin, as the proxy's CORS handling matches them: * allows any origin, and
+// a * label in a rule matches any subdomain, ex: https://*.example.com
+func MatchRule(rules []string, origin string) string {
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "*" || rule == origin {
+			return rule
+		}
+		if matchSubdomain(origin, rule) {
+			return rule
+		}
+	}
+	return ""
+}
+
+// matchSubdomain returns true if the origin has the scheme of the rule and
+// lies below its * label
+func matchSubdomain(origin, rule string) bool {
+	scheme, pattern, ok := strings.Cut(rule, "://*.")
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+pattern)
+}
@@ -0,0 +1,68 @@
+package corsdebug
+
+import (
+	"testing"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestMatchRule will test that origins match allowed origin rules the way
+// the proxy's CORS handling does
+func TestMatchRule(t *testing.T) {
+	rules := []string{"https://maps.example.com", " https://*.example.org"}
+
+	tests := []struct {
+		origin string
+		rule   string
+	}{
+		{"https://maps.example.com", "https://maps.example.com"},
+		{"https://a.example.org", "https://*.example.org"},
+		{"https://a.b.example.org", "https://*.example.org"},
+		{"http://a.example.org", ""},
+		{"https://example.org", ""},
+		{"https://evil.com", ""},
+	}
+
+	for _, test := range tests {
+		if rule := MatchRule(rules, test.origin); rule != test.rule {
+			t.Errorf(str.TCORSDebugBadRule, test.origin, rule, test.rule)
+		}
+	}
+
+	if rule := MatchRule([]string{"*"}, "https://any.com"); rule != "*" {
+		t.Errorf(str.TCORSDebugBadRule, "https://any.com", rule, "*")
+	}
+}
+
+// TestRecorder will test that the most recent captures are kept newest first
+// and filtered by origin and mismatch
+func TestRecorder(t *testing.T) {
+	r := newRecorder(config.CORSDebug{SampleRate: 1, Capacity: 2})
+
+	r.Record(Capture{Origin: "https://a.com", Allowed: true})
+	r.Record(Capture{Origin: "https://b.com"})
+	r.Record(Capture{Origin: "https://c.com", Allowed: true})
+
+	captures, _, recorded := r.Captures("", false)
+	if recorded != 3 || len(captures) != 2 || captures[0].Origin != "https://c.com" ||
+		captures[1].Origin != "https://b.com" {
+		t.Errorf(str.TCORSDebugBadCaptures, captures)
+	}
+
+	captures, _, _ = r.Captures("", true)
+	if len(captures) != 1 || captures[0].Origin != "https://b.com" {
+		t.Errorf(str.TCORSDebugBadCaptures, captures)
+	}
+
+	captures, _, _ = r.Captures("https://C.com", false)
+	if len(captures) != 1 || captures[0].Origin != "https://c.com" {
+		t.Errorf(str.TCORSDebugBadCaptures, captures)
+	}
+
+	mismatches := newRecorder(config.CORSDebug{SampleRate: 1, Capacity: 2, MismatchesOnly: true})
+	mismatches.Record(Capture{Origin: "https://a.com", Allowed: true})
+	if captures, _, _ = mismatches.Captures("", false); len(captures) != 0 {
+		t.Errorf(str.TCORSDebugBadCaptures, captures)
+	}
+}
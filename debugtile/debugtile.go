@@ -0,0 +1,201 @@
+package debugtile
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/dechristopher/lod/mvt"
+)
+
+// Formats of generated debug tiles
+const (
+	FormatPNG = "png"
+	FormatMVT = "mvt"
+)
+
+// Content types of generated debug tiles
+const (
+	ContentTypePNG = "image/png"
+	ContentTypeMVT = "application/vnd.mapbox-vector-tile"
+)
+
+// LayerName is the name of the single layer within generated vector tiles
+const LayerName = "debug"
+
+// tileSize is the pixel size of generated raster tiles
+const tileSize = 256
+
+// extent is the layer extent of generated vector tiles
+const extent = 4096
+
+// glyphs is a 3x5 bitmap font covering the characters of tile labels, each
+// row is three bits wide with the most significant bit on the left
+var glyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'/': {1, 1, 2, 4, 4},
+}
+
+// Label returns the human-readable z/x/y label of a tile
+func Label(z, x, y int) string {
+	return fmt.Sprintf("%d/%d/%d", z, x, y)
+}
+
+// Render generates a labeled debug tile in the given format along with its
+// content type
+func Render(format string, z, x, y int) ([]byte, string, error) {
+	switch format {
+	case FormatPNG:
+		data, err := PNG(z, x, y)
+		return data, ContentTypePNG, err
+	case FormatMVT:
+		return MVT(z, x, y), ContentTypeMVT, nil
+	}
+
+	return nil, "", ErrUnknownFormat{Format: format}
+}
+
+// PNG renders a raster tile outlined at its edges with its z/x/y label drawn
+// in the center, shaded by zoom level so adjacent zooms are told apart
+func PNG(z, x, y int) ([]byte, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, tileSize, tileSize))
+
+	shade := uint8(160 + (z*24)%96)
+	background := color.NRGBA{R: shade, G: 255 - shade/2, B: 255 - shade, A: 255}
+	ink := color.NRGBA{A: 255}
+
+	for py := 0; py < tileSize; py++ {
+		for px := 0; px < tileSize; px++ {
+			if px == 0 || py == 0 || px == tileSize-1 || py == tileSize-1 {
+				img.SetNRGBA(px, py, ink)
+			} else {
+				img.SetNRGBA(px, py, background)
+			}
+		}
+	}
+
+	drawLabel(img, Label(z, x, y), ink)
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// drawLabel draws the label centered on the image, scaled as large as fits
+func drawLabel(img *image.NRGBA, label string, ink color.NRGBA) {
+	runes := []rune(label)
+
+	// each glyph is three columns wide followed by a column of spacing
+	columns := len(runes)*4 - 1
+	scale := (tileSize - 16) / columns
+	if scale > 8 {
+		scale = 8
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	left := (tileSize - columns*scale) / 2
+	top := (tileSize - 5*scale) / 2
+
+	for i, r := range runes {
+		glyph := glyphs[r]
+		for row := 0; row < 5; row++ {
+			for col := 0; col < 3; col++ {
+				if glyph[row]&(4>>col) == 0 {
+					continue
+				}
+
+				x0 := left + (i*4+col)*scale
+				y0 := top + row*scale
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.SetNRGBA(x0+dx, y0+dy, ink)
+					}
+				}
+			}
+		}
+	}
+}
+
+// MVT encodes a vector tile with a single layer holding the outline of the
+// tile as a polygon and a point at its center, both tagged with the tile's
+// coordinates and label
+func MVT(z, x, y int) []byte {
+	tags := []uint64{0, 0, 1, 1, 2, 2, 3, 3}
+
+	tile := mvt.Tile{Layers: []mvt.Layer{{
+		Version: 2,
+		Name:    LayerName,
+		Keys:    []string{"z", "x", "y", "label"},
+		Values: [][]byte{
+			uintValue(z),
+			uintValue(x),
+			uintValue(y),
+			stringValue(Label(z, x, y)),
+		},
+		Extent: extent,
+		Features: []mvt.Feature{
+			{
+				ID:    1,
+				HasID: true,
+				Tags:  tags,
+				Type:  mvt.Polygon,
+				Geometry: []uint32{
+					command(1, 1), zigzag(0), zigzag(0),
+					command(2, 3), zigzag(0), zigzag(extent),
+					zigzag(extent), zigzag(0),
+					zigzag(0), zigzag(-extent),
+					command(7, 1),
+				},
+			},
+			{
+				ID:       2,
+				HasID:    true,
+				Tags:     tags,
+				Type:     mvt.Point,
+				Geometry: []uint32{command(1, 1), zigzag(extent / 2), zigzag(extent / 2)},
+			},
+		},
+	}}}
+
+	return tile.Encode()
+}
+
+// command encodes a geometry command integer
+func command(id, count uint32) uint32 {
+	return id&0x7 | count<<3
+}
+
+// zigzag encodes a geometry parameter integer
+func zigzag(n int32) uint32 {
+	return uint32((n << 1) ^ (n >> 31))
+}
+
+// uintValue encodes a vector tile uint_value
+func uintValue(n int) []byte {
+	data := protowire.AppendTag(nil, 5, protowire.VarintType)
+	return protowire.AppendVarint(data, uint64(n))
+}
+
+// stringValue encodes a vector tile string_value
+func stringValue(s string) []byte {
+	data := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendString(data, s)
+}
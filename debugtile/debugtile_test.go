@@ -0,0 +1,60 @@
+package debugtile
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/dechristopher/lod/mvt"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestPNG will test that raster debug tiles decode as full size PNG images
+func TestPNG(t *testing.T) {
+	data, err := PNG(14, 4823, 6160)
+	if err != nil {
+		t.Fatalf(str.TDebugTileBadPNG, err.Error())
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf(str.TDebugTileBadPNG, err.Error())
+	}
+
+	if size := img.Bounds().Size(); size.X != tileSize || size.Y != tileSize {
+		t.Errorf(str.TDebugTileBadSize, size)
+	}
+
+	again, _ := PNG(14, 4823, 6160)
+	if !bytes.Equal(data, again) {
+		t.Errorf(str.TDebugTileUnstable)
+	}
+}
+
+// TestMVT will test that vector debug tiles are valid and labeled
+func TestMVT(t *testing.T) {
+	tile, err := mvt.Decode(MVT(3, 2, 1))
+	if err != nil {
+		t.Fatalf(str.TMVTBadDecode, err.Error())
+	}
+
+	if err = tile.Validate(); err != nil {
+		t.Fatalf(str.TMVTBadValidation, err.Error())
+	}
+
+	if len(tile.Layers) != 1 || tile.Layers[0].Name != LayerName {
+		t.Fatalf(str.TMVTNoLayers)
+	}
+
+	label := string(stringValue(Label(3, 2, 1)))
+	if got := string(tile.Layers[0].Values[3]); got != label {
+		t.Errorf(str.TDebugTileBadLabel, got, label)
+	}
+}
+
+// TestRenderUnknownFormat will test that unknown formats are rejected
+func TestRenderUnknownFormat(t *testing.T) {
+	if _, _, err := Render("jpg", 0, 0, 0); err == nil {
+		t.Errorf(str.TDebugTileNoError)
+	}
+}
@@ -0,0 +1,14 @@
+package debugtile
+
+import "fmt"
+
+// ErrUnknownFormat is an error struct for debug tiles requested
+// in a format that can't be generated
+type ErrUnknownFormat struct {
+	Format string
+}
+
+// Error returns the string representation of ErrUnknownFormat
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("debugtile: unknown tile format: %s", e.Format)
+}
@@ -0,0 +1,228 @@
+// Package dnscache resolves upstream hostnames through an internal cache
+// honoring the TTLs of their DNS records, rather than resolving them again on
+// every new upstream connection
+package dnscache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// resolvConf is the system resolver configuration nameservers default to
+const resolvConf = "/etc/resolv.conf"
+
+// Resolver caches the addresses of hostnames for the TTL of their records
+type Resolver struct {
+	config      config.DNS
+	nameservers []string
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+	group   singleflight.Group
+}
+
+// entry is the cached addresses of a single hostname
+type entry struct {
+	addrs   []string
+	expires time.Time
+}
+
+var (
+	mu       sync.RWMutex
+	resolver *Resolver
+)
+
+// Init builds the resolver if the DNS cache is enabled, dropping any
+// resolutions cached by a previous configuration
+func Init() {
+	var next *Resolver
+	if dns := config.Get().Instance.DNS; dns.Enabled {
+		next = NewResolver(dns)
+	}
+
+	mu.Lock()
+	resolver = next
+	mu.Unlock()
+}
+
+// LookupHost resolves the hostname through the active resolver, or the
+// system resolver if the DNS cache is disabled
+func LookupHost(ctx context.Context, host string) ([]string, error) {
+	mu.RLock()
+	r := resolver
+	mu.RUnlock()
+
+	if r == nil {
+		return net.DefaultResolver.LookupHost(ctx, host)
+	}
+	return r.LookupHost(ctx, host)
+}
+
+// NewResolver builds a resolver querying the configured nameservers, or
+// those of the system resolver configuration if none are
+func NewResolver(dns config.DNS) *Resolver {
+	nameservers := dns.Nameservers
+	if len(nameservers) == 0 {
+		nameservers = systemNameservers()
+	}
+
+	return &Resolver{
+		config:      dns,
+		nameservers: nameservers,
+		entries:     make(map[string]*entry),
+	}
+}
+
+// LookupHost returns the addresses of the hostname, from the cache while its
+// records are fresh. Expired addresses are served if resolving them again
+// fails, and kept for the minimum TTL before the next attempt.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.mu.RLock()
+	cached := r.entries[host]
+	r.mu.RUnlock()
+
+	if cached != nil && time.Now().Before(cached.expires) {
+		metrics.lookups.WithLabelValues("hit").Inc()
+		return cached.addrs, nil
+	}
+
+	result, err, _ := r.group.Do(host, func() (interface{}, error) {
+		addrs, ttl, err := r.resolve(ctx, host)
+		if err != nil {
+			metrics.failures.WithLabelValues(host).Inc()
+			util.Error(str.CProxy, str.EDNSResolve, host, err.Error())
+			if cached == nil {
+				return nil, err
+			}
+			addrs, ttl = cached.addrs, r.config.MinTTLDuration
+		}
+
+		r.mu.Lock()
+		r.entries[host] = &entry{addrs: addrs, expires: time.Now().Add(ttl)}
+		r.mu.Unlock()
+
+		return addrs, err
+	})
+
+	switch {
+	case err != nil && result != nil:
+		metrics.lookups.WithLabelValues("stale").Inc()
+		return result.([]string), nil
+	case err != nil:
+		return nil, err
+	}
+
+	metrics.lookups.WithLabelValues("miss").Inc()
+	return result.([]string), nil
+}
+
+// resolve looks the hostname up from the first nameserver to answer, falling
+// back to the system resolver for hostnames they don't resolve, and returns
+// its addresses along with the clamped TTL to keep them for
+func (r *Resolver) resolve(ctx context.Context, host string) ([]string, time.Duration, error) {
+	start := time.Now()
+
+	var addrs []string
+	var ttl time.Duration
+	var err error
+	for _, nameserver := range r.nameservers {
+		if addrs, ttl, err = r.queryNameserver(ctx, nameserver, host); err == nil || err == errNotFound {
+			break
+		}
+	}
+
+	if len(addrs) > 0 {
+		metrics.latency.WithLabelValues("nameserver").Observe(time.Since(start).Seconds())
+		return addrs, r.clamp(ttl), nil
+	}
+
+	// the system resolver also reads hosts files and applies search domains
+	start = time.Now()
+	if addrs, err = net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return nil, 0, err
+	}
+	metrics.latency.WithLabelValues("system").Observe(time.Since(start).Seconds())
+
+	return addrs, r.config.MinTTLDuration, nil
+}
+
+// queryNameserver queries the nameserver for IPv4 and IPv6 addresses of the
+// hostname at once, returning both along with the shortest TTL of either
+func (r *Resolver) queryNameserver(ctx context.Context, nameserver, host string) ([]string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.TimeoutDuration)
+	defer cancel()
+
+	var v6 answer
+	var v6Err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v6, v6Err = query(ctx, nameserver, host, dnsmessage.TypeAAAA)
+	}()
+
+	v4, v4Err := query(ctx, nameserver, host, dnsmessage.TypeA)
+	<-done
+
+	if v4Err != nil && v6Err != nil {
+		return nil, 0, v4Err
+	}
+
+	ttl := v4.ttl
+	if len(v4.addrs) == 0 || (len(v6.addrs) > 0 && v6.ttl < ttl) {
+		ttl = v6.ttl
+	}
+
+	return append(v4.addrs, v6.addrs...), ttl, nil
+}
+
+// clamp bounds a record TTL between the configured minimum and maximum
+func (r *Resolver) clamp(ttl time.Duration) time.Duration {
+	if ttl < r.config.MinTTLDuration {
+		return r.config.MinTTLDuration
+	}
+	if ttl > r.config.MaxTTLDuration {
+		return r.config.MaxTTLDuration
+	}
+	return ttl
+}
+
+// systemNameservers reads the nameservers of the system resolver
+// configuration, defaulting to a local nameserver if there are none
+func systemNameservers() []string {
+	var nameservers []string
+
+	file, err := os.Open(resolvConf)
+	if err == nil {
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+				nameservers = append(nameservers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+
+	if len(nameservers) == 0 {
+		nameservers = []string{"127.0.0.1:53"}
+	}
+
+	return nameservers
+}
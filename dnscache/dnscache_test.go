@@ -0,0 +1,106 @@
+package dnscache
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// testNameserver answers A queries for every name with 10.0.0.1 and the
+// given TTL, and AAAA queries with no records, counting the queries received
+func testNameserver(t *testing.T, ttl uint32, queries *int32) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if err = msg.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			atomic.AddInt32(queries, 1)
+
+			msg.Header.Response = true
+			question := msg.Questions[0]
+			if question.Type == dnsmessage.TypeA {
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{
+						Name:  question.Name,
+						Type:  dnsmessage.TypeA,
+						Class: dnsmessage.ClassINET,
+						TTL:   ttl,
+					},
+					Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+				}}
+			}
+
+			packed, _ := msg.Pack()
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn
+}
+
+// TestLookupHost will test that resolutions are cached for the clamped TTL
+// of their records, and served stale once the nameserver stops answering
+func TestLookupHost(t *testing.T) {
+	var queries int32
+	server := testNameserver(t, 3600, &queries)
+
+	r := NewResolver(config.DNS{
+		Nameservers:     []string{server.LocalAddr().String()},
+		MinTTLDuration:  10 * time.Millisecond,
+		MaxTTLDuration:  50 * time.Millisecond,
+		TimeoutDuration: 100 * time.Millisecond,
+	})
+
+	lookup := func() []string {
+		addrs, err := r.LookupHost(context.Background(), "tiles.lod.invalid")
+		if err != nil {
+			t.Fatalf(str.TDNSBadLookup, err, "10.0.0.1")
+		}
+		if len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+			t.Fatalf(str.TDNSBadLookup, addrs, "10.0.0.1")
+		}
+		return addrs
+	}
+
+	// resolved once for both A and AAAA records, then served from the cache
+	lookup()
+	lookup()
+	if got := atomic.LoadInt32(&queries); got != 2 {
+		t.Fatalf(str.TDNSBadQueries, got, 2)
+	}
+
+	// the hour long TTL is clamped to the maximum
+	time.Sleep(60 * time.Millisecond)
+	lookup()
+	if got := atomic.LoadInt32(&queries); got != 4 {
+		t.Fatalf(str.TDNSBadQueries, got, 4)
+	}
+
+	// expired addresses are served when resolving them again fails
+	_ = server.Close()
+	time.Sleep(60 * time.Millisecond)
+	lookup()
+
+	if addrs, err := r.LookupHost(context.Background(), "10.0.0.2"); err != nil || addrs[0] != "10.0.0.2" {
+		t.Fatalf(str.TDNSBadLookup, addrs, "10.0.0.2")
+	}
+}
@@ -0,0 +1,38 @@
+package dnscache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "dns"
+
+// metrics of the DNS cache, which survive its resolver being rebuilt on
+// config reloads
+var metrics = struct {
+	lookups  *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	failures *prometheus.CounterVec
+}{
+	lookups: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "lookups_total",
+		Help:      "The total number of upstream hostname lookups by result, hit, miss or stale when served expired after a failed resolution",
+	}, []string{"result"}),
+	latency: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "resolve_duration_seconds",
+		Help:      "The time taken to resolve upstream hostnames by source, nameserver or system",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{"source"}),
+	failures: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "resolve_failures_total",
+		Help:      "The total number of failed resolutions of each upstream hostname",
+	}, []string{"host"}),
+}
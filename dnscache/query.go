@@ -0,0 +1,182 @@
+package dnscache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxUDPSize is the largest response read over UDP, responses not fitting
+// the 512 bytes servers send without EDNS are retried over TCP
+const maxUDPSize = 512
+
+var (
+	// errNotFound is returned for hostnames that don't exist
+	errNotFound = errors.New("dnscache: no such host")
+	// errBadResponse is returned for responses that don't answer the query
+	errBadResponse = errors.New("dnscache: mismatched response")
+)
+
+// answer is the addresses of a single record type along with the shortest
+// TTL of the records answering for them
+type answer struct {
+	addrs []string
+	ttl   time.Duration
+}
+
+// query asks the nameserver for records of the given type, over UDP and then
+// over TCP if the response was truncated
+func query(ctx context.Context, nameserver, host string, kind dnsmessage.Type) (answer, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return answer{}, err
+	}
+
+	id := uint16(rand.Uint32())
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  name,
+			Type:  kind,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return answer{}, err
+	}
+
+	response, err := exchange(ctx, "udp", nameserver, packed)
+	if err != nil {
+		return answer{}, err
+	}
+
+	result, truncated, err := parse(response, id, kind)
+	if truncated {
+		if response, err = exchange(ctx, "tcp", nameserver, packed); err != nil {
+			return answer{}, err
+		}
+		result, _, err = parse(response, id, kind)
+	}
+
+	return result, err
+}
+
+// exchange sends a packed query to the nameserver and reads its response,
+// framing messages with their length over TCP
+func exchange(ctx context.Context, network, nameserver string, packed []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, nameserver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err = conn.Write(packed); err != nil {
+			return nil, err
+		}
+		response := make([]byte, maxUDPSize)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, err
+		}
+		return response[:n], nil
+	}
+
+	framed := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(framed, uint16(len(packed)))
+	copy(framed[2:], packed)
+	if _, err = conn.Write(framed); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err = io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err = io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// parse reads the addresses and shortest TTL of the records of the given
+// type from a response, and whether it was truncated
+func parse(response []byte, id uint16, kind dnsmessage.Type) (answer, bool, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return answer{}, false, err
+	}
+	if header.ID != id || !header.Response {
+		return answer{}, false, errBadResponse
+	}
+	if header.Truncated {
+		return answer{}, true, nil
+	}
+
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return answer{}, false, errNotFound
+	default:
+		return answer{}, false, errors.New("dnscache: nameserver returned " + header.RCode.String())
+	}
+
+	if err = parser.SkipAllQuestions(); err != nil {
+		return answer{}, false, err
+	}
+
+	var result answer
+	for {
+		resource, err := parser.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return answer{}, false, err
+		}
+
+		// CNAME records are followed by the nameserver, their TTLs bound the
+		// lifetime of the addresses they point to
+		ttl := time.Duration(resource.TTL) * time.Second
+		if resource.Type == kind || resource.Type == dnsmessage.TypeCNAME {
+			if result.ttl == 0 || ttl < result.ttl {
+				result.ttl = ttl
+			}
+		}
+
+		switch {
+		case resource.Type == kind && kind == dnsmessage.TypeA:
+			record, err := parser.AResource()
+			if err != nil {
+				return answer{}, false, err
+			}
+			result.addrs = append(result.addrs, net.IP(record.A[:]).String())
+		case resource.Type == kind && kind == dnsmessage.TypeAAAA:
+			record, err := parser.AAAAResource()
+			if err != nil {
+				return answer{}, false, err
+			}
+			result.addrs = append(result.addrs, net.IP(record.AAAA[:]).String())
+		default:
+			if err = parser.SkipAnswer(); err != nil {
+				return answer{}, false, err
+			}
+		}
+	}
+
+	return result, false, nil
+}
@@ -0,0 +1,46 @@
+package fleet
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// draining is set once the instance starts draining, and never cleared
+var draining atomic.Bool
+
+// Draining returns true if the instance is draining before shutdown
+func Draining() bool {
+	return draining.Load()
+}
+
+// Drain marks the instance draining, failing readiness checks and announcing
+// it in the fleet registry right away, then waits the configured delay for
+// load balancers to deregister the instance or for the context to be done.
+// It returns the time waited.
+func Drain(ctx context.Context) time.Duration {
+	if !draining.Swap(true) {
+		util.Info(str.CMain, str.MDrain, config.Get().Instance.Drain.DelayDuration)
+	}
+
+	mu.RLock()
+	r := registry
+	mu.RUnlock()
+	if r != nil {
+		r.heartbeat()
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(config.Get().Instance.Drain.DelayDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return time.Since(start)
+}
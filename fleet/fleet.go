@@ -0,0 +1,210 @@
+// Package fleet registers the instance in a Redis registry shared by all
+// instances of a deployment, so each can list its peers
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Member is a single instance registered in the fleet
+type Member struct {
+	ID          string    `json:"id"`          // node ID of the instance
+	Version     string    `json:"version"`     // LOD version the instance runs
+	Commit      string    `json:"commit"`      // VCS revision of the build the instance runs
+	Environment string    `json:"environment"` // environment the instance runs in
+	Proxies     []string  `json:"proxies"`     // names of the proxies the instance serves
+	Started     time.Time `json:"started"`     // time the instance booted
+	Heartbeat   time.Time `json:"heartbeat"`   // time of the instance's last heartbeat
+	Uptime      float64   `json:"uptime"`      // uptime in seconds as of the last heartbeat
+	Draining    bool      `json:"draining"`    // whether the instance is draining before shutdown
+}
+
+// Registry heartbeats the instance into the fleet registry every interval
+type Registry struct {
+	config config.Fleet
+	client *redis.Client
+	self   Member
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+var (
+	mu       sync.RWMutex
+	registry *Registry
+)
+
+// Init starts heartbeating the instance into the registry if one is
+// configured, stopping any registry left over from a previous configuration
+func Init() {
+	var next *Registry
+	if fleet := config.Get().Instance.Fleet; fleet.RedisURL != "" {
+		next = NewRegistry(fleet, self(config.Get()))
+		go next.run()
+	}
+
+	mu.Lock()
+	prev := registry
+	registry = next
+	mu.Unlock()
+
+	// leave the registry entry to the new heartbeats if still registered
+	prev.close(next == nil)
+}
+
+// Close stops heartbeating and removes the instance from the registry
+func Close() {
+	mu.Lock()
+	prev := registry
+	registry = nil
+	mu.Unlock()
+
+	prev.close(true)
+}
+
+// List returns the live members of the fleet, nil if no registry is configured
+func List(ctx context.Context) ([]Member, error) {
+	mu.RLock()
+	r := registry
+	mu.RUnlock()
+
+	if r == nil {
+		return nil, nil
+	}
+	return r.List(ctx)
+}
+
+// self returns the registry entry of the instance with the given capabilities
+func self(c *config.Capabilities) Member {
+	proxies := make([]string, 0, len(c.Proxies))
+	for _, proxy := range c.Proxies {
+		proxies = append(proxies, proxy.Name)
+	}
+
+	return Member{
+		ID:          c.Instance.NodeID,
+		Version:     config.Version,
+		Commit:      config.GetBuild().Commit,
+		Environment: string(env.GetEnv()),
+		Proxies:     proxies,
+		Started:     util.BootTime,
+	}
+}
+
+// NewRegistry builds a registry heartbeating the given member into the
+// configured fleet registry
+func NewRegistry(fleet config.Fleet, member Member) *Registry {
+	return &Registry{
+		config: fleet,
+		client: redis.NewClient(fleet.RedisOpts),
+		self:   member,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// run heartbeats every interval until stopped
+func (r *Registry) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.config.IntervalDuration)
+	defer ticker.Stop()
+
+	for {
+		r.heartbeat()
+
+		select {
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// heartbeat writes the instance's entry with the current time to the
+// registry, unless the instance is read-only and never writes to Redis
+func (r *Registry) heartbeat() {
+	if config.IsReadOnly() {
+		return
+	}
+
+	now := time.Now()
+	member := r.self
+	member.Heartbeat = now
+	member.Uptime = now.Sub(member.Started).Seconds()
+	member.Draining = Draining()
+
+	data, err := json.Marshal(member)
+	if err != nil {
+		util.Error(str.CMain, str.EFleetHeartbeat, r.config.Key, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.IntervalDuration)
+	defer cancel()
+
+	if err = r.client.HSet(ctx, r.config.Key, member.ID, data).Err(); err != nil {
+		util.Error(str.CMain, str.EFleetHeartbeat, r.config.Key, err.Error())
+	}
+}
+
+// List returns the members of the fleet with a heartbeat within the TTL,
+// sorted by ID, dropping the entries of members that are gone unless the
+// instance is read-only
+func (r *Registry) List(ctx context.Context) ([]Member, error) {
+	entries, err := r.client.HGetAll(ctx, r.config.Key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(entries))
+	var gone []string
+	for id, data := range entries {
+		var member Member
+		if json.Unmarshal([]byte(data), &member) != nil ||
+			time.Since(member.Heartbeat) > r.config.TTLDuration {
+			gone = append(gone, id)
+			continue
+		}
+		members = append(members, member)
+	}
+
+	// dropping entries is best effort, they are skipped by every listing
+	if len(gone) > 0 && !config.IsReadOnly() {
+		_ = r.client.HDel(ctx, r.config.Key, gone...).Err()
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+	return members, nil
+}
+
+// close stops heartbeating, removing the instance's entry if deregister is
+// set and the instance isn't read-only, and closes the registry's connection
+func (r *Registry) close(deregister bool) {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+
+	if deregister && !config.IsReadOnly() {
+		ctx, cancel := context.WithTimeout(context.Background(), r.config.IntervalDuration)
+		defer cancel()
+		if err := r.client.HDel(ctx, r.config.Key, r.self.ID).Err(); err != nil {
+			util.Error(str.CMain, str.EFleetHeartbeat, r.config.Key, err.Error())
+		}
+	}
+	_ = r.client.Close()
+}
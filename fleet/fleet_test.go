@@ -0,0 +1,126 @@
+package fleet
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestRegistry will test that instances heartbeating into the same registry
+// list each other, and that instances closed or without a recent heartbeat
+// are no longer listed
+func TestRegistry(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	fleet := config.Fleet{
+		Key:              "lod:fleet",
+		RedisOpts:        &redis.Options{Addr: server.Addr()},
+		IntervalDuration: time.Hour,
+		TTLDuration:      time.Minute,
+	}
+
+	a := NewRegistry(fleet, Member{ID: "a", Proxies: []string{"tiles"}, Started: time.Now()})
+	b := NewRegistry(fleet, Member{ID: "b", Proxies: []string{"tiles"}, Started: time.Now()})
+	a.heartbeat()
+	b.heartbeat()
+
+	// a member whose heartbeats stopped without deregistering
+	server.HSet(fleet.Key, "c", `{"id":"c","heartbeat":"2020-01-01T00:00:00Z"}`)
+
+	members, err := a.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := memberIDs(members); !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Errorf(str.TFleetBadMembers, ids, []string{"a", "b"})
+	}
+	if keys, _ := server.HKeys(fleet.Key); len(keys) != 2 {
+		t.Errorf(str.TFleetBadMembers, keys, []string{"a", "b"})
+	}
+
+	go b.run()
+	b.close(true)
+
+	members, err = a.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := memberIDs(members); !reflect.DeepEqual(ids, []string{"a"}) {
+		t.Errorf(str.TFleetBadMembers, ids, []string{"a"})
+	}
+}
+
+// memberIDs returns the IDs of the given members
+func memberIDs(members []Member) []string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.ID)
+	}
+	return ids
+}
+
+// TestDrainingHeartbeat will test that heartbeats announce the instance
+// draining once it starts draining
+func TestDrainingHeartbeat(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	r := NewRegistry(config.Fleet{
+		Key:              "lod:fleet",
+		RedisOpts:        &redis.Options{Addr: server.Addr()},
+		IntervalDuration: time.Hour,
+		TTLDuration:      time.Minute,
+	}, Member{ID: "a", Started: time.Now()})
+
+	draining.Store(true)
+	defer draining.Store(false)
+	r.heartbeat()
+
+	members, err := r.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 1 || !members[0].Draining {
+		t.Errorf(str.TFleetBadMembers, members, "a draining")
+	}
+}
+
+// TestReadOnlyRegistry will test that read-only instances list the fleet
+// without registering, dropping gone members or deregistering
+func TestReadOnlyRegistry(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	fleet := config.Fleet{
+		Key:              "lod:fleet",
+		RedisOpts:        &redis.Options{Addr: server.Addr()},
+		IntervalDuration: time.Hour,
+		TTLDuration:      time.Minute,
+	}
+
+	a := NewRegistry(fleet, Member{ID: "a", Started: time.Now()})
+	a.heartbeat()
+	server.HSet(fleet.Key, "c", `{"id":"c","heartbeat":"2020-01-01T00:00:00Z"}`)
+
+	t.Setenv("READ_ONLY", "true")
+	b := NewRegistry(fleet, Member{ID: "b", Started: time.Now()})
+	go b.run()
+
+	members, err := b.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := memberIDs(members); !reflect.DeepEqual(ids, []string{"a"}) {
+		t.Errorf(str.TFleetBadMembers, ids, []string{"a"})
+	}
+
+	b.close(true)
+	if keys, _ := server.HKeys(fleet.Key); !reflect.DeepEqual(keys, []string{"a", "c"}) {
+		t.Errorf(str.TFleetBadMembers, keys, []string{"a", "c"})
+	}
+}
@@ -0,0 +1,15 @@
+package geoip
+
+import "fmt"
+
+// ErrOpenDatabase is an error struct for a GeoIP database
+// that could not be opened
+type ErrOpenDatabase struct {
+	Path string
+	Err  error
+}
+
+// Error returns the string representation of ErrOpenDatabase
+func (e ErrOpenDatabase) Error() string {
+	return fmt.Sprintf("geoip: failed to open database '%s': %s", e.Path, e.Err.Error())
+}
@@ -0,0 +1,99 @@
+// Package geoip resolves client IP addresses to countries using an optional
+// MaxMind GeoIP2 or GeoLite2 database
+package geoip
+
+import (
+	"net"
+	"sync"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Unknown is the country of addresses missing from the database, or of all
+// addresses if no database is configured
+const Unknown = "unknown"
+
+var (
+	mu     sync.RWMutex
+	reader *maxminddb.Reader
+)
+
+// record is the subset of a country or city database record LOD reads
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// Init opens the configured database, closing any previously opened one.
+// Country lookups are disabled if no database is configured.
+func Init() error {
+	var next *maxminddb.Reader
+	if path := config.Get().Instance.GeoIP.Database; path != "" {
+		var err error
+		if next, err = maxminddb.Open(path); err != nil {
+			return ErrOpenDatabase{Path: path, Err: err}
+		}
+	}
+
+	mu.Lock()
+	prev := reader
+	reader = next
+	mu.Unlock()
+
+	if prev != nil {
+		_ = prev.Close()
+	}
+	return nil
+}
+
+// Enabled returns true if a database is open
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return reader != nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country the IP address
+// is located in, or Unknown
+func Country(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Unknown
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if reader == nil {
+		return Unknown
+	}
+
+	var r record
+	if err := reader.Lookup(addr, &r); err != nil || r.Country.ISOCode == "" {
+		return Unknown
+	}
+	return r.Country.ISOCode
+}
+
+// Allowed returns true if the country passes the given allow and deny lists.
+// A non-empty allow list admits only its countries, rejecting Unknown.
+func Allowed(country string, allow, deny []string) bool {
+	for _, denied := range deny {
+		if country == denied {
+			return false
+		}
+	}
+
+	if len(allow) == 0 {
+		return true
+	}
+	for _, allowed := range allow {
+		if country == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package geoip
+
+import (
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestAllowed will test that countries are checked against allow and deny
+// lists, with unknown countries rejected by allow lists only
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		country  string
+		allow    []string
+		deny     []string
+		expected bool
+	}{
+		{"US", nil, nil, true},
+		{"US", []string{"US", "CA"}, nil, true},
+		{"DE", []string{"US", "CA"}, nil, false},
+		{Unknown, []string{"US"}, nil, false},
+		{"RU", nil, []string{"RU"}, false},
+		{Unknown, nil, []string{"RU"}, true},
+		{"US", []string{"US"}, []string{"US"}, false},
+	}
+
+	for _, test := range tests {
+		if got := Allowed(test.country, test.allow, test.deny); got != test.expected {
+			t.Errorf(str.TGeoBadAllowed, test.country, test.allow, test.deny, got)
+		}
+	}
+}
+
+// TestCountryDisabled will test that lookups without a database are unknown
+func TestCountryDisabled(t *testing.T) {
+	if got := Country("8.8.8.8"); got != Unknown {
+		t.Errorf(str.TGeoBadCountry, "8.8.8.8", got, Unknown)
+	}
+}
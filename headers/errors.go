@@ -0,0 +1,15 @@
+package headers
+
+import "fmt"
+
+// ErrInvalidPattern is an error struct for a header pattern with an
+// invalid regular expression
+type ErrInvalidPattern struct {
+	Pattern string
+	Err     error
+}
+
+// Error returns the string representation of ErrInvalidPattern
+func (e ErrInvalidPattern) Error() string {
+	return fmt.Sprintf("headers: invalid pattern '%s', got error %s", e.Pattern, e.Err.Error())
+}
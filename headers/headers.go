@@ -0,0 +1,96 @@
+package headers
+
+import (
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches header names against a list of patterns. Patterns are exact
+// header names, globs using * as a wildcard (ex: X-Backend-*), or regular
+// expressions enclosed in slashes (ex: /^X-Debug-\d+$/). All matching is
+// case-insensitive, like header names themselves.
+type Matcher struct {
+	exact    map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// Compile builds a Matcher from the given header patterns
+func Compile(patterns []string) (Matcher, error) {
+	m := Matcher{exact: map[string]bool{}}
+	for _, pattern := range patterns {
+		switch {
+		case len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+			re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+			if err != nil {
+				return Matcher{}, ErrInvalidPattern{Pattern: pattern, Err: err}
+			}
+			m.patterns = append(m.patterns, re)
+		case strings.Contains(pattern, "*"):
+			glob := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+			m.patterns = append(m.patterns, regexp.MustCompile("(?i)^"+glob+"$"))
+		default:
+			m.exact[textproto.CanonicalMIMEHeaderKey(pattern)] = true
+		}
+	}
+	return m, nil
+}
+
+// Match returns true if the header name matches any of the patterns
+func (m Matcher) Match(name string) bool {
+	if m.exact[textproto.CanonicalMIMEHeaderKey(name)] {
+		return true
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Literals returns the exact header names within the given patterns, leaving
+// out globs and regular expressions
+func Literals(patterns []string) []string {
+	literals := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "*") && !strings.HasPrefix(pattern, "/") {
+			literals = append(literals, pattern)
+		}
+	}
+	return literals
+}
+
+// Policy decides which upstream headers are pulled into the cache alongside
+// tile data and which are deleted from responses. Deletion is applied after
+// pulling and always wins, so a broad pull pattern can be narrowed by a more
+// specific delete pattern.
+type Policy struct {
+	Pull   Matcher
+	Delete Matcher
+}
+
+// NewPolicy compiles a Policy from the given pull and delete patterns
+func NewPolicy(pull, del []string) (Policy, error) {
+	pullMatcher, err := Compile(pull)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	deleteMatcher, err := Compile(del)
+	if err != nil {
+		return Policy{}, err
+	}
+
+	return Policy{Pull: pullMatcher, Delete: deleteMatcher}, nil
+}
+
+// Pulls returns true if the header is pulled from the upstream and kept
+func (p Policy) Pulls(name string) bool {
+	return p.Pull.Match(name) && !p.Delete.Match(name)
+}
+
+// Deletes returns true if the header is deleted from responses
+func (p Policy) Deletes(name string) bool {
+	return p.Delete.Match(name)
+}
@@ -0,0 +1,67 @@
+package headers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+func TestMatch(t *testing.T) {
+	m, err := Compile([]string{"X-Exact", "X-Backend-*", `/^x-debug-\d+$/`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"X-Exact":         true,
+		"x-exact":         true,
+		"X-Exactly":       false,
+		"X-Backend-Host":  true,
+		"x-backend-":      true,
+		"X-Backend":       false,
+		"X-Debug-42":      true,
+		"X-Debug-Trace":   false,
+		"Content-Type":    false,
+		"X-Other-Backend": false,
+	}
+
+	for name, expected := range tests {
+		if got := m.Match(name); got != expected {
+			t.Errorf(str.THeaderBadMatch, name, got, expected)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	_, err := Compile([]string{"/(/"})
+	var patternErr ErrInvalidPattern
+	if !errors.As(err, &patternErr) {
+		t.Fatalf(str.THeaderBadPattern, err)
+	}
+}
+
+func TestPolicyOrder(t *testing.T) {
+	policy, err := NewPolicy(
+		[]string{"Content-Type", "X-Backend-*"},
+		[]string{"X-Backend-Secret*", "X-Powered-By"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deletion is applied after pulling and wins over broader pull patterns
+	tests := map[string]bool{
+		"Content-Type":       true,
+		"X-Backend-Region":   true,
+		"X-Backend-Secret":   false,
+		"X-Backend-Secret-2": false,
+		"X-Powered-By":       false,
+		"X-Unlisted":         false,
+	}
+
+	for name, expected := range tests {
+		if got := policy.Pulls(name); got != expected {
+			t.Errorf(str.THeaderBadMatch, name, got, expected)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package heatmap
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+// MaxPNGResolution is the deepest resolution rendered as PNG, 1024 pixels square
+const MaxPNGResolution = 10
+
+// FeatureCollection is a GeoJSON feature collection of heatmap cells
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON polygon feature covering a heatmap cell
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON polygon geometry
+type Geometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// GeoJSON returns the cells of a requested zoom level as polygon features in
+// WGS84, with their tile and request count as properties
+func (e *Export) GeoJSON(zoom int) FeatureCollection {
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for _, cell := range e.Zooms[zoom] {
+		b := cell.Tile.Bounds()
+		fc.Features = append(fc.Features, Feature{
+			Type: "Feature",
+			Geometry: Geometry{
+				Type: "Polygon",
+				Coordinates: [][][2]float64{{
+					{b.West, b.South}, {b.East, b.South}, {b.East, b.North},
+					{b.West, b.North}, {b.West, b.South},
+				}},
+			},
+			Properties: map[string]interface{}{
+				"z":     cell.Tile.Zoom,
+				"x":     cell.Tile.X,
+				"y":     cell.Tile.Y,
+				"count": cell.Count,
+			},
+		})
+	}
+	return fc
+}
+
+// PNG renders the cells of a requested zoom level as a Web Mercator image of
+// the whole world, one pixel per tile at the export's resolution, shading
+// cells from transparent yellow to opaque red by log-scaled request count
+func (e *Export) PNG(zoom int) ([]byte, error) {
+	resolution := e.Resolution
+	if resolution > MaxPNGResolution {
+		resolution = MaxPNGResolution
+	}
+	size := 1 << resolution
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	cells := e.Zooms[zoom]
+	var max uint64
+	for _, cell := range cells {
+		if cell.Count > max {
+			max = cell.Count
+		}
+	}
+
+	for _, cell := range cells {
+		heat := math.Log1p(float64(cell.Count)) / math.Log1p(float64(max))
+		c := color.NRGBA{
+			R: 255,
+			G: uint8(255 * (1 - heat)),
+			A: uint8(64 + 191*heat),
+		}
+
+		// cells are at most one pixel at the rendered resolution, coarser
+		// cells cover a square of pixels
+		t := aggregate(cell.Tile, resolution)
+		span := 1 << (resolution - t.Zoom)
+		for y := t.Y * span; y < (t.Y+1)*span; y++ {
+			for x := t.X * span; x < (t.X+1)*span; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,177 @@
+// Package heatmap aggregates the tiles requested from each proxy into coarse
+// per-zoom heatmaps, exported periodically as GeoJSON or PNG
+package heatmap
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/tile"
+)
+
+// TrackersMap is an alias type for the map of proxy name to its heatmap tracker
+type TrackersMap map[string]*Tracker
+
+// Trackers of proxies with heatmaps enabled
+var Trackers = make(TrackersMap)
+
+// Tracker counts a proxy's tile requests by requested zoom level, aggregated
+// to their ancestor tiles at the configured resolution. Counts are exported
+// once per interval, starting a new window.
+type Tracker struct {
+	mu       sync.Mutex
+	config   config.Heatmap
+	start    time.Time                    // start of the window being counted
+	current  map[int]map[tile.Tile]uint64 // counts of the current window by requested zoom
+	exported *Export                      // counts of the last completed window, nil until one completes
+}
+
+// Cell is the request count of an aggregated tile
+type Cell struct {
+	Tile  tile.Tile `json:"tile"`
+	Count uint64    `json:"count"`
+}
+
+// Export is the heatmap of one window of requests at a single requested zoom
+// level, or of all windows' zoom levels
+type Export struct {
+	Start      time.Time      `json:"start"`      // start of the window
+	End        time.Time      `json:"end"`        // end of the window
+	Resolution int            `json:"resolution"` // zoom level requests are aggregated to
+	Zooms      map[int][]Cell `json:"zooms"`      // aggregated request counts by requested zoom level
+}
+
+// Init builds trackers for all proxies with heatmaps enabled, keeping the
+// counts of proxies that already had one across config reloads
+func Init() {
+	trackers := make(TrackersMap)
+	for _, proxy := range config.Get().Proxies {
+		if !proxy.Heatmap.Enabled {
+			continue
+		}
+
+		if tracker := Trackers[proxy.Name]; tracker != nil && tracker.config.Resolution == proxy.Heatmap.Resolution {
+			tracker.mu.Lock()
+			tracker.config = proxy.Heatmap
+			tracker.mu.Unlock()
+			trackers[proxy.Name] = tracker
+		} else {
+			trackers[proxy.Name] = newTracker(proxy.Heatmap, time.Now())
+		}
+	}
+	Trackers = trackers
+}
+
+// Get a heatmap tracker by proxy name, nil if the proxy has no heatmap
+func Get(name string) *Tracker {
+	return Trackers[name]
+}
+
+// newTracker builds a tracker with its first window starting at the given time
+func newTracker(heatmap config.Heatmap, now time.Time) *Tracker {
+	return &Tracker{
+		config:  heatmap,
+		start:   now,
+		current: map[int]map[tile.Tile]uint64{},
+	}
+}
+
+// Record counts a request for the given tile
+func (t *Tracker) Record(requested tile.Tile) {
+	if t == nil {
+		return
+	}
+	t.record(time.Now(), requested)
+}
+
+// record counts a request for the given tile made at the given time
+func (t *Tracker) record(now time.Time, requested tile.Tile) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rotate(now)
+
+	cells := t.current[requested.Zoom]
+	if cells == nil {
+		cells = map[tile.Tile]uint64{}
+		t.current[requested.Zoom] = cells
+	}
+	cells[aggregate(requested, t.config.Resolution)]++
+}
+
+// rotate exports the current window once its interval has passed, starting
+// the window the given time falls in. Must be called with the lock held.
+func (t *Tracker) rotate(now time.Time) {
+	interval := t.config.IntervalDuration
+	windows := now.Sub(t.start) / interval
+	if windows < 1 {
+		return
+	}
+
+	t.exported = t.export(t.start.Add(interval))
+
+	// the last completed window had no requests if more than one has passed
+	if windows > 1 {
+		t.exported = &Export{
+			Start:      t.start.Add((windows - 1) * interval),
+			End:        t.start.Add(windows * interval),
+			Resolution: t.config.Resolution,
+			Zooms:      map[int][]Cell{},
+		}
+	}
+
+	t.start = t.start.Add(windows * interval)
+	t.current = map[int]map[tile.Tile]uint64{}
+}
+
+// export returns the counts of the current window, ending at the given time.
+// Must be called with the lock held.
+func (t *Tracker) export(end time.Time) *Export {
+	e := &Export{
+		Start:      t.start,
+		End:        end,
+		Resolution: t.config.Resolution,
+		Zooms:      make(map[int][]Cell, len(t.current)),
+	}
+
+	for zoom, counts := range t.current {
+		cells := make([]Cell, 0, len(counts))
+		for aggregated, count := range counts {
+			cells = append(cells, Cell{Tile: aggregated, Count: count})
+		}
+		sort.Slice(cells, func(i, j int) bool {
+			if cells[i].Count != cells[j].Count {
+				return cells[i].Count > cells[j].Count
+			}
+			return cells[i].Tile.Quadkey() < cells[j].Tile.Quadkey()
+		})
+		e.Zooms[zoom] = cells
+	}
+	return e
+}
+
+// Exported returns the heatmap of the last completed window, or of the
+// current window so far if live is set. Nil if no window completed yet.
+func (t *Tracker) Exported(live bool) *Export {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.rotate(now)
+	if live {
+		return t.export(now)
+	}
+	return t.exported
+}
+
+// aggregate returns the ancestor of the tile at the given zoom level, or the
+// tile itself if it's not deeper
+func aggregate(t tile.Tile, zoom int) tile.Tile {
+	if t.Zoom <= zoom {
+		return t
+	}
+	shift := t.Zoom - zoom
+	return tile.Tile{X: t.X >> shift, Y: t.Y >> shift, Zoom: zoom}
+}
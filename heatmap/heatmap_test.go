@@ -0,0 +1,80 @@
+package heatmap
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+)
+
+// TestRecord will test that requests are aggregated to their ancestors at the
+// resolution and exported once their window passes
+func TestRecord(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tracker := newTracker(config.Heatmap{Resolution: 2, IntervalDuration: time.Hour}, start)
+
+	tracker.record(start, tile.Tile{Zoom: 4, X: 3, Y: 1})
+	tracker.record(start.Add(time.Minute), tile.Tile{Zoom: 4, X: 2, Y: 0})
+	tracker.record(start.Add(2*time.Minute), tile.Tile{Zoom: 4, X: 15, Y: 15})
+	tracker.record(start.Add(3*time.Minute), tile.Tile{Zoom: 1, X: 1, Y: 1})
+
+	if tracker.exported != nil {
+		t.Errorf(str.THeatmapBadExport, tracker.exported)
+	}
+
+	// the first request of the next window exports the first one
+	tracker.record(start.Add(time.Hour), tile.Tile{Zoom: 4, X: 0, Y: 0})
+
+	e := tracker.exported
+	expected := []Cell{
+		{Tile: tile.Tile{Zoom: 2, X: 0, Y: 0}, Count: 2},
+		{Tile: tile.Tile{Zoom: 2, X: 3, Y: 3}, Count: 1},
+	}
+	if e == nil || !e.End.Equal(start.Add(time.Hour)) || len(e.Zooms[4]) != 2 ||
+		e.Zooms[4][0] != expected[0] || e.Zooms[4][1] != expected[1] || e.Zooms[1][0].Count != 1 {
+		t.Fatalf(str.THeatmapBadExport, e)
+	}
+
+	// windows without requests export empty
+	tracker.record(start.Add(3*time.Hour+time.Minute), tile.Tile{Zoom: 4, X: 0, Y: 0})
+	if e = tracker.exported; !e.Start.Equal(start.Add(2*time.Hour)) || len(e.Zooms) != 0 {
+		t.Errorf(str.THeatmapBadExport, e)
+	}
+}
+
+// TestExportFormats will test that cells are rendered as GeoJSON polygons and
+// as pixels of a world image at the resolution
+func TestExportFormats(t *testing.T) {
+	e := &Export{Resolution: 2, Zooms: map[int][]Cell{
+		4: {{Tile: tile.Tile{Zoom: 2, X: 3, Y: 0}, Count: 5}},
+		1: {{Tile: tile.Tile{Zoom: 1, X: 0, Y: 1}, Count: 1}},
+	}}
+
+	fc := e.GeoJSON(4)
+	if len(fc.Features) != 1 || fc.Features[0].Properties["count"] != uint64(5) ||
+		fc.Features[0].Geometry.Coordinates[0][2][0] != 180 ||
+		math.Abs(fc.Features[0].Geometry.Coordinates[0][2][1]-tile.MaxLat) > 1e-9 {
+		t.Errorf(str.THeatmapBadGeoJSON, fc)
+	}
+
+	data, err := e.PNG(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the zoom 1 cell covers the southwest quarter of the 4x4 image
+	for _, px := range [][3]int{{0, 2, 1}, {1, 3, 1}, {2, 2, 0}, {0, 0, 0}} {
+		if _, _, _, a := img.At(px[0], px[1]).RGBA(); (a > 0) != (px[2] == 1) {
+			t.Errorf(str.THeatmapBadPixel, px[0], px[1], a)
+		}
+	}
+}
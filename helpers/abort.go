@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// StatusClientClosedRequest is the non-standard status logged for requests
+// aborted by their client, never actually received by it
+const StatusClientClosedRequest = 499
+
+// clientPollInterval is how often the connection of a request waiting on the
+// upstream is checked for its client having gone away
+const clientPollInterval = 100 * time.Millisecond
+
+// clientWatch watches the connection of a request for its client going away,
+// polling it only once ClientDone asked for it
+type clientWatch struct {
+	conn  net.Conn
+	start sync.Once
+	gone  chan struct{} // closed once the client went away
+	stop  chan struct{} // closed once the request has been handled
+}
+
+// WatchClient is middleware letting ClientDone watch the connections of
+// HTTP/1.1 requests, which fasthttp doesn't watch while handling them, and
+// stopping the watch once the request has been handled
+func WatchClient(ctx *fiber.Ctx) error {
+	// HTTP/3 requests carry their own client context
+	if _, ok := ctx.Locals(str.LocalClientCtx).(context.Context); ok {
+		return ctx.Next()
+	}
+
+	watch := &clientWatch{
+		conn: ctx.Context().Conn(),
+		gone: make(chan struct{}),
+		stop: make(chan struct{}),
+	}
+	ctx.Locals(str.LocalClientWatch, watch)
+	defer close(watch.stop)
+
+	return ctx.Next()
+}
+
+// ClientDone returns a channel closed once the client of the request goes
+// away, or nil if the transport can't tell. HTTP/3 requests report aborts
+// through their stream, and HTTP/1.1 requests through WatchClient polling
+// their connection.
+func ClientDone(ctx *fiber.Ctx) <-chan struct{} {
+	if clientCtx, ok := ctx.Locals(str.LocalClientCtx).(context.Context); ok {
+		return clientCtx.Done()
+	}
+	if watch, ok := ctx.Locals(str.LocalClientWatch).(*clientWatch); ok {
+		watch.start.Do(func() {
+			go watch.poll()
+		})
+		return watch.gone
+	}
+	return nil
+}
+
+// poll checks the watched connection until its client goes away or the
+// request has been handled
+func (w *clientWatch) poll() {
+	ticker := time.NewTicker(clientPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if connClosed(w.conn) {
+				close(w.gone)
+				return
+			}
+		}
+	}
+}
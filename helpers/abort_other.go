@@ -0,0 +1,13 @@
+//go:build !unix
+
+package helpers
+
+import (
+	"net"
+)
+
+// connClosed can't tell whether the peer closed the connection on this
+// platform, so HTTP/1.1 aborts are only detected once the response is written
+func connClosed(net.Conn) bool {
+	return false
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package helpers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestClientDone will test that HTTP/1.1 clients closing their connection
+// while their request is handled are detected, and connected ones aren't
+func TestClientDone(t *testing.T) {
+	handling := make(chan struct{})
+	gone := make(chan bool, 1)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(WatchClient)
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		handling <- struct{}{}
+		select {
+		case <-ClientDone(ctx):
+			gone <- true
+		case <-time.After(5 * clientPollInterval):
+			gone <- false
+		}
+		return ctx.SendString("tile")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	defer func() {
+		_ = app.Shutdown()
+	}()
+
+	request := func() net.Conn {
+		conn, errDial := net.Dial("tcp", ln.Addr().String())
+		if errDial != nil {
+			t.Fatal(errDial)
+		}
+		if _, errDial = conn.Write([]byte("GET / HTTP/1.1\r\nHost: lod\r\n\r\n")); errDial != nil {
+			t.Fatal(errDial)
+		}
+		<-handling
+		return conn
+	}
+
+	conn := request()
+	_ = conn.Close()
+	if detected := <-gone; !detected {
+		t.Errorf(str.TAbortBadDetect, "closed", detected, true)
+	}
+
+	conn = request()
+	defer conn.Close()
+	if detected := <-gone; detected {
+		t.Errorf(str.TAbortBadDetect, "connected", detected, false)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package helpers
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// connClosed returns true if the peer of the connection closed or reset it,
+// peeking at the socket without consuming pipelined requests or waiting
+func connClosed(conn net.Conn) bool {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	raw, err := sysConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	// Control rather than Read, which fails once fasthttp's read deadline
+	// for the request has passed
+	closed := false
+	buf := make([]byte, 1)
+	_ = raw.Control(func(fd uintptr) {
+		n, _, errPeek := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = (n == 0 && errPeek == nil) || errors.Is(errPeek, syscall.ECONNRESET)
+	})
+	return closed
+}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/upstream"
+)
+
+// BackgroundFetchPayload is used by background jobs priming, seeding and
+// warming up a proxy's cache to fetch tiles through FetchBackground
+type BackgroundFetchPayload struct {
+	Ctx       *fiber.Ctx   // request context built for the tile, ex: by AcquireEntryCtx
+	Cache     *cache.Cache // cache of the proxy the tile is fetched into
+	Tile      tile.Tile
+	CacheKey  string
+	Client    string // fair queuing client the job's fetches are queued as
+	WriteData bool   // whether the tile is written to the context's response
+}
+
+// FetchBackground fetches a tile from the proxy's upstream into its cache on
+// behalf of a background job. Fetches are fair queued as the job's client
+// against proxy traffic, within the proxy's background job limits, and count
+// against the proxy's upstream request budget, failing with
+// cache.ErrBudgetSpent once it is spent.
+func FetchBackground(payload BackgroundFetchPayload) error {
+	p := *payload.Cache.Proxy
+
+	tileUrl, err := BuildTileUrl(p, payload.Ctx, payload.Tile)
+	if err != nil {
+		return err
+	}
+
+	body, err := BuildTileBody(p, payload.Ctx, payload.Tile)
+	if err != nil {
+		return err
+	}
+
+	fetch := upstream.GetBackground(p.Name).Wrap(upstream.GetScheduler(p.Name).Wrap(payload.Client,
+		payload.Cache.Budgeted(FetchUpstream(tileUrl, p, Origin{}, body))))
+	response, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	proxyResp, ok := response.(ProxyResponse)
+	if !ok {
+		return ErrInvalidResponse{ProxyName: p.Name}
+	}
+
+	return ProcessResponse(ProcessResponsePayload{
+		Ctx:       payload.Ctx,
+		Cache:     payload.Cache,
+		Proxy:     p,
+		Tile:      payload.Tile,
+		CacheKey:  payload.CacheKey,
+		Response:  proxyResp,
+		WriteData: payload.WriteData,
+	})
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/tile"
+)
+
+// CDN cache control headers, honored by most CDNs instead of Cache-Control
+const (
+	HeaderSurrogateControl = "Surrogate-Control"
+	HeaderCDNCacheControl  = "CDN-Cache-Control"
+)
+
+// HeaderTileTTL is the upstream response header setting the lifetime of a
+// tile in seconds, taking precedence over Cache-Control
+const HeaderTileTTL = "X-Tile-TTL"
+
+// SetCacheHeaders sets the browser and CDN cache headers configured for the
+// zoom level of the given tile in the response
+func SetCacheHeaders(ctx *fiber.Ctx, proxy config.Proxy, t tile.Tile) {
+	browser, cdn := proxy.CacheHeaders.Browser, proxy.CacheHeaders.CDN
+
+	for _, band := range proxy.CacheHeaders.ZoomBands {
+		if t.Zoom < band.MinZoom || t.Zoom > band.MaxZoom {
+			continue
+		}
+
+		if band.Browser != "" {
+			browser = band.Browser
+		}
+		if band.CDN != "" {
+			cdn = band.CDN
+		}
+		break
+	}
+
+	if browser != "" {
+		ctx.Set(fiber.HeaderCacheControl, browser)
+	}
+
+	if cdn != "" {
+		ctx.Set(HeaderSurrogateControl, cdn)
+		ctx.Set(HeaderCDNCacheControl, cdn)
+	}
+}
+
+// TTLHeaders returns the headers a tile is cached with, carrying the tile's
+// own lifetime if the proxy honors upstream TTLs and the upstream set one.
+// The given headers are copied rather than modified if a lifetime is added.
+func TTLHeaders(proxy config.Proxy, resp *fiber.Response, headers map[string]string) map[string]string {
+	if !proxy.Cache.UpstreamTTL {
+		return headers
+	}
+
+	ttl, ok := UpstreamTTL(string(resp.Header.Peek(HeaderTileTTL)),
+		string(resp.Header.Peek(fiber.HeaderCacheControl)))
+	if !ok {
+		return headers
+	}
+	if proxy.Cache.MaxUpstreamTTLDuration > 0 && ttl > proxy.Cache.MaxUpstreamTTLDuration {
+		ttl = proxy.Cache.MaxUpstreamTTLDuration
+	}
+
+	cached := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		cached[k] = v
+	}
+	cached[packet.HeaderTTL] = strconv.FormatInt(int64(ttl/time.Second), 10)
+	return cached
+}
+
+// UpstreamTTL returns the tile lifetime set by an X-Tile-TTL header in
+// seconds, or else by the s-maxage or max-age directive of a Cache-Control
+// header, and false if neither sets one. No-store and no-cache directives
+// give tiles a lifetime of zero, keeping them out of the cache.
+func UpstreamTTL(tileTTL, cacheControl string) (time.Duration, bool) {
+	if tileTTL != "" {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(tileTTL), 10, 64); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	var maxAge, sharedMaxAge int64 = -1, -1
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0, true
+		case "max-age":
+			maxAge = parseSeconds(value)
+		case "s-maxage":
+			sharedMaxAge = parseSeconds(value)
+		}
+	}
+
+	switch {
+	case sharedMaxAge >= 0:
+		return time.Duration(sharedMaxAge) * time.Second, true
+	case maxAge >= 0:
+		return time.Duration(maxAge) * time.Second, true
+	default:
+		return 0, false
+	}
+}
+
+// parseSeconds parses a Cache-Control delta-seconds value, -1 if invalid
+func parseSeconds(value string) int64 {
+	seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || seconds < 0 {
+		return -1
+	}
+	return seconds
+}
@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// injectChaos delays an upstream request by the proxy's configured latency
+// and jitter, then fails it at the configured error rate
+func injectChaos(p config.Proxy) error {
+	chaos := p.Chaos
+	if !chaos.Enabled() {
+		return nil
+	}
+
+	delay := chaos.LatencyDuration
+	if chaos.JitterDuration > 0 {
+		delay += time.Duration(rand.Int63n(int64(chaos.JitterDuration)))
+	}
+	time.Sleep(delay)
+
+	if rand.Float64() < chaos.UpstreamErrorRate {
+		return ErrChaosUpstream{ProxyName: p.Name}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// ClientKey identifies the client making a request for fair queuing, using the
+// configured key header if present and falling back to the client IP
+func ClientKey(ctx *fiber.Ctx, proxy config.Proxy) string {
+	if header := proxy.Upstream.Fairness.KeyHeader; header != "" {
+		if key := ctx.Get(header); key != "" {
+			return key
+		}
+	}
+
+	return ctx.IP()
+}
+
+// SkipsMemory returns true if tiles fetched by the request are kept out of
+// the in-memory cache, as for bots if configured
+func SkipsMemory(ctx *fiber.Ctx) bool {
+	return ctx != nil && ctx.Locals(str.LocalSkipMemory) == true
+}
@@ -0,0 +1,115 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/mvt"
+)
+
+// sniffLen is the number of decompressed bytes inspected when inferring the
+// content type of gzipped tile data
+const sniffLen = 512
+
+// contentTypeOctetStream is the generic binary content type that browsers
+// refuse to render tiles as
+const contentTypeOctetStream = "application/octet-stream"
+
+// tileSignatures maps magic byte signatures to the content types they identify
+var tileSignatures = []struct {
+	offset      int
+	magic       []byte
+	contentType string
+}{
+	{0, []byte("\x89PNG\r\n\x1a\n"), contentTypePNG},
+	{0, []byte{0xff, 0xd8, 0xff}, "image/jpeg"},
+	{8, []byte("WEBP"), "image/webp"},
+	{0, []byte("GIF8"), "image/gif"},
+	{0, []byte("II*\x00"), "image/tiff"},
+	{0, []byte("MM\x00*"), "image/tiff"},
+}
+
+// InferContentType determines the content type and encoding of tile data from
+// its magic bytes, trusting the upstream provided content type only when it is
+// specific and the data itself is inconclusive. The encoding is "gzip" for
+// gzipped tile data and empty otherwise.
+func InferContentType(data []byte, upstreamType string) (contentType, contentEncoding string) {
+	sniff := data
+	if bytes.HasPrefix(data, gzipMagic) {
+		contentEncoding = "gzip"
+		sniff = gunzipPrefix(data)
+	}
+
+	// nothing to infer from empty tiles
+	if len(sniff) == 0 {
+		return upstreamType, contentEncoding
+	}
+
+	for _, sig := range tileSignatures {
+		if len(sniff) >= sig.offset+len(sig.magic) &&
+			bytes.Equal(sniff[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			return sig.contentType, contentEncoding
+		}
+	}
+
+	// keep specific upstream content types for data without a known signature
+	mediaType := strings.TrimSpace(strings.Split(upstreamType, ";")[0])
+	if mediaType != "" && mediaType != contentTypeOctetStream &&
+		!strings.HasPrefix(mediaType, "text/") && !strings.HasPrefix(mediaType, "image/") {
+		return upstreamType, contentEncoding
+	}
+
+	// vector tiles have no signature, but begin with a layer field and decode cleanly
+	if sniff[0] == 0x1a {
+		if contentEncoding != "" {
+			return contentTypeMVT, contentEncoding
+		}
+		if _, err := mvt.Decode(data); err == nil {
+			return contentTypeMVT, contentEncoding
+		}
+	}
+
+	// TileJSON and GeoJSON tiles
+	if trimmed := bytes.TrimSpace(sniff); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return fiber.MIMEApplicationJSON, contentEncoding
+	}
+
+	return http.DetectContentType(sniff), contentEncoding
+}
+
+// gunzipPrefix decompresses the first few bytes of gzipped data for sniffing
+func gunzipPrefix(data []byte) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+
+	prefix, _ := io.ReadAll(io.LimitReader(reader, sniffLen))
+	return prefix
+}
+
+// fileExtensions maps tile content types to their conventional file extensions
+var fileExtensions = map[string]string{
+	contentTypeMVT:            "pbf",
+	contentTypePNG:            "png",
+	"image/jpeg":              "jpg",
+	"image/webp":              "webp",
+	"image/gif":               "gif",
+	"image/tiff":              "tiff",
+	fiber.MIMEApplicationJSON: "json",
+}
+
+// FileExtension returns the conventional file extension for a tile content
+// type, falling back to "bin" for unknown types
+func FileExtension(contentType string) string {
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if ext, ok := fileExtensions[mediaType]; ok {
+		return ext
+	}
+	return "bin"
+}
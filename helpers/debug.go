@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/debugtile"
+)
+
+// debugScheme prefixes the internal tile URLs of debug upstreams
+const debugScheme = "debug://"
+
+// fetchDebugTile generates the labeled tile addressed by a filled debug tile
+// URL in place of an upstream request, responding 404 to anything else
+func fetchDebugTile(tileUrl string) ProxyResponse {
+	resp := &fiber.Response{}
+
+	parts := strings.Split(strings.TrimPrefix(tileUrl, debugScheme), "/")
+	coords, ok := debugCoords(parts)
+	if !ok {
+		resp.SetStatusCode(fiber.StatusNotFound)
+		return ProxyResponse{Code: fiber.StatusNotFound, Resp: resp}
+	}
+
+	data, contentType, err := debugtile.Render(parts[0], coords[0], coords[1], coords[2])
+	if err != nil {
+		resp.SetStatusCode(fiber.StatusNotFound)
+		return ProxyResponse{Code: fiber.StatusNotFound, Resp: resp}
+	}
+
+	resp.SetStatusCode(fiber.StatusOK)
+	resp.Header.SetContentType(contentType)
+	resp.SetBody(data)
+
+	return ProxyResponse{Code: fiber.StatusOK, Body: resp.Body(), Resp: resp}
+}
+
+// debugCoords parses the z, x and y coordinates following the format of a
+// split debug tile URL
+func debugCoords(parts []string) ([3]int, bool) {
+	var coords [3]int
+	if len(parts) != 4 {
+		return coords, false
+	}
+
+	for i, part := range parts[1:] {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return coords, false
+		}
+		coords[i] = n
+	}
+
+	return coords, true
+}
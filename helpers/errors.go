@@ -16,3 +16,38 @@ func (e ErrInvalidStatusCode) Error() string {
 	return fmt.Sprintf("resp: got non-2xx status code: %d for tile at cache key '%s'",
 		e.StatusCode, e.CacheKey)
 }
+
+// ErrInvalidTile is an error struct returned from ProcessResponse when
+// strict vector tile validation rejects the upstream tile data
+type ErrInvalidTile struct {
+	CacheKey string
+	Err      error
+}
+
+// Error returns the string representation of ErrInvalidTile
+func (e ErrInvalidTile) Error() string {
+	return fmt.Sprintf("resp: rejected invalid vector tile at cache key '%s': %s",
+		e.CacheKey, e.Err.Error())
+}
+
+// ErrChaosUpstream is an error struct for upstream requests deliberately
+// failed by a proxy's fault injection
+type ErrChaosUpstream struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrChaosUpstream
+func (e ErrChaosUpstream) Error() string {
+	return fmt.Sprintf("chaos: injected upstream failure for proxy '%s'", e.ProxyName)
+}
+
+// ErrInvalidResponse is an error struct returned from FetchBackground when
+// the upstream fetch returns something other than a ProxyResponse
+type ErrInvalidResponse struct {
+	ProxyName string
+}
+
+// Error returns the string representation of ErrInvalidResponse
+func (e ErrInvalidResponse) Error() string {
+	return fmt.Sprintf("resp: invalid upstream response for proxy '%s'", e.ProxyName)
+}
@@ -1,20 +1,44 @@
 package helpers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 
 	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/str"
 	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/timing"
+	"github.com/dechristopher/lod/upstream"
+	"github.com/dechristopher/lod/util"
 )
 
 // BuildTileUrl will substitute URL tile params into the proxy tile URL
 func BuildTileUrl(proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile) (string, error) {
+	template := proxy.TileURL
+	// pinned data versions may be served by their own upstream URL variant
+	if pin := GetVersionPin(ctx); pin != nil && pin.TileURL != "" {
+		template = pin.TileURL
+	}
+	return buildUrl(template, proxy, ctx, tileOverride...)
+}
+
+// BuildPublicUrl will substitute URL tile params into the proxy's public CDN URL
+func BuildPublicUrl(proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile) (string, error) {
+	return buildUrl(proxy.CDN.PublicURL, proxy, ctx, tileOverride...)
+}
+
+// buildUrl will substitute URL tile params into the given URL template
+func buildUrl(template string, proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile) (string, error) {
 	var currentTile *tile.Tile
 	var err error
 
@@ -28,14 +52,21 @@ func BuildTileUrl(proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile)
 	}
 
 	// replace XYZ values in the tile URL
-	baseUrl := currentTile.InjectString(proxy.TileURL)
+	return fillUrl(currentTile.InjectString(template), proxy, ctx)
+}
 
+// fillUrl will substitute the endpoint, custom path placeholders and URL
+// params of the request into the given URL
+func fillUrl(baseUrl string, proxy config.Proxy, ctx *fiber.Ctx) (string, error) {
 	// replace dynamic endpoint parameter in URL if configured
 	if proxy.HasEndpointParam {
 		endpoint := ctx.Params(str.ParamEndpoint)
 		baseUrl = strings.ReplaceAll(baseUrl, str.EndpointTemplate, endpoint)
 	}
 
+	// replace custom path placeholders in URL if configured
+	baseUrl = injectPathParams(baseUrl, ctx)
+
 	// fetch params from context for possible addition to URL
 	paramsMap := GetParamsFromCtx(ctx)
 
@@ -86,18 +117,57 @@ func BuildCacheKey(proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile
 		key = strings.ReplaceAll(key, str.EndpointTemplate, endpoint)
 	}
 
+	// replace custom path placeholders in cache key if configured
+	key = injectPathParams(key, ctx)
+
 	// fetch params from context for possible substitution
 	paramsMap := GetParamsFromCtx(ctx)
-	if paramsMap == nil {
-		return key, nil
-	}
 
 	// replace params by name in the key template if any exist
 	for param, val := range paramsMap {
 		key = strings.ReplaceAll(key, fmt.Sprintf("{%s}", param), val)
 	}
 
-	return key, nil
+	// fold the upstream request body into the key, since it selects the tile
+	body, err := BuildTileBody(proxy, ctx, *currentTile)
+	if err != nil {
+		return "", err
+	}
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		key = key + "#" + hex.EncodeToString(sum[:16])
+	}
+
+	return namespaceKey(proxy, ctx, key), nil
+}
+
+// namespaceKey namespaces the key by pinned data version, or otherwise by cache
+// generation so a data release can be seeded alongside the one being served
+func namespaceKey(proxy config.Proxy, ctx *fiber.Ctx, key string) string {
+	if pin := GetVersionPin(ctx); pin != nil {
+		return pin.Namespace + ":" + key
+	}
+	if generation := CacheGeneration(proxy, ctx); generation != "" {
+		return generation + ":" + key
+	}
+	return key
+}
+
+// CacheGeneration returns the cache generation a request reads and writes,
+// either one explicitly targeted by the request or the proxy's active one
+func CacheGeneration(proxy config.Proxy, ctx *fiber.Ctx) string {
+	if !proxy.Generations.Enabled {
+		return ""
+	}
+
+	if generation, ok := ctx.Locals(str.LocalGeneration).(string); ok && generation != "" {
+		return generation
+	}
+
+	if c := cache.FromCtx(ctx); c != nil {
+		return c.Generation()
+	}
+	return proxy.Generations.Active
 }
 
 // FillParamsMap will populate a map local to the request context with configured
@@ -113,6 +183,108 @@ func FillParamsMap(proxy config.Proxy, ctx *fiber.Ctx) {
 	if len(paramsMap) > 0 {
 		ctx.Locals(str.LocalParams, paramsMap)
 	}
+
+	// custom path placeholders fall back to query parameters of the same
+	// name, so admin and warm-up requests without the custom route can set them
+	pathParams := make(map[string]string, len(proxy.PathParams))
+	for _, name := range proxy.PathParams {
+		if val := ctx.Params(name, ctx.Query(name)); val != "" {
+			pathParams[name] = val
+		}
+	}
+
+	if len(pathParams) > 0 {
+		ctx.Locals(str.LocalPathParams, pathParams)
+	}
+}
+
+// injectPathParams fills custom path placeholder tokens in the given template
+// with their values from the request context
+func injectPathParams(template string, ctx *fiber.Ctx) string {
+	pathParams, _ := ctx.Locals(str.LocalPathParams).(map[string]string)
+	for name, val := range pathParams {
+		template = strings.ReplaceAll(template, fmt.Sprintf("{%s}", name), val)
+	}
+	return template
+}
+
+// BuildTileBody returns the upstream request body for a tile, either the
+// proxy's configured body template or a body passed through by the client.
+// Returns nil for upstream requests without a body.
+func BuildTileBody(proxy config.Proxy, ctx *fiber.Ctx, tileOverride ...tile.Tile) ([]byte, error) {
+	if proxy.Upstream.Body == "" {
+		body, _ := ctx.Locals(str.LocalBody).([]byte)
+		return body, nil
+	}
+
+	var currentTile *tile.Tile
+	var err error
+
+	if len(tileOverride) == 0 || tileOverride == nil {
+		currentTile, err = tile.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		currentTile = &tileOverride[0]
+	}
+
+	// replace XYZ values in the body template
+	body := currentTile.InjectString(proxy.Upstream.Body)
+
+	// replace dynamic endpoint parameter in body if configured
+	if proxy.HasEndpointParam {
+		body = strings.ReplaceAll(body, str.EndpointTemplate, ctx.Params(str.ParamEndpoint))
+	}
+
+	// replace custom path placeholders in body if configured
+	body = injectPathParams(body, ctx)
+
+	// replace params by name in the body template if any exist
+	for param, val := range GetParamsFromCtx(ctx) {
+		body = strings.ReplaceAll(body, fmt.Sprintf("{%s}", param), val)
+	}
+
+	return []byte(body), nil
+}
+
+// FillBody will store the request body of clients using one of the proxy's
+// passthrough methods in the request context locals, to be sent upstream
+func FillBody(proxy config.Proxy, ctx *fiber.Ctx) {
+	if ctx.Method() == fiber.MethodGet || len(ctx.Body()) == 0 {
+		return
+	}
+
+	// copy, since fasthttp reuses the request body buffer
+	ctx.Locals(str.LocalBody, append([]byte(nil), ctx.Body()...))
+}
+
+// FillVersion will store the data version pinned by the request, if any, in
+// the request context locals. Returns false if the request pins a version
+// that isn't configured for the proxy.
+func FillVersion(proxy config.Proxy, ctx *fiber.Ctx) bool {
+	if len(proxy.Versions.Pins) == 0 {
+		return true
+	}
+
+	version := ctx.Query(proxy.Versions.Param)
+	if version == "" {
+		return true
+	}
+
+	pin := proxy.Versions.Pin(version)
+	if pin == nil {
+		return false
+	}
+
+	ctx.Locals(str.LocalVersion, pin)
+	return true
+}
+
+// GetVersionPin returns the data version pinned by the request, if any
+func GetVersionPin(ctx *fiber.Ctx) *config.VersionPin {
+	pin, _ := ctx.Locals(str.LocalVersion).(*config.VersionPin)
+	return pin
 }
 
 // GetParamsFromCtx will attempt to fetch the params map from the request
@@ -124,49 +296,125 @@ func GetParamsFromCtx(ctx *fiber.Ctx) map[string]string {
 	return nil
 }
 
+// AcquireEntryCtx returns a detached request context for a tile list entry of
+// the given proxy, with the entry's query parameters filled so they segment
+// the cache exactly as they would for a live request. Returns false if the
+// entry pins a data version that isn't configured. Contexts returned must be
+// released with ReleaseCtx of the given app.
+func AcquireEntryCtx(r *fiber.App, p config.Proxy, c *cache.Cache, entry tile.ListEntry) (*fiber.Ctx, bool) {
+	// init the context against a stand-in server so it can be used as a
+	// context.Context, ex: by shared redis lookups waiting on it
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init(&fasthttp.Request{}, nil, nil)
+	fctx.Request.SetRequestURI("/" + p.Name + "/" + entry.Path())
+	ctx := r.AcquireCtx(fctx)
+	ctx.Locals(str.LocalCache, c)
+
+	FillParamsMap(p, ctx)
+	if !FillVersion(p, ctx) {
+		r.ReleaseCtx(ctx)
+		return nil, false
+	}
+	return ctx, true
+}
+
 // ProxyResponse is a container struct encapsulating data retrieved from the
 // upstream tile server during an agent-proxied request
 type ProxyResponse struct {
-	Code int
-	Body []byte
-	Resp *fiber.Response
+	Code   int
+	Body   []byte
+	Resp   *fiber.Response
+	Stream io.ReadCloser // unread body of streamed responses, set instead of Body
 }
 
-// FetchUpstream will fetch and return relevant data from the configured
-// upstream tileserver
-func FetchUpstream(tileUrl string, p config.Proxy) func() (interface{}, error) {
-	return func() (interface{}, error) {
-		// configure proxy agent
-		agent := fiber.AcquireAgent()
+// upstreamAgent prepares an agent requesting the given tile URL from the
+// proxy's upstream, along with the balanced upstream target it will dial.
+// Agents share keep-alive connections to the upstream, and streaming agents
+// return responses with their body unread.
+func upstreamAgent(tileUrl string, p config.Proxy, origin Origin, body []byte, stream bool) (*fiber.Agent, *upstream.Pool, *upstream.Target) {
+	// configure proxy agent
+	agent := fiber.AcquireAgent()
+
+	req := agent.Request()
+	req.Header.SetMethod(p.Upstream.Method)
+
+	// send the tile's request body to upstreams queried with one
+	if body != nil {
+		req.SetBody(body)
+		if p.Upstream.ContentType != "" {
+			req.Header.SetContentType(p.Upstream.ContentType)
+		}
+	}
+
+	// set agent request URL
+	req.SetRequestURI(tileUrl)
+
+	// identify this instance and the client to the upstream, before any
+	// configured headers which may override them
+	setIdentity(req, p, origin)
 
-		req := agent.Request()
-		req.Header.SetMethod(fiber.MethodGet)
+	// inject headers to upstream request if any are configured
+	for _, header := range p.AddHeaders {
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	// mark the request as passing through this instance, so it is refused
+	// instead of recursing if the upstream leads back here
+	req.Header.Set(HeaderLODLoop, appendHop(origin.Loop, loopMarker))
 
-		// set agent request URL
-		req.SetRequestURI(tileUrl)
-		
-		// inject headers to upstream request if any are configured
-		for _, header := range p.AddHeaders {
-			req.Header.Add(header.Name, header.Value)
+	// identify this instance to the origin LOD instance of edge proxies,
+	// signing the request if the origin requires it
+	if p.Tier == config.TierEdge {
+		req.Header.Set(HeaderLODVia, appendVia(origin.PeerVia))
+		if p.PeerSecret != "" {
+			signPeerRequest(req, p.PeerSecret, time.Now())
+		}
+	}
+
+	// parse agent request to find issues before making it
+	if err := agent.Parse(); err != nil {
+		panic(err)
+	}
+
+	// balance requests across upstream servers if configured, reusing the
+	// connections of the shared client routing through the configured
+	// outbound proxy and TLS settings
+	pool := upstream.Get(p.Name)
+	target := pool.Pick()
+	agent.HostClient = upstream.Client(&p, agent.HostClient, target, stream)
+
+	return agent, pool, target
+}
+
+// FetchUpstream will fetch and return relevant data from the configured
+// upstream tileserver on behalf of the given origin request, whose via chain
+// is forwarded to origin LOD instances of edge proxies for loop prevention.
+func FetchUpstream(tileUrl string, p config.Proxy, origin Origin, body []byte) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		if err := injectChaos(p); err != nil {
+			return nil, err
 		}
 
-		// parse agent request to find issues before making it
-		if err := agent.Parse(); err != nil {
-			panic(err)
+		if p.Upstream.Type == config.UpstreamDebug {
+			return fetchDebugTile(tileUrl), nil
 		}
 
+		agent, pool, target := upstreamAgent(tileUrl, p, origin, body, false)
+
 		// placeholder response for extracting headers from agent proxy request
 		resp := fiber.AcquireResponse()
 		agent.SetResponse(resp)
 
+		// make agent-proxied request
+		start := time.Now()
+		code, body, errs := agent.Bytes()
+		pool.Done(target, time.Since(start), len(errs) > 0 || code >= fiber.StatusInternalServerError)
+
 		// copy agent response, so we can transport its contents elsewhere while
 		// returning the agent and its request pool to the fiber memory pool
 		returnResponse := fiber.Response{}
 		resp.CopyTo(&returnResponse)
 
-		// make agent-proxied request
-		code, body, errs := agent.Bytes()
-
 		// immediately release response instance back to memory pool
 		fiber.ReleaseResponse(resp)
 
@@ -189,51 +437,111 @@ type ProcessResponsePayload struct {
 	Ctx       *fiber.Ctx
 	Cache     *cache.Cache
 	Proxy     config.Proxy
+	Tile      tile.Tile
 	CacheKey  string
 	Response  ProxyResponse
 	WriteData bool
+	Result    *ProcessedTile // optionally receives the processed tile data and headers
+}
+
+// ProcessedTile is the final tile data and headers produced by ProcessResponse
+type ProcessedTile struct {
+	Data    []byte
+	Headers map[string]string
 }
 
 // ProcessResponse will cache fetched tile data, wrangle headers, and return the
 // tile body in the provided fiber request context
 func ProcessResponse(payload ProcessResponsePayload) error {
+	// stream large tiles to the client as they arrive if configured
+	if payload.Response.Stream != nil {
+		return streamResponse(payload)
+	}
+
+	payload.Cache.RecordUpstream(len(payload.Response.Body))
+
 	// make sure a common 2XX response is received with relevant data, otherwise
 	// we complain and throw a 500 due to misconfiguration of the proxy
 
 	// TODO reason about this condition. Can tile servers return nothing for a tile that truly has no data?
 	if payload.Response.Code == fiber.StatusNoContent || (len(payload.Response.Body) > 0 && payload.Response.Code == fiber.StatusOK) {
+		stopEncode := timing.Track(payload.Ctx, timing.Encode)
+
+		// strictly validate vector tiles before they reach the cache, if configured
+		body, err := ValidateTile(payload.Cache, payload.Proxy, util.Log(payload.Ctx), payload.CacheKey, payload.Response.Body)
+		if err != nil {
+			stopEncode()
+			return err
+		}
+
 		// copy tile data into separate slice, so we don't lose the reference
-		tileData := make([]byte, len(payload.Response.Body))
-		copy(tileData, payload.Response.Body)
+		tileData := make([]byte, len(body))
+		copy(tileData, body)
+
+		// tag the tile with its configured surrogate keys
+		tags := BuildTags(payload.Proxy, payload.Tile, tileData, &payload.Response)
+
+		// infer content type from the tile data in case the upstream omits or lies about it
+		contentType, contentEncoding := InferContentType(body,
+			string(payload.Response.Resp.Header.ContentType()))
 
+		// pull configured upstream headers, replacing the content headers with
+		// the inferred ones
 		headers := map[string]string{}
-		headers["Content-Encoding"] = "gzip"
-		headers["Content-Type"] = "application/x-protobuf"
+		payload.Proxy.DoPullHeaders(payload.Response.Resp, headers)
+		delete(headers, fiber.HeaderContentType)
+		delete(headers, fiber.HeaderContentEncoding)
+		if contentType != "" {
+			headers[fiber.HeaderContentType] = contentType
+		}
+		if contentEncoding != "" {
+			headers[fiber.HeaderContentEncoding] = contentEncoding
+		}
+
+		// expose tags to the downstream CDN for tag purging, if supported
+		if payload.Cache.CDN != nil && len(tags) > 0 {
+			if tagHeader, tagValue := payload.Cache.CDN.TagHeader(tags); tagHeader != "" {
+				headers[tagHeader] = tagValue
+			}
+		}
+
+		if payload.Result != nil {
+			payload.Result.Data = tileData
+			payload.Result.Headers = headers
+		}
+
+		stopEncode()
 
-		// Store configured headers into the tile cache for this tile
-		//payload.Proxy.DoPullHeaders(payload.Response.Resp, headers)
 		// write data to parent fiber request context if write mode is specified
 		if payload.WriteData {
-			// Delete headers from the final response that are on the DeleteHeaders list
-			// if we got them from the tileserver. This can be used to prevent leaking
-			// internals of the tileserver if you don't control what it returns
-			//payload.Proxy.DoDeleteHeaders(payload.Ctx)
-
-			// set 204 Status No Content if upstream tileserver returned no/empty tile
+			defer timing.Track(payload.Ctx, timing.Write)()
 			if payload.Response.Code == fiber.StatusNoContent {
-				payload.Ctx.Status(fiber.StatusNoContent)
-			}
-			payload.Ctx.Set("Content-Encoding", "gzip")
-
-			// write agent proxied response body to the response
-			_, err := payload.Ctx.Write(payload.Response.Body)
-			if err != nil {
-				return err
+				// respond to empty tiles using configured missing tile behavior,
+				// defaulting to 204 Status No Content like the upstream tileserver
+				if err := SendMissingTile(payload.Ctx, payload.Proxy, fiber.StatusNoContent); err != nil {
+					return err
+				}
+			} else {
+				for key, val := range headers {
+					payload.Ctx.Set(key, val)
+				}
+
+				// write agent proxied response body to the response
+				_, err = payload.Ctx.Write(body)
+				if err != nil {
+					return err
+				}
+				payload.Cache.RecordServed(cache.SourceUpstream, len(body))
 			}
 		}
 
 		// spin off a routine to cache the tile without blocking the response
-		go payload.Cache.EncodeSet(payload.CacheKey, tileData, headers)
+		skipMemory := SkipsMemory(payload.Ctx)
+		cached := TTLHeaders(payload.Proxy, payload.Response.Resp, headers)
+		go func() {
+			payload.Cache.EncodeSet(payload.CacheKey, tileData, cached, skipMemory)
+			payload.Cache.Tag(context.Background(), payload.CacheKey, tags)
+		}()
 	} else {
 		return ErrInvalidStatusCode{
 			StatusCode: payload.Response.Code,
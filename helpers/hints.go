@@ -0,0 +1,107 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+)
+
+// neighborOffsets are the tile offsets of edge neighbors, then diagonals,
+// ordered by how likely a panning client is to request them next
+var neighborOffsets = [][2]int{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+}
+
+// NeighborLinks builds preload Link header values for the neighbors of the
+// requested tile, wrapping around the antimeridian
+func NeighborLinks(ctx *fiber.Ctx, proxy config.Proxy, t tile.Tile) []string {
+	route := "/" + proxy.Name + proxy.Route()
+
+	// keep query parameters such as access tokens and URL params
+	query := ""
+	if raw := ctx.Request().URI().QueryString(); len(raw) > 0 {
+		query = "?" + string(raw)
+	}
+
+	n := 1 << t.Zoom
+	links := make([]string, 0, proxy.Hints.Neighbors)
+	for _, offset := range neighborOffsets[:proxy.Hints.Neighbors] {
+		x := ((t.X+offset[0])%n + n) % n
+		y := t.Y + offset[1]
+		if y < 0 || y >= n || (x == t.X && y == t.Y) {
+			continue
+		}
+
+		neighbor := tile.Tile{Zoom: t.Zoom, X: x, Y: y}
+		links = append(links, fmt.Sprintf("<%s%s>; rel=preload; as=fetch; crossorigin",
+			fillRoute(route, ctx, neighbor), query))
+	}
+
+	return links
+}
+
+// routeToken matches named placeholders, with optional modifiers, and
+// wildcards in a fiber route
+var routeToken = regexp.MustCompile(`:([a-zA-Z0-9_]+)[?+]?|\*`)
+
+// fillRoute builds the path of the given tile on a proxy route, keeping the
+// values of all other placeholders from the current request
+func fillRoute(route string, ctx *fiber.Ctx, t tile.Tile) string {
+	return routeToken.ReplaceAllStringFunc(route, func(token string) string {
+		if token == "*" {
+			return ctx.Params("*")
+		}
+
+		name := strings.TrimRight(strings.TrimPrefix(token, ":"), "?+")
+		switch name {
+		case str.ParamZ:
+			return strconv.Itoa(t.Zoom)
+		case str.ParamX:
+			return strconv.Itoa(t.X)
+		case str.ParamY:
+			return strconv.Itoa(t.Y)
+		}
+		return ctx.Params(name)
+	})
+}
+
+// SendEarlyHints writes a 103 Early Hints interim response with the given
+// preload links ahead of the final response. Only HTTP/1.1 clients are sent
+// hints, since earlier clients may not understand interim responses.
+func SendEarlyHints(ctx *fiber.Ctx, links []string) error {
+	if len(links) == 0 || !ctx.Request().Header.IsHTTP11() {
+		return nil
+	}
+
+	hints := &strings.Builder{}
+	hints.WriteString("HTTP/1.1 103 Early Hints\r\n")
+	for _, link := range links {
+		hints.WriteString(fiber.HeaderLink + ": " + link + "\r\n")
+	}
+	hints.WriteString("\r\n")
+
+	_, err := ctx.Context().Conn().Write([]byte(hints.String()))
+	return err
+}
+
+// SetHints emits the configured preload hints for the neighbors of the
+// requested tile, either as Link headers on the response or as early hints
+func SetHints(ctx *fiber.Ctx, proxy config.Proxy, t tile.Tile) {
+	switch proxy.Hints.Mode {
+	case config.HintsLink:
+		for _, link := range NeighborLinks(ctx, proxy, t) {
+			ctx.Response().Header.Add(fiber.HeaderLink, link)
+		}
+	case config.HintsEarly:
+		// a failed write will fail the final response on the same connection too
+		_ = SendEarlyHints(ctx, NeighborLinks(ctx, proxy, t))
+	}
+}
@@ -0,0 +1,81 @@
+package helpers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/metadata"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// FetchMetadata fetches the proxy's upstream metadata document and normalizes
+// it, without tile URLs pointing at LOD
+func FetchMetadata(p config.Proxy) (metadata.Document, error) {
+	// metadata documents are plain documents fetched without a templated body
+	p.Upstream.Method = fiber.MethodGet
+	p.Upstream.Body = ""
+
+	response, err := FetchUpstream(p.Metadata.URL, p, Origin{}, nil)()
+	if err != nil {
+		return metadata.Document{}, err
+	}
+
+	proxyResp, ok := response.(ProxyResponse)
+	if !ok || proxyResp.Code != fiber.StatusOK {
+		return metadata.Document{}, ErrInvalidStatusCode{
+			StatusCode: proxyResp.Code,
+			CacheKey:   p.Metadata.URL,
+		}
+	}
+
+	doc, err := metadata.Normalize(proxyResp.Body, p.Metadata.Format)
+	doc.Tiles = []string{}
+	return doc, err
+}
+
+// DiscoverCoverage populates the unset zoom levels and bounds of the proxy
+// served by the given cache from its upstream metadata, returning the
+// resulting coverage. Configured values always take precedence.
+func DiscoverCoverage(c *cache.Cache) (config.Coverage, error) {
+	doc, err := FetchMetadata(*c.Proxy)
+	if err != nil {
+		return c.Coverage(), err
+	}
+
+	discovered := config.Coverage{
+		MinZoom: doc.MinZoom,
+		MaxZoom: doc.MaxZoom,
+		Bounds:  doc.Bounds,
+	}
+	if err = config.ValidateCoverage(discovered); err != nil {
+		return c.Coverage(), err
+	}
+
+	coverage := c.Proxy.Coverage.Merge(discovered)
+	if err = config.ValidateCoverage(coverage); err != nil {
+		return c.Coverage(), err
+	}
+
+	c.SetCoverage(coverage)
+	return coverage, nil
+}
+
+// DiscoverAll discovers the coverage of all proxies configured to do so in
+// the background, logging the outcome
+func DiscoverAll(caches *cache.Manager) {
+	for name, c := range caches.All() {
+		if !c.Proxy.Coverage.Discover {
+			continue
+		}
+		go func(name string, c *cache.Cache) {
+			coverage, err := DiscoverCoverage(c)
+			if err != nil {
+				util.Error(str.CMain, str.EDiscover, name, err.Error())
+				return
+			}
+			util.Info(str.CMain, str.MDiscover, name, coverage)
+		}(name, c)
+	}
+}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Content types of generated empty tiles
+const (
+	contentTypeMVT = "application/vnd.mapbox-vector-tile"
+	contentTypePNG = "image/png"
+)
+
+// emptyPNG is a fully transparent 256x256 PNG tile, generated once at startup
+var emptyPNG = encodeEmptyPNG()
+
+// encodeEmptyPNG renders a fully transparent 256x256 PNG tile
+func encodeEmptyPNG() []byte {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 256, 256))); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// SendMissingTile responds to a request for a tile that does not exist using
+// the proxy's configured missing tile behavior, falling back to the given
+// status code if no behavior is configured
+func SendMissingTile(ctx *fiber.Ctx, proxy config.Proxy, fallbackStatus int) error {
+	switch proxy.MissingTile {
+	case config.MissingTileNotFound:
+		return ctx.Status(fiber.StatusNotFound).SendString("")
+	case config.MissingTileNoContent:
+		return ctx.Status(fiber.StatusNoContent).SendString("")
+	case config.MissingTileEmpty:
+		return SendEmptyTile(ctx, proxy)
+	}
+
+	return ctx.Status(fallbackStatus).SendString("")
+}
+
+// SendEmptyTile writes a generated empty tile in the proxy's configured
+// empty tile format with the proper content type
+func SendEmptyTile(ctx *fiber.Ctx, proxy config.Proxy) error {
+	ctx.Response().Header.Del(fiber.HeaderContentEncoding)
+	ctx.Status(fiber.StatusOK)
+
+	if proxy.EmptyTileFormat == config.EmptyTilePNG {
+		ctx.Set(fiber.HeaderContentType, contentTypePNG)
+		return ctx.Send(emptyPNG)
+	}
+
+	// a protobuf message with no layers is a valid empty vector tile
+	ctx.Set(fiber.HeaderContentType, contentTypeMVT)
+	return ctx.Send([]byte{})
+}
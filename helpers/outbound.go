@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Origin describes the client request an upstream request is made on behalf
+// of, identified to the upstream in outbound headers. Requests shared by
+// several clients carry the origin of the first.
+type Origin struct {
+	PeerVia      string // chain of LOD node IDs the request passed through
+	Loop         string // loop markers of the LOD instances the request passed through
+	Via          string // Via header of the request
+	Forwarded    string // RFC 7239 Forwarded header of the request
+	Version      string // HTTP version the request was received over, ex: 1.1
+	Scheme       string // scheme the request was received over, http or https
+	Host         string // Host header of the request
+	ForwardedFor string // X-Forwarded-For header of the request
+	RemoteIP     string // IP address of the connecting client or proxy
+}
+
+// RequestOrigin returns the origin of a client request, copied so upstream
+// requests outliving the client request can still use it
+func RequestOrigin(ctx *fiber.Ctx) Origin {
+	return Origin{
+		PeerVia:      utils.CopyString(PeerVia(ctx)),
+		Loop:         utils.CopyString(ctx.Get(HeaderLODLoop)),
+		Via:          utils.CopyString(ctx.Get(fiber.HeaderVia)),
+		Forwarded:    utils.CopyString(ctx.Get(fiber.HeaderForwarded)),
+		Version:      strings.TrimPrefix(string(ctx.Request().Header.Protocol()), "HTTP/"),
+		Scheme:       utils.CopyString(ctx.Protocol()),
+		Host:         utils.CopyString(ctx.Hostname()),
+		ForwardedFor: utils.CopyString(ctx.Get(fiber.HeaderXForwardedFor)),
+		RemoteIP:     ctx.Context().RemoteIP().String(),
+	}
+}
+
+// setIdentity sets the User-Agent of an upstream request, and identifies the
+// client and this instance in X-Forwarded-For, Forwarded and Via headers if
+// configured. Hops are appended to those of the client request so multi-hop
+// proxy chains stay traceable.
+func setIdentity(req *fasthttp.Request, p config.Proxy, origin Origin) {
+	req.Header.SetUserAgent(p.Upstream.UserAgent)
+
+	// requests made by LOD itself, ex: warmups, have no client to forward
+	if p.Upstream.ForwardedFor && origin.RemoteIP != "" {
+		req.Header.Set(fiber.HeaderXForwardedFor, appendHop(origin.ForwardedFor, origin.RemoteIP))
+	}
+
+	if p.Upstream.Forwarded {
+		req.Header.Set(fiber.HeaderForwarded, appendHop(origin.Forwarded, forwardedElement(origin)))
+	}
+
+	if p.Upstream.Via {
+		req.Header.Set(fiber.HeaderVia, appendHop(origin.Via, viaHop(origin.Version)))
+	}
+}
+
+// SetResponseVia appends this instance to the Via header of a response
+func SetResponseVia(ctx *fiber.Ctx) {
+	version := strings.TrimPrefix(string(ctx.Request().Header.Protocol()), "HTTP/")
+	ctx.Set(fiber.HeaderVia, appendHop(ctx.GetRespHeader(fiber.HeaderVia), viaHop(version)))
+}
+
+// viaHop returns the Via hop of this instance for a message received over the
+// given HTTP version, using its node ID as the pseudonym
+func viaHop(version string) string {
+	if version == "" {
+		version = "1.1"
+	}
+	return version + " " + config.Get().Instance.NodeID
+}
+
+// forwardedElement returns the RFC 7239 Forwarded element of this instance
+// for the origin request, naming the client and this instance's node
+func forwardedElement(origin Origin) string {
+	element := "by=" + forwardedNode(config.Get().Instance.NodeID)
+	if origin.RemoteIP != "" {
+		element = "for=" + forwardedValue(origin.RemoteIP) + ";" + element
+	}
+	if origin.Host != "" {
+		element += ";host=" + forwardedValue(origin.Host)
+	}
+	if origin.Scheme != "" {
+		element += ";proto=" + origin.Scheme
+	}
+	return element
+}
+
+// forwardedNode returns the obfuscated RFC 7239 node identifier of a node ID,
+// an underscore followed by its letters, digits, dots, underscores and dashes
+func forwardedNode(nodeID string) string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, nodeID)
+}
+
+// forwardedValue formats a Forwarded parameter value, quoting IPv6 addresses
+// in brackets and any other value that isn't a valid token
+func forwardedValue(value string) string {
+	if ip := net.ParseIP(value); ip != nil && ip.To4() == nil {
+		value = "[" + value + "]"
+	}
+
+	for _, r := range value {
+		if !isTokenChar(r) {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+	}
+	return value
+}
+
+// isTokenChar returns true if the rune may appear in an HTTP token
+func isTokenChar(r rune) bool {
+	if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
+
+// appendHop adds a hop to a comma separated list of hops
+func appendHop(hops, hop string) string {
+	if hops == "" {
+		return hop
+	}
+	return hops + ", " + hop
+}
+
+// viaHasNode returns true if one of the hops of a Via header was received by
+// the given node
+func viaHasNode(via, nodeID string) bool {
+	for _, hop := range strings.Split(via, ",") {
+		fields := strings.Fields(hop)
+		if len(fields) >= 2 && fields[1] == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHasNode returns true if one of the elements of a Forwarded header
+// was added by the given node
+func forwardedHasNode(forwarded, nodeID string) bool {
+	node := forwardedNode(nodeID)
+	for _, element := range strings.Split(forwarded, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(name, "by") && strings.Trim(value, `"`) == node {
+				return true
+			}
+		}
+	}
+	return false
+}
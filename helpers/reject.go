@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rejection is the JSON body sent to clients turned away while LOD is
+// unavailable or overloaded, so they can back off consistently
+type Rejection struct {
+	Status     string `json:"status"`      // always "failed"
+	Error      string `json:"error"`       // reason the request was rejected
+	RetryAfter int    `json:"retry_after"` // seconds to wait before retrying, mirroring Retry-After
+}
+
+// SendRejection rejects a request with the given status and reason, hinting
+// when to retry with a Retry-After header rounded up to whole seconds
+func SendRejection(ctx *fiber.Ctx, status int, reason string, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return ctx.Status(status).JSON(Rejection{
+		Status:     "failed",
+		Error:      reason,
+		RetryAfter: seconds,
+	})
+}
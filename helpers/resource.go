@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// BuildResourceUrl will substitute request params into the upstream URL of a
+// resource route, which has no XYZ values
+func BuildResourceUrl(proxy config.Proxy, ctx *fiber.Ctx) (string, error) {
+	return fillUrl(proxy.TileURL, proxy, ctx)
+}
+
+// BuildResourceKey will put together the cache key of a resource route from
+// the request path and configured URL params
+func BuildResourceKey(proxy config.Proxy, ctx *fiber.Ctx) string {
+	key := proxy.Cache.KeyTemplate + ctx.Path()
+
+	// encoded params are sorted, so equivalent requests share a key
+	if paramsMap := GetParamsFromCtx(ctx); len(paramsMap) > 0 {
+		params := url.Values{}
+		for param, val := range paramsMap {
+			params.Add(param, val)
+		}
+		key = key + "?" + params.Encode()
+	}
+
+	return namespaceKey(proxy, ctx, key)
+}
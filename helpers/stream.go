@@ -0,0 +1,252 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/util"
+)
+
+// streamPeekLen is the number of leading body bytes read to infer a streamed
+// tile's content type before any of it is sent
+const streamPeekLen = 4096
+
+// StreamUpstream behaves like FetchUpstream, except that successful responses
+// without a Content-Length or larger than the streaming size cutoff are
+// returned with their body unread in ProxyResponse.Stream so it can be
+// streamed to the client as it arrives. Smaller responses are read whole, so
+// they can be shared by requests waiting on the same tile. Streams can only
+// be read by a single request, which must claim and close them.
+func StreamUpstream(tileUrl string, p config.Proxy, origin Origin, body []byte) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		if err := injectChaos(p); err != nil {
+			return nil, err
+		}
+
+		if p.Upstream.Type == config.UpstreamDebug {
+			return fetchDebugTile(tileUrl), nil
+		}
+
+		agent, pool, target := upstreamAgent(tileUrl, p, origin, body, true)
+
+		resp := fiber.AcquireResponse()
+
+		// make agent-proxied request, returning once headers are received
+		start := time.Now()
+		err := agent.HostClient.Do(agent.Request(), resp)
+		code := resp.StatusCode()
+		pool.Done(target, time.Since(start), err != nil || code >= fiber.StatusInternalServerError)
+		fiber.ReleaseAgent(agent)
+
+		if err != nil {
+			fiber.ReleaseResponse(resp)
+			return nil, err
+		}
+
+		// only successful tiles are worth streaming, buffer anything else
+		if code != fiber.StatusOK {
+			returnResponse := fiber.Response{}
+			resp.CopyTo(&returnResponse)
+			fiber.ReleaseResponse(resp)
+
+			return ProxyResponse{
+				Code: code,
+				Body: returnResponse.Body(),
+				Resp: &returnResponse,
+			}, nil
+		}
+
+		// tiles known to be small enough to cache are read whole
+		if size := resp.Header.ContentLength(); size >= 0 && size <= p.Streaming.MaxCacheSize*cache.OneMB {
+			data, errRead := io.ReadAll(resp.BodyStream())
+			_ = resp.CloseBodyStream()
+			if errRead != nil {
+				fiber.ReleaseResponse(resp)
+				return nil, errRead
+			}
+
+			returnResponse := fiber.Response{}
+			resp.Header.CopyTo(&returnResponse.Header)
+			returnResponse.SetBody(data)
+			fiber.ReleaseResponse(resp)
+
+			return ProxyResponse{
+				Code: code,
+				Body: returnResponse.Body(),
+				Resp: &returnResponse,
+			}, nil
+		}
+
+		return ProxyResponse{
+			Code:   code,
+			Resp:   resp,
+			Stream: &upstreamStream{resp: resp},
+		}, nil
+	}
+}
+
+// ClaimStream claims the unread body stream of an upstream response for the
+// calling request, returning false if another request sharing the response
+// already claimed it. Responses without a stream can always be claimed.
+func ClaimStream(resp ProxyResponse) bool {
+	stream, ok := resp.Stream.(*upstreamStream)
+	if !ok {
+		return true
+	}
+	return stream.claimed.CompareAndSwap(false, true)
+}
+
+// DiscardStream closes the body stream of an upstream response if no
+// request claimed it
+func DiscardStream(resp ProxyResponse) {
+	if resp.Stream != nil && ClaimStream(resp) {
+		_ = resp.Stream.Close()
+	}
+}
+
+// upstreamStream reads a streamed upstream response body, releasing the
+// response back to the memory pool once closed
+type upstreamStream struct {
+	resp    *fiber.Response
+	claimed atomic.Bool // whether a request claimed the stream to read it
+}
+
+// Read reads from the upstream response body
+func (s *upstreamStream) Read(p []byte) (int, error) {
+	return s.resp.BodyStream().Read(p)
+}
+
+// Close closes the upstream response body and releases the response
+func (s *upstreamStream) Close() error {
+	err := s.resp.CloseBodyStream()
+	fiber.ReleaseResponse(s.resp)
+	return err
+}
+
+// streamResponse streams a successful upstream tile body to the client,
+// caching it once fully read if it fits within the streaming size cutoff
+func streamResponse(payload ProcessResponsePayload) error {
+	body := bufio.NewReaderSize(payload.Response.Stream, streamPeekLen)
+
+	// infer content type from the first bytes of the tile before sending any
+	prefix, _ := body.Peek(streamPeekLen)
+	contentType, contentEncoding := InferContentType(prefix,
+		string(payload.Response.Resp.Header.ContentType()))
+
+	// pull configured upstream headers, replacing the content headers with
+	// the inferred ones
+	headers := map[string]string{}
+	payload.Proxy.DoPullHeaders(payload.Response.Resp, headers)
+	delete(headers, fiber.HeaderContentType)
+	delete(headers, fiber.HeaderContentEncoding)
+	if contentType != "" {
+		headers[fiber.HeaderContentType] = contentType
+	}
+	if contentEncoding != "" {
+		headers[fiber.HeaderContentEncoding] = contentEncoding
+	}
+
+	for key, val := range headers {
+		payload.Ctx.Set(key, val)
+	}
+
+	// always send chunked, since anything reading the body of a response with
+	// a known length, like the request logger, would buffer the whole stream
+	payload.Ctx.Context().SetBodyStream(&cacheTee{
+		payload: payload,
+		log:     util.Log(payload.Ctx),
+		body:    body,
+		headers: TTLHeaders(payload.Proxy, payload.Response.Resp, headers),
+		limit:   payload.Proxy.Streaming.MaxCacheSize * cache.OneMB,
+		skipMem: SkipsMemory(payload.Ctx),
+	}, -1)
+
+	return nil
+}
+
+// cacheTee is the body stream of a streamed tile response, buffering the
+// tile as it is sent to the client so it can be cached afterwards
+type cacheTee struct {
+	payload ProcessResponsePayload
+	log     *util.Logger // logger of the request, whose context is gone once cached
+	body    io.Reader
+	headers map[string]string
+	buf     bytes.Buffer
+	limit   int   // maximum bytes buffered for caching
+	read    int   // total bytes read from the upstream
+	over    bool  // whether the body outgrew the limit and won't be cached
+	done    bool  // whether the body was read to completion
+	err     error // error reading the upstream body, if any
+	skipMem bool  // whether the tile is kept out of the in-memory cache
+}
+
+// Read reads the next chunk of the upstream body, buffering it for caching
+func (t *cacheTee) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	t.read += n
+
+	if !t.over {
+		if t.buf.Len()+n > t.limit {
+			// too large to cache, stop holding on to it
+			t.over = true
+			t.buf = bytes.Buffer{}
+		} else {
+			t.buf.Write(p[:n])
+		}
+	}
+
+	if err == io.EOF {
+		t.done = true
+	} else if err != nil {
+		t.err = err
+	}
+	return n, err
+}
+
+// Close caches the buffered tile if it was read completely and within the
+// size cutoff, then closes the upstream stream
+func (t *cacheTee) Close() error {
+	c := t.payload.Cache
+	c.RecordUpstream(t.read)
+	c.RecordServed(cache.SourceUpstream, t.read)
+
+	// tiles aborted mid-stream or over the size cutoff are never cached
+	if t.done && !t.over {
+		t.cache()
+	}
+
+	// the body is closed early without an upstream error once the client goes
+	// away, which also stops reading from the upstream
+	if !t.done && t.err == nil {
+		c.Metrics.ClientAborts.WithLabelValues(cache.AbortStream).Inc()
+	}
+
+	return t.payload.Response.Stream.Close()
+}
+
+// cache validates and caches the fully buffered tile
+func (t *cacheTee) cache() {
+	p := t.payload
+
+	tileData, err := ValidateTile(p.Cache, p.Proxy, t.log, p.CacheKey, t.buf.Bytes())
+	if err != nil {
+		return
+	}
+
+	// tags are computed from the complete tile, so streamed responses
+	// themselves don't carry a CDN tag header
+	tags := BuildTags(p.Proxy, p.Tile, tileData, &p.Response)
+
+	go func() {
+		p.Cache.EncodeSet(p.CacheKey, tileData, t.headers, t.skipMem)
+		p.Cache.Tag(context.Background(), p.CacheKey, tags)
+	}()
+}
@@ -0,0 +1,179 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+const streamConfig = `
+[[proxies]]
+name = "stream"
+tile_url = "http://tiles.invalid/{z}/{x}/{y}.png"
+[proxies.cache]
+mem_enabled = true
+mem_cap = 10
+mem_ttl = "1h"
+key_template = "{z}/{x}/{y}"
+`
+
+// testStream is an upstream body stream recording whether it was closed
+type testStream struct {
+	io.Reader
+	closed bool
+}
+
+func (s *testStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+// failingReader returns its data, then fails instead of ending
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errors.New("upstream reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// TestCacheTee will test that streamed tiles are cached only once read
+// completely within the size cutoff, that aborted reads count as client
+// aborts, and that the upstream body is always closed
+func TestCacheTee(t *testing.T) {
+	if err := config.LoadData([]byte(streamConfig)); err != nil {
+		t.Fatal(err)
+	}
+	p := config.Get().Proxies[0]
+	c, err := cache.New(p, config.Get().Instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	app := fiber.New()
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	aborts := func() float64 {
+		var m dto.Metric
+		_ = c.Metrics.ClientAborts.WithLabelValues(cache.AbortStream).Write(&m)
+		return m.GetCounter().GetValue()
+	}
+
+	tests := []struct {
+		name   string
+		body   io.Reader
+		read   int // bytes read before closing, all if negative
+		cached bool
+		abort  bool
+	}{
+		{name: "over limit", body: bytes.NewReader(make([]byte, 64)), read: -1},
+		{name: "aborted", body: bytes.NewReader(make([]byte, 16)), read: 4, abort: true},
+		{name: "upstream error", body: &failingReader{data: make([]byte, 8)}, read: -1},
+		{name: "complete", body: bytes.NewReader([]byte("tile")), read: -1, cached: true},
+	}
+
+	for i, test := range tests {
+		key := tile.ListEntry{Tile: tile.Tile{X: i, Y: i, Zoom: 4}}.Path()
+		stream := &testStream{Reader: test.body}
+		tee := &cacheTee{
+			payload: ProcessResponsePayload{
+				Ctx:      ctx,
+				Cache:    c,
+				Proxy:    p,
+				CacheKey: key,
+				Response: ProxyResponse{Code: fiber.StatusOK, Resp: &fiber.Response{}, Stream: stream},
+			},
+			log:     util.Log(ctx),
+			body:    test.body,
+			headers: map[string]string{},
+			limit:   32,
+		}
+
+		before := aborts()
+		if test.read < 0 {
+			_, _ = io.Copy(io.Discard, tee)
+		} else {
+			_, _ = tee.Read(make([]byte, test.read))
+		}
+		if err = tee.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !stream.closed {
+			t.Errorf(str.TStreamBadClosed, test.name)
+		}
+		if got, want := aborts()-before, map[bool]float64{true: 1}[test.abort]; got != want {
+			t.Errorf(str.TStreamBadAborts, test.name, got, want)
+		}
+
+		// tiles are cached in the background, while tiles that aren't are
+		// never handed off for caching at all
+		cached := c.Fetch(key, ctx) != nil
+		deadline := time.Now().Add(5 * time.Second)
+		for test.cached && !cached && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			cached = c.Fetch(key, ctx) != nil
+		}
+		if cached != test.cached {
+			t.Errorf(str.TStreamBadCached, test.name, cached, test.cached)
+		}
+	}
+}
+
+// TestStreamUpstream will test that only tiles without a Content-Length or
+// larger than the streaming size cutoff are streamed, and that a stream can
+// only be claimed once
+func TestStreamUpstream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte("tile"))
+	}))
+	defer server.Close()
+
+	p := config.Proxy{Name: "stream", Streaming: config.Streaming{Enabled: true, MaxCacheSize: 1}}
+
+	for path, streamed := range map[string]bool{"/small": false, "/chunked": true} {
+		response, err := StreamUpstream(server.URL+path, p, Origin{}, nil)()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := response.(ProxyResponse)
+		if (resp.Stream != nil) != streamed {
+			t.Errorf(str.TStreamBadStreamed, path, resp.Stream != nil, streamed)
+		}
+		if !streamed {
+			continue
+		}
+
+		if !ClaimStream(resp) || ClaimStream(resp) {
+			t.Errorf(str.TStreamBadClaim, path)
+		}
+		if data, _ := io.ReadAll(resp.Stream); string(data) != "tile" {
+			t.Errorf(str.TStreamBadBody, path, data)
+		}
+		_ = resp.Stream.Close()
+	}
+}
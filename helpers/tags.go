@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/mvt"
+	"github.com/dechristopher/lod/tile"
+)
+
+// BuildTags computes the surrogate key tags for a tile fetched from the
+// upstream according to the proxy's tagging configuration
+func BuildTags(proxy config.Proxy, t tile.Tile, data []byte, resp *ProxyResponse) []string {
+	if !proxy.Tags.Enabled() {
+		return nil
+	}
+
+	tags := append([]string{}, proxy.Tags.Static...)
+
+	if band := zoomBand(proxy.Tags.ZoomBands, t.Zoom); band != "" {
+		tags = append(tags, band)
+	}
+
+	if proxy.Tags.VersionHeader != "" && resp != nil && resp.Resp != nil {
+		if version := resp.Resp.Header.Peek(proxy.Tags.VersionHeader); len(version) > 0 {
+			tags = append(tags, fmt.Sprintf("version:%s", version))
+		}
+	}
+
+	if proxy.Tags.Layers && len(data) > 0 {
+		if raw, _, err := decompress(data); err == nil {
+			if vectorTile, errDecode := mvt.Decode(raw); errDecode == nil {
+				for _, layer := range vectorTile.Layers {
+					tags = append(tags, fmt.Sprintf("layer:%s", layer.Name))
+				}
+			}
+		}
+	}
+
+	return tags
+}
+
+// zoomBand returns the zoom band tag for the given zoom level, formatted as
+// zoom:{start}-{end}, or zoom:{start}+ for the last band
+func zoomBand(bands []int, zoom int) string {
+	for i := len(bands) - 1; i >= 0; i-- {
+		if zoom < bands[i] {
+			continue
+		}
+
+		if i == len(bands)-1 {
+			return fmt.Sprintf("zoom:%d+", bands[i])
+		}
+		return fmt.Sprintf("zoom:%d-%d", bands[i], bands[i+1]-1)
+	}
+
+	return ""
+}
@@ -0,0 +1,159 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// Peer protocol headers exchanged between tiered LOD instances
+const (
+	// HeaderLODVia lists the node IDs of every LOD instance a request passed through
+	HeaderLODVia = "X-LOD-Via"
+	// HeaderLODCache reports the cache status of a LOD instance to its peers
+	HeaderLODCache = "X-LOD-Cache"
+	// HeaderLODTimestamp carries the unix time a peer request was signed at
+	HeaderLODTimestamp = "X-LOD-Timestamp"
+	// HeaderLODSignature carries the HMAC-SHA256 signature of a peer request
+	HeaderLODSignature = "X-LOD-Signature"
+	// HeaderLODLoop lists the loop markers of every LOD instance an upstream
+	// request passed through, sent to all upstreams
+	HeaderLODLoop = "X-LOD-Loop"
+)
+
+// loopMarker is a random token identifying this LOD instance process in the
+// loop header, without revealing anything about it to third-party upstreams
+var loopMarker = newLoopMarker()
+
+// newLoopMarker generates a random loop marker
+func newLoopMarker() string {
+	marker := make([]byte, 8)
+	_, _ = rand.Read(marker)
+	return hex.EncodeToString(marker)
+}
+
+// maximum clock skew between signing edges and verifying origins, bounding
+// how long a captured signed request can be replayed
+const peerSignatureMaxSkew = 5 * time.Minute
+
+// Cache statuses reported to peers in the HeaderLODCache header
+const (
+	PeerCacheHit  = "HIT"
+	PeerCacheMiss = "MISS"
+)
+
+// PeerVia returns the chain of LOD node IDs the request has passed through
+func PeerVia(ctx *fiber.Ctx) string {
+	return ctx.Get(HeaderLODVia)
+}
+
+// IsPeerLoop returns true if the request has already passed through this
+// LOD instance, meaning the tiered deployment or proxy chain is misconfigured
+// into a loop, ex: a proxy's upstream leading back to this instance. Besides
+// the peer via chain and loop markers, hops this instance added to the
+// standard Via and Forwarded headers are honored.
+func IsPeerLoop(ctx *fiber.Ctx) bool {
+	if markers := ctx.Get(HeaderLODLoop); markers != "" {
+		for _, marker := range strings.Split(markers, ",") {
+			if strings.TrimSpace(marker) == loopMarker {
+				return true
+			}
+		}
+	}
+
+	nodeID := config.Get().Instance.NodeID
+
+	if via := PeerVia(ctx); via != "" {
+		for _, node := range strings.Split(via, ",") {
+			if strings.TrimSpace(node) == nodeID {
+				return true
+			}
+		}
+	}
+
+	if via := ctx.Get(fiber.HeaderVia); via != "" && viaHasNode(via, nodeID) {
+		return true
+	}
+
+	forwarded := ctx.Get(fiber.HeaderForwarded)
+	return forwarded != "" && forwardedHasNode(forwarded, nodeID)
+}
+
+// appendVia adds this LOD instance's node ID to the given via chain
+func appendVia(via string) string {
+	nodeID := config.Get().Instance.NodeID
+	if via == "" {
+		return nodeID
+	}
+	return via + ", " + nodeID
+}
+
+// SetPeerCacheStatus reports the cache status of the request to the peer LOD
+// instance that made it, doing nothing for requests made by regular clients
+func SetPeerCacheStatus(ctx *fiber.Ctx) {
+	if PeerVia(ctx) == "" {
+		return
+	}
+
+	status, _ := ctx.Locals(str.LocalCacheStatus).(string)
+	if strings.HasPrefix(status, ":hit") {
+		ctx.Set(HeaderLODCache, PeerCacheHit)
+	} else {
+		ctx.Set(HeaderLODCache, PeerCacheMiss)
+	}
+}
+
+// OriginCacheHit returns true if the origin LOD instance of an edge proxy
+// reported serving the response from its cache
+func OriginCacheHit(resp ProxyResponse) bool {
+	return resp.Resp != nil &&
+		string(resp.Resp.Header.Peek(HeaderLODCache)) == PeerCacheHit
+}
+
+// signPeerRequest signs a request to an origin LOD instance with the shared
+// peer secret of an edge proxy
+func signPeerRequest(req *fasthttp.Request, secret string, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(HeaderLODTimestamp, timestamp)
+	req.Header.Set(HeaderLODSignature, peerSignature(secret, string(req.Header.Method()),
+		string(req.URI().RequestURI()), timestamp, req.Body()))
+}
+
+// VerifyPeerSignature returns true if the request was signed by an edge LOD
+// instance holding the shared peer secret, within the allowed clock skew
+func VerifyPeerSignature(ctx *fiber.Ctx, secret string, now time.Time) bool {
+	timestamp := ctx.Get(HeaderLODTimestamp)
+	signed, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	skew := now.Sub(time.Unix(signed, 0))
+	if skew > peerSignatureMaxSkew || skew < -peerSignatureMaxSkew {
+		return false
+	}
+
+	expected := peerSignature(secret, ctx.Method(), ctx.OriginalURL(), timestamp, ctx.Body())
+	return hmac.Equal([]byte(ctx.Get(HeaderLODSignature)), []byte(expected))
+}
+
+// peerSignature computes the hex HMAC-SHA256 of a peer request's method, URI,
+// signing timestamp and body hash
+func peerSignature(secret, method, uri, timestamp string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + uri + "\n" + timestamp + "\n"))
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
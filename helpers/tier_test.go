@@ -0,0 +1,67 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestPeerSignature will test that origins accept requests signed by edges
+// holding the shared peer secret, and reject requests that are stale, signed
+// with another secret, tampered with after signing or unsigned
+func TestPeerSignature(t *testing.T) {
+	const secret = "peer-secret"
+	signed := time.Unix(1700000000, 0)
+	app := fiber.New()
+
+	tests := []struct {
+		name     string
+		secret   string                      // secret the request is signed with
+		now      time.Time                   // time the origin verifies the request at
+		tamper   func(req *fasthttp.Request) // changes made to the request after signing
+		expected bool
+	}{
+		{name: "valid", secret: secret, now: signed, expected: true},
+		{name: "within skew", secret: secret, now: signed.Add(peerSignatureMaxSkew), expected: true},
+		{name: "stale", secret: secret, now: signed.Add(peerSignatureMaxSkew + time.Second)},
+		{name: "future", secret: secret, now: signed.Add(-peerSignatureMaxSkew - time.Second)},
+		{name: "wrong secret", secret: "other-secret", now: signed},
+		{name: "tampered uri", secret: secret, now: signed, tamper: func(req *fasthttp.Request) {
+			req.SetRequestURI("/tiles/4/5/7.pbf?style=dark")
+		}},
+		{name: "tampered body", secret: secret, now: signed, tamper: func(req *fasthttp.Request) {
+			req.SetBody([]byte(`{"layer":"roads"}`))
+		}},
+		{name: "tampered timestamp", secret: secret, now: signed, tamper: func(req *fasthttp.Request) {
+			req.Header.Set(HeaderLODTimestamp, "1700000001")
+		}},
+		{name: "missing signature", secret: secret, now: signed, tamper: func(req *fasthttp.Request) {
+			req.Header.Del(HeaderLODSignature)
+		}},
+		{name: "missing timestamp", secret: secret, now: signed, tamper: func(req *fasthttp.Request) {
+			req.Header.Del(HeaderLODTimestamp)
+		}},
+	}
+
+	for _, test := range tests {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Request.Header.SetMethod(fiber.MethodPost)
+		fctx.Request.SetRequestURI("/tiles/4/5/6.pbf?style=dark")
+		fctx.Request.SetBody([]byte(`{"layer":"water"}`))
+
+		signPeerRequest(&fctx.Request, test.secret, signed)
+		if test.tamper != nil {
+			test.tamper(&fctx.Request)
+		}
+
+		ctx := app.AcquireCtx(fctx)
+		if got := VerifyPeerSignature(ctx, secret, test.now); got != test.expected {
+			t.Errorf(str.TPeerBadSignature, test.name, got, test.expected)
+		}
+		app.ReleaseCtx(ctx)
+	}
+}
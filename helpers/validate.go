@@ -0,0 +1,92 @@
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/mvt"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// gzipMagic is the two byte header present at the start of all gzip streams
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ValidateTile strictly decodes vector tile data received from the upstream
+// according to the proxy's validation mode, returning the tile data to cache
+// and serve. Invalid tiles are either rejected or repaired and re-encoded,
+// logging repairs to the given request logger.
+func ValidateTile(c *cache.Cache, proxy config.Proxy, log *util.Logger, cacheKey string, data []byte) ([]byte, error) {
+	if proxy.MVTValidation == "" || len(data) == 0 {
+		return data, nil
+	}
+
+	// vector tiles are usually served gzipped by the upstream
+	raw, compressed, err := decompress(data)
+	if err != nil {
+		return nil, rejectTile(c, cacheKey, err)
+	}
+
+	tile, err := mvt.Decode(raw)
+	if err != nil {
+		// malformed protobuf data is beyond repair
+		return nil, rejectTile(c, cacheKey, err)
+	}
+
+	errValidate := tile.Validate()
+	if errValidate == nil {
+		return data, nil
+	}
+
+	if proxy.MVTValidation == config.MVTValidationReject {
+		return nil, rejectTile(c, cacheKey, errValidate)
+	}
+
+	repairs := tile.Repair()
+	c.Metrics.InvalidTiles.WithLabelValues("repaired").Inc()
+	log.DebugFlag("mvt", str.CProxy, str.DTileRepaired, cacheKey, repairs, errValidate.Error())
+
+	repaired := tile.Encode()
+	if !compressed {
+		return repaired, nil
+	}
+
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	if _, err = writer.Write(repaired); err != nil {
+		return nil, rejectTile(c, cacheKey, err)
+	}
+	if err = writer.Close(); err != nil {
+		return nil, rejectTile(c, cacheKey, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompress returns the uncompressed contents of possibly gzipped tile data
+// and whether the data was gzipped
+func decompress(data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, false, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, true, err
+	}
+
+	raw, err := io.ReadAll(reader)
+	return raw, true, err
+}
+
+// rejectTile counts a rejected invalid tile and wraps the reason for rejection
+func rejectTile(c *cache.Cache, cacheKey string, err error) error {
+	c.Metrics.InvalidTiles.WithLabelValues("rejected").Inc()
+	return ErrInvalidTile{
+		CacheKey: cacheKey,
+		Err:      err,
+	}
+}
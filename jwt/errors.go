@@ -0,0 +1,73 @@
+package jwt
+
+import "fmt"
+
+// ErrMalformed is an error struct for a token that
+// cannot be decoded as a compact JWS
+type ErrMalformed struct {
+	Reason string
+}
+
+// Error returns the string representation of ErrMalformed
+func (e ErrMalformed) Error() string {
+	return fmt.Sprintf("jwt: malformed token: %s", e.Reason)
+}
+
+// ErrUnsupportedAlg is an error struct for a token signed
+// with an algorithm that isn't accepted
+type ErrUnsupportedAlg struct {
+	Alg string
+}
+
+// Error returns the string representation of ErrUnsupportedAlg
+func (e ErrUnsupportedAlg) Error() string {
+	return fmt.Sprintf("jwt: unsupported signing algorithm '%s'", e.Alg)
+}
+
+// ErrSignature is an error struct for a token whose
+// signature doesn't verify against its key
+type ErrSignature struct{}
+
+// Error returns the string representation of ErrSignature
+func (e ErrSignature) Error() string {
+	return "jwt: invalid signature"
+}
+
+// ErrUnknownKey is an error struct for a token signed
+// with a key the key set doesn't contain
+type ErrUnknownKey struct {
+	KeyID string
+}
+
+// Error returns the string representation of ErrUnknownKey
+func (e ErrUnknownKey) Error() string {
+	return fmt.Sprintf("jwt: unknown signing key '%s'", e.KeyID)
+}
+
+// ErrClaim is an error struct for a token
+// with an invalid registered claim
+type ErrClaim struct {
+	Claim  string
+	Reason string
+}
+
+// Error returns the string representation of ErrClaim
+func (e ErrClaim) Error() string {
+	return fmt.Sprintf("jwt: invalid claim '%s': %s", e.Claim, e.Reason)
+}
+
+// ErrJWKSFetch is an error struct for a key set
+// that couldn't be fetched or decoded
+type ErrJWKSFetch struct {
+	URL    string
+	Status int
+	Err    error
+}
+
+// Error returns the string representation of ErrJWKSFetch
+func (e ErrJWKSFetch) Error() string {
+	if e.Status != 0 {
+		return fmt.Sprintf("jwt: failed to fetch key set from '%s', got status %d", e.URL, e.Status)
+	}
+	return fmt.Sprintf("jwt: failed to fetch key set from '%s', got error %s", e.URL, e.Err.Error())
+}
@@ -0,0 +1,216 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// minimum time between key set fetch attempts, whether triggered by unknown
+// key IDs or retrying failed fetches, so neither tokens with made up key IDs
+// nor an identity provider outage cause a fetch per request
+const refetchInterval = 30 * time.Second
+
+// JWKS is a key source backed by a JSON Web Key Set URL. Keys are cached and
+// refetched in the background every refresh interval, or sooner when a token
+// names an unknown key ID after the provider rotated its keys. If a refetch
+// fails, previously fetched keys keep being used. Concurrent fetches are
+// shared, and requests never wait on a fetch while keys are cached for them.
+type JWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	fetches singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetched   time.Time
+	attempted time.Time
+	err       error // error of the last fetch, nil if it succeeded
+}
+
+// NewJWKS returns a key source for the key set at the given URL
+func NewJWKS(url string, refresh time.Duration) *JWKS {
+	return &JWKS{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the public key with the given key ID. Tokens without a key ID
+// are accepted if the key set holds a single key.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	now := time.Now()
+
+	j.mu.Lock()
+	key := j.lookup(kid)
+	cached := j.keys != nil
+	stale := now.Sub(j.fetched) > j.refresh
+	due := now.Sub(j.attempted) > refetchInterval
+	if due && (!cached || key == nil || stale) {
+		// claim the attempt so concurrent requests don't start their own
+		j.attempted = now
+	}
+	errPrev := j.err
+	j.mu.Unlock()
+
+	switch {
+	case !cached:
+		// nothing to serve until the first fetch succeeds, failing fast
+		// between attempts while the provider is unavailable
+		if errPrev != nil && !due {
+			return nil, errPrev
+		}
+		if err := j.refetch(); err != nil {
+			return nil, err
+		}
+		key = j.cachedKey(kid)
+	case key == nil && due:
+		// the provider may have rotated its keys
+		_ = j.refetch()
+		key = j.cachedKey(kid)
+	case stale && due:
+		go func() { _ = j.refetch() }()
+	}
+
+	if key == nil {
+		return nil, ErrUnknownKey{KeyID: kid}
+	}
+	return key, nil
+}
+
+// cachedKey returns a cached key by ID
+func (j *JWKS) cachedKey(kid string) crypto.PublicKey {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.lookup(kid)
+}
+
+// refetch fetches the key set, sharing a fetch already in flight
+func (j *JWKS) refetch() error {
+	_, err, _ := j.fetches.Do(j.url, func() (interface{}, error) {
+		return nil, j.fetch()
+	})
+	return err
+}
+
+// lookup a cached key by ID
+func (j *JWKS) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key
+		}
+	}
+	return j.keys[kid]
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch the key set, replacing the cached keys on success
+func (j *JWKS) fetch() error {
+	keys, err := j.download()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.err = err
+	if err == nil {
+		j.keys = keys
+		j.fetched = time.Now()
+	}
+	return err
+}
+
+// download the key set and parse its keys
+func (j *JWKS) download() (map[string]crypto.PublicKey, error) {
+	resp, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, ErrJWKSFetch{URL: j.url, Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrJWKSFetch{URL: j.url, Status: resp.StatusCode}
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, ErrJWKSFetch{URL: j.url, Err: err}
+	}
+
+	return parseKeys(set.Keys), nil
+}
+
+// parseKeys converts JSON Web Keys to public keys by key ID, skipping
+// encryption keys and unsupported key types
+func parseKeys(keys []jsonWebKey) map[string]crypto.PublicKey {
+	parsed := make(map[string]crypto.PublicKey, len(keys))
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if publicKey := key.publicKey(); publicKey != nil {
+			parsed[key.Kid] = publicKey
+		}
+	}
+	return parsed
+}
+
+// publicKey decodes an RSA or EC JSON Web Key, nil if invalid
+func (k jsonWebKey) publicKey() crypto.PublicKey {
+	switch k.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) > 4 {
+			return nil
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil
+		}
+		return key
+	}
+	return nil
+}
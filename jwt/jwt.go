@@ -0,0 +1,199 @@
+// Package jwt verifies JSON Web Tokens signed with asymmetric keys, as issued
+// by identity providers publishing their keys as a JSON Web Key Set
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// KeySource looks up public keys by their key ID
+type KeySource interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// Verifier verifies tokens against a key source and the expected issuer and
+// audience, skipping checks whose expected value is empty. Tokens must carry
+// an exp claim unless AllowNoExpiry is set.
+type Verifier struct {
+	Keys          KeySource     // source of the signing keys
+	Issuer        string        // expected iss claim
+	Audience      string        // expected entry of the aud claim
+	Leeway        time.Duration // allowed clock skew for exp and nbf
+	AllowNoExpiry bool          // accept tokens without an exp claim
+}
+
+// Claims of a verified token
+type Claims map[string]interface{}
+
+// Subject returns the sub claim, empty if unset
+func (c Claims) Subject() string {
+	sub, _ := c["sub"].(string)
+	return sub
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// algorithm describes how a signing algorithm verifies signatures
+type algorithm struct {
+	hash  crypto.Hash
+	kind  string         // "rsa", "pss" or "ecdsa"
+	curve elliptic.Curve // curve of the key required by ECDSA algorithms
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {crypto.SHA256, "rsa", nil},
+	"RS384": {crypto.SHA384, "rsa", nil},
+	"RS512": {crypto.SHA512, "rsa", nil},
+	"PS256": {crypto.SHA256, "pss", nil},
+	"PS384": {crypto.SHA384, "pss", nil},
+	"PS512": {crypto.SHA512, "pss", nil},
+	"ES256": {crypto.SHA256, "ecdsa", elliptic.P256()},
+	"ES384": {crypto.SHA384, "ecdsa", elliptic.P384()},
+	"ES512": {crypto.SHA512, "ecdsa", elliptic.P521()},
+}
+
+// Verify the signature and registered claims of a compact serialized token at
+// the given time, returning its claims
+func (v Verifier) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed{Reason: "expected three dot-separated parts"}
+	}
+
+	var head header
+	if err := decodeSegment(parts[0], &head); err != nil {
+		return nil, err
+	}
+
+	alg, ok := algorithms[head.Alg]
+	if !ok {
+		return nil, ErrUnsupportedAlg{Alg: head.Alg}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed{Reason: "signature is not base64url"}
+	}
+
+	key, err := v.Keys.Key(head.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !verifySignature(alg, key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrSignature{}
+	}
+
+	var claims Claims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	return claims, v.verifyClaims(claims, now)
+}
+
+// verifyClaims checks the time bounds, issuer and audience of a token
+func (v Verifier) verifyClaims(claims Claims, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok && !v.AllowNoExpiry {
+		return ErrClaim{Claim: "exp", Reason: "token has no expiry"}
+	}
+	if ok && now.After(unix(exp).Add(v.Leeway)) {
+		return ErrClaim{Claim: "exp", Reason: "token expired"}
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.Leeway).Before(unix(nbf)) {
+		return ErrClaim{Claim: "nbf", Reason: "token not yet valid"}
+	}
+
+	if v.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.Issuer {
+			return ErrClaim{Claim: "iss", Reason: "unexpected issuer"}
+		}
+	}
+
+	if v.Audience != "" && !hasAudience(claims["aud"], v.Audience) {
+		return ErrClaim{Claim: "aud", Reason: "unexpected audience"}
+	}
+
+	return nil
+}
+
+// hasAudience returns true if the aud claim, a string or list of strings,
+// contains the given audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, entry := range aud {
+			if entry == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifySignature verifies the signature over the signing input with the key
+// type required by the algorithm
+func verifySignature(alg algorithm, key crypto.PublicKey, input, signature []byte) bool {
+	hasher := alg.hash.New()
+	hasher.Write(input)
+	digest := hasher.Sum(nil)
+
+	switch alg.kind {
+	case "rsa", "pss":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		if alg.kind == "pss" {
+			return rsa.VerifyPSS(rsaKey, alg.hash, digest, signature, nil) == nil
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, alg.hash, digest, signature) == nil
+	case "ecdsa":
+		// each ECDSA algorithm is only defined for keys on its own curve
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve.Params().Name != alg.curve.Params().Name {
+			return false
+		}
+		// JWS encodes ECDSA signatures as fixed-size r || s
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(ecKey, digest, r, s)
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed{Reason: "segment is not base64url"}
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return ErrMalformed{Reason: "segment is not a JSON object"}
+	}
+	return nil
+}
+
+// unix converts a NumericDate claim to a time
+func unix(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
@@ -0,0 +1,286 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// staticKeys is a key source holding fixed keys
+type staticKeys map[string]crypto.PublicKey
+
+func (s staticKeys) Key(kid string) (crypto.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey{KeyID: kid}
+}
+
+// sign builds a token signed with the given key
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+
+	head, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	hash := algorithms[alg].hash
+	hasher := hash.New()
+	hasher.Write([]byte(input))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	var err error
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if alg == "PS256" {
+			signature, err = rsa.SignPSS(rand.Reader, key, hash, digest, nil)
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest)
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// TestVerify will test that tokens signed with supported algorithms verify,
+// and that tokens with invalid signatures or claims are rejected
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	now := time.Unix(1700000000, 0)
+	verifier := Verifier{
+		Keys:     staticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey, "ec384": &ec384Key.PublicKey},
+		Issuer:   "https://id.example.com",
+		Audience: "lod",
+		Leeway:   time.Minute,
+	}
+
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "user",
+			"iss": "https://id.example.com",
+			"aud": []string{"other", "lod"},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	valid := []struct {
+		alg, kid string
+		key      crypto.Signer
+	}{
+		{"RS256", "rsa", rsaKey},
+		{"PS256", "rsa", rsaKey},
+		{"ES256", "ec", ecKey},
+		{"ES384", "ec384", ec384Key},
+	}
+	for _, v := range valid {
+		verified, err := verifier.Verify(sign(t, v.alg, v.kid, v.key, claims(nil)), now)
+		if err != nil {
+			t.Fatalf(str.TJWTBadVerify, v.alg, err.Error())
+		}
+		if verified.Subject() != "user" {
+			t.Errorf(str.TJWTBadSubject, verified.Subject(), "user")
+		}
+	}
+
+	// expiry within the leeway is still accepted
+	if _, err := verifier.Verify(sign(t, "RS256", "rsa", rsaKey,
+		claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})), now); err != nil {
+		t.Errorf(str.TJWTBadVerify, "RS256", err.Error())
+	}
+
+	// tokens without expiry are only accepted if allowed
+	noExpiry := sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": nil}))
+	lenient := verifier
+	lenient.AllowNoExpiry = true
+	if _, err := lenient.Verify(noExpiry, now); err != nil {
+		t.Errorf(str.TJWTBadVerify, "RS256", err.Error())
+	}
+
+	invalid := map[string]string{
+		"wrong key":     sign(t, "RS256", "rsa", otherKey, claims(nil)),
+		"unknown key":   sign(t, "RS256", "missing", rsaKey, claims(nil)),
+		"key type":      sign(t, "ES256", "rsa", ecKey, claims(nil)),
+		"key curve":     sign(t, "ES384", "ec", ecKey, claims(nil)),
+		"alg curve":     sign(t, "ES256", "ec384", ec384Key, claims(nil)),
+		"no expiry":     noExpiry,
+		"expired":       sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})),
+		"not yet valid": sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})),
+		"issuer":        sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"audience":      sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})),
+		"malformed":     "not.a-token",
+		"unsigned":      base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.",
+		"symmetric":     base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256"}`)) + ".e30.c2ln",
+	}
+	for name, token := range invalid {
+		if _, err := verifier.Verify(token, now); err == nil {
+			t.Errorf(str.TJWTNoError, name)
+		}
+	}
+}
+
+// testJWK returns the JSON Web Key of an RSA signing key
+func testJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// TestJWKS will test that key sets are fetched, cached and refetched for
+// unknown key IDs after the provider rotates its keys
+func TestJWKS(t *testing.T) {
+	first, _ := rsa.GenerateKey(rand.Reader, 2048)
+	second, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var fetches atomic.Int32
+	var rotated atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{testJWK("first", first)}
+		if rotated.Load() {
+			keys = []map[string]string{testJWK("second", second)}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer server.Close()
+
+	keys := NewJWKS(server.URL, time.Hour)
+	verifier := Verifier{Keys: keys}
+	claims := map[string]interface{}{"sub": "user", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(sign(t, "RS256", "first", first, claims), time.Now()); err != nil {
+			t.Fatalf(str.TJWTBadVerify, "RS256", err.Error())
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf(str.TJWTBadFetches, fetches.Load(), 1)
+	}
+
+	// unknown key IDs refetch the key set once rate limiting allows it
+	rotated.Store(true)
+	keys.attempted = time.Now().Add(-2 * refetchInterval)
+	if _, err := verifier.Verify(sign(t, "RS256", "second", second, claims), time.Now()); err != nil {
+		t.Fatalf(str.TJWTBadVerify, "RS256", err.Error())
+	}
+	if _, err := verifier.Verify(sign(t, "RS256", "third", second, claims), time.Now()); err == nil {
+		t.Errorf(str.TJWTNoError, "unknown key")
+	}
+	if fetches.Load() != 2 {
+		t.Errorf(str.TJWTBadFetches, fetches.Load(), 2)
+	}
+}
+
+// TestJWKSOutage will test that an unavailable identity provider is neither
+// waited on while keys are cached nor retried on every request
+func TestJWKSOutage(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			started <- struct{}{}
+			<-release
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{testJWK("first", key)},
+		})
+	}))
+	defer server.Close()
+
+	keys := NewJWKS(server.URL, time.Minute)
+	if _, err := keys.Key("first"); err != nil {
+		t.Fatalf(str.TJWTBadVerify, "RS256", err.Error())
+	}
+
+	// stale keys are served while the refresh hangs in the background
+	failing.Store(true)
+	keys.mu.Lock()
+	keys.fetched = time.Now().Add(-time.Hour)
+	keys.attempted = time.Now().Add(-time.Hour)
+	keys.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := keys.Key("first"); err != nil {
+				t.Errorf(str.TJWTBadVerify, "RS256", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	// fetches made while the refresh is in flight share it
+	<-started
+	close(release)
+	if err := keys.refetch(); err == nil {
+		t.Errorf(str.TJWTNoError, "failed refresh")
+	}
+	if fetches.Load() != 2 {
+		t.Errorf(str.TJWTBadFetches, fetches.Load(), 2)
+	}
+
+	// failed refreshes are retried only after backing off
+	for i := 0; i < 10; i++ {
+		if _, err := keys.Key("first"); err != nil {
+			t.Errorf(str.TJWTBadVerify, "RS256", err.Error())
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Errorf(str.TJWTBadFetches, fetches.Load(), 2)
+	}
+
+	// without cached keys, requests fail fast between attempts
+	unavailable := NewJWKS(server.URL, time.Minute)
+	for i := 0; i < 10; i++ {
+		if _, err := unavailable.Key("first"); err == nil {
+			t.Errorf(str.TJWTNoError, "unavailable key set")
+		}
+	}
+	if fetches.Load() != 3 {
+		t.Errorf(str.TJWTBadFetches, fetches.Load(), 3)
+	}
+}
@@ -0,0 +1,145 @@
+// Package lodtest runs LOD in-process for integration tests, backed by a
+// scriptable fake tile upstream and an embedded Redis server.
+//
+// Configs reference the fake upstream and Redis through environment
+// variables, which LOD expands when loading its config:
+//
+//	[[proxies]]
+//	name = "osm"
+//	tile_url = "${LODTEST_UPSTREAM}/{z}/{x}/{y}.pbf"
+//	[proxies.cache]
+//	redis_enabled = true
+//	redis_url = "${LODTEST_REDIS}"
+//
+// LOD keeps its state in package globals, so only one instance may run at a
+// time within a test binary and tests using it must not run in parallel.
+package lodtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/accesslog"
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/corsdebug"
+	"github.com/dechristopher/lod/dnscache"
+	"github.com/dechristopher/lod/fleet"
+	"github.com/dechristopher/lod/geoip"
+	"github.com/dechristopher/lod/heatmap"
+	"github.com/dechristopher/lod/probe"
+	"github.com/dechristopher/lod/slo"
+	"github.com/dechristopher/lod/statsd"
+	"github.com/dechristopher/lod/upstream"
+	"github.com/dechristopher/lod/util"
+	"github.com/dechristopher/lod/webhook"
+	"github.com/dechristopher/lod/www"
+)
+
+// Environment variables set to the fake upstream and embedded Redis while an
+// instance runs
+const (
+	EnvUpstream = "LODTEST_UPSTREAM" // base URL of the fake upstream
+	EnvRedis    = "LODTEST_REDIS"    // redis:// URL of the embedded Redis
+)
+
+// Instance is an in-process LOD instance serving a test's config
+type Instance struct {
+	App      *fiber.App           // the LOD app, served without a listener
+	Caches   *cache.Manager       // the cache instances of the config's proxies
+	Upstream *Upstream            // the fake tile server
+	Redis    *miniredis.Miniredis // the embedded Redis server
+}
+
+// Start loads the given TOML config and starts an in-process LOD instance,
+// stopped when the test finishes. Synthetic probes are not served, since the
+// instance has no listener.
+func Start(t testing.TB, configData string) *Instance {
+	t.Helper()
+
+	fake := NewUpstream(t)
+	redis := miniredis.RunT(t)
+
+	t.Setenv(EnvUpstream, fake.URL)
+	t.Setenv(EnvRedis, "redis://"+redis.Addr())
+
+	if util.BootTime.IsZero() {
+		util.BootTime = time.Now()
+	}
+
+	if err := config.LoadData([]byte(configData)); err != nil {
+		t.Fatalf("lodtest: failed to load config: %s", err.Error())
+	}
+
+	caches := cache.NewManager()
+	if err := initInstance(caches); err != nil {
+		t.Fatalf("lodtest: failed to start instance: %s", err.Error())
+	}
+
+	app := www.New(caches)
+	t.Cleanup(func() {
+		_ = app.Shutdown()
+		stop(caches)
+	})
+
+	return &Instance{
+		App:      app,
+		Caches:   caches,
+		Upstream: fake,
+		Redis:    redis,
+	}
+}
+
+// Get requests the given path from the instance, ex: "/osm/0/0/0.pbf"
+func (i *Instance) Get(path string) (*http.Response, error) {
+	return i.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// Do sends a request to the instance in-process
+func (i *Instance) Do(req *http.Request) (*http.Response, error) {
+	return i.App.Test(req, -1)
+}
+
+// initInstance initializes the caches, upstream pools and trackers of the
+// loaded config like the lod command does
+func initInstance(caches *cache.Manager) error {
+	if err := caches.Init(config.Get()); err != nil {
+		return err
+	}
+
+	dnscache.Init()
+	upstream.Init()
+	if err := upstream.CheckLoops(config.Get()); err != nil {
+		return err
+	}
+	if err := geoip.Init(); err != nil {
+		return err
+	}
+	slo.Init()
+	heatmap.Init()
+	corsdebug.Init()
+	webhook.Init()
+	if err := accesslog.Init(); err != nil {
+		return err
+	}
+	if err := statsd.Init(); err != nil {
+		return err
+	}
+	fleet.Init()
+	probe.Init()
+
+	return nil
+}
+
+// stop tears down the running instance by loading an empty config, which
+// drops its caches and stops its background routines
+func stop(caches *cache.Manager) {
+	if err := config.LoadData(nil); err == nil {
+		_ = initInstance(caches)
+	}
+}
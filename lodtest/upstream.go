@@ -0,0 +1,113 @@
+package lodtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Response is a scripted upstream response
+type Response struct {
+	Status  int               // status code, defaults to 200
+	Body    []byte            // response body
+	Headers map[string]string // response headers
+}
+
+// Upstream is a scriptable fake tile server. Paths without a scripted
+// response are answered with 404 Not Found.
+type Upstream struct {
+	URL string // base URL of the server, ex: URL + "/{z}/{x}/{y}.pbf" as tile_url
+
+	server *httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]Response
+	handler   http.HandlerFunc
+	hits      map[string]int
+}
+
+// NewUpstream starts a fake tile server, closed when the test finishes
+func NewUpstream(t testing.TB) *Upstream {
+	u := &Upstream{
+		responses: make(map[string]Response),
+		hits:      make(map[string]int),
+	}
+
+	u.server = httptest.NewServer(http.HandlerFunc(u.serve))
+	u.URL = u.server.URL
+	t.Cleanup(u.server.Close)
+
+	return u
+}
+
+// SetResponse scripts the response to requests for the given path,
+// ex: "/0/0/0.pbf"
+func (u *Upstream) SetResponse(path string, resp Response) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.responses[path] = resp
+}
+
+// SetTile scripts a successful response with the given data to requests for
+// the given path
+func (u *Upstream) SetTile(path string, data []byte) {
+	u.SetResponse(path, Response{Body: data})
+}
+
+// SetHandler replaces scripted responses with the given handler, or restores
+// them if nil. Requests are still counted.
+func (u *Upstream) SetHandler(handler http.HandlerFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.handler = handler
+}
+
+// Hits returns the number of requests received for the given path
+func (u *Upstream) Hits(path string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.hits[path]
+}
+
+// Requests returns the total number of requests received
+func (u *Upstream) Requests() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	total := 0
+	for _, hits := range u.hits {
+		total += hits
+	}
+	return total
+}
+
+// serve answers a request with its scripted response
+func (u *Upstream) serve(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	u.hits[r.URL.Path]++
+	handler := u.handler
+	resp, ok := u.responses[r.URL.Path]
+	u.mu.Unlock()
+
+	if handler != nil {
+		handler(w, r)
+		return
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	for key, val := range resp.Headers {
+		w.Header().Set(key, val)
+	}
+
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(resp.Body)
+}
@@ -0,0 +1,26 @@
+package metadata
+
+import "fmt"
+
+// ErrMalformed is an error struct for an upstream metadata
+// document that cannot be parsed in its format
+type ErrMalformed struct {
+	Format string
+	Err    error
+}
+
+// Error returns the string representation of ErrMalformed
+func (e ErrMalformed) Error() string {
+	return fmt.Sprintf("metadata: malformed %s document: %s", e.Format, e.Err.Error())
+}
+
+// ErrUnknownFormat is an error struct for a metadata
+// format LOD can't normalize
+type ErrUnknownFormat struct {
+	Format string
+}
+
+// Error returns the string representation of ErrUnknownFormat
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("metadata: unknown format '%s'", e.Format)
+}
@@ -0,0 +1,269 @@
+// Package metadata normalizes upstream dataset metadata, such as TileJSON,
+// MBTiles metadata tables and WMTS capabilities, into a single TileJSON
+// document describing a proxy
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// Metadata formats LOD normalizes
+const (
+	FormatTileJSON = "tilejson"
+	FormatMBTiles  = "mbtiles"
+	FormatWMTS     = "wmts"
+)
+
+// TileJSONVersion is the TileJSON specification version of normalized documents
+const TileJSONVersion = "3.0.0"
+
+// Document is the normalized metadata of a proxy's dataset, a TileJSON
+// document whose tiles point at LOD
+type Document struct {
+	TileJSON     string          `json:"tilejson"`                // TileJSON specification version
+	Name         string          `json:"name,omitempty"`          // display name of the dataset
+	Description  string          `json:"description,omitempty"`   // description of the dataset
+	Attribution  string          `json:"attribution,omitempty"`   // attribution to display with the tiles
+	Version      string          `json:"version,omitempty"`       // version of the dataset
+	Format       string          `json:"format,omitempty"`        // tile format, ex: pbf, png, jpg
+	Tiles        []string        `json:"tiles"`                   // tile URL templates
+	MinZoom      *int            `json:"minzoom,omitempty"`       // minimum zoom level with tiles
+	MaxZoom      *int            `json:"maxzoom,omitempty"`       // maximum zoom level with tiles
+	Bounds       []float64       `json:"bounds,omitempty"`        // west, south, east and north bounds in WGS84
+	Center       []float64       `json:"center,omitempty"`        // longitude, latitude and zoom of the default view
+	VectorLayers json.RawMessage `json:"vector_layers,omitempty"` // vector layers of vector tile datasets
+	Source       string          `json:"source"`                  // format of the upstream metadata document
+}
+
+// Normalize parses an upstream metadata document of the given format into
+// a Document, detecting the format from the document itself if empty
+func Normalize(body []byte, format string) (Document, error) {
+	if format == "" {
+		format = Detect(body)
+	}
+
+	var doc Document
+	var err error
+	switch format {
+	case FormatTileJSON:
+		doc, err = parseTileJSON(body)
+	case FormatMBTiles:
+		doc, err = parseMBTiles(body)
+	case FormatWMTS:
+		doc, err = parseWMTS(body)
+	default:
+		return Document{}, ErrUnknownFormat{Format: format}
+	}
+	if err != nil {
+		return Document{}, ErrMalformed{Format: format, Err: err}
+	}
+
+	doc.TileJSON = TileJSONVersion
+	doc.Source = format
+	if doc.Tiles == nil {
+		doc.Tiles = []string{}
+	}
+	return doc, nil
+}
+
+// Detect guesses the format of a metadata document. XML documents are WMTS
+// capabilities, JSON objects with tiles are TileJSON and any other JSON is
+// an MBTiles metadata table.
+func Detect(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if bytes.HasPrefix(body, []byte("<")) {
+		return FormatWMTS
+	}
+
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(body, &probe) == nil {
+		if _, ok := probe["tilejson"]; ok {
+			return FormatTileJSON
+		}
+		if _, ok := probe["tiles"]; ok {
+			return FormatTileJSON
+		}
+	}
+	return FormatMBTiles
+}
+
+// parseTileJSON reads a TileJSON document, which is already normalized
+func parseTileJSON(body []byte) (Document, error) {
+	var doc Document
+	err := json.Unmarshal(body, &doc)
+	return doc, err
+}
+
+// parseMBTiles reads an MBTiles metadata table, either as an object of names
+// to values or as an array of name and value rows
+func parseMBTiles(body []byte) (Document, error) {
+	table := map[string]string{}
+
+	var rows []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &rows); err == nil {
+		for _, row := range rows {
+			table[row.Name] = row.Value
+		}
+	} else {
+		var object map[string]interface{}
+		if err = json.Unmarshal(body, &object); err != nil {
+			return Document{}, err
+		}
+		for name, value := range object {
+			if s, ok := value.(string); ok {
+				table[name] = s
+			} else {
+				encoded, _ := json.Marshal(value)
+				table[name] = string(encoded)
+			}
+		}
+	}
+
+	doc := Document{
+		Name:        table["name"],
+		Description: table["description"],
+		Attribution: table["attribution"],
+		Version:     table["version"],
+		Format:      table["format"],
+		MinZoom:     parseZoom(table["minzoom"]),
+		MaxZoom:     parseZoom(table["maxzoom"]),
+		Bounds:      parseFloats(table["bounds"], ",", 4),
+		Center:      parseFloats(table["center"], ",", 3),
+	}
+
+	// vector tilesets keep their layers in a JSON encoded json row
+	if encoded := table["json"]; encoded != "" {
+		var layers struct {
+			VectorLayers json.RawMessage `json:"vector_layers"`
+		}
+		if json.Unmarshal([]byte(encoded), &layers) == nil {
+			doc.VectorLayers = layers.VectorLayers
+		}
+	}
+	return doc, nil
+}
+
+// capabilities is the subset of a WMTS capabilities document LOD reads
+type capabilities struct {
+	Title  string `xml:"ServiceIdentification>Title"`
+	Layers []struct {
+		Identifier  string   `xml:"Identifier"`
+		Title       string   `xml:"Title"`
+		Abstract    string   `xml:"Abstract"`
+		LowerCorner string   `xml:"WGS84BoundingBox>LowerCorner"`
+		UpperCorner string   `xml:"WGS84BoundingBox>UpperCorner"`
+		Formats     []string `xml:"Format"`
+		MatrixSets  []string `xml:"TileMatrixSetLink>TileMatrixSet"`
+	} `xml:"Contents>Layer"`
+	MatrixSets []struct {
+		Identifier string   `xml:"Identifier"`
+		Matrices   []string `xml:"TileMatrix>Identifier"`
+	} `xml:"Contents>TileMatrixSet"`
+}
+
+// parseWMTS reads the first layer of a WMTS capabilities document
+func parseWMTS(body []byte) (Document, error) {
+	var caps capabilities
+	if err := xml.Unmarshal(body, &caps); err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{Name: caps.Title}
+	if len(caps.Layers) == 0 {
+		return doc, nil
+	}
+
+	layer := caps.Layers[0]
+	if layer.Title != "" {
+		doc.Name = layer.Title
+	} else if layer.Identifier != "" {
+		doc.Name = layer.Identifier
+	}
+	doc.Description = layer.Abstract
+
+	lower := parseFloats(layer.LowerCorner, " ", 2)
+	upper := parseFloats(layer.UpperCorner, " ", 2)
+	if lower != nil && upper != nil {
+		doc.Bounds = []float64{lower[0], lower[1], upper[0], upper[1]}
+	}
+
+	if len(layer.Formats) > 0 {
+		doc.Format = formatOf(layer.Formats[0])
+	}
+
+	// zoom levels are the tile matrices of the layer's first matrix set
+	for _, set := range caps.MatrixSets {
+		if len(layer.MatrixSets) == 0 || set.Identifier != layer.MatrixSets[0] || len(set.Matrices) == 0 {
+			continue
+		}
+		minZoom, maxZoom := 0, len(set.Matrices)-1
+		if first, err := strconv.Atoi(lastPart(set.Matrices[0])); err == nil {
+			if last, err := strconv.Atoi(lastPart(set.Matrices[len(set.Matrices)-1])); err == nil {
+				minZoom, maxZoom = first, last
+			}
+		}
+		doc.MinZoom, doc.MaxZoom = &minZoom, &maxZoom
+		break
+	}
+	return doc, nil
+}
+
+// lastPart returns the part of a tile matrix identifier after its last colon,
+// as in EPSG:3857:12
+func lastPart(identifier string) string {
+	return identifier[strings.LastIndex(identifier, ":")+1:]
+}
+
+// formatOf returns the tile format of a MIME type
+func formatOf(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return "png"
+	case "image/jpeg":
+		return "jpg"
+	case "image/webp":
+		return "webp"
+	case "application/vnd.mapbox-vector-tile", "application/x-protobuf":
+		return "pbf"
+	}
+	return strings.TrimPrefix(mimeType, "image/")
+}
+
+// parseZoom parses a zoom level, nil if missing or invalid
+func parseZoom(value string) *int {
+	zoom, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil
+	}
+	return &zoom
+}
+
+// parseFloats parses exactly n separated numbers, nil if missing or invalid
+func parseFloats(value, sep string, n int) []float64 {
+	var parts []string
+	if sep == " " {
+		parts = strings.Fields(value)
+	} else {
+		parts = strings.Split(value, sep)
+	}
+	if len(parts) != n {
+		return nil
+	}
+
+	floats := make([]float64, n)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil
+		}
+		floats[i] = f
+	}
+	return floats
+}
@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+const tileJSON = `{
+	"tilejson": "2.2.0",
+	"name": "basemap",
+	"format": "pbf",
+	"tiles": ["https://tiles.example.com/{z}/{x}/{y}.pbf"],
+	"minzoom": 0,
+	"maxzoom": 14,
+	"bounds": [-180, -85.0511, 180, 85.0511],
+	"vector_layers": [{"id": "water"}]
+}`
+
+const mbtilesRows = `[
+	{"name": "name", "value": "basemap"},
+	{"name": "format", "value": "pbf"},
+	{"name": "minzoom", "value": "0"},
+	{"name": "maxzoom", "value": "14"},
+	{"name": "bounds", "value": "-180,-85.0511,180,85.0511"},
+	{"name": "json", "value": "{\"vector_layers\":[{\"id\":\"water\"}]}"}
+]`
+
+const mbtilesObject = `{
+	"name": "basemap",
+	"format": "pbf",
+	"minzoom": "0",
+	"maxzoom": "14",
+	"bounds": "-180,-85.0511,180,85.0511",
+	"json": "{\"vector_layers\":[{\"id\":\"water\"}]}"
+}`
+
+const wmts = `<?xml version="1.0" encoding="UTF-8"?>
+<Capabilities xmlns="http://www.opengis.net/wmts/1.0" xmlns:ows="http://www.opengis.net/ows/1.1">
+	<ows:ServiceIdentification><ows:Title>Tile Service</ows:Title></ows:ServiceIdentification>
+	<Contents>
+		<Layer>
+			<ows:Title>basemap</ows:Title>
+			<ows:WGS84BoundingBox>
+				<ows:LowerCorner>-180 -85.0511</ows:LowerCorner>
+				<ows:UpperCorner>180 85.0511</ows:UpperCorner>
+			</ows:WGS84BoundingBox>
+			<ows:Identifier>basemap</ows:Identifier>
+			<Format>application/vnd.mapbox-vector-tile</Format>
+			<TileMatrixSetLink><TileMatrixSet>GoogleMapsCompatible</TileMatrixSet></TileMatrixSetLink>
+		</Layer>
+		<TileMatrixSet>
+			<ows:Identifier>GoogleMapsCompatible</ows:Identifier>
+			<TileMatrix><ows:Identifier>0</ows:Identifier></TileMatrix>
+			<TileMatrix><ows:Identifier>1</ows:Identifier></TileMatrix>
+			<TileMatrix><ows:Identifier>14</ows:Identifier></TileMatrix>
+		</TileMatrixSet>
+	</Contents>
+</Capabilities>`
+
+// TestDetect will test that metadata formats are detected from documents
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		tileJSON:      FormatTileJSON,
+		mbtilesRows:   FormatMBTiles,
+		mbtilesObject: FormatMBTiles,
+		wmts:          FormatWMTS,
+	}
+
+	for body, expected := range tests {
+		if got := Detect([]byte(body)); got != expected {
+			t.Errorf(str.TMetadataBadFormat, got, expected)
+		}
+	}
+}
+
+// TestNormalize will test that every format normalizes to the same document
+func TestNormalize(t *testing.T) {
+	for _, body := range []string{tileJSON, mbtilesRows, mbtilesObject, wmts} {
+		doc, err := Normalize([]byte(body), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if doc.TileJSON != TileJSONVersion || doc.Name != "basemap" || doc.Format != "pbf" ||
+			doc.MinZoom == nil || *doc.MinZoom != 0 || doc.MaxZoom == nil || *doc.MaxZoom != 14 ||
+			!reflect.DeepEqual(doc.Bounds, []float64{-180, -85.0511, 180, 85.0511}) {
+			t.Errorf(str.TMetadataBadDocument, doc.Source, doc)
+		}
+
+		if doc.Source != FormatWMTS && string(doc.VectorLayers) != `[{"id":"water"}]` &&
+			string(doc.VectorLayers) != `[{"id": "water"}]` {
+			t.Errorf(str.TMetadataBadDocument, doc.Source, doc)
+		}
+	}
+}
+
+// TestNormalizeMalformed will test that unparseable documents are rejected
+func TestNormalizeMalformed(t *testing.T) {
+	if _, err := Normalize([]byte("{"), FormatTileJSON); err == nil {
+		t.Errorf(str.TMetadataBadDocument, FormatTileJSON, "{")
+	}
+	if _, err := Normalize([]byte("{}"), "wms"); err == nil {
+		t.Errorf(str.TMetadataBadDocument, "wms", "{}")
+	}
+}
@@ -0,0 +1,79 @@
+package mvt
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encode the tile back into raw, uncompressed vector tile data
+func (t *Tile) Encode() []byte {
+	var data []byte
+
+	for _, layer := range t.Layers {
+		data = protowire.AppendTag(data, 3, protowire.BytesType)
+		data = protowire.AppendBytes(data, layer.encode())
+	}
+
+	return data
+}
+
+// encode a single layer message
+func (l *Layer) encode() []byte {
+	var data []byte
+
+	data = protowire.AppendTag(data, 1, protowire.BytesType)
+	data = protowire.AppendString(data, l.Name)
+
+	for _, feature := range l.Features {
+		data = protowire.AppendTag(data, 2, protowire.BytesType)
+		data = protowire.AppendBytes(data, feature.encode())
+	}
+
+	for _, key := range l.Keys {
+		data = protowire.AppendTag(data, 3, protowire.BytesType)
+		data = protowire.AppendString(data, key)
+	}
+
+	for _, value := range l.Values {
+		data = protowire.AppendTag(data, 4, protowire.BytesType)
+		data = protowire.AppendBytes(data, value)
+	}
+
+	data = protowire.AppendTag(data, 5, protowire.VarintType)
+	data = protowire.AppendVarint(data, l.Extent)
+
+	data = protowire.AppendTag(data, 15, protowire.VarintType)
+	data = protowire.AppendVarint(data, l.Version)
+
+	return data
+}
+
+// encode a single feature message
+func (f *Feature) encode() []byte {
+	var data []byte
+
+	if f.HasID {
+		data = protowire.AppendTag(data, 1, protowire.VarintType)
+		data = protowire.AppendVarint(data, f.ID)
+	}
+
+	if len(f.Tags) > 0 {
+		var tags []byte
+		for _, tag := range f.Tags {
+			tags = protowire.AppendVarint(tags, tag)
+		}
+		data = protowire.AppendTag(data, 2, protowire.BytesType)
+		data = protowire.AppendBytes(data, tags)
+	}
+
+	data = protowire.AppendTag(data, 3, protowire.VarintType)
+	data = protowire.AppendVarint(data, uint64(f.Type))
+
+	var geometry []byte
+	for _, g := range f.Geometry {
+		geometry = protowire.AppendVarint(geometry, uint64(g))
+	}
+	data = protowire.AppendTag(data, 4, protowire.BytesType)
+	data = protowire.AppendBytes(data, geometry)
+
+	return data
+}
@@ -0,0 +1,61 @@
+package mvt
+
+import "fmt"
+
+// ErrMalformed is an error struct for vector tile data
+// that cannot be decoded as protobuf
+type ErrMalformed struct {
+	Reason string
+}
+
+// Error returns the string representation of ErrMalformed
+func (e ErrMalformed) Error() string {
+	return fmt.Sprintf("mvt: malformed tile data: %s", e.Reason)
+}
+
+// ErrInvalidLayer is an error struct for a layer
+// that violates the vector tile specification
+type ErrInvalidLayer struct {
+	Name   string
+	Reason string
+}
+
+// Error returns the string representation of ErrInvalidLayer
+func (e ErrInvalidLayer) Error() string {
+	return fmt.Sprintf("mvt: invalid layer '%s': %s", e.Name, e.Reason)
+}
+
+// ErrDuplicateLayer is an error struct for a tile
+// containing more than one layer with the same name
+type ErrDuplicateLayer struct {
+	Name string
+}
+
+// Error returns the string representation of ErrDuplicateLayer
+func (e ErrDuplicateLayer) Error() string {
+	return fmt.Sprintf("mvt: duplicate layer name '%s'", e.Name)
+}
+
+// ErrInvalidFeature is an error struct for a feature
+// that violates the vector tile specification
+type ErrInvalidFeature struct {
+	Layer  string
+	Number int
+	Reason string
+}
+
+// Error returns the string representation of ErrInvalidFeature
+func (e ErrInvalidFeature) Error() string {
+	return fmt.Sprintf("mvt: invalid feature #%d in layer '%s': %s", e.Number, e.Layer, e.Reason)
+}
+
+// ErrInvalidGeometry is an error struct for feature
+// tags or geometry that are not well-formed
+type ErrInvalidGeometry struct {
+	Reason string
+}
+
+// Error returns the string representation of ErrInvalidGeometry
+func (e ErrInvalidGeometry) Error() string {
+	return e.Reason
+}
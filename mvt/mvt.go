@@ -0,0 +1,242 @@
+package mvt
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// GeomType is the geometry type of a vector tile feature
+type GeomType uint64
+
+// Geometry types defined by the Mapbox Vector Tile specification
+const (
+	Unknown    GeomType = 0
+	Point      GeomType = 1
+	LineString GeomType = 2
+	Polygon    GeomType = 3
+)
+
+// Geometry commands defined by the Mapbox Vector Tile specification
+const (
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+)
+
+// defaultExtent is the layer extent assumed when a layer does not declare one
+const defaultExtent = 4096
+
+// Tile is a decoded Mapbox Vector Tile. Layer values are kept in their raw
+// encoded form since they only need to be validated and carried through.
+type Tile struct {
+	Layers []Layer
+}
+
+// Layer is a single named layer within a vector tile
+type Layer struct {
+	Version  uint64    // vector tile spec version, 1 if not declared
+	Name     string    // unique layer name
+	Features []Feature // features within the layer
+	Keys     []string  // feature tag keys
+	Values   [][]byte  // raw encoded feature tag values
+	Extent   uint64    // tile extent in layer coordinates, 4096 if not declared
+}
+
+// Feature is a single feature within a vector tile layer
+type Feature struct {
+	ID       uint64   // optional feature identifier
+	HasID    bool     // whether the feature declares an identifier
+	Tags     []uint64 // alternating key and value indexes into the layer tables
+	Type     GeomType // geometry type
+	Geometry []uint32 // encoded geometry command stream
+}
+
+// Decode strictly decodes raw, uncompressed vector tile data. Malformed
+// protobuf data results in an error, semantic problems are left to Validate.
+func Decode(data []byte) (*Tile, error) {
+	tile := &Tile{}
+
+	err := walk(data, func(num protowire.Number, typ protowire.Type, raw []byte) error {
+		if num != 3 {
+			return nil
+		}
+		if typ != protowire.BytesType {
+			return ErrMalformed{Reason: "layer is not a message"}
+		}
+
+		layer, err := decodeLayer(raw)
+		if err != nil {
+			return err
+		}
+
+		tile.Layers = append(tile.Layers, *layer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tile, nil
+}
+
+// decodeLayer decodes a single encoded layer message
+func decodeLayer(data []byte) (*Layer, error) {
+	layer := &Layer{
+		Version: 1,
+		Extent:  defaultExtent,
+	}
+
+	err := walk(data, func(num protowire.Number, typ protowire.Type, raw []byte) error {
+		switch num {
+		case 1:
+			if typ != protowire.BytesType {
+				return ErrMalformed{Reason: "layer name is not a string"}
+			}
+			layer.Name = string(raw)
+		case 2:
+			if typ != protowire.BytesType {
+				return ErrMalformed{Reason: "feature is not a message"}
+			}
+			feature, err := decodeFeature(raw)
+			if err != nil {
+				return err
+			}
+			layer.Features = append(layer.Features, *feature)
+		case 3:
+			if typ != protowire.BytesType {
+				return ErrMalformed{Reason: "layer key is not a string"}
+			}
+			layer.Keys = append(layer.Keys, string(raw))
+		case 4:
+			if typ != protowire.BytesType {
+				return ErrMalformed{Reason: "layer value is not a message"}
+			}
+			if err := validateValue(raw); err != nil {
+				return err
+			}
+			layer.Values = append(layer.Values, append([]byte{}, raw...))
+		case 5, 15:
+			if typ != protowire.VarintType {
+				return ErrMalformed{Reason: "layer extent or version is not a varint"}
+			}
+			v, _ := protowire.ConsumeVarint(raw)
+			if num == 5 {
+				layer.Extent = v
+			} else {
+				layer.Version = v
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return layer, nil
+}
+
+// decodeFeature decodes a single encoded feature message
+func decodeFeature(data []byte) (*Feature, error) {
+	feature := &Feature{}
+
+	err := walk(data, func(num protowire.Number, typ protowire.Type, raw []byte) error {
+		switch num {
+		case 1:
+			if typ != protowire.VarintType {
+				return ErrMalformed{Reason: "feature id is not a varint"}
+			}
+			feature.ID, _ = protowire.ConsumeVarint(raw)
+			feature.HasID = true
+		case 2, 4:
+			values, err := decodePacked(typ, raw)
+			if err != nil {
+				return err
+			}
+			if num == 2 {
+				feature.Tags = append(feature.Tags, values...)
+			} else {
+				for _, v := range values {
+					feature.Geometry = append(feature.Geometry, uint32(v))
+				}
+			}
+		case 3:
+			if typ != protowire.VarintType {
+				return ErrMalformed{Reason: "feature type is not a varint"}
+			}
+			v, _ := protowire.ConsumeVarint(raw)
+			feature.Type = GeomType(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return feature, nil
+}
+
+// decodePacked decodes a packed or unpacked repeated varint field
+func decodePacked(typ protowire.Type, raw []byte) ([]uint64, error) {
+	if typ == protowire.VarintType {
+		v, _ := protowire.ConsumeVarint(raw)
+		return []uint64{v}, nil
+	}
+
+	if typ != protowire.BytesType {
+		return nil, ErrMalformed{Reason: "repeated field is not packed varints"}
+	}
+
+	var values []uint64
+	for len(raw) > 0 {
+		v, n := protowire.ConsumeVarint(raw)
+		if n < 0 {
+			return nil, ErrMalformed{Reason: "truncated packed varint"}
+		}
+		values = append(values, v)
+		raw = raw[n:]
+	}
+
+	return values, nil
+}
+
+// validateValue ensures an encoded layer value message is well-formed
+func validateValue(data []byte) error {
+	return walk(data, func(num protowire.Number, typ protowire.Type, raw []byte) error {
+		return nil
+	})
+}
+
+// walk iterates over every field of an encoded protobuf message, handing the
+// field number, wire type, and field payload to the given visitor. Varint
+// payloads are passed in their encoded form.
+func walk(data []byte, visit func(num protowire.Number, typ protowire.Type, raw []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return ErrMalformed{Reason: "invalid field tag"}
+		}
+		data = data[n:]
+
+		var raw []byte
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return ErrMalformed{Reason: "truncated length-delimited field"}
+			}
+			raw, n = v, m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return ErrMalformed{Reason: "truncated field"}
+			}
+			raw = data[:n]
+		}
+		data = data[n:]
+
+		if err := visit(num, typ, raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package mvt
+
+import (
+	_ "embed"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+//go:embed data/test.pbf
+var testTile []byte
+
+// TestDecode will test that a valid tile decodes and validates properly
+func TestDecode(t *testing.T) {
+	tile, err := Decode(testTile)
+	if err != nil {
+		t.Fatalf(str.TMVTBadDecode, err.Error())
+	}
+
+	if len(tile.Layers) == 0 {
+		t.Fatalf(str.TMVTNoLayers)
+	}
+
+	if err = tile.Validate(); err != nil {
+		t.Errorf(str.TMVTBadValidation, err.Error())
+	}
+}
+
+// TestEncode will test that a decoded tile encodes back to equivalent tile data
+func TestEncode(t *testing.T) {
+	tile, err := Decode(testTile)
+	if err != nil {
+		t.Fatalf(str.TMVTBadDecode, err.Error())
+	}
+
+	reDecoded, err := Decode(tile.Encode())
+	if err != nil {
+		t.Fatalf(str.TMVTBadDecode, err.Error())
+	}
+
+	if !reflect.DeepEqual(tile, reDecoded) {
+		t.Errorf(str.TMVTBadEncode)
+	}
+}
+
+// TestDecodeMalformed will test that truncated tile data fails to decode
+func TestDecodeMalformed(t *testing.T) {
+	if _, err := Decode(testTile[:len(testTile)/2]); err == nil {
+		t.Errorf(str.TMVTNoError)
+	}
+}
+
+// TestRepairDuplicateLayers will test that duplicate layers are merged with
+// their tags remapped onto the first layer's tables
+func TestRepairDuplicateLayers(t *testing.T) {
+	point := []uint32{9, 50, 34}
+	tile := &Tile{Layers: []Layer{
+		{Version: 2, Name: "pois", Extent: 4096, Keys: []string{"a"}, Values: [][]byte{{0x0a, 0x01, 'x'}},
+			Features: []Feature{{Type: Point, Tags: []uint64{0, 0}, Geometry: point}}},
+		{Version: 2, Name: "pois", Extent: 4096, Keys: []string{"b", "a"}, Values: [][]byte{{0x0a, 0x01, 'y'}},
+			Features: []Feature{{Type: Point, Tags: []uint64{1, 0}, Geometry: point}}},
+	}}
+
+	var duplicate ErrDuplicateLayer
+	if !errors.As(tile.Validate(), &duplicate) {
+		t.Fatalf(str.TMVTNoError)
+	}
+
+	if repairs := tile.Repair(); repairs != 1 {
+		t.Errorf(str.TMVTRepairs, repairs, 1)
+	}
+
+	if len(tile.Layers) != 1 || len(tile.Layers[0].Features) != 2 {
+		t.Fatalf(str.TMVTBadRepair)
+	}
+
+	if !reflect.DeepEqual(tile.Layers[0].Features[1].Tags, []uint64{0, 1}) {
+		t.Errorf(str.TMVTBadRepair)
+	}
+
+	if err := tile.Validate(); err != nil {
+		t.Errorf(str.TMVTBadValidation, err.Error())
+	}
+}
+
+// TestRepairInvalidGeometry will test that features with invalid geometry are dropped
+func TestRepairInvalidGeometry(t *testing.T) {
+	tile := &Tile{Layers: []Layer{
+		{Version: 2, Name: "roads", Extent: 4096, Features: []Feature{
+			// line string with a single point
+			{Type: LineString, Geometry: []uint32{9, 50, 34}},
+			{Type: LineString, Geometry: []uint32{9, 50, 34, 10, 2, 2}},
+		}},
+	}}
+
+	var invalid ErrInvalidFeature
+	if !errors.As(tile.Validate(), &invalid) {
+		t.Fatalf(str.TMVTNoError)
+	}
+
+	if repairs := tile.Repair(); repairs != 1 {
+		t.Errorf(str.TMVTRepairs, repairs, 1)
+	}
+
+	if err := tile.Validate(); err != nil {
+		t.Errorf(str.TMVTBadValidation, err.Error())
+	}
+}
+
+// TestValidateUnknownGeometry will test that features of unknown geometry
+// type pass validation and survive repairs, as allowed by the specification
+func TestValidateUnknownGeometry(t *testing.T) {
+	tile := &Tile{Layers: []Layer{
+		{Version: 2, Name: "extras", Extent: 4096, Features: []Feature{
+			{Type: Unknown, Geometry: []uint32{50, 34}},
+			{Type: Unknown},
+			{Type: Point, Geometry: []uint32{9, 50, 34}},
+		}},
+	}}
+
+	if err := tile.Validate(); err != nil {
+		t.Errorf(str.TMVTBadValidation, err.Error())
+	}
+
+	if repairs := tile.Repair(); repairs != 0 {
+		t.Errorf(str.TMVTRepairs, repairs, 0)
+	}
+}
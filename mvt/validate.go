@@ -0,0 +1,212 @@
+package mvt
+
+// Validate checks the tile against the Mapbox Vector Tile specification,
+// returning the first problem found, if any
+func (t *Tile) Validate() error {
+	names := make(map[string]bool, len(t.Layers))
+
+	for _, layer := range t.Layers {
+		if err := layer.validate(); err != nil {
+			return err
+		}
+
+		if names[layer.Name] {
+			return ErrDuplicateLayer{Name: layer.Name}
+		}
+		names[layer.Name] = true
+
+		for i, feature := range layer.Features {
+			if err := feature.validate(&layer); err != nil {
+				return ErrInvalidFeature{
+					Layer:  layer.Name,
+					Number: i + 1,
+					Reason: err.Error(),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Repair fixes common problems in the tile in place by dropping invalid
+// layers and features and merging layers with duplicate names. Returns the
+// number of repairs made.
+func (t *Tile) Repair() int {
+	repairs := 0
+	layers := make([]Layer, 0, len(t.Layers))
+	index := make(map[string]int, len(t.Layers))
+
+	for _, layer := range t.Layers {
+		if layer.validate() != nil {
+			repairs++
+			continue
+		}
+
+		// drop features that fail validation
+		features := make([]Feature, 0, len(layer.Features))
+		for _, feature := range layer.Features {
+			if feature.validate(&layer) != nil {
+				repairs++
+				continue
+			}
+			features = append(features, feature)
+		}
+		layer.Features = features
+
+		first, duplicate := index[layer.Name]
+		if !duplicate {
+			index[layer.Name] = len(layers)
+			layers = append(layers, layer)
+			continue
+		}
+
+		// merge duplicate layers sharing a coordinate space, otherwise keep the first
+		repairs++
+		if layers[first].Extent == layer.Extent && layers[first].Version == layer.Version {
+			layers[first].merge(&layer)
+		}
+	}
+
+	t.Layers = layers
+	return repairs
+}
+
+// validate checks layer level fields against the specification
+func (l *Layer) validate() error {
+	if l.Name == "" {
+		return ErrInvalidLayer{Reason: "layer has no name"}
+	}
+
+	if l.Version != 1 && l.Version != 2 {
+		return ErrInvalidLayer{Name: l.Name, Reason: "unsupported version"}
+	}
+
+	if l.Extent == 0 {
+		return ErrInvalidLayer{Name: l.Name, Reason: "zero extent"}
+	}
+
+	return nil
+}
+
+// merge appends the features of another layer, remapping their tags onto
+// this layer's key and value tables
+func (l *Layer) merge(other *Layer) {
+	keys := make(map[string]uint64, len(l.Keys))
+	for i, key := range l.Keys {
+		keys[key] = uint64(i)
+	}
+
+	values := make(map[string]uint64, len(l.Values))
+	for i, value := range l.Values {
+		values[string(value)] = uint64(i)
+	}
+
+	for _, feature := range other.Features {
+		tags := make([]uint64, 0, len(feature.Tags))
+		for i := 0; i < len(feature.Tags); i += 2 {
+			key := other.Keys[feature.Tags[i]]
+			keyIndex, ok := keys[key]
+			if !ok {
+				keyIndex = uint64(len(l.Keys))
+				keys[key] = keyIndex
+				l.Keys = append(l.Keys, key)
+			}
+
+			value := other.Values[feature.Tags[i+1]]
+			valueIndex, ok := values[string(value)]
+			if !ok {
+				valueIndex = uint64(len(l.Values))
+				values[string(value)] = valueIndex
+				l.Values = append(l.Values, value)
+			}
+
+			tags = append(tags, keyIndex, valueIndex)
+		}
+
+		feature.Tags = tags
+		l.Features = append(l.Features, feature)
+	}
+}
+
+// validate checks a feature's tags and geometry against its layer
+func (f *Feature) validate(layer *Layer) error {
+	if len(f.Tags)%2 != 0 {
+		return ErrInvalidGeometry{Reason: "odd number of tags"}
+	}
+
+	for i := 0; i < len(f.Tags); i += 2 {
+		if f.Tags[i] >= uint64(len(layer.Keys)) || f.Tags[i+1] >= uint64(len(layer.Values)) {
+			return ErrInvalidGeometry{Reason: "tag index out of range"}
+		}
+	}
+
+	return validateGeometry(f.Type, f.Geometry)
+}
+
+// validateGeometry checks that a geometry command stream is well-formed for
+// the given geometry type. Features of unknown type are allowed by the
+// specification with geometry left to the consumer, so aren't checked.
+func validateGeometry(geomType GeomType, geometry []uint32) error {
+	if geomType == Unknown {
+		return nil
+	}
+
+	if len(geometry) == 0 {
+		return ErrInvalidGeometry{Reason: "empty geometry"}
+	}
+
+	// command integers of the stream in order, with their repeat counts
+	var commands, counts []uint32
+	for i := 0; i < len(geometry); {
+		id, count := geometry[i]&0x7, geometry[i]>>3
+		i++
+
+		switch id {
+		case cmdMoveTo, cmdLineTo:
+			if count == 0 || i+int(count)*2 > len(geometry) {
+				return ErrInvalidGeometry{Reason: "truncated command parameters"}
+			}
+			i += int(count) * 2
+		case cmdClosePath:
+			if count != 1 {
+				return ErrInvalidGeometry{Reason: "close path count must be 1"}
+			}
+		default:
+			return ErrInvalidGeometry{Reason: "unknown command"}
+		}
+
+		commands = append(commands, id)
+		counts = append(counts, count)
+	}
+
+	switch geomType {
+	case Point:
+		if len(commands) != 1 || commands[0] != cmdMoveTo {
+			return ErrInvalidGeometry{Reason: "point must be a single move to"}
+		}
+	case LineString:
+		if len(commands)%2 != 0 {
+			return ErrInvalidGeometry{Reason: "line string must be move to and line to pairs"}
+		}
+		for i := 0; i < len(commands); i += 2 {
+			if commands[i] != cmdMoveTo || counts[i] != 1 || commands[i+1] != cmdLineTo {
+				return ErrInvalidGeometry{Reason: "line string must be move to and line to pairs"}
+			}
+		}
+	case Polygon:
+		if len(commands)%3 != 0 {
+			return ErrInvalidGeometry{Reason: "polygon rings must be move to, line to, and close path"}
+		}
+		for i := 0; i < len(commands); i += 3 {
+			if commands[i] != cmdMoveTo || counts[i] != 1 || commands[i+1] != cmdLineTo ||
+				counts[i+1] < 2 || commands[i+2] != cmdClosePath {
+				return ErrInvalidGeometry{Reason: "polygon rings must be move to, line to, and close path"}
+			}
+		}
+	default:
+		return ErrInvalidGeometry{Reason: "unknown geometry type"}
+	}
+
+	return nil
+}
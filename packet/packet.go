@@ -3,6 +3,8 @@ package packet
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -17,6 +19,20 @@ import (
 // |----------------------------------------------------------------------|
 type TilePacket []byte
 
+// HeaderCreated is a reserved TilePacket header holding the unix time the
+// tile was fetched from the upstream. It is never sent to clients.
+const HeaderCreated = "X-LOD-Created"
+
+// HeaderTTL is a reserved TilePacket header holding the lifetime in seconds
+// the upstream set for the tile, counted from its creation time. It is never
+// sent to clients.
+const HeaderTTL = "X-LOD-TTL"
+
+// HeaderAdaptiveTTL is a reserved TilePacket header holding the lifetime in
+// seconds LOD adapted to how often the tile changes, counted from its creation
+// time. It is never sent to clients.
+const HeaderAdaptiveTTL = "X-LOD-Adaptive-TTL"
+
 // FromBytes wraps tile data from the cache and validates the
 // contents, returning a TilePacket for additional processing
 func FromBytes(data []byte, cacheKey string) (*TilePacket, error) {
@@ -106,3 +122,63 @@ func (t TilePacket) Headers() map[string]string {
 func (t TilePacket) LenHeaders() int {
 	return int(t[36:37][0])
 }
+
+// Created returns the time the tile was fetched from the upstream, and false
+// if the packet predates creation times being recorded
+func (t TilePacket) Created() (time.Time, bool) {
+	val, ok := t.Headers()[HeaderCreated]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	unix, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0), true
+}
+
+// Expires returns the time the tile expires at, as set by the upstream, and
+// false if the tile carries no lifetime of its own
+func (t TilePacket) Expires() (time.Time, bool) {
+	return t.expiresBy(HeaderTTL)
+}
+
+// AdaptiveTTL returns the lifetime adapted to how often the tile changes, and
+// false if the tile wasn't given one
+func (t TilePacket) AdaptiveTTL() (time.Duration, bool) {
+	ttl, err := strconv.ParseInt(t.Headers()[HeaderAdaptiveTTL], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ttl) * time.Second, true
+}
+
+// AdaptiveExpires returns the time the tile expires at by its adaptive
+// lifetime, and false if the tile wasn't given one
+func (t TilePacket) AdaptiveExpires() (time.Time, bool) {
+	return t.expiresBy(HeaderAdaptiveTTL)
+}
+
+// expiresBy returns the creation time of the tile plus the lifetime in
+// seconds held by the given header, and false if either is missing
+func (t TilePacket) expiresBy(header string) (time.Time, bool) {
+	headers := t.Headers()
+	val, ok := headers[header]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	ttl, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	created, err := strconv.ParseInt(headers[HeaderCreated], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(created+ttl, 0), true
+}
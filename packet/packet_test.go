@@ -3,7 +3,9 @@ package packet
 import (
 	_ "embed"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/dechristopher/lod/str"
 )
@@ -30,6 +32,58 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+// TestCreated will test that a recorded creation time is read back
+func TestCreated(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	tile := Encode(testTile, map[string]string{
+		HeaderCreated: strconv.FormatInt(created.Unix(), 10),
+	})
+
+	if got, ok := tile.Created(); !ok || !got.Equal(created) {
+		t.Errorf(str.TCacheBadCreated, got, created)
+	}
+
+	// packets cached before creation times were recorded have no age
+	if got, ok := Encode(testTile, testHeaders).Created(); ok {
+		t.Errorf(str.TCacheBadCreated, got, time.Time{})
+	}
+}
+
+// TestExpires will test that lifetimes set by the upstream or adapted by LOD
+// are counted from the recorded creation time
+func TestExpires(t *testing.T) {
+	created := time.Unix(1700000000, 0)
+	tile := Encode(testTile, map[string]string{
+		HeaderCreated: strconv.FormatInt(created.Unix(), 10),
+		HeaderTTL:     "300",
+	})
+
+	expected := created.Add(5 * time.Minute)
+	if got, ok := tile.Expires(); !ok || !got.Equal(expected) {
+		t.Errorf(str.TCacheBadExpires, got, expected)
+	}
+
+	// tiles without a lifetime of their own never expire by it
+	if got, ok := Encode(testTile, map[string]string{
+		HeaderCreated: strconv.FormatInt(created.Unix(), 10),
+	}).Expires(); ok {
+		t.Errorf(str.TCacheBadExpires, got, time.Time{})
+	}
+
+	// adaptive lifetimes are counted the same way, apart from upstream ones
+	adaptive := Encode(testTile, map[string]string{
+		HeaderCreated:     strconv.FormatInt(created.Unix(), 10),
+		HeaderAdaptiveTTL: "600",
+	})
+	expected = created.Add(10 * time.Minute)
+	if got, ok := adaptive.AdaptiveExpires(); !ok || !got.Equal(expected) {
+		t.Errorf(str.TCacheBadExpires, got, expected)
+	}
+	if got, ok := adaptive.Expires(); ok {
+		t.Errorf(str.TCacheBadExpires, got, time.Time{})
+	}
+}
+
 // BenchmarkDecode will benchmark a standard tile and metadata decode
 func BenchmarkDecode(b *testing.B) {
 	// encode test tile
@@ -0,0 +1,37 @@
+package probe
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "probe"
+
+// metrics for all probes, labeled by proxy and path so they survive probers
+// being rebuilt on config reloads
+var metrics = struct {
+	success  *prometheus.GaugeVec
+	duration *prometheus.GaugeVec
+	requests *prometheus.CounterVec
+}{
+	success: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "success",
+		Help:      "Whether the last synthetic probe of each path succeeded",
+	}, []string{"proxy", "path"}),
+	duration: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "duration_seconds",
+		Help:      "The latency of the last successful synthetic probe of each path",
+	}, []string{"proxy", "path"}),
+	requests: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "requests_total",
+		Help:      "The total number of synthetic probes made of each path by result",
+	}, []string{"proxy", "path", "result"}),
+}
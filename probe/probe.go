@@ -0,0 +1,131 @@
+package probe
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// HeaderProbe marks synthetic probe requests, so they can be told apart
+// from client traffic in logs
+const HeaderProbe = "X-LOD-Probe"
+
+// ProbersMap is an alias type for the map of proxy name to its prober
+type ProbersMap map[string]*Prober
+
+// Probers of proxies with synthetic probes configured
+var Probers = make(ProbersMap)
+
+// Prober periodically requests a proxy's representative tiles from the
+// instance's own listener, exercising the full request stack
+type Prober struct {
+	Proxy *config.Proxy // a reference to the proxy's configuration
+
+	stop chan struct{}
+}
+
+// Init starts probers for all proxies with probes configured, stopping any
+// probers left over from a previous configuration
+func Init() {
+	for name, prober := range Probers {
+		close(prober.stop)
+		delete(Probers, name)
+	}
+
+	proxies := config.Get().Proxies
+	for i := range proxies {
+		if len(proxies[i].Probes.Paths) == 0 {
+			continue
+		}
+
+		prober := &Prober{
+			Proxy: &proxies[i],
+			stop:  make(chan struct{}),
+		}
+		Probers[proxies[i].Name] = prober
+		go prober.run()
+	}
+}
+
+// run probes every path once per interval until stopped, starting one
+// interval after boot so the listener is up
+func (p *Prober) run() {
+	ticker := time.NewTicker(p.Proxy.Probes.IntervalDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			for _, path := range p.Proxy.Probes.Paths {
+				p.probe(path)
+			}
+		}
+	}
+}
+
+// probe requests a single path through the loopback listener, recording
+// whether it succeeded and how long it took
+func (p *Prober) probe(path string) {
+	probeUrl, err := p.url(path)
+	if err != nil {
+		p.record(path, 0, err)
+		return
+	}
+
+	agent := fiber.Get(probeUrl)
+	agent.Set(HeaderProbe, "1")
+	agent.Timeout(p.Proxy.Probes.TimeoutDuration)
+
+	start := time.Now()
+	code, _, errs := agent.Bytes()
+	latency := time.Since(start)
+
+	switch {
+	case len(errs) > 0:
+		err = errs[0]
+	case code != fiber.StatusOK && code != fiber.StatusNoContent:
+		err = fmt.Errorf("unexpected status %d", code)
+	}
+	p.record(path, latency, err)
+}
+
+// url builds the loopback URL of a probe path, authenticating it with the
+// proxy's access token if configured
+func (p *Prober) url(path string) (string, error) {
+	probeUrl, err := url.Parse("http://127.0.0.1:" + strconv.Itoa(config.GetPort()) +
+		"/" + p.Proxy.Name + path)
+	if err != nil {
+		return "", err
+	}
+
+	if p.Proxy.AccessToken != "" {
+		query := probeUrl.Query()
+		query.Set("token", p.Proxy.AccessToken)
+		probeUrl.RawQuery = query.Encode()
+	}
+
+	return probeUrl.String(), nil
+}
+
+// record exports the outcome of a probe
+func (p *Prober) record(path string, latency time.Duration, err error) {
+	if err != nil {
+		util.Error(str.CProxy, str.EProbe, p.Proxy.Name, path, err.Error())
+		metrics.success.WithLabelValues(p.Proxy.Name, path).Set(0)
+		metrics.requests.WithLabelValues(p.Proxy.Name, path, "failure").Inc()
+		return
+	}
+
+	metrics.success.WithLabelValues(p.Proxy.Name, path).Set(1)
+	metrics.requests.WithLabelValues(p.Proxy.Name, path, "success").Inc()
+	metrics.duration.WithLabelValues(p.Proxy.Name, path).Set(latency.Seconds())
+}
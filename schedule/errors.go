@@ -0,0 +1,25 @@
+package schedule
+
+import "fmt"
+
+// ErrInvalidExpr is an error struct for a cron expression that
+// could not be parsed
+type ErrInvalidExpr struct {
+	Expr   string
+	Reason string
+}
+
+// Error returns the string representation of ErrInvalidExpr
+func (e ErrInvalidExpr) Error() string {
+	return fmt.Sprintf("schedule: invalid expression '%s': %s", e.Expr, e.Reason)
+}
+
+// errBadStep returns the reason for an invalid step in a field item
+func errBadStep(item string) error {
+	return fmt.Errorf("invalid step in '%s'", item)
+}
+
+// errBadRange returns the reason for an out of bounds value or range
+func errBadRange(item string) error {
+	return fmt.Errorf("invalid value or range '%s'", item)
+}
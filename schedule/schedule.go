@@ -0,0 +1,191 @@
+// Package schedule matches times against cron-like expressions, used to open
+// proxies and API keys only during configured serving windows
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed five field cron expression, "minute hour day month weekday",
+// matching every minute it describes. Fields accept *, values, ranges (a-b),
+// steps (*/n, a-b/n) and comma separated lists of those. Weekdays run from 0
+// (Sunday) to 6, with 7 also meaning Sunday. As in cron, a minute matches
+// either day field if both are restricted.
+type Expr struct {
+	minute, hour, day, month, weekday uint64
+	anyDay, anyWeekday                bool
+}
+
+// field bounds, in the order of the expression
+var fields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day", 1, 31},
+	{"month", 1, 12},
+	{"weekday", 0, 7},
+}
+
+// Parse parses a five field cron expression
+func Parse(expr string) (*Expr, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, ErrInvalidExpr{Expr: expr, Reason: "expected 5 fields"}
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i].min, fields[i].max)
+		if err != nil {
+			return nil, ErrInvalidExpr{Expr: expr, Reason: fields[i].name + " " + err.Error()}
+		}
+		sets[i] = set
+	}
+
+	// fold Sunday as 7 into 0
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &Expr{
+		minute:     sets[0],
+		hour:       sets[1],
+		day:        sets[2],
+		month:      sets[3],
+		weekday:    sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// parseField parses one comma separated field into a bit set of its values
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, errBadStep(item)
+			}
+			rng, step = item[:i], n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			if i := strings.IndexByte(rng, '-'); i >= 0 {
+				lo, err = strconv.Atoi(rng[:i])
+				if err == nil {
+					hi, err = strconv.Atoi(rng[i+1:])
+				}
+			} else {
+				lo, err = strconv.Atoi(rng)
+				hi = lo
+			}
+			if err != nil || lo < min || hi > max || lo > hi {
+				return 0, errBadRange(item)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches returns true if the minute of the given time is described by the
+// expression, in the time's location
+func (e *Expr) Matches(t time.Time) bool {
+	if e.minute&(1<<uint(t.Minute())) == 0 || e.hour&(1<<uint(t.Hour())) == 0 ||
+		e.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	day := e.day&(1<<uint(t.Day())) != 0
+	weekday := e.weekday&(1<<uint(t.Weekday())) != 0
+	switch {
+	case e.anyDay && e.anyWeekday:
+		return true
+	case e.anyDay:
+		return weekday
+	case e.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Window is a set of serving windows bounded by an optional release and end
+// time. Requests are served while the current time lies within the bounds
+// and, if any expressions are given, matches one of them.
+type Window struct {
+	Exprs     []*Expr        // minutes during which the window is open, always if empty
+	NotBefore time.Time      // time the window first opens, unbounded if zero
+	NotAfter  time.Time      // time the window closes for good, unbounded if zero
+	Location  *time.Location // location expressions are evaluated in, UTC if nil
+}
+
+// maxScan bounds the minutes scanned for the next opening of a window
+const maxScan = 7 * 24 * 60
+
+// Open returns true if the window is open at the given time
+func (w Window) Open(t time.Time) bool {
+	if !w.NotBefore.IsZero() && t.Before(w.NotBefore) {
+		return false
+	}
+	if !w.NotAfter.IsZero() && !t.Before(w.NotAfter) {
+		return false
+	}
+	if len(w.Exprs) == 0 {
+		return true
+	}
+
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	for _, expr := range w.Exprs {
+		if expr.Matches(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns when the window next opens after the given time, and false if
+// it never opens again or doesn't within the next week
+func (w Window) Next(t time.Time) (time.Time, bool) {
+	if !w.NotAfter.IsZero() && !t.Before(w.NotAfter) {
+		return time.Time{}, false
+	}
+	if w.Open(t) {
+		return t, true
+	}
+
+	next := t
+	if !w.NotBefore.IsZero() && next.Before(w.NotBefore) {
+		next = w.NotBefore
+		if w.Open(next) {
+			return next, true
+		}
+	}
+
+	next = next.Truncate(time.Minute)
+	for i := 0; i < maxScan; i++ {
+		next = next.Add(time.Minute)
+		if !w.NotAfter.IsZero() && !next.Before(w.NotAfter) {
+			return time.Time{}, false
+		}
+		if w.Open(next) {
+			return next, true
+		}
+	}
+	return time.Time{}, false
+}
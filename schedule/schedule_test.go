@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestMatches will test that expressions match the minutes they describe,
+// including ranges, steps, lists and either day field when both are set
+func TestMatches(t *testing.T) {
+	// 2024-06-03 is a Monday
+	monday := time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		at       time.Time
+		expected bool
+	}{
+		{"* * * * *", monday, true},
+		{"30 9 * * *", monday, true},
+		{"31 9 * * *", monday, false},
+		{"* 8-17 * * 1-5", monday, true},
+		{"* 8-17 * * 1-5", monday.Add(-2 * time.Hour), false},
+		{"* 8-17 * * 6,0", monday, false},
+		{"*/15 * * * *", monday, true},
+		{"*/20 * * * *", monday, false},
+		{"* * * 6 *", monday, true},
+		{"* * 1 * 1", monday, true},
+		{"* * 1 * 2", monday, false},
+		{"* * * * 7", monday.AddDate(0, 0, 6), true},
+	}
+
+	for _, test := range tests {
+		expr, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := expr.Matches(test.at); got != test.expected {
+			t.Errorf(str.TScheduleBadMatch, test.expr, test.at, got, test.expected)
+		}
+	}
+}
+
+// TestParseInvalid will test that malformed expressions are rejected
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *",
+		"* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf(str.TScheduleNoError, expr)
+		}
+	}
+}
+
+// TestWindowNext will test that the next opening of a window respects its
+// release time, serving minutes and end time
+func TestWindowNext(t *testing.T) {
+	weekdays, _ := Parse("* 8-17 * * 1-5")
+	release := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+
+	w := Window{Exprs: []*Expr{weekdays}, NotBefore: release, NotAfter: end}
+
+	tests := []struct {
+		at       time.Time
+		expected time.Time
+	}{
+		// before release on a Saturday, opens Monday morning
+		{time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)},
+		// open now
+		{time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC), time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)},
+		// after hours, opens the next morning
+		{time.Date(2024, 6, 3, 18, 0, 30, 0, time.UTC), time.Date(2024, 6, 4, 8, 0, 0, 0, time.UTC)},
+		// after the end, never opens again
+		{time.Date(2024, 6, 8, 18, 0, 0, 0, time.UTC), time.Time{}},
+	}
+
+	for _, test := range tests {
+		got, _ := w.Next(test.at)
+		if !got.Equal(test.expected) {
+			t.Errorf(str.TScheduleBadNext, test.at, got, test.expected)
+		}
+	}
+}
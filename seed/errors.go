@@ -0,0 +1,40 @@
+package seed
+
+import (
+	"fmt"
+)
+
+// ErrInvalidRequest is an error struct for a seed request that can't be run
+type ErrInvalidRequest struct {
+	ProxyName string
+	Reason    string
+}
+
+// Error returns the string representation of ErrInvalidRequest
+func (e ErrInvalidRequest) Error() string {
+	return fmt.Sprintf("seed: proxy(%s) invalid request: %s", e.ProxyName, e.Reason)
+}
+
+// ErrJobRunning is an error struct for a seed request made while another seed
+// job of the proxy is still running
+type ErrJobRunning struct {
+	ProxyName string
+	ID        string
+}
+
+// Error returns the string representation of ErrJobRunning
+func (e ErrJobRunning) Error() string {
+	return fmt.Sprintf("seed: proxy(%s) already running seed job %s", e.ProxyName, e.ID)
+}
+
+// ErrTileSkipped is an error struct for a seed tile that couldn't be fetched
+// from the upstream
+type ErrTileSkipped struct {
+	ProxyName string
+	Reason    string
+}
+
+// Error returns the string representation of ErrTileSkipped
+func (e ErrTileSkipped) Error() string {
+	return fmt.Sprintf("seed: proxy(%s) skipped tile: %s", e.ProxyName, e.Reason)
+}
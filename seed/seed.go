@@ -0,0 +1,445 @@
+package seed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+const (
+	maxTiles       = 1 << 20 // most tiles a single seed job may cover
+	maxConcurrency = 256     // most tiles a single seed job may fetch at once
+)
+
+// Seed job states
+const (
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateCancelled = "cancelled"
+)
+
+// jobKind is the kind seed jobs are recorded under against a proxy's cache
+const jobKind = "seed"
+
+// Request describes the tiles a seed job fetches into a proxy's cache, every
+// tile covering the bounding box at every zoom level of the range
+type Request struct {
+	BBox        []float64         `json:"bbox"`        // west, south, east and north bounds in WGS84 degrees
+	MinZoom     int               `json:"min_zoom"`    // lowest zoom level seeded
+	MaxZoom     int               `json:"max_zoom"`    // highest zoom level seeded
+	Concurrency int               `json:"concurrency"` // tiles fetched at once, the proxy's background workers if unset
+	Params      map[string]string `json:"params"`      // query parameters tiles are requested with
+	Matrix      bool              `json:"matrix"`      // whether tiles are seeded with every combination of the proxy's param values
+	Force       bool              `json:"force"`       // whether tiles already cached are fetched again
+	Restart     bool              `json:"restart"`     // whether progress checkpointed by an interrupted run is dropped rather than resumed
+}
+
+// Job is a seed job running or run against a proxy's cache
+type Job struct {
+	ID      string
+	Proxy   string
+	Request Request
+	Total   int // tiles covered by the request
+	Started time.Time
+
+	resumed atomic.Int64 // tiles completed by interrupted runs of the job
+	seeded  atomic.Int64 // tiles fetched from the upstream and cached
+	cached  atomic.Int64 // tiles already cached and left as is
+	skipped atomic.Int64 // tiles outside of the proxy's coverage
+	failed  atomic.Int64 // tiles that couldn't be fetched or cached
+
+	mu        sync.Mutex
+	state     string
+	cancelled bool
+	finished  time.Time
+
+	checkpoint *cache.Checkpoint         // checkpoint the job resumes from, nil if not checkpointed
+	progress   *cache.CheckpointProgress // checkpointed progress of the job, nil if not checkpointed
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// work is a single tile of a seed job, along with its position among the
+// tiles of its zoom level for checkpointing
+type work struct {
+	entry tile.ListEntry
+	index int
+}
+
+// Progress is a snapshot of the progress of a seed job
+type Progress struct {
+	ID       string     `json:"id"`
+	Proxy    string     `json:"proxy"`
+	State    string     `json:"state"`
+	Request  Request    `json:"request"`
+	Total    int        `json:"total"`
+	Resumed  int64      `json:"resumed"`
+	Seeded   int64      `json:"seeded"`
+	Cached   int64      `json:"cached"`
+	Skipped  int64      `json:"skipped"`
+	Failed   int64      `json:"failed"`
+	Percent  float64    `json:"percent"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// Start validates the seed request and starts a job fetching its tiles into
+// the given cache in the background, requesting them as the given cache
+// generation if set. Only one seed job may run per proxy at a time, and jobs
+// are cancelled when the cache is closed. Progress is checkpointed to Redis,
+// so repeating the request of an interrupted job resumes it.
+func Start(app *fiber.App, c *cache.Cache, req Request, generation string) (*Job, error) {
+	total, err := validate(app, c, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:      jobID(c, req, generation),
+		Proxy:   c.Proxy.Name,
+		Request: req,
+		Total:   total,
+		Started: time.Now(),
+		state:   StateRunning,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	latest, err := c.AddJob(jobKind, job)
+	if err != nil || latest != cache.Job(job) {
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrJobRunning{ProxyName: c.Proxy.Name, ID: latest.(*Job).ID}
+	}
+
+	job.resume(c)
+	util.Info(str.CAdmin, str.MSeedStart, job.Proxy, job.ID, total, req.MinZoom, req.MaxZoom, req.BBox)
+	go job.run(ctx, app, c, generation)
+
+	return job, nil
+}
+
+// jobID identifies a seed job by the proxy, the tiles of its request and the
+// generation seeded, so repeating the request resumes it
+func jobID(c *cache.Cache, req Request, generation string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%v|%d|%d|%s|%t|%t|%s", c.Proxy.Name, req.BBox,
+		req.MinZoom, req.MaxZoom, entry(tile.Tile{}, req.Params).Query, req.Matrix, req.Force, generation)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// resume loads the checkpoint of an interrupted run of the job to skip the
+// tiles it completed, unless asked to restart the job
+func (j *Job) resume(c *cache.Cache) {
+	description, _ := json.Marshal(j.Request)
+	checkpoint, err := c.ResumeCheckpoint(context.Background(), j.ID,
+		"seed "+string(description), j.Total, j.Request.Restart)
+	if err != nil {
+		util.Error(str.CAdmin, str.ECacheCheckpoint, j.Proxy, j.ID, err.Error())
+	}
+
+	j.checkpoint = checkpoint
+	j.progress = cache.NewCheckpointProgress(c, checkpoint)
+	if checkpoint != nil && checkpoint.Completed() > 0 {
+		j.resumed.Store(int64(checkpoint.Completed()))
+		util.Info(str.CAdmin, str.MPrimeResume, j.Proxy, j.ID, checkpoint.Completed(), j.Total)
+	}
+}
+
+// Get returns the latest seed job run against the cache, running or not, nil
+// if none was started
+func Get(c *cache.Cache) *Job {
+	job, _ := c.Job(jobKind).(*Job)
+	return job
+}
+
+// Cancel stops the running seed job of the cache, returning the job and
+// whether it was running. Tiles already in flight are still cached.
+func Cancel(c *cache.Cache) (*Job, bool) {
+	job := Get(c)
+	if job == nil {
+		return nil, false
+	}
+	return job, job.Cancel()
+}
+
+// State returns the current state of the job
+func (j *Job) State() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+// Running returns whether the job is still running
+func (j *Job) Running() bool {
+	return j.State() == StateRunning
+}
+
+// Cancel stops the job if running, returning whether it was
+func (j *Job) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != StateRunning {
+		return false
+	}
+
+	j.cancelled = true
+	j.cancel()
+	return true
+}
+
+// Wait blocks until the job has stopped running
+func (j *Job) Wait() {
+	<-j.done
+}
+
+// Progress returns a snapshot of the job's progress
+func (j *Job) Progress() Progress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	p := Progress{
+		ID:      j.ID,
+		Proxy:   j.Proxy,
+		State:   j.state,
+		Request: j.Request,
+		Total:   j.Total,
+		Resumed: j.resumed.Load(),
+		Seeded:  j.seeded.Load(),
+		Cached:  j.cached.Load(),
+		Skipped: j.skipped.Load(),
+		Failed:  j.failed.Load(),
+		Started: j.Started,
+	}
+	if j.Total > 0 {
+		p.Percent = float64(p.Resumed+p.Seeded+p.Cached+p.Skipped+p.Failed) / float64(j.Total) * 100
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		p.Finished = &finished
+	}
+	return p
+}
+
+// validate checks the request against the proxy, defaulting its concurrency,
+// and returns the number of tiles it covers
+func validate(app *fiber.App, c *cache.Cache, req *Request) (int, error) {
+	invalid := func(reason string) (int, error) {
+		return 0, ErrInvalidRequest{ProxyName: c.Proxy.Name, Reason: reason}
+	}
+
+	if len(req.BBox) != 4 {
+		return invalid("bbox must be [west, south, east, north]")
+	}
+	b := bounds(req.BBox)
+	if b.West < -180 || b.West > 180 || b.East < -180 || b.East > 180 ||
+		b.South < -90 || b.North > 90 || b.South >= b.North {
+		return invalid("bbox out of range")
+	}
+
+	if req.MinZoom < 0 || req.MaxZoom > tile.MaxZoom || req.MinZoom > req.MaxZoom {
+		return invalid("zoom range must lie within 0-" + strconv.Itoa(tile.MaxZoom) + " with min_zoom <= max_zoom")
+	}
+
+	if req.Concurrency == 0 {
+		req.Concurrency = c.Proxy.Background.Workers
+	}
+	if req.Concurrency < 1 || req.Concurrency > maxConcurrency {
+		return invalid("concurrency must lie within 1-" + strconv.Itoa(maxConcurrency))
+	}
+
+	if req.Matrix && c.Proxy.ParamMatrix() == nil {
+		return invalid("no param values configured for proxy")
+	}
+
+	// fail early on params the proxy would reject for every tile
+	variants := req.entries(c, tile.Tile{})
+	for _, e := range variants {
+		ctx, ok := helpers.AcquireEntryCtx(app, *c.Proxy, c, e)
+		if !ok {
+			return invalid("invalid data version")
+		}
+		app.ReleaseCtx(ctx)
+	}
+
+	total := 0
+	for zoom := req.MinZoom; zoom <= req.MaxZoom; zoom++ {
+		total += tile.CoverCount(b, zoom) * len(variants)
+		if total > maxTiles {
+			return invalid("request covers more than " + strconv.Itoa(maxTiles) + " tiles")
+		}
+	}
+	return total, nil
+}
+
+// run fetches every tile of the job's request into the cache with the
+// requested concurrency, zoom level by zoom level, until done or cancelled.
+// Tiles completed in order by interrupted runs of the job are skipped.
+func (j *Job) run(ctx context.Context, app *fiber.App, c *cache.Cache, generation string) {
+	defer close(j.done)
+
+	tiles := make(chan work)
+	wg := &sync.WaitGroup{}
+	wg.Add(j.Request.Concurrency)
+
+	for i := 0; i < j.Request.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for w := range tiles {
+				j.seed(app, c, w, generation)
+			}
+		}()
+	}
+
+	// every tile is requested with the same params, so expand them just once
+	variants := j.Request.entries(c, tile.Tile{})
+
+	b := bounds(j.Request.BBox)
+walk:
+	for zoom := j.Request.MinZoom; zoom <= j.Request.MaxZoom; zoom++ {
+		index := 0
+		for _, t := range tile.Cover(b, zoom) {
+			for _, e := range variants {
+				w := work{entry: tile.ListEntry{Tile: t, Query: e.Query}, index: index}
+				index++
+				if j.checkpoint != nil && w.index < j.checkpoint.Done[zoom] {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					break walk
+				case tiles <- w:
+				}
+			}
+		}
+	}
+	close(tiles)
+	wg.Wait()
+
+	j.mu.Lock()
+	j.state = StateCompleted
+	if j.cancelled {
+		j.state = StateCancelled
+	}
+	j.finished = time.Now()
+	j.mu.Unlock()
+	j.cancel()
+
+	// keep the checkpoint of cancelled or partly failed jobs to resume them
+	j.progress.Finish(j.State() == StateCompleted && j.failed.Load() == 0)
+
+	p := j.Progress()
+	util.Info(str.CAdmin, str.MSeedDone, j.Proxy, j.ID, p.State, p.Seeded+p.Cached+p.Skipped,
+		j.Total, j.finished.Sub(j.Started), p.Failed)
+}
+
+// seed fetches a single tile into the cache, recording the outcome. Failed
+// tiles are left out of the job's checkpoint to be retried when resumed.
+func (j *Job) seed(app *fiber.App, c *cache.Cache, w work, generation string) {
+	t := w.entry.Tile
+	if !c.Coverage().Contains(t) {
+		j.skipped.Add(1)
+		j.progress.Complete(t.Zoom, w.index)
+		return
+	}
+
+	fetched, err := seedTile(app, c, w.entry, generation, j.Request.Force)
+	switch {
+	case err != nil:
+		util.Debug(str.CAdmin, str.DSeedFail, j.Proxy, t.String(), err.Error())
+		j.failed.Add(1)
+		return
+	case fetched:
+		j.seeded.Add(1)
+	default:
+		j.cached.Add(1)
+	}
+	j.progress.Complete(t.Zoom, w.index)
+}
+
+// seedTile fetches a tile from the upstream into the cache, returning false
+// if it was already cached and not forced to be fetched again
+func seedTile(app *fiber.App, c *cache.Cache, e tile.ListEntry, generation string, force bool) (bool, error) {
+	p := *c.Proxy
+
+	// build a detached request context so query parameters segment the
+	// cache exactly as they would for a live request
+	ctx, ok := helpers.AcquireEntryCtx(app, p, c, e)
+	if !ok {
+		return false, ErrTileSkipped{ProxyName: p.Name, Reason: "invalid data version"}
+	}
+	defer app.ReleaseCtx(ctx)
+
+	if generation != "" {
+		ctx.Locals(str.LocalGeneration, generation)
+	}
+
+	cacheKey, err := helpers.BuildCacheKey(p, ctx, e.Tile)
+	if err != nil {
+		return false, err
+	}
+
+	if !force && c.Fetch(cacheKey, ctx) != nil {
+		return false, nil
+	}
+
+	// seeding must never contact the upstream while in maintenance mode
+	if c.InMaintenance() {
+		return false, ErrTileSkipped{ProxyName: p.Name, Reason: "maintenance mode"}
+	}
+
+	// seed jobs are fair queued as a single background client, and tiles not
+	// fetched once the upstream request budget is spent count as failed
+	if err = helpers.FetchBackground(helpers.BackgroundFetchPayload{
+		Ctx:      ctx,
+		Cache:    c,
+		Tile:     e.Tile,
+		CacheKey: cacheKey,
+		Client:   str.ClientSeed,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// entries returns the tile list entries requesting the tile, once for every
+// combination of the proxy's param values if seeding the param matrix
+func (r Request) entries(c *cache.Cache, t tile.Tile) []tile.ListEntry {
+	entries := []tile.ListEntry{entry(t, r.Params)}
+	if r.Matrix {
+		entries = tile.ExpandMatrix(entries, c.Proxy.ParamMatrix())
+	}
+	return entries
+}
+
+// entry returns the tile list entry requesting the tile with the given params
+func entry(t tile.Tile, params map[string]string) tile.ListEntry {
+	values := url.Values{}
+	for name, value := range params {
+		values.Set(name, value)
+	}
+	return tile.ListEntry{Tile: t, Query: values.Encode()}
+}
+
+// bounds returns the bounding box of a west, south, east, north bbox
+func bounds(bbox []float64) tile.Bounds {
+	return tile.Bounds{West: bbox[0], South: bbox[1], East: bbox[2], North: bbox[3]}
+}
@@ -0,0 +1,296 @@
+package seed_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/lodtest"
+	"github.com/dechristopher/lod/seed"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+)
+
+const seedConfig = `
+[[proxies]]
+name = "osm"
+tile_url = "${LODTEST_UPSTREAM}/{z}/{x}/{y}.png"
+
+[proxies.cache]
+mem_enabled = true
+mem_cap = 1000
+mem_ttl = "1h"
+key_template = "{z}/{x}/{y}"
+`
+
+// TestSeed will test that a seed job fetches every tile of its request once,
+// leaves tiles already cached alone and rejects invalid requests
+func TestSeed(t *testing.T) {
+	i := lodtest.Start(t, seedConfig)
+	i.Upstream.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\ntile"))
+	})
+	c := i.Caches.Get("osm")
+	req := seed.Request{BBox: []float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 4}
+
+	job, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	job.Wait()
+	if p := job.Progress(); p.State != seed.StateCompleted || p.Seeded != int64(p.Total) ||
+		i.Upstream.Requests() != p.Total {
+		t.Errorf(str.TSeedBadProgress, p, p.Total, "seeded")
+	}
+
+	// tiles are cached in the background once fetched
+	waitCached(t, i, c, req, job.Total)
+
+	job, err = seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	job.Wait()
+	if p := job.Progress(); p.Cached != int64(p.Total) || i.Upstream.Requests() != p.Total {
+		t.Errorf(str.TSeedBadProgress, p, p.Total, "cached")
+	}
+
+	for _, invalid := range []seed.Request{
+		{BBox: []float64{-10, -10, 10}},
+		{BBox: []float64{-10, -10, 10, 10}, MinZoom: 4, MaxZoom: 2},
+		{BBox: []float64{-180, -85, 180, 85}, MaxZoom: 20},
+	} {
+		if _, err = seed.Start(i.App, c, invalid, ""); err == nil {
+			t.Errorf(str.TSeedNoError, invalid)
+		}
+	}
+}
+
+// TestSeedCancel will test that cancelling a running seed job stops it
+// before fetching the rest of its tiles, and only while it runs
+func TestSeedCancel(t *testing.T) {
+	i := lodtest.Start(t, seedConfig)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	i.Upstream.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\ntile"))
+	})
+	c := i.Caches.Get("osm")
+	req := seed.Request{BBox: []float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 4, Concurrency: 1}
+
+	if _, cancelled := seed.Cancel(c); cancelled {
+		t.Fatalf(str.TSeedBadCancel, cancelled, false)
+	}
+
+	job, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+
+	// hold the job on its first tile so it's still running when cancelled
+	<-started
+	if _, err = seed.Start(i.App, c, req, ""); !errors.As(err, &seed.ErrJobRunning{}) {
+		t.Errorf(str.TSeedBadStart, err)
+	}
+	cancelledJob, cancelled := seed.Cancel(c)
+	if !cancelled || cancelledJob != job {
+		t.Fatalf(str.TSeedBadCancel, cancelled, true)
+	}
+	close(release)
+	job.Wait()
+
+	if p := job.Progress(); p.State != seed.StateCancelled || p.Seeded != 1 || i.Upstream.Requests() != 1 {
+		t.Errorf(str.TSeedBadProgress, p, 1, "seeded")
+	}
+	if _, cancelled = seed.Cancel(c); cancelled {
+		t.Errorf(str.TSeedBadCancel, cancelled, false)
+	}
+}
+
+// waitCached waits for the given number of tiles of the seed request to be
+// cached, failing the test if they aren't within a deadline
+func waitCached(t *testing.T, i *lodtest.Instance, c *cache.Cache, req seed.Request, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := cached(i, c, req)
+		if n == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf(str.TSeedBadCached, n, expected)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// cached returns the number of tiles of the seed request in the cache
+func cached(i *lodtest.Instance, c *cache.Cache, req seed.Request) int {
+	ctx := i.App.AcquireCtx(&fasthttp.RequestCtx{})
+	defer i.App.ReleaseCtx(ctx)
+
+	n := 0
+	b := tile.Bounds{West: req.BBox[0], South: req.BBox[1], East: req.BBox[2], North: req.BBox[3]}
+	for zoom := req.MinZoom; zoom <= req.MaxZoom; zoom++ {
+		for _, t := range tile.Cover(b, zoom) {
+			if c.Fetch(tile.ListEntry{Tile: t}.Path(), ctx) != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// TestSeedBudget will test that seed fetches count against the proxy's
+// upstream request budget, and tiles not fetched once it is spent fail
+func TestSeedBudget(t *testing.T) {
+	i := lodtest.Start(t, seedConfig+`
+[proxies.budget]
+hourly = 3
+`)
+	i.Upstream.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\ntile"))
+	})
+	c := i.Caches.Get("osm")
+
+	job, err := seed.Start(i.App, c, seed.Request{BBox: []float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 2}, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	job.Wait()
+	if p := job.Progress(); p.Seeded != 3 || p.Failed != int64(p.Total-3) || i.Upstream.Requests() != 3 {
+		t.Errorf(str.TSeedBadProgress, p, p.Total-3, "failed")
+	}
+}
+
+// TestSeedResume will test that repeating the request of an interrupted seed
+// job resumes it from its checkpoint, unless asked to restart it
+func TestSeedResume(t *testing.T) {
+	i := lodtest.Start(t, seedConfig+`
+redis_enabled = true
+redis_url = "${LODTEST_REDIS}"
+`)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	i.Upstream.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\ntile"))
+	})
+	c := i.Caches.Get("osm")
+	req := seed.Request{BBox: []float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 4, Concurrency: 1}
+
+	// interrupt the job after its first tile
+	job, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	<-started
+	seed.Cancel(c)
+	close(release)
+	job.Wait()
+
+	checkpoints, err := c.Checkpoints(context.Background())
+	if err != nil || len(checkpoints) != 1 || checkpoints[0].ID != job.ID || checkpoints[0].Completed() != 1 {
+		t.Fatalf(str.TSeedBadCheckpoints, checkpoints, err)
+	}
+
+	resumed, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	resumed.Wait()
+	if p := resumed.Progress(); p.ID != job.ID || p.Resumed != 1 || p.Seeded != int64(p.Total-1) ||
+		p.Percent != 100 || i.Upstream.Requests() != p.Total {
+		t.Errorf(str.TSeedBadProgress, p, p.Total-1, "seeded")
+	}
+
+	// completed jobs drop their checkpoint
+	if checkpoints, err = c.Checkpoints(context.Background()); err != nil || len(checkpoints) != 0 {
+		t.Errorf(str.TSeedBadCheckpoints, checkpoints, err)
+	}
+
+	req.Restart = true
+	restarted, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	restarted.Wait()
+	if p := restarted.Progress(); p.Resumed != 0 || p.Seeded+p.Cached != int64(p.Total) {
+		t.Errorf(str.TSeedBadProgress, p, p.Total, "seeded or cached")
+	}
+}
+
+// TestSeedMatrix will test that seed jobs asked to fetch every combination of
+// the proxy's param values request each tile with every combination
+func TestSeedMatrix(t *testing.T) {
+	i := lodtest.Start(t, `
+[[proxies]]
+name = "osm"
+tile_url = "${LODTEST_UPSTREAM}/{z}/{x}/{y}.png?style={style}"
+
+[[proxies.params]]
+name = "style"
+default = "light"
+values = ["light", "dark"]
+
+[proxies.cache]
+mem_enabled = true
+mem_cap = 1000
+mem_ttl = "1h"
+key_template = "{z}/{x}/{y}/{style}"
+`)
+	var dark atomic.Int32
+	i.Upstream.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("style") == "dark" {
+			dark.Add(1)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\ntile"))
+	})
+	c := i.Caches.Get("osm")
+	req := seed.Request{BBox: []float64{-10, -10, 10, 10}, MinZoom: 0, MaxZoom: 2, Matrix: true}
+
+	job, err := seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	job.Wait()
+
+	tiles := 0
+	for zoom := req.MinZoom; zoom <= req.MaxZoom; zoom++ {
+		tiles += tile.CoverCount(tile.Bounds{West: -10, South: -10, East: 10, North: 10}, zoom)
+	}
+	if p := job.Progress(); p.Total != 2*tiles || p.Seeded != int64(p.Total) ||
+		i.Upstream.Requests() != p.Total || int(dark.Load()) != tiles {
+		t.Errorf(str.TSeedBadProgress, p, 2*tiles, "seeded")
+	}
+
+	// explicitly set params aren't enumerated
+	req.Params = map[string]string{"style": "dark"}
+	job, err = seed.Start(i.App, c, req, "")
+	if err != nil {
+		t.Fatalf(str.TSeedBadStart, err.Error())
+	}
+	job.Wait()
+	if p := job.Progress(); p.Total != tiles {
+		t.Errorf(str.TSeedBadProgress, p, tiles, "total")
+	}
+}
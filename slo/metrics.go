@@ -0,0 +1,64 @@
+package slo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "slo"
+
+// collector exports the objectives and burn rates of all trackers at scrape
+// time, so metrics follow trackers across config reloads
+type collector struct{}
+
+var (
+	objectiveDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(config.Namespace, Subsystem, "objective"),
+		"The configured target of each service level objective",
+		[]string{"proxy", "slo"}, nil)
+	burnRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(config.Namespace, Subsystem, "burn_rate"),
+		"The rate the error budget of each objective is spent at over each window, 1 spends it exactly",
+		[]string{"proxy", "slo", "window"}, nil)
+	budgetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(config.Namespace, Subsystem, "error_budget_remaining"),
+		"The fraction of each objective's error budget left over the longest window",
+		[]string{"proxy", "slo"}, nil)
+)
+
+func init() {
+	prometheus.MustRegister(collector{})
+}
+
+// Describe sends the descriptors of all SLO metrics
+func (collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- objectiveDesc
+	ch <- burnRateDesc
+	ch <- budgetDesc
+}
+
+// Collect sends the current values of all SLO metrics
+func (collector) Collect(ch chan<- prometheus.Metric) {
+	for name, tracker := range Trackers {
+		tracker.mu.Lock()
+		slo := tracker.slo
+		tracker.mu.Unlock()
+
+		for _, objective := range []string{Latency, Availability} {
+			target := tracker.Objective(objective)
+			if target == 0 {
+				continue
+			}
+
+			ch <- prometheus.MustNewConstMetric(objectiveDesc, prometheus.GaugeValue,
+				target, name, objective)
+			for i, window := range slo.WindowDurations {
+				ch <- prometheus.MustNewConstMetric(burnRateDesc, prometheus.GaugeValue,
+					tracker.BurnRate(objective, window), name, objective, slo.Windows[i])
+			}
+			ch <- prometheus.MustNewConstMetric(budgetDesc, prometheus.GaugeValue,
+				tracker.BudgetRemaining(objective), name, objective)
+		}
+	}
+}
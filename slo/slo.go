@@ -0,0 +1,190 @@
+package slo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Objectives tracked per proxy
+const (
+	Latency      = "latency"
+	Availability = "availability"
+)
+
+// resolution of the sliding window buckets
+const resolution = time.Minute
+
+// TrackersMap is an alias type for the map of proxy name to its SLO tracker
+type TrackersMap map[string]*Tracker
+
+// Trackers of proxies with service level objectives configured
+var Trackers = make(TrackersMap)
+
+// Tracker counts a proxy's requests in per-minute buckets covering its longest
+// burn rate window, measuring them against its objectives
+type Tracker struct {
+	mu      sync.Mutex
+	slo     config.SLO
+	buckets []bucket // ring of buckets indexed by minute
+}
+
+// bucket counts requests within a single minute
+type bucket struct {
+	minute int64 // minutes since the unix epoch
+	total  int64 // all requests
+	failed int64 // requests failed with a server error
+	slow   int64 // successful requests slower than the latency target
+}
+
+// Init builds trackers for all proxies with objectives configured, keeping the
+// request history of proxies that already had one across config reloads
+func Init() {
+	trackers := make(TrackersMap)
+	for _, proxy := range config.Get().Proxies {
+		if !proxy.SLO.Enabled() {
+			continue
+		}
+
+		if tracker := Trackers[proxy.Name]; tracker != nil {
+			tracker.configure(proxy.SLO)
+			trackers[proxy.Name] = tracker
+		} else {
+			trackers[proxy.Name] = newTracker(proxy.SLO)
+		}
+	}
+	Trackers = trackers
+}
+
+// Get an SLO tracker by proxy name, nil if the proxy has no objectives
+func Get(name string) *Tracker {
+	return Trackers[name]
+}
+
+// newTracker builds a tracker for the given objectives
+func newTracker(slo config.SLO) *Tracker {
+	t := &Tracker{}
+	t.configure(slo)
+	return t
+}
+
+// configure applies the given objectives, resizing the bucket ring to cover
+// the longest window while keeping the history that still fits
+func (t *Tracker) configure(slo config.SLO) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	longest := time.Duration(0)
+	for _, window := range slo.WindowDurations {
+		if window > longest {
+			longest = window
+		}
+	}
+
+	buckets := make([]bucket, int(longest/resolution)+1)
+	for _, b := range t.buckets {
+		slot := &buckets[b.minute%int64(len(buckets))]
+		if b.total > 0 && b.minute > slot.minute {
+			*slot = b
+		}
+	}
+
+	t.slo = slo
+	t.buckets = buckets
+}
+
+// Observe records a request served with the given latency, and whether it
+// failed with a server error
+func (t *Tracker) Observe(latency time.Duration, failed bool) {
+	if t == nil {
+		return
+	}
+	t.observe(time.Now(), latency, failed)
+}
+
+// observe records a request served at the given time
+func (t *Tracker) observe(now time.Time, latency time.Duration, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	minute := now.Unix() / int64(resolution/time.Second)
+	b := &t.buckets[minute%int64(len(t.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+
+	b.total++
+	if failed {
+		b.failed++
+	} else if t.slo.LatencyDuration > 0 && latency > t.slo.LatencyDuration {
+		b.slow++
+	}
+}
+
+// Objective returns the configured target of the given objective, 0 if unset
+func (t *Tracker) Objective(objective string) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.objective(objective)
+}
+
+// objective returns the configured target of the given objective, 0 if unset
+func (t *Tracker) objective(objective string) float64 {
+	if objective == Latency {
+		return t.slo.LatencyObjective
+	}
+	return t.slo.Availability
+}
+
+// BurnRate returns how many times faster than sustainable the error budget of
+// an objective was spent over the window ending now. A burn rate of 1 spends
+// exactly the whole budget over the window.
+func (t *Tracker) BurnRate(objective string, window time.Duration) float64 {
+	return t.burnRate(time.Now(), objective, window)
+}
+
+// burnRate returns the burn rate of an objective over the window ending at now
+func (t *Tracker) burnRate(now time.Time, objective string, window time.Duration) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	target := t.objective(objective)
+	if target == 0 {
+		return 0
+	}
+
+	current := now.Unix() / int64(resolution/time.Second)
+	oldest := current - int64(window/resolution) + 1
+
+	var good, bad int64
+	for _, b := range t.buckets {
+		if b.minute < oldest || b.minute > current {
+			continue
+		}
+
+		if objective == Latency {
+			// latency is only measured for successful requests
+			good += b.total - b.failed - b.slow
+			bad += b.slow
+		} else {
+			good += b.total - b.failed
+			bad += b.failed
+		}
+	}
+
+	if good+bad == 0 {
+		return 0
+	}
+	return float64(bad) / float64(good+bad) / (1 - target)
+}
+
+// BudgetRemaining returns the fraction of an objective's error budget left
+// over the longest window, negative once overspent
+func (t *Tracker) BudgetRemaining(objective string) float64 {
+	t.mu.Lock()
+	longest := time.Duration(len(t.buckets)-1) * resolution
+	t.mu.Unlock()
+
+	return 1 - t.BurnRate(objective, longest)
+}
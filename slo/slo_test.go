@@ -0,0 +1,66 @@
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+func testTracker() *Tracker {
+	return newTracker(config.SLO{
+		LatencyDuration:  100 * time.Millisecond,
+		LatencyObjective: 0.9,
+		Availability:     0.99,
+		Windows:          []string{"5m", "1h"},
+		WindowDurations:  []time.Duration{5 * time.Minute, time.Hour},
+	})
+}
+
+func expectBurnRate(t *testing.T, got, expected float64, objective string) {
+	t.Helper()
+	if math.Abs(got-expected) > 1e-9 {
+		t.Fatalf(str.TSLOBadBurnRate, objective, got, expected)
+	}
+}
+
+func TestBurnRate(t *testing.T) {
+	tracker := testTracker()
+	now := time.Unix(1700000000, 0)
+
+	// 100 requests: 2 failed, 9 slow, 89 good
+	for i := 0; i < 100; i++ {
+		switch {
+		case i < 2:
+			tracker.observe(now, time.Millisecond, true)
+		case i < 11:
+			tracker.observe(now, time.Second, false)
+		default:
+			tracker.observe(now, time.Millisecond, false)
+		}
+	}
+
+	// 2% failed against a 1% budget burns twice as fast as sustainable
+	expectBurnRate(t, tracker.burnRate(now, Availability, 5*time.Minute), 2, Availability)
+	// 9 of 98 successful requests slow against a 10% budget
+	expectBurnRate(t, tracker.burnRate(now, Latency, 5*time.Minute), 9.0/98/0.1, Latency)
+}
+
+func TestBurnRateWindow(t *testing.T) {
+	tracker := testTracker()
+	now := time.Unix(1700000000, 0)
+
+	tracker.observe(now.Add(-30*time.Minute), time.Millisecond, true)
+	tracker.observe(now, time.Millisecond, false)
+
+	// the failure only falls within the longer window
+	expectBurnRate(t, tracker.burnRate(now, Availability, 5*time.Minute), 0, Availability)
+	expectBurnRate(t, tracker.burnRate(now, Availability, time.Hour), 0.5/0.01, Availability)
+
+	// buckets older than the longest window are reused for new minutes
+	later := now.Add(2 * time.Hour)
+	tracker.observe(later, time.Millisecond, false)
+	expectBurnRate(t, tracker.burnRate(later, Availability, time.Hour), 0, Availability)
+}
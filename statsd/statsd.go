@@ -0,0 +1,270 @@
+// Package statsd periodically emits every metric registered with Prometheus
+// to a statsd or DogStatsD agent, for deployments standardized on agents
+// rather than scrapes
+package statsd
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// maxPacket is the largest payload written per datagram, keeping packets
+// within a typical MTU
+const maxPacket = 1432
+
+// Emitter gathers metrics from Prometheus and writes them to the agent as
+// statsd lines on every interval
+type Emitter struct {
+	config   config.StatsD
+	gatherer prometheus.Gatherer
+	conn     net.Conn
+	last     map[string]float64 // cumulative values of counter series at the previous emission
+	next     map[string]float64 // cumulative values of counter series seen by this emission
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	mu      sync.Mutex
+	emitter *Emitter
+)
+
+// Init starts an emitter if a statsd agent is configured, stopping any
+// emitter left over from a previous configuration
+func Init() error {
+	var next *Emitter
+	if statsd := config.Get().Instance.StatsD; statsd.Address != "" {
+		var err error
+		if next, err = newEmitter(statsd, prometheus.DefaultGatherer); err != nil {
+			return err
+		}
+		go next.run()
+	}
+
+	mu.Lock()
+	prev := emitter
+	emitter = next
+	mu.Unlock()
+
+	prev.Close()
+	return nil
+}
+
+// Close emits metrics a final time and stops the active emitter
+func Close() {
+	mu.Lock()
+	prev := emitter
+	emitter = nil
+	mu.Unlock()
+
+	prev.Close()
+}
+
+// newEmitter builds an emitter writing metrics gathered from the gatherer
+// to the configured agent
+func newEmitter(statsd config.StatsD, gatherer prometheus.Gatherer) (*Emitter, error) {
+	conn, err := net.Dial("udp", statsd.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Emitter{
+		config:   statsd,
+		gatherer: gatherer,
+		conn:     conn,
+		last:     make(map[string]float64),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Close stops the emitter after a final emission, and closes its connection
+func (e *Emitter) Close() {
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	_ = e.conn.Close()
+}
+
+// run emits metrics on every interval until stopped
+func (e *Emitter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.config.IntervalDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.emit()
+		case <-e.stop:
+			e.emit()
+			return
+		}
+	}
+}
+
+// emit gathers all metrics and writes them to the agent, batching lines into
+// as few datagrams as possible
+func (e *Emitter) emit() {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		// gathering is best effort, the families gathered are still emitted
+		util.Error(str.CMain, str.EStatsDGather, err.Error())
+	}
+
+	// forget series that are no longer exported, ex: of removed proxies
+	e.next = make(map[string]float64, len(e.last))
+	defer func() {
+		e.last = e.next
+	}()
+
+	var packet bytes.Buffer
+	write := func(line string) {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > maxPacket {
+			e.send(packet.Bytes())
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, line := range e.lines(family, metric) {
+				write(line)
+			}
+		}
+	}
+
+	if packet.Len() > 0 {
+		e.send(packet.Bytes())
+	}
+}
+
+// send writes a single datagram to the agent
+func (e *Emitter) send(packet []byte) {
+	if _, err := e.conn.Write(packet); err != nil {
+		util.Error(str.CMain, str.EStatsDWrite, e.config.Address, err.Error())
+	}
+}
+
+// lines returns the statsd lines for a single metric series
+func (e *Emitter) lines(family *dto.MetricFamily, metric *dto.Metric) []string {
+	name := family.GetName()
+	labels := metric.GetLabel()
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		return e.counter(name, labels, metric.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		return []string{e.line(name, labels, metric.GetGauge().GetValue(), "g")}
+	case dto.MetricType_UNTYPED:
+		return []string{e.line(name, labels, metric.GetUntyped().GetValue(), "g")}
+	case dto.MetricType_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		return append(e.counter(name+".count", labels, float64(histogram.GetSampleCount())),
+			e.counter(name+".sum", labels, histogram.GetSampleSum())...)
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		return append(e.counter(name+".count", labels, float64(summary.GetSampleCount())),
+			e.counter(name+".sum", labels, summary.GetSampleSum())...)
+	}
+	return nil
+}
+
+// counter returns the line for the increase of a cumulative value since the
+// previous emission, or none if it did not change. The full value is sent when
+// a counter starts over, ex: a proxy's metrics being recreated on reload.
+func (e *Emitter) counter(name string, labels []*dto.LabelPair, value float64) []string {
+	// the zero valued line identifies the series
+	series := e.line(name, labels, 0, "c")
+
+	delta := value - e.last[series]
+	if delta < 0 {
+		delta = value
+	}
+	e.next[series] = value
+
+	if delta == 0 {
+		return nil
+	}
+	return []string{e.line(name, labels, delta, "c")}
+}
+
+// line formats a single statsd line. DogStatsD lines carry labels and the
+// configured tags as tags, while plain statsd lines append label values to
+// the metric name in label order.
+func (e *Emitter) line(name string, labels []*dto.LabelPair, value float64, kind string) string {
+	var b strings.Builder
+	b.WriteString(e.config.Prefix)
+	b.WriteString(name)
+
+	if e.config.Flavor == config.StatsDPlain {
+		for _, label := range labels {
+			b.WriteByte('.')
+			b.WriteString(strings.ReplaceAll(sanitize(label.GetValue()), ".", "_"))
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(formatValue(value))
+	b.WriteByte('|')
+	b.WriteString(kind)
+
+	if e.config.Flavor == config.StatsDDog && (len(labels) > 0 || len(e.config.Tags) > 0) {
+		b.WriteString("|#")
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label.GetName())
+			b.WriteByte(':')
+			b.WriteString(sanitize(label.GetValue()))
+		}
+		for i, tag := range e.config.Tags {
+			if i > 0 || len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(tag)
+		}
+	}
+
+	return b.String()
+}
+
+// formatValue formats a metric value, sending non-finite values as zero
+// since agents cannot parse them
+func formatValue(value float64) string {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return "0"
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// sanitize replaces the characters reserved by the statsd line format
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,95 @@
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestEmitter will test that counters are emitted as deltas, skipped when
+// unchanged, and that labels are formatted per flavor
+func TestEmitter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "requests_total",
+	}, []string{"proxy"})
+	entries := prometheus.NewGauge(prometheus.GaugeOpts{Name: "entries"})
+	registry.MustRegister(requests, entries)
+
+	tests := []struct {
+		flavor string
+		tags   []string
+		first  []string
+		second []string
+	}{
+		{
+			flavor: config.StatsDDog,
+			tags:   []string{"env:test"},
+			first:  []string{"lod.entries:5|g|#env:test", "lod.requests_total:3|c|#proxy:osm,env:test"},
+			second: []string{"lod.entries:5|g|#env:test", "lod.requests_total:2|c|#proxy:osm,env:test"},
+		},
+		{
+			flavor: config.StatsDPlain,
+			first:  []string{"lod.entries:5|g", "lod.requests_total.osm:3|c"},
+			second: []string{"lod.entries:5|g", "lod.requests_total.osm:2|c"},
+		},
+	}
+
+	for _, test := range tests {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := newEmitter(config.StatsD{
+			Address: listener.LocalAddr().String(),
+			Flavor:  test.flavor,
+			Prefix:  "lod.",
+			Tags:    test.tags,
+		}, registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		requests.Reset()
+		requests.WithLabelValues("osm").Add(3)
+		entries.Set(5)
+		e.emit()
+		if got := receive(t, listener); !equal(got, test.first) {
+			t.Errorf(str.TStatsDBadLines, got, test.first)
+		}
+
+		requests.WithLabelValues("osm").Add(2)
+		e.emit()
+		if got := receive(t, listener); !equal(got, test.second) {
+			t.Errorf(str.TStatsDBadLines, got, test.second)
+		}
+
+		_ = e.conn.Close()
+		_ = listener.Close()
+	}
+}
+
+// receive reads the lines of a single datagram
+func receive(t *testing.T, listener net.PacketConn) []string {
+	buf := make([]byte, maxPacket)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func equal(a, b []string) bool {
+	return strings.Join(a, "\n") == strings.Join(b, "\n")
+}
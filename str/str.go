@@ -3,11 +3,14 @@ package str
 // Flags, log formats and miscellaneous strings
 const (
 	FConfigFile      = "conf"
-	FConfigFileUsage = "Path/URL to TOML configuration file. Default: config.toml"
+	FConfigFileUsage = "Path/URL to TOML, YAML or HCL configuration file. Default: config.toml"
 
 	FDevMode      = "dev"
 	FDevModeUsage = "Whether to enable developer mode. Default: false"
 
+	FReadOnly      = "readonly"
+	FReadOnlyUsage = "Whether to serve from caches without writing to Redis. Default: false"
+
 	FDebugFlags      = "debug"
 	FDebugFlagsUsage = "Optional comma separated debug flags. Ex: foo,bar,baz"
 
@@ -27,9 +30,35 @@ const EndpointTemplate = "{e}"
 const (
 	LocalCacheStatus = "lod-cache"
 	LocalCacheName   = "cacheName"
+	LocalCache       = "cache"
 	LocalParams      = "params"
+	LocalGeneration  = "generation"
+	LocalVersion     = "version"
+	LocalBody        = "body"
+	LocalPathParams  = "pathParams"
+	LocalClientCtx   = "clientCtx"
+	LocalClientWatch = "clientWatch"
+	LocalLogger      = "logger"
+	LocalRequestID   = "requestid"
+	LocalClientClass = "clientClass"
+	LocalSkipMemory  = "skipMemory"
+	LocalCountry     = "country"
+	LocalTiming      = "timing"
 )
 
+// ClientBackground prefixes the clients of LOD's own jobs, whose upstream
+// requests are background traffic yielding to interactive requests
+const ClientBackground = "lod:"
+
+// ClientAdmin identifies administrative jobs as a client for fair queuing
+const ClientAdmin = "lod:admin"
+
+// ClientWarmup identifies boot-time cache warm-up jobs as a client for fair queuing
+const ClientWarmup = "lod:warmup"
+
+// ClientSeed identifies bounding box seed jobs as a client for fair queuing
+const ClientSeed = "lod:seed"
+
 // (P) Parameter names
 const (
 	ParamEndpoint = "e"
@@ -38,6 +67,18 @@ const (
 	ParamX        = "x"
 )
 
+// (R) Rejection reasons sent to clients turned away with a Retry-After hint
+const (
+	RMaintenance        = "proxy in maintenance mode"
+	RQueueTimeout       = "upstream queue is full"
+	RBotRateLimit       = "rate limit exceeded"
+	RGeoBlocked         = "not available in your country"
+	RScheduleClosed     = "outside of serving window"
+	RBudgetSpent        = "upstream request budget spent"
+	RQueryTooLong       = "query string or parameter too long"
+	RQueryTooManyParams = "too many query parameters"
+)
+
 // (C) Log caller names
 const (
 	CMain  = "LOD"
@@ -56,19 +97,52 @@ const (
 	ECacheBuildKey      = "failed to build cache key: %s"
 	ECacheEntry         = "invalid MAX_ENTRY_SIZE (int MB) provided: %s"
 	ECacheFetch         = "failed to fetch tile from cache, key=%s error=%s"
+	ECacheFetchMany     = "failed to fetch %d tiles from redis, error=%s"
 	ECacheDelete        = "failed to delete tile from cache, key=%s error=%s"
 	ECacheSet           = "failed to set cache entry, key=%s error=%s"
+	ECacheSetMany       = "failed to set batch of %d cache entries in redis, error=%s"
 	ECacheFlush         = "failed to flush cache, name=%s error=%s"
+	ECacheTag           = "failed to tag cache entry, key=%s error=%s"
+	ECacheSparse        = "proxy[%s]: failed to persist known empty subtrees: %s"
+	ECacheBudget        = "proxy[%s]: failed to count upstream request budget: %s"
+	EBudgetExhausted    = "proxy[%s]: %s upstream request budget of %d spent, serving from cache only until %s"
+	ECacheKeyIndex      = "proxy[%s]: failed to look up hashed key %s in debug index: %s"
+	ECacheCheckpoint    = "proxy[%s]: failed to checkpoint seed job %s: %s"
+	EPurgeTag           = "failed to purge tag %s error=%s"
+	ECDNPurge           = "failed to purge proxy %s from CDN, error=%s"
+	ECertReload         = "failed to reload upstream client certificate %s, error=%s"
+	EUpstreamResolve    = "proxy[%s]: failed to resolve upstream: %s"
+	EProxyLoop          = "proxy[%s]: refused request that looped back through this instance, check that the upstream does not lead back to LOD"
 	EProxyAgentError    = "proxy[%s]: agent request failed (%s): %s"
 	EProxyBadCast       = "proxy[%s]: agent response invalid (%s): check the configuration"
 	EProxyWrite         = "proxy[%s]: failed to write response (%s): %s"
+	EMetadata           = "proxy[%s]: failed to normalize metadata from %s: %s"
+	EWebhook            = "failed to post batch of %d request summaries to webhook: %s"
+	EAccessLog          = "failed to insert batch of %d access log records into %s: %s"
+	EDNSResolve         = "failed to resolve upstream host %s: %s"
+	EStatsDGather       = "failed to gather metrics for statsd: %s"
+	EStatsDWrite        = "failed to write metrics to statsd agent %s: %s"
+	EFleetHeartbeat     = "failed to update fleet registry %s: %s"
+	EFleetList          = "failed to list fleet registry members: %s"
+	EDiscover           = "proxy[%s]: failed to discover coverage from upstream metadata: %s"
 	EInvalidateTileDeep = "failed to invalidate tile %s with depth error=%s"
 	EInvalidateTile     = "failed to invalidate tile %s error=%s"
 	EPrimeTileDeep      = "failed to prime tile %s with depth error=%s"
 	EPrimeTile          = "failed to prime tile %s error=%s"
+	EPrimeList          = "failed to prime tiles from tile list %s error=%s"
 	EWrite              = "write err: error=%s meta=%+v"
 	EReload             = "failed to reload instance capabilities, error=%s"
+	EHTTP3              = "HTTP/3 listener failed: %s"
+	EGenerationSwitch   = "failed to switch generation of proxy %s, error=%s"
+	EWarmupList         = "proxy[%s]: failed to read warm-up tile list %s: %s"
+	ESeed               = "proxy[%s]: failed to start seed job: %s"
+	EProbe              = "proxy[%s]: probe of %s failed: %s"
+	EDynamic            = "failed to change dynamic proxy, error=%s"
+	EDynamicShadowed    = "dynamic proxy %s is shadowed by the config file and not served"
 	ERequest            = "generic uncaught error in request chain, ctx=%s error=%s"
+	ESlowRequest        = "slow request took %s (threshold %s) status=%s cache=%s redis=%s upstream=%s encode=%s write=%s other=%s"
+	EPanic              = "recovered from panic handling request for proxy %s, request_id=%s panic=%v\n%s"
+	EFetchPanic         = "recovered from panic in shared fetch %s of proxy %s, panic=%v\n%s"
 )
 
 // (U) User-facing error messages and codes
@@ -77,45 +151,203 @@ const ()
 // (M) Standard info log messages
 const (
 	MDevMode            = "!! DEVELOPER MODE !!"
+	MReadOnly           = "read-only mode enabled, redis will not be written to"
 	MInit               = "LOD v%s - copyright 2021-2022 Andrew DeChristopher <me@dchr.host>\n"
 	MStarted            = "started in %s [env: %s][http: %d]"
 	MProxy              = "configured proxy [mem: %t / redis: %t][%s] -> %s"
+	MHTTP3              = "HTTP/3 listening [udp: %d]"
 	MReload             = "reloaded instance capabilities"
+	MDynamicPut         = "registered dynamic proxy %s"
+	MDynamicDelete      = "deleted dynamic proxy %s"
 	MOldCacheDeleted    = "old cache instance '%s' removed"
+	MScheduledFlush     = "proxy[%s]: scheduled flush of cache tiers (memory: %t, redis: %t)"
+	MMaintenance        = "proxy %s maintenance mode set to %t (mode: %s)"
 	MInvalidateTile     = "invalidated tile %s with no depth (%d) (%d tiles)"
 	MInvalidateTileDeep = "invalidated tile %s with depth %d (%d tiles)"
 	MPrimeTile          = "primed tile %s with no depth (%d) (%d tiles)"
 	MPrimeTileDeep      = "primed tile %s with depth %d (%d tiles)"
+	MPrimeList          = "primed %d/%d tiles from tile list %s (%d invalid entries)"
+	MPrimeResume        = "proxy[%s]: resuming seed job %s after %d/%d tiles"
+	MPurgeTag           = "purged tag %s from proxy %s (%d tiles)"
+	MCDNPurge           = "purged proxy %s from CDN (%d urls)"
+	MCDNPurgeTags       = "purged proxy %s from CDN (tags: %v)"
+	MCertReload         = "reloaded upstream client certificate %s"
+	MUpstreamHealth     = "proxy[%s]: upstream %s healthy=%t"
+	MGenerationSwitch   = "proxy %s now serving cache generation %s"
+	MWarmupDone         = "proxy[%s]: warmed %d/%d tiles from tile list in %s"
+	MSeedStart          = "proxy[%s]: started seed job %s of %d tiles (zoom %d-%d, bbox %v)"
+	MSeedDone           = "proxy[%s]: seed job %s %s after %d/%d tiles in %s (%d failed)"
+	MChaos              = "proxy[%s]: CHAOS MODE injecting faults %+v"
+	MSparseReset        = "proxy[%s]: forgot all known empty subtrees"
+	MCORSDebugReset     = "proxy[%s]: dropped captured cors decisions"
+	MDiscover           = "proxy[%s]: discovered coverage %s"
 	MShutdown           = "shutting down"
+	MDrain              = "draining, waiting %s for load balancers to deregister the instance"
 	MExit               = "exit"
 )
 
 // (D) Debug log messages
 const (
-	DCacheUp        = "cache online name=%s"
-	DCacheSet       = "cache set key=%s len=%d"
-	DCacheMiss      = "cache internal miss key=%s"
-	DCacheMissExt   = "cache external miss key=%s"
-	DCacheHit       = "cache hit key=%s len=%d"
-	DCalcTiles      = "admin: proxy %s: depth search found %d tiles from via %s to depth %d"
-	DPrimeFail      = "failed to prime tile %s, err=%s"
-	DInvalidateFail = "failed to invalidate tile %s, err=%s"
+	DCacheUp              = "cache online name=%s"
+	DCacheSet             = "cache set key=%s len=%d"
+	DCacheDropped         = "cache set dropped by memory engine key=%s"
+	DCacheMiss            = "cache internal miss key=%s"
+	DCacheMissExt         = "cache external miss key=%s"
+	DCacheHit             = "cache hit key=%s len=%d"
+	DCacheStale           = "cache stale key=%s"
+	DCacheEarly           = "cache early expiry key=%s"
+	DCacheExpired         = "cache upstream ttl expired key=%s"
+	DCacheAdaptiveExpired = "cache adaptive ttl expired key=%s"
+	DTileRepaired         = "repaired invalid vector tile key=%s repairs=%d error=%s"
+	DUpstreamResolved     = "proxy[%s]: upstream resolved to %d addresses"
+	DProbeFail            = "proxy[%s]: upstream %s health probe failed: %s"
+	DCalcTiles            = "admin: proxy %s: depth search found %d tiles from via %s to depth %d"
+	DPrimeFail            = "failed to prime tile %s, err=%s"
+	DWarmupFail           = "proxy[%s]: failed to warm tile %s, err=%s"
+	DSeedFail             = "proxy[%s]: failed to seed tile %s, err=%s"
+	DInvalidateFail       = "failed to invalidate tile %s, err=%s"
+	DCDNPurgeFail         = "failed to build CDN purge URL for tile %s, err=%s"
+	DJWTRejected          = "proxy[%s]: rejected bearer token: %s"
 )
 
 // (T) Test messages
 const (
-	TCacheEncodeHeaders = "retrieved headers length did not match input, got=%d expected=%d"
-	TCacheBadHeaderData = "header data not properly encoded into tile packet"
-	TCacheBadTileData   = "tile data not properly encoded into tile packet"
-	TCacheBadValidation = "tile data corrupted, checksum failed"
-	TCacheBadDecode     = "tile decode failed, error=%s"
+	TCacheEncodeHeaders        = "retrieved headers length did not match input, got=%d expected=%d"
+	TCacheBadHeaderData        = "header data not properly encoded into tile packet"
+	TCacheBadTileData          = "tile data not properly encoded into tile packet"
+	TCacheBadValidation        = "tile data corrupted, checksum failed"
+	TCacheBadDecode            = "tile decode failed, error=%s"
+	TCacheBadCreated           = "tile creation time did not match, got=%s expected=%s"
+	TCacheBadExpires           = "tile expiry time did not match, got=%s expected=%s"
+	TCacheBadWarm              = "unexpected warm state, got=%t expected=%t"
+	TCacheBadFill              = "unexpected in-memory bytes of engine %s after %s, got=%d expected=%d"
+	TCacheBadSavings           = "cache savings did not match expected totals, got=%+v"
+	TCacheBadManager           = "cache manager init failed, error=%s"
+	TCacheBadManaged           = "unexpected managed caches, got=%v"
+	TDebugTileBadPNG           = "debug tile is not a valid png, error=%s"
+	TDebugTileBadSize          = "debug tile has unexpected size, got=%v"
+	TDebugTileBadLabel         = "debug tile label did not match, got=%s expected=%s"
+	TDebugTileUnstable         = "debug tile rendering is not deterministic"
+	TDebugTileNoError          = "expected unknown debug tile format error, got none"
+	THeaderBadMatch            = "unexpected match for header %s, got=%t expected=%t"
+	THeaderBadPattern          = "expected invalid pattern error, got=%v"
+	TSLOBadBurnRate            = "unexpected %s burn rate, got=%f expected=%f"
+	TMVTBadDecode              = "vector tile decode failed, error=%s"
+	TMVTBadEncode              = "vector tile did not survive an encode and decode round trip"
+	TMVTBadValidation          = "vector tile failed validation, error=%s"
+	TMVTBadRepair              = "vector tile not properly repaired"
+	TMVTNoLayers               = "vector tile decoded without any layers"
+	TMVTNoError                = "expected vector tile error, got none"
+	TTileBadTile               = "unexpected tile, got=%s expected=%s"
+	TTileBadBounds             = "unexpected tile bounds, got=%+v expected=%+v"
+	TTileBadQuadkey            = "unexpected quadkey, got=%s expected=%s"
+	TTileBadCover              = "unexpected tile cover, got=%v expected=%v"
+	TTileNoError               = "expected invalid quadkey error for %s, got none"
+	TTileBadListEntry          = "unexpected tile list entry for %q, got=%+v,%v expected=%+v"
+	TTileBadList               = "unexpected tile list lines, got=%q expected=%q"
+	TDNSBadLookup              = "unexpected resolved addresses, got=%v expected=%s"
+	TDNSBadQueries             = "unexpected number of nameserver queries, got=%d expected=%d"
+	TUpstreamBadDial           = "failed to dial upstream %s, error=%v"
+	TUpstreamBadDialOrder      = "upstream addresses preferring %s dialed in wrong order, got=%v expected=%v"
+	TUpstreamBadConns          = "upstream connections incorrect, got=%+v expected=%+v"
+	TUpstreamBadLoop           = "upstream %s loop check returned %v, expected loop=%t"
+	TUpstreamBadPick           = "upstream target picked incorrectly, got=%s expected=%s"
+	TUpstreamBadAcquire        = "unexpected fair queue acquire result, got=%v"
+	TUpstreamBadGrant          = "fair queue granted slot to wrong client, got=%s expected=%s"
+	TUpstreamBadClass          = "unexpected priority class of client %s, got=%s expected=%s"
+	TUpstreamBadBackgroundWait = "unexpected background rate limit wait of request #%d, got=%s expected=%s"
+	TUpstreamBadBackgroundPeak = "unexpected peak of background requests in flight, got=%d expected=%d"
+	TUpstreamBadShare          = "upstream slow-start share incorrect, got=%f expected=%f"
+	TUpstreamBadSpread         = "upstream picks not spread as expected, got=%v"
+	TUpstreamBadRebuild        = "unexpected upstream pool of proxy %s after rebuild, replaced=%t expected=%t"
+	TMVTRepairs                = "vector tile repair count did not match, got=%d expected=%d"
+	TCacheBadXFetch            = "unexpected early expiration decision, age=%s random=%f got=%t"
+	TCacheBadJitter            = "unexpected jittered TTL, ttl=%s percent=%g random=%f got=%s expected=%s"
+	TCacheBadBoundTTL          = "unexpected bounded TTL, ttl=%s remaining=%s got=%s,%t expected=%s,%t"
+	TCacheBadBudget            = "unexpected budget result of fetch #%d: %v"
+	TCacheBadBudgetFetches     = "unexpected number of budgeted fetches, got=%d expected=%d"
+	TCacheBadBudgetWrite       = "read-only instance wrote budget counts to redis, keys=%v count=%s"
+	TCacheBadBudgetReset       = "unexpected spent budget state, until=%s spent=%t"
+	TCacheBadBudgetWindows     = "unexpected budget windows %+v"
+	TCacheBadPurgeTag          = "unexpected read-only tag purge, purged=%d err=%v keys=%v"
+	TCacheBadFetchMany         = "unexpected tile fetched for key %s, got=%q expected=%q"
+	TCacheBadWriteBehind       = "unexpected keys written to redis %v"
+	TCacheBadFlush             = "unexpected redis lookup of %q after scheduled flush, got=%q expected=%q"
+	TCacheBadKeyHash           = "unexpected redis key for %q, got %q, expected %q"
+	TCacheBadKeyIndex          = "unexpected debug index entry for %q, got %q, expected %q"
+	TCacheBadCheckpoint        = "unexpected seed checkpoint, got=%+v expected=%+v"
+	TCacheBadLookup            = "unexpected lookup of %s with memory delay %s, got=%q from %s err=%v expected=%q from %s"
+	TCacheBadSharedLookup      = "unexpected shared redis lookup, got=%q after %d GETs expected=%q after %d GETs"
+	TCacheBadBackoff           = "unexpected backoff, attempt=%d random=%f got=%s expected=%s"
+	TCacheBadRetry             = "unexpected retries of case #%d, got=%d,%v expected=%d,%v"
+	TCacheBadShardHash         = "unexpected shard hash, key=%s got=%d expected=%d"
+	TCacheBadShardLoad         = "operation not counted against shard, key=%s"
+	TCacheBadShardSummary      = "unexpected shard load summary, min=%d max=%d mean=%f"
+	TCacheBadHotShards         = "unexpected hot shards %+v"
+	TCacheBadEviction          = "unexpected eviction, policy=%s evicted=%v expected=%s"
+	TCacheBadEvictionUsage     = "unexpected eviction index usage, policy=%s used=%d entries=%d"
+	TCacheBadAdaptiveTTL       = "unexpected adaptive ttl of refetch #%d with data %q, got=%v expected=%v"
+	TCacheBadHotKey            = "unexpected pinned tile %s, got=%q expected=%q"
+	TCacheBadSketch            = "unexpected request count estimate, key=%s got=%d expected=%d"
+	TCacheBadSparse            = "unexpected known empty state of %s, got=%t expected=%t"
+	TCacheBadSparseRemove      = "unexpected removed empty subtrees, got=%v expected=%v"
+	TCacheBadSketchAdmits      = "too many one-off keys estimated as repeated, %d of %d"
+	TCacheBadGeneration        = "unexpected active generation of instance %d, got=%s expected=%s"
+	TCacheBadJob               = "unexpected background job of cache, got=%v expected=%v"
+	TStreamBadCached           = "unexpected cached state of streamed tile (%s), got=%t expected=%t"
+	TStreamBadClosed           = "streamed tile upstream body not closed (%s)"
+	TStreamBadAborts           = "unexpected streamed tile client aborts (%s), got=%v expected=%v"
+	TAbortBadDetect            = "unexpected client gone state of request (%s), got=%t expected=%t"
+	TPeerBadSignature          = "unexpected peer signature verification (%s), got=%t expected=%t"
+	TStreamBadStreamed         = "unexpected streamed state of upstream tile %s, got=%t expected=%t"
+	TStreamBadClaim            = "stream of upstream tile %s not claimable exactly once"
+	TStreamBadBody             = "unexpected streamed body of upstream tile %s, got=%q"
+	TJWTBadVerify              = "token failed verification, alg=%s error=%s"
+	TJWTBadSubject             = "verified token subject did not match, got=%s expected=%s"
+	TJWTNoError                = "expected token to be rejected (%s), got no error"
+	TJWTBadFetches             = "unexpected number of key set fetches, got=%d expected=%d"
+	TBotsBadClass              = "unexpected class of user agent %q, got=%s expected=%s"
+	TBotsBadRateClass          = "unexpected class of request %d, got=%s expected=%s"
+	TGeoBadAllowed             = "unexpected country decision, country=%s allow=%v deny=%v got=%t"
+	TGeoBadCountry             = "unexpected country of %s, got=%s expected=%s"
+	THeatmapBadExport          = "unexpected heatmap export %+v"
+	THeatmapBadGeoJSON         = "unexpected heatmap GeoJSON %+v"
+	THeatmapBadPixel           = "unexpected heatmap pixel at %d,%d alpha=%d"
+	TWebhookBadHeader          = "unexpected webhook header %q"
+	TWebhookBadBatches         = "unexpected webhook batches %+v"
+	TWebhookBlocked            = "webhook emit blocked on a full queue"
+	TWebhookBadQueue           = "unexpected webhook queue length %d"
+	TAccessLogBadBatches       = "unexpected access log batches %+v"
+	TAccessLogNotClosed        = "access log sink not closed"
+	TAccessLogBlocked          = "access log emit blocked on a full queue"
+	TTimingUnexpected          = "unexpected timing breakdown attached to request"
+	TTimingBadSpent            = "unexpected time spent in phase %s: %s"
+	TTimingBadHeader           = "unexpected Server-Timing header, got=%q expected=%q"
+	TTimingBadOther            = "unexpected time spent outside of tracked phases: %s"
+	TFleetBadMembers           = "unexpected fleet members, got=%v expected=%v"
+	TStatsDBadLines            = "unexpected statsd lines, got=%q expected=%q"
+	TAccessLogBadQueue         = "unexpected access log queue length %d"
+	TCORSDebugBadRule          = "unexpected cors rule for origin %s: %q, expected %q"
+	TCORSDebugBadCaptures      = "unexpected cors captures %+v"
+	TScheduleBadMatch          = "unexpected match of %q at %s, got=%t expected=%t"
+	TScheduleBadNext           = "unexpected next window opening after %s, got=%s expected=%s"
+	TScheduleNoError           = "expected invalid expression error for %q, got none"
+	TMetadataBadFormat         = "unexpected detected metadata format, got=%s expected=%s"
+	TMetadataBadDocument       = "unexpected normalized %s metadata, got=%+v"
+	TSeedBadStart              = "seed job failed to start, error=%s"
+	TSeedBadProgress           = "unexpected seed job progress, got=%+v expected %d %s tiles"
+	TSeedNoError               = "expected invalid seed request error for %+v, got none"
+	TSeedBadCancel             = "unexpected seed job cancellation, cancelled=%t expected=%t"
+	TSeedBadCached             = "seeded tiles not cached in time, got=%d expected=%d"
+	TSeedBadCheckpoints        = "unexpected seed job checkpoints, got=%+v error=%v"
 )
 
 // Help message
 const Help = `
 Flags:
-  --conf  Path/URL to TOML configuration file. Default: config.toml
+  --conf  Path/URL to TOML, YAML or HCL configuration file. Default: config.toml
   --dev   Whether to enable developer mode. Default: false
+  --readonly Whether to serve from caches without writing to Redis. Default: false
   --debug Optional comma separated debug flags. Ex: foo,bar,baz
   --help  Shows this help menu.
 Usage:
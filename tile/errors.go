@@ -0,0 +1,25 @@
+package tile
+
+import "fmt"
+
+// ErrInvalidQuadkey is an error struct for a quadkey with digits
+// other than 0-3 or deeper than the tile pyramid
+type ErrInvalidQuadkey struct {
+	Quadkey string
+}
+
+// Error returns the string representation of ErrInvalidQuadkey
+func (e ErrInvalidQuadkey) Error() string {
+	return fmt.Sprintf("tile: invalid quadkey '%s'", e.Quadkey)
+}
+
+// ErrInvalidListEntry is an error struct for a tile list entry that isn't a
+// valid in-range z/x/y tile
+type ErrInvalidListEntry struct {
+	Line string
+}
+
+// Error returns the string representation of ErrInvalidListEntry
+func (e ErrInvalidListEntry) Error() string {
+	return fmt.Sprintf("tile: invalid tile list entry '%s', expected z/x/y or z,x,y", e.Line)
+}
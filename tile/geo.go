@@ -0,0 +1,186 @@
+package tile
+
+import (
+	"math"
+	"strings"
+)
+
+// MaxLat is the latitude limit of the Web Mercator projection in degrees,
+// beyond which no tile covers the globe
+const MaxLat = 85.0511287798066
+
+// edgeEpsilon keeps coordinates on the east and south edges of a bounding box
+// from covering the neighboring tiles they only touch
+const edgeEpsilon = 1e-9
+
+// Bounds is a bounding box in WGS84 longitude and latitude degrees
+type Bounds struct {
+	West  float64 `json:"west"`
+	South float64 `json:"south"`
+	East  float64 `json:"east"`
+	North float64 `json:"north"`
+}
+
+// Intersects returns true if the bounding boxes overlap by more than an edge
+func (b Bounds) Intersects(other Bounds) bool {
+	return b.West < other.East && other.West < b.East &&
+		b.South < other.North && other.South < b.North
+}
+
+// Bounds calculates the bounding box of the tile based on the tile's X and Y
+// value and zoom level
+func (t Tile) Bounds() Bounds {
+	// northwest corner of the tile
+	north, west := corner(t.XFloat(), t.YFloat(), t.ZoomFloat())
+	// northwest corner of the tile southeast of the tile, which is the
+	// southeast corner of the tile
+	south, east := corner(t.XFloat()+1, t.YFloat()+1, t.ZoomFloat())
+
+	return Bounds{West: west, South: south, East: east, North: north}
+}
+
+// Center returns the longitude and latitude of the center of the tile
+func (t Tile) Center() (float64, float64) {
+	lat, lng := corner(t.XFloat()+0.5, t.YFloat()+0.5, t.ZoomFloat())
+	return lng, lat
+}
+
+// FromLngLat returns the tile containing the given longitude and latitude at
+// the given zoom level, clamping coordinates outside the tile pyramid to it
+func FromLngLat(lng, lat float64, zoom int) Tile {
+	n := math.Exp2(float64(zoom))
+	lat = math.Max(-MaxLat, math.Min(MaxLat, lat))
+	latRad := lat * math.Pi / 180
+
+	x := int(math.Floor((lng + 180) / 360 * n))
+	y := int(math.Floor((1 - math.Asinh(math.Tan(latRad))/math.Pi) / 2 * n))
+
+	last := int(n) - 1
+	return Tile{X: clamp(x, 0, last), Y: clamp(y, 0, last), Zoom: zoom}
+}
+
+// Quadkey returns the Bing Maps quadkey of the tile, empty at zoom level 0
+func (t Tile) Quadkey() string {
+	key := strings.Builder{}
+	key.Grow(t.Zoom)
+
+	for z := t.Zoom; z > 0; z-- {
+		digit := '0'
+		mask := 1 << (z - 1)
+		if t.X&mask != 0 {
+			digit++
+		}
+		if t.Y&mask != 0 {
+			digit += 2
+		}
+		key.WriteRune(digit)
+	}
+
+	return key.String()
+}
+
+// FromQuadkey returns the tile addressed by the given Bing Maps quadkey
+func FromQuadkey(quadkey string) (Tile, error) {
+	if len(quadkey) > MaxZoom {
+		return Tile{}, ErrInvalidQuadkey{Quadkey: quadkey}
+	}
+
+	t := Tile{Zoom: len(quadkey)}
+	for i, digit := range quadkey {
+		mask := 1 << (t.Zoom - i - 1)
+		switch digit {
+		case '0':
+		case '1':
+			t.X |= mask
+		case '2':
+			t.Y |= mask
+		case '3':
+			t.X |= mask
+			t.Y |= mask
+		default:
+			return Tile{}, ErrInvalidQuadkey{Quadkey: quadkey}
+		}
+	}
+
+	return t, nil
+}
+
+// Cover returns all tiles at the given zoom level intersecting the bounding
+// box, ordered by row then column. Boxes whose west edge lies east of their
+// east edge cross the antimeridian.
+func Cover(b Bounds, zoom int) []Tile {
+	if b.West > b.East {
+		west := Cover(Bounds{West: b.West, South: b.South, East: 180, North: b.North}, zoom)
+		return append(west, Cover(Bounds{West: -180, South: b.South, East: b.East, North: b.North}, zoom)...)
+	}
+
+	nw, se := corners(b, zoom)
+
+	tiles := make([]Tile, 0, (se.X-nw.X+1)*(se.Y-nw.Y+1))
+	for y := nw.Y; y <= se.Y; y++ {
+		for x := nw.X; x <= se.X; x++ {
+			tiles = append(tiles, Tile{X: x, Y: y, Zoom: zoom})
+		}
+	}
+
+	return tiles
+}
+
+// CoverCount returns the number of tiles Cover returns for the bounding box
+// at the given zoom level, without building them
+func CoverCount(b Bounds, zoom int) int {
+	if b.West > b.East {
+		return CoverCount(Bounds{West: b.West, South: b.South, East: 180, North: b.North}, zoom) +
+			CoverCount(Bounds{West: -180, South: b.South, East: b.East, North: b.North}, zoom)
+	}
+
+	nw, se := corners(b, zoom)
+	return (se.X - nw.X + 1) * (se.Y - nw.Y + 1)
+}
+
+// corners returns the NW and SE tiles of a bounding box that doesn't cross
+// the antimeridian at the given zoom level
+func corners(b Bounds, zoom int) (Tile, Tile) {
+	nw := FromLngLat(b.West, b.North, zoom)
+	se := FromLngLat(math.Max(b.West, b.East-edgeEpsilon), math.Min(b.North, b.South+edgeEpsilon), zoom)
+	return nw, se
+}
+
+// CoverFunc returns the tile and all of its descendants up to the given zoom
+// level whose bounds satisfy intersects, such as an intersection test against
+// an arbitrary geometry. Descendants of tiles that don't intersect are skipped.
+func CoverFunc(t Tile, maxZoom int, intersects func(Bounds) bool) []Tile {
+	if !intersects(t.Bounds()) {
+		return nil
+	}
+
+	tiles := []Tile{t}
+	if t.Zoom >= maxZoom {
+		return tiles
+	}
+
+	for _, child := range t.Children() {
+		tiles = append(tiles, CoverFunc(child, maxZoom, intersects)...)
+	}
+
+	return tiles
+}
+
+// corner calculates the latitude and longitude of the NW corner of a tile
+func corner(x, y, zoom float64) (float64, float64) {
+	n := math.Pow(2.0, zoom)
+	lonDeg := x/n*360.0 - 180.0
+	latDeg := math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * (180 / math.Pi)
+	return latDeg, lonDeg
+}
+
+// clamp n to the range [low, high]
+func clamp(n, low, high int) int {
+	if n < low {
+		return low
+	}
+	if n > high {
+		return high
+	}
+	return n
+}
@@ -0,0 +1,18 @@
+// Package geom covers arbitrary geometries with tiles using GEOS. It is kept
+// apart from the tile package so users of the tile helpers don't need cgo.
+package geom
+
+import (
+	"github.com/twpayne/go-geos"
+
+	"github.com/dechristopher/lod/tile"
+)
+
+// Cover returns the given tile and all of its descendants up to the given zoom
+// level that intersect the geometry, in WGS84 longitude and latitude
+func Cover(geometry *geos.Geom, t tile.Tile, maxZoom int) []tile.Tile {
+	return tile.CoverFunc(t, maxZoom, func(b tile.Bounds) bool {
+		box := geos.NewGeomFromBounds(geos.NewBounds(b.West, b.South, b.East, b.North))
+		return geometry.Intersects(box)
+	})
+}
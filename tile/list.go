@@ -0,0 +1,126 @@
+package tile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ListEntry is a single tile of a tile list, along with the query parameters
+// it is requested with
+type ListEntry struct {
+	Tile  Tile
+	Query string // encoded query parameters, ex: osm_id=123&lang=en
+}
+
+// Path returns the "z/x/y" path of the entry with its query parameters
+func (e ListEntry) Path() string {
+	path := fmt.Sprintf("%d/%d/%d", e.Tile.Zoom, e.Tile.X, e.Tile.Y)
+	if e.Query == "" {
+		return path
+	}
+	return path + "?" + e.Query
+}
+
+// ReadList reads the non-empty, non-comment lines of a tile list, skipping
+// the header row of CSV lists
+func ReadList(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(lines) == 0 && strings.EqualFold(strings.SplitN(line, ",", 2)[0], "z") {
+			continue
+		}
+		lines = append(lines, strings.TrimPrefix(line, "/"))
+	}
+	return lines, scanner.Err()
+}
+
+// ParseListEntry parses a tile list line, either a "z/x/y" path with optional
+// file extension and query parameters, ex: 4/5/6.pbf?osm_id=123, or a CSV row
+// of z,x,y followed by optional name=value parameter columns
+func ParseListEntry(line string) (ListEntry, error) {
+	var coords []string
+	query := url.Values{}
+
+	if strings.Contains(line, ",") {
+		columns := strings.Split(line, ",")
+		if len(columns) < 3 {
+			return ListEntry{}, ErrInvalidListEntry{Line: line}
+		}
+		coords = columns[:3]
+		for _, column := range columns[3:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(column), "=")
+			if !ok || name == "" {
+				return ListEntry{}, ErrInvalidListEntry{Line: line}
+			}
+			query.Add(name, value)
+		}
+	} else {
+		path, rawQuery, _ := strings.Cut(line, "?")
+		parsed, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return ListEntry{}, ErrInvalidListEntry{Line: line}
+		}
+		query = parsed
+
+		coords = strings.Split(path, "/")
+		if len(coords) != 3 {
+			return ListEntry{}, ErrInvalidListEntry{Line: line}
+		}
+		coords[2], _, _ = strings.Cut(coords[2], ".")
+	}
+
+	n := [3]int{}
+	for i, coord := range coords {
+		c, err := strconv.Atoi(strings.TrimSpace(coord))
+		if err != nil {
+			return ListEntry{}, ErrInvalidListEntry{Line: line}
+		}
+		n[i] = c
+	}
+
+	t := Tile{Zoom: n[0], X: n[1], Y: n[2]}
+	if !t.InRange() {
+		return ListEntry{}, ErrInvalidListEntry{Line: line}
+	}
+	return ListEntry{Tile: t, Query: query.Encode()}, nil
+}
+
+// ExpandMatrix returns the given tile list entries once for every combination
+// of param values in the given matrix, ex: a proxy's param matrix. Params set
+// explicitly by an entry keep their value rather than being enumerated.
+func ExpandMatrix(entries []ListEntry, matrix []map[string]string) []ListEntry {
+	expanded := make([]ListEntry, 0, len(entries)*len(matrix))
+	for _, entry := range entries {
+		query, _ := url.ParseQuery(entry.Query)
+
+		seen := make(map[string]bool, len(matrix))
+		for _, combination := range matrix {
+			values := url.Values{}
+			for name, value := range query {
+				values[name] = value
+			}
+			for name, value := range combination {
+				if !query.Has(name) {
+					values.Set(name, value)
+				}
+			}
+
+			encoded := values.Encode()
+			if seen[encoded] {
+				continue
+			}
+			seen[encoded] = true
+			expanded = append(expanded, ListEntry{Tile: entry.Tile, Query: encoded})
+		}
+	}
+	return expanded
+}
@@ -0,0 +1,56 @@
+package tile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestReadList will test that comments, blank lines and CSV headers are
+// skipped when reading tile lists
+func TestReadList(t *testing.T) {
+	list := "z,x,y,osm_id\n# hot tiles\n\n/4/5/6\n1,0,1,osm_id=3\n"
+	expected := []string{"4/5/6", "1,0,1,osm_id=3"}
+
+	lines, err := ReadList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf(str.TTileBadList, lines, expected)
+	}
+}
+
+// TestParseListEntry will test that path and CSV tile list entries parse to
+// the same tiles and parameters, and that invalid entries are rejected
+func TestParseListEntry(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected ListEntry
+		valid    bool
+	}{
+		{"4/5/6", ListEntry{Tile: Tile{Zoom: 4, X: 5, Y: 6}}, true},
+		{"4/5/6.pbf?osm_id=123", ListEntry{Tile: Tile{Zoom: 4, X: 5, Y: 6}, Query: "osm_id=123"}, true},
+		{"4,5,6", ListEntry{Tile: Tile{Zoom: 4, X: 5, Y: 6}}, true},
+		{"4, 5, 6, osm_id=123, lang=en", ListEntry{Tile: Tile{Zoom: 4, X: 5, Y: 6}, Query: "lang=en&osm_id=123"}, true},
+		{"4/5", ListEntry{}, false},
+		{"4,5", ListEntry{}, false},
+		{"4,5,6,osm_id", ListEntry{}, false},
+		{"1/2/0", ListEntry{}, false},
+		{"a/b/c", ListEntry{}, false},
+	}
+
+	for _, test := range tests {
+		entry, err := ParseListEntry(test.line)
+		if (err == nil) != test.valid || entry != test.expected {
+			t.Errorf(str.TTileBadListEntry, test.line, entry, err, test.expected)
+		}
+	}
+
+	entry := ListEntry{Tile: Tile{Zoom: 4, X: 5, Y: 6}, Query: "osm_id=123"}
+	if path := entry.Path(); path != "4/5/6?osm_id=123" {
+		t.Errorf(str.TTileBadList, path, "4/5/6?osm_id=123")
+	}
+}
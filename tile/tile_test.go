@@ -0,0 +1,164 @@
+package tile
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// boundsEqual compares bounding boxes within floating point error
+func boundsEqual(a, b Bounds) bool {
+	const tolerance = 1e-9
+	return math.Abs(a.West-b.West) < tolerance && math.Abs(a.South-b.South) < tolerance &&
+		math.Abs(a.East-b.East) < tolerance && math.Abs(a.North-b.North) < tolerance
+}
+
+// TestBounds will test that tile bounds match the Web Mercator tile grid
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		tile     Tile
+		expected Bounds
+	}{
+		{Tile{Zoom: 0}, Bounds{West: -180, South: -MaxLat, East: 180, North: MaxLat}},
+		{Tile{X: 1, Y: 0, Zoom: 1}, Bounds{West: 0, South: 0, East: 180, North: MaxLat}},
+		{Tile{X: 0, Y: 1, Zoom: 1}, Bounds{West: -180, South: -MaxLat, East: 0, North: 0}},
+	}
+
+	for _, test := range tests {
+		if got := test.tile.Bounds(); !boundsEqual(got, test.expected) {
+			t.Errorf(str.TTileBadBounds, got, test.expected)
+		}
+	}
+}
+
+// TestFromLngLat will test that coordinates map to the tiles containing them,
+// including tile centers, edges and coordinates outside the tile pyramid
+func TestFromLngLat(t *testing.T) {
+	tests := []struct {
+		lng, lat float64
+		zoom     int
+		expected Tile
+	}{
+		{0, 0, 0, Tile{Zoom: 0}},
+		{-73.9857, 40.7484, 10, Tile{X: 301, Y: 384, Zoom: 10}},
+		{139.6917, 35.6895, 12, Tile{X: 3637, Y: 1612, Zoom: 12}},
+		{180, -90, 2, Tile{X: 3, Y: 3, Zoom: 2}},
+		{-180, 90, 2, Tile{X: 0, Y: 0, Zoom: 2}},
+		{0, 0, 1, Tile{X: 1, Y: 1, Zoom: 1}},
+	}
+
+	for _, test := range tests {
+		if got := FromLngLat(test.lng, test.lat, test.zoom); got != test.expected {
+			t.Errorf(str.TTileBadTile, got, test.expected)
+		}
+	}
+
+	// the center of every tile maps back to the tile
+	for _, tile := range (Tile{X: 5, Y: 9, Zoom: 4}).DeepChildren(7) {
+		lng, lat := tile.Center()
+		if got := FromLngLat(lng, lat, tile.Zoom); got != tile {
+			t.Errorf(str.TTileBadTile, got, tile)
+		}
+	}
+}
+
+// TestQuadkey will test quadkey encoding and decoding round trips
+func TestQuadkey(t *testing.T) {
+	tests := []struct {
+		tile    Tile
+		quadkey string
+	}{
+		{Tile{Zoom: 0}, ""},
+		{Tile{X: 1, Y: 0, Zoom: 1}, "1"},
+		{Tile{X: 3, Y: 5, Zoom: 3}, "213"},
+		{Tile{X: 301, Y: 384, Zoom: 10}, "0320101101"},
+	}
+
+	for _, test := range tests {
+		if got := test.tile.Quadkey(); got != test.quadkey {
+			t.Errorf(str.TTileBadQuadkey, got, test.quadkey)
+		}
+
+		got, err := FromQuadkey(test.quadkey)
+		if err != nil || got != test.tile {
+			t.Errorf(str.TTileBadTile, got, test.tile)
+		}
+	}
+
+	for _, quadkey := range []string{"4", "01a", "0123012301230123012301230123012"} {
+		if _, err := FromQuadkey(quadkey); err == nil {
+			t.Errorf(str.TTileNoError, quadkey)
+		}
+	}
+}
+
+// TestParentChildren will test that tiles are the parent of their children
+func TestParentChildren(t *testing.T) {
+	parent := Tile{X: 301, Y: 384, Zoom: 10}
+
+	for _, child := range parent.Children() {
+		if got, ok := child.Parent(); !ok || got != parent {
+			t.Errorf(str.TTileBadTile, got, parent)
+		}
+		if !parent.Bounds().Intersects(child.Bounds()) {
+			t.Errorf(str.TTileBadBounds, child.Bounds(), parent.Bounds())
+		}
+	}
+
+	if got, ok := (Tile{Zoom: 0}).Parent(); ok {
+		t.Errorf(str.TTileBadTile, got, "none")
+	}
+
+	if got := len(parent.DeepChildren(12)); got != 1+4+16 {
+		t.Errorf(str.TTileBadCover, got, 1+4+16)
+	}
+}
+
+// TestCover will test bounding box covers, including boxes matching tile
+// edges exactly and boxes crossing the antimeridian
+func TestCover(t *testing.T) {
+	tile := Tile{X: 301, Y: 384, Zoom: 10}
+
+	if got := Cover(tile.Bounds(), tile.Zoom); !reflect.DeepEqual(got, []Tile{tile}) {
+		t.Errorf(str.TTileBadCover, got, []Tile{tile})
+	}
+
+	children := tile.Children()
+	if got := Cover(tile.Bounds(), tile.Zoom+1); !reflect.DeepEqual(got, children[:]) {
+		t.Errorf(str.TTileBadCover, got, children)
+	}
+
+	expected := []Tile{
+		{X: 1, Y: 0, Zoom: 1}, {X: 1, Y: 1, Zoom: 1},
+		{X: 0, Y: 0, Zoom: 1}, {X: 0, Y: 1, Zoom: 1},
+	}
+	antimeridian := Bounds{West: 170, South: -10, East: -170, North: 10}
+	if got := Cover(antimeridian, 1); !reflect.DeepEqual(got, expected) {
+		t.Errorf(str.TTileBadCover, got, expected)
+	}
+
+	for zoom := 0; zoom <= 6; zoom++ {
+		if got, want := CoverCount(antimeridian, zoom), len(Cover(antimeridian, zoom)); got != want {
+			t.Errorf(str.TTileBadCover, got, want)
+		}
+	}
+}
+
+// TestCoverFunc will test that descendants of tiles failing the predicate are
+// skipped
+func TestCoverFunc(t *testing.T) {
+	target := Bounds{West: 1, South: 1, East: 2, North: 2}
+
+	got := CoverFunc(Tile{Zoom: 0}, 3, target.Intersects)
+	expected := []Tile{
+		{X: 0, Y: 0, Zoom: 0},
+		{X: 1, Y: 0, Zoom: 1},
+		{X: 2, Y: 1, Zoom: 2},
+		{X: 4, Y: 3, Zoom: 3},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf(str.TTileBadCover, got, expected)
+	}
+}
@@ -0,0 +1,119 @@
+// Package timing breaks down the time taken to handle a request into the
+// phases it was spent in, for slow request logging and Server-Timing headers
+package timing
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// Phase is a part of handling a request whose time is tracked
+type Phase int
+
+// Phases of handling a request
+const (
+	Cache    Phase = iota // in-memory cache lookups
+	Redis                 // redis cache lookups
+	Upstream              // waiting for the upstream response
+	Encode                // validating and preparing upstream tiles for the response and cache
+	Write                 // writing the tile and its headers to the response
+	phases
+)
+
+// names of the phases, in order
+var names = [phases]string{"cache", "redis", "upstream", "encode", "write"}
+
+// String returns the name of the phase
+func (p Phase) String() string {
+	return names[p]
+}
+
+// Breakdown is the time a request spent in each phase. Requests are handled
+// on a single goroutine, so a breakdown is not safe for concurrent use.
+type Breakdown struct {
+	spent [phases]time.Duration
+}
+
+// Start attaches a new breakdown to the request
+func Start(ctx *fiber.Ctx) *Breakdown {
+	b := &Breakdown{}
+	ctx.Locals(str.LocalTiming, b)
+	return b
+}
+
+// Get returns the breakdown attached to the request, nil if there is none or
+// the work is done outside of a request
+func Get(ctx *fiber.Ctx) *Breakdown {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Locals(str.LocalTiming).(*Breakdown)
+	return b
+}
+
+// Track starts timing a phase of the request, returning a function that
+// stops it, ex: defer timing.Track(ctx, timing.Redis)()
+func Track(ctx *fiber.Ctx, phase Phase) func() {
+	b := Get(ctx)
+	if b == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		b.spent[phase] += time.Since(start)
+	}
+}
+
+// Add counts time spent in a phase of the request, for phases timed outside
+// of the request's goroutine
+func Add(ctx *fiber.Ctx, phase Phase, d time.Duration) {
+	if b := Get(ctx); b != nil {
+		b.spent[phase] += d
+	}
+}
+
+// Spent returns the time spent in the given phase
+func (b *Breakdown) Spent(phase Phase) time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.spent[phase]
+}
+
+// ServerTiming formats the breakdown as a Server-Timing header value, with
+// durations in milliseconds and the total time taken last, ex:
+// cache;dur=0.012, redis;dur=1.3, upstream;dur=0, encode;dur=0, write;dur=0.004, total;dur=1.5
+func (b *Breakdown) ServerTiming(total time.Duration) string {
+	var header strings.Builder
+	for phase := Phase(0); phase < phases; phase++ {
+		header.WriteString(phase.String())
+		header.WriteString(";dur=")
+		header.WriteString(milliseconds(b.Spent(phase)))
+		header.WriteString(", ")
+	}
+	header.WriteString("total;dur=")
+	header.WriteString(milliseconds(total))
+	return header.String()
+}
+
+// milliseconds formats a duration in milliseconds with microsecond precision
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
+
+// Other returns the time of the total not spent in any tracked phase
+func (b *Breakdown) Other(total time.Duration) time.Duration {
+	for phase := Phase(0); phase < phases; phase++ {
+		total -= b.Spent(phase)
+	}
+	if total < 0 {
+		return 0
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package timing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/str"
+)
+
+// TestBreakdown will test that tracked phases accumulate into the breakdown
+// attached to the request, and that requests without one are left alone
+func TestBreakdown(t *testing.T) {
+	app := fiber.New()
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// tracking without a breakdown is a no-op
+	Track(ctx, Redis)()
+	if Get(ctx) != nil {
+		t.Fatal(str.TTimingUnexpected)
+	}
+
+	b := Start(ctx)
+	for i := 0; i < 2; i++ {
+		stop := Track(ctx, Redis)
+		time.Sleep(5 * time.Millisecond)
+		stop()
+	}
+
+	if spent := b.Spent(Redis); spent < 10*time.Millisecond {
+		t.Errorf(str.TTimingBadSpent, Redis, spent)
+	}
+	if spent := b.Spent(Upstream); spent != 0 {
+		t.Errorf(str.TTimingBadSpent, Upstream, spent)
+	}
+	if other := b.Other(time.Second); other != time.Second-b.Spent(Redis) {
+		t.Errorf(str.TTimingBadOther, other)
+	}
+	if other := b.Other(0); other != 0 {
+		t.Errorf(str.TTimingBadOther, other)
+	}
+}
+
+// TestServerTiming will test that breakdowns are formatted as Server-Timing
+// header values in milliseconds
+func TestServerTiming(t *testing.T) {
+	b := &Breakdown{}
+	b.spent[Redis] = 1500 * time.Microsecond
+	b.spent[Write] = 12 * time.Microsecond
+
+	expected := "cache;dur=0, redis;dur=1.5, upstream;dur=0, encode;dur=0, write;dur=0.012, total;dur=2"
+	if header := b.ServerTiming(2 * time.Millisecond); header != expected {
+		t.Errorf(str.TTimingBadHeader, header, expected)
+	}
+}
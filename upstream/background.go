@@ -0,0 +1,89 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Background limits the concurrency and rate of the upstream requests made
+// by a proxy's background jobs, ex: priming and warm-up, so they cannot
+// degrade requests served to clients however many jobs run at once
+type Background struct {
+	name  string
+	slots chan struct{} // held by requests in flight, nil if unlimited
+
+	mu       sync.Mutex
+	interval time.Duration // time between requests at the rate limit, 0 if unlimited
+	burst    int           // requests allowed at once before being paced
+	next     time.Time     // theoretical arrival time of the next request
+}
+
+// GetBackground gets the background pool by proxy name, nil if the proxy
+// isn't configured
+func GetBackground(name string) *Background {
+	return load().backgrounds[name]
+}
+
+// newBackground builds a background pool from the proxy's configuration
+func newBackground(proxy *config.Proxy) *Background {
+	b := &Background{
+		name:  proxy.Name,
+		burst: proxy.Background.Burst,
+	}
+	if proxy.Background.Workers > 0 {
+		b.slots = make(chan struct{}, proxy.Background.Workers)
+	}
+	if proxy.Background.RateLimit > 0 {
+		b.interval = time.Duration(float64(time.Second) / proxy.Background.RateLimit)
+	}
+	return b
+}
+
+// Wrap returns fn run within the pool's concurrency and rate limits. A nil
+// pool returns fn as is.
+func (b *Background) Wrap(fn func() (interface{}, error)) func() (interface{}, error) {
+	if b == nil {
+		return fn
+	}
+
+	return func() (interface{}, error) {
+		if wait := b.reserve(time.Now()); wait > 0 {
+			metrics.backgroundThrottled.WithLabelValues(b.name).Add(wait.Seconds())
+			time.Sleep(wait)
+		}
+
+		if b.slots != nil {
+			b.slots <- struct{}{}
+			defer func() { <-b.slots }()
+		}
+		metrics.backgroundActive.WithLabelValues(b.name).Inc()
+		defer metrics.backgroundActive.WithLabelValues(b.name).Dec()
+
+		return fn()
+	}
+}
+
+// reserve returns how long a request made at the given time must wait to
+// stay within the rate limit, allowing bursts of up to burst requests
+func (b *Background) reserve(now time.Time) time.Duration {
+	if b.interval == 0 {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	arrival := b.next
+	if arrival.Before(now) {
+		arrival = now
+	}
+	b.next = arrival.Add(b.interval)
+
+	wait := arrival.Sub(now) - time.Duration(b.burst-1)*b.interval
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,69 @@
+package upstream
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestBackgroundRate will test that background requests are paced at the
+// rate limit once a burst is spent
+func TestBackgroundRate(t *testing.T) {
+	b := newBackground(&config.Proxy{
+		Name:       "test",
+		Background: config.Background{Workers: 1, RateLimit: 10, Burst: 2},
+	})
+
+	now := time.Now()
+	expected := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i, wait := range expected {
+		if got := b.reserve(now); got != wait {
+			t.Errorf(str.TUpstreamBadBackgroundWait, i, got, wait)
+		}
+	}
+
+	// requests pace from the last one once the limit has caught up
+	later := now.Add(time.Second)
+	if got := b.reserve(later); got != 0 {
+		t.Errorf(str.TUpstreamBadBackgroundWait, len(expected), got, time.Duration(0))
+	}
+}
+
+// TestBackgroundWorkers will test that no more background requests than the
+// configured workers are in flight at once
+func TestBackgroundWorkers(t *testing.T) {
+	b := newBackground(&config.Proxy{
+		Name:       "test",
+		Background: config.Background{Workers: 2, Burst: 1},
+	})
+
+	var active, peak atomic.Int32
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = b.Wrap(func() (interface{}, error) {
+				n := active.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				active.Add(-1)
+				return nil, nil
+			})()
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf(str.TUpstreamBadBackgroundPeak, got, 2)
+	}
+}
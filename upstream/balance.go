@@ -0,0 +1,146 @@
+package upstream
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// ewmaDecay is the weight given to each new latency sample in the moving average
+const ewmaDecay = 0.3
+
+// Pick selects an upstream target using the proxy's balancing strategy and
+// marks a request to it as in flight. Returns nil if the pool is nil or has
+// no targets yet. Every picked target must be released with Done.
+func (p *Pool) Pick() *Target {
+	if p == nil {
+		return nil
+	}
+
+	targets := ramping(healthy(p.Targets()), p.Proxy.Upstream.SlowStartDuration)
+	if len(targets) == 0 {
+		return nil
+	}
+
+	var target *Target
+	switch p.Proxy.Upstream.Strategy {
+	case config.StrategyLeastInflight:
+		target = p.pickLeastInflight(targets)
+	case config.StrategyWeighted:
+		target = p.pickWeighted(targets)
+	case config.StrategyEWMA:
+		target = p.pickEWMA(targets)
+	default:
+		target = p.pickRoundRobin(targets)
+	}
+
+	atomic.AddInt64(&target.inflight, 1)
+	metrics.inflight.WithLabelValues(p.Proxy.Name, target.Addr).Inc()
+
+	return target
+}
+
+// Done releases a target picked for a request, recording its latency and
+// whether the request failed
+func (p *Pool) Done(target *Target, latency time.Duration, failed bool) {
+	if target == nil {
+		return
+	}
+
+	atomic.AddInt64(&target.inflight, -1)
+	target.observe(latency)
+
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+
+	metrics.inflight.WithLabelValues(p.Proxy.Name, target.Addr).Dec()
+	metrics.requests.WithLabelValues(p.Proxy.Name, target.Addr, result).Inc()
+	metrics.latency.WithLabelValues(p.Proxy.Name, target.Addr).Observe(latency.Seconds())
+}
+
+// pickRoundRobin cycles through targets in order
+func (p *Pool) pickRoundRobin(targets []*Target) *Target {
+	next := atomic.AddUint64(&p.next, 1)
+	return targets[next%uint64(len(targets))]
+}
+
+// pickLeastInflight picks the target with the fewest in-flight requests,
+// rotating the starting point so ties are spread across targets
+func (p *Pool) pickLeastInflight(targets []*Target) *Target {
+	start := int(atomic.AddUint64(&p.next, 1) % uint64(len(targets)))
+
+	best := targets[start]
+	for i := 1; i < len(targets); i++ {
+		target := targets[(start+i)%len(targets)]
+		if atomic.LoadInt64(&target.inflight) < atomic.LoadInt64(&best.inflight) {
+			best = target
+		}
+	}
+
+	return best
+}
+
+// pickWeighted distributes picks proportionally to target weights using
+// smooth weighted round-robin, avoiding bursts to heavily weighted targets
+func (p *Pool) pickWeighted(targets []*Target) *Target {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *Target
+	total := 0
+	for _, target := range targets {
+		target.current += target.Weight
+		total += target.Weight
+		if best == nil || target.current > best.current {
+			best = target
+		}
+	}
+	best.current -= total
+
+	return best
+}
+
+// pickEWMA picks the target with the lowest latency moving average scaled by
+// its in-flight requests. Targets without samples are picked first.
+func (p *Pool) pickEWMA(targets []*Target) *Target {
+	start := int(atomic.AddUint64(&p.next, 1) % uint64(len(targets)))
+
+	var best *Target
+	bestScore := math.Inf(1)
+	for i := 0; i < len(targets); i++ {
+		target := targets[(start+i)%len(targets)]
+		score := target.latency() * float64(atomic.LoadInt64(&target.inflight)+1)
+		if score < bestScore {
+			best, bestScore = target, score
+		}
+	}
+
+	return best
+}
+
+// latency returns the target's latency moving average in seconds
+func (t *Target) latency() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&t.ewma))
+}
+
+// observe folds a latency sample into the target's moving average
+func (t *Target) observe(latency time.Duration) {
+	sample := latency.Seconds()
+	for {
+		old := atomic.LoadUint64(&t.ewma)
+		avg := math.Float64frombits(old)
+		if avg == 0 {
+			avg = sample
+		} else {
+			avg = avg*(1-ewmaDecay) + sample*ewmaDecay
+		}
+
+		if atomic.CompareAndSwapUint64(&t.ewma, old, math.Float64bits(avg)) {
+			return
+		}
+	}
+}
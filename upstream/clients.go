@@ -0,0 +1,138 @@
+package upstream
+
+import (
+	"sync"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// clientKey identifies a shared client by proxy, upstream host, the balanced
+// target dialed if any, and whether it streams response bodies
+type clientKey struct {
+	proxy  string
+	addr   string
+	target string
+	stream bool
+}
+
+// clients holds the shared clients keeping connections to upstream hosts
+// alive across requests, rebuilt on config reloads
+var clients = struct {
+	mu sync.Mutex
+	m  map[clientKey]*fasthttp.HostClient
+}{m: make(map[clientKey]*fasthttp.HostClient)}
+
+// Client returns the shared client holding keep-alive connections to the
+// proxy's upstream host, dialing the balanced target if one is given. The
+// client parsed by a request agent supplies the host address and TLS mode.
+func Client(proxy *config.Proxy, parsed *fasthttp.HostClient, target *Target, stream bool) *fasthttp.HostClient {
+	key := clientKey{proxy: proxy.Name, addr: parsed.Addr, stream: stream}
+	if target != nil {
+		key.target = target.Addr
+	}
+
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+
+	if client, ok := clients.m[key]; ok {
+		return client
+	}
+
+	pool := proxy.Upstream.Pool
+	client := &fasthttp.HostClient{
+		Addr:                     parsed.Addr,
+		Name:                     parsed.Name,
+		NoDefaultUserAgentHeader: parsed.NoDefaultUserAgentHeader,
+		IsTLS:                    parsed.IsTLS,
+		TLSConfig:                proxy.Upstream.TLSConfig,
+		Dial:                     proxy.Upstream.Dial,
+		MaxConns:                 pool.MaxConns,
+		MaxIdleConnDuration:      pool.IdleTimeoutDuration,
+		MaxConnDuration:          pool.MaxLifetimeDuration,
+		MaxConnWaitTimeout:       pool.MaxWaitDuration,
+		StreamResponseBody:       stream,
+	}
+	if target != nil {
+		client.Dial = target.Dial
+	} else if client.Dial == nil {
+		client.Dial = Dialer(proxy)
+	}
+
+	clients.m[key] = client
+	return client
+}
+
+// resetClients drops all shared clients so requests pick up reloaded
+// settings, closing their idle connections. Requests in flight on dropped
+// clients complete normally.
+func resetClients() {
+	clients.mu.Lock()
+	prev := clients.m
+	clients.m = make(map[clientKey]*fasthttp.HostClient)
+	clients.mu.Unlock()
+
+	for _, client := range prev {
+		client.CloseIdleConnections()
+	}
+}
+
+// dropClients drops the shared clients of the proxy by name, closing their
+// idle connections
+func dropClients(name string) {
+	dropClientsWhere(func(key clientKey) bool { return key.proxy == name })
+}
+
+// dropClientsWhere drops the shared clients whose keys match, closing their
+// idle connections. Requests in flight on dropped clients complete normally.
+func dropClientsWhere(match func(clientKey) bool) {
+	var dropped []*fasthttp.HostClient
+
+	clients.mu.Lock()
+	for key, client := range clients.m {
+		if match(key) {
+			dropped = append(dropped, client)
+			delete(clients.m, key)
+		}
+	}
+	clients.mu.Unlock()
+
+	for _, client := range dropped {
+		client.CloseIdleConnections()
+	}
+}
+
+// hostConns counts the connections of shared clients to a single upstream
+// host or target of a proxy
+type hostConns struct {
+	proxy    string
+	host     string
+	open     int // open connections, idle or in use
+	inflight int // requests in flight or waiting for a free connection
+}
+
+// connStats sums the connections of shared clients per proxy and upstream
+// host or target, across their streaming and buffering clients
+func connStats() map[[2]string]*hostConns {
+	clients.mu.Lock()
+	defer clients.mu.Unlock()
+
+	stats := make(map[[2]string]*hostConns, len(clients.m))
+	for key, client := range clients.m {
+		host := key.addr
+		if key.target != "" {
+			host = key.target
+		}
+
+		conns, ok := stats[[2]string{key.proxy, host}]
+		if !ok {
+			conns = &hostConns{proxy: key.proxy, host: host}
+			stats[[2]string{key.proxy, host}] = conns
+		}
+		conns.open += client.ConnsCount()
+		conns.inflight += client.PendingRequests()
+	}
+
+	return stats
+}
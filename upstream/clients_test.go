@@ -0,0 +1,106 @@
+package upstream
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestClientKeepAlive will test that requests through the shared client of
+// an upstream reuse a single connection, and that reloads drop the client
+func TestClientKeepAlive(t *testing.T) {
+	var dialed int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("tile"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&dialed, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	proxy := &config.Proxy{Name: "keepalive", Upstream: config.Upstream{Pool: config.ConnPool{
+		MaxConns:            4,
+		IdleTimeoutDuration: time.Minute,
+	}}}
+	parsed := &fasthttp.HostClient{Addr: server.Listener.Addr().String()}
+
+	resetClients()
+	defer resetClients()
+
+	for i := 0; i < 3; i++ {
+		client := Client(proxy, parsed, nil, false)
+		code, _, err := client.Get(nil, server.URL)
+		if err != nil || code != fasthttp.StatusOK {
+			t.Fatalf(str.TUpstreamBadConns, err, nil)
+		}
+	}
+
+	if got := atomic.LoadInt32(&dialed); got != 1 {
+		t.Fatalf(str.TUpstreamBadConns, got, 1)
+	}
+
+	expected := hostConns{proxy: proxy.Name, host: parsed.Addr, open: 1}
+	stats := connStats()
+	if len(stats) != 1 || *stats[[2]string{proxy.Name, parsed.Addr}] != expected {
+		t.Fatalf(str.TUpstreamBadConns, stats, expected)
+	}
+
+	// reloads drop shared clients and close their idle connections
+	client := Client(proxy, parsed, nil, false)
+	resetClients()
+	if client.ConnsCount() != 0 || Client(proxy, parsed, nil, false) == client {
+		t.Fatalf(str.TUpstreamBadConns, client.ConnsCount(), 0)
+	}
+}
+
+// TestClientTargetRemoved will test that targets vanishing from resolution
+// drop their shared clients and health gauges, keeping those of the rest
+func TestClientTargetRemoved(t *testing.T) {
+	pool := testPool(config.StrategyRoundRobin,
+		config.Server{Addr: "10.0.0.1:80", Weight: 1},
+		config.Server{Addr: "10.0.0.2:80", Weight: 1})
+	parsed := &fasthttp.HostClient{Addr: "tiles.invalid:80"}
+
+	resetClients()
+	defer resetClients()
+
+	kept, removed := pool.targets[0], pool.targets[1]
+	client := Client(pool.Proxy, parsed, kept, false)
+	Client(pool.Proxy, parsed, removed, false)
+	for _, target := range pool.targets {
+		metrics.healthy.WithLabelValues(pool.Proxy.Name, target.Addr).Set(1)
+	}
+
+	pool.update([]*Target{{Addr: kept.Addr, Weight: 1}})
+
+	if targets := pool.Targets(); len(targets) != 1 || targets[0] != kept {
+		t.Fatalf(str.TUpstreamBadConns, targets, []*Target{kept})
+	}
+
+	stats := connStats()
+	if len(stats) != 1 || stats[[2]string{pool.Proxy.Name, kept.Addr}] == nil {
+		t.Fatalf(str.TUpstreamBadConns, stats, kept.Addr)
+	}
+	if Client(pool.Proxy, parsed, kept, false) != client {
+		t.Fatalf(str.TUpstreamBadConns, "replaced client", kept.Addr)
+	}
+
+	// deleting reports whether the gauge was still present
+	if metrics.healthy.DeleteLabelValues(pool.Proxy.Name, removed.Addr) {
+		t.Fatalf(str.TUpstreamBadConns, "stale health gauge", removed.Addr)
+	}
+	if !metrics.healthy.DeleteLabelValues(pool.Proxy.Name, kept.Addr) {
+		t.Fatalf(str.TUpstreamBadConns, "missing health gauge", kept.Addr)
+	}
+}
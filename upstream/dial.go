@@ -0,0 +1,147 @@
+package upstream
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/dnscache"
+)
+
+// dialTimeout bounds resolving and connecting to an upstream address,
+// matching the default of fasthttp clients
+const dialTimeout = fasthttp.DefaultDialTimeout
+
+// dialResult is the outcome of a single connection attempt
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// Dialer returns the dialer of direct connections to the proxy's upstream.
+// Hostnames are resolved through the DNS cache if enabled, and their IPv4
+// and IPv6 addresses raced with Happy Eyeballs (RFC 8305), starting with the
+// preferred family, so single-stack upstreams of either family are reachable.
+func Dialer(proxy *config.Proxy) fasthttp.DialFunc {
+	prefer := proxy.Upstream.PreferIP
+	delay := proxy.Upstream.FallbackDelayDuration
+
+	return func(addr string) (net.Conn, error) {
+		return dial(addr, prefer, delay)
+	}
+}
+
+// dial connects to the first address of the host to accept a connection,
+// starting an attempt on the next address whenever the previous fails or
+// hasn't connected within the fallback delay
+func dial(addr, prefer string, delay time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// bounds the whole dial, and aborts losing attempts once one connects
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	ips, err := dnscache.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host}
+	}
+	ips = sortIPs(ips, prefer)
+
+	results := make(chan dialResult, len(ips))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	next, pending := 0, 0
+	start := func() {
+		ip := ips[next]
+		go func() {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+			results <- dialResult{conn: conn, err: err}
+		}()
+		next++
+		pending++
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+	}
+
+	start()
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				go closeLate(results, pending)
+				return result.conn, nil
+			}
+			err = result.err
+
+			// failures start the next attempt without waiting out the delay
+			if next < len(ips) {
+				start()
+			}
+		case <-timer.C:
+			if next < len(ips) {
+				start()
+			}
+		}
+	}
+
+	return nil, err
+}
+
+// closeLate closes the connections of attempts still pending once another
+// attempt has won the race
+func closeLate(results chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		if result := <-results; result.err == nil {
+			_ = result.conn.Close()
+		}
+	}
+}
+
+// sortIPs orders addresses for dialing, alternating between address families
+// starting with the preferred one while keeping the resolved order within
+// each family
+func sortIPs(ips []string, prefer string) []string {
+	var v4, v6 []string
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			v6 = append(v6, ip)
+		} else {
+			v4 = append(v4, ip)
+		}
+	}
+
+	primary, secondary := v4, v6
+	if prefer == config.PreferIPv6 {
+		primary, secondary = v6, v4
+	}
+
+	sorted := make([]string, 0, len(ips))
+	for i := 0; i < len(primary) || i < len(secondary); i++ {
+		if i < len(primary) {
+			sorted = append(sorted, primary[i])
+		}
+		if i < len(secondary) {
+			sorted = append(sorted, secondary[i])
+		}
+	}
+
+	return sorted
+}
@@ -0,0 +1,47 @@
+package upstream
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+func TestSortIPs(t *testing.T) {
+	ips := []string{"10.0.0.1", "10.0.0.2", "2001:db8::1", "10.0.0.3", "2001:db8::2"}
+
+	tests := map[string][]string{
+		config.PreferIPv4: {"10.0.0.1", "2001:db8::1", "10.0.0.2", "2001:db8::2", "10.0.0.3"},
+		config.PreferIPv6: {"2001:db8::1", "10.0.0.1", "2001:db8::2", "10.0.0.2", "10.0.0.3"},
+	}
+
+	for prefer, expected := range tests {
+		if sorted := sortIPs(ips, prefer); !reflect.DeepEqual(sorted, expected) {
+			t.Errorf(str.TUpstreamBadDialOrder, prefer, sorted, expected)
+		}
+	}
+}
+
+// TestDialIPv6 will test that IPv6-only upstreams are reachable while IPv4
+// is preferred
+func TestDialIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 loopback unavailable")
+	}
+	defer listener.Close()
+
+	proxy := &config.Proxy{Upstream: config.Upstream{
+		PreferIP:              config.PreferIPv4,
+		FallbackDelayDuration: 300 * time.Millisecond,
+	}}
+
+	conn, err := Dialer(proxy)(listener.Addr().String())
+	if err != nil {
+		t.Fatalf(str.TUpstreamBadDial, listener.Addr(), err)
+	}
+	_ = conn.Close()
+}
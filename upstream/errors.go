@@ -0,0 +1,40 @@
+package upstream
+
+import "fmt"
+
+// ErrQueueTimeout is an error struct for upstream requests that waited in the
+// fair queue longer than the configured queue timeout
+type ErrQueueTimeout struct {
+	ProxyName string
+	Client    string
+}
+
+// Error returns the string representation of ErrQueueTimeout
+func (e ErrQueueTimeout) Error() string {
+	return fmt.Sprintf("upstream: proxy '%s' queue timeout for client '%s'", e.ProxyName, e.Client)
+}
+
+// ErrClientAborted is an error struct for upstream requests given up while
+// queued because their client went away
+type ErrClientAborted struct {
+	ProxyName string
+	Client    string
+}
+
+// Error returns the string representation of ErrClientAborted
+func (e ErrClientAborted) Error() string {
+	return fmt.Sprintf("upstream: proxy '%s' client '%s' aborted while queued", e.ProxyName, e.Client)
+}
+
+// ErrUpstreamLoop is an error struct for a proxy whose upstream resolves back
+// to this LOD instance, caught before serving
+type ErrUpstreamLoop struct {
+	ProxyName string
+	Addr      string
+}
+
+// Error returns the string representation of ErrUpstreamLoop
+func (e ErrUpstreamLoop) Error() string {
+	return fmt.Sprintf("upstream: proxy '%s' upstream %s resolves to this instance, "+
+		"requests would loop back through it", e.ProxyName, e.Addr)
+}
@@ -0,0 +1,250 @@
+package upstream
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// Priority classes of upstream requests
+const (
+	// ClassInteractive requests fetch tiles missed by clients waiting on them
+	ClassInteractive = "interactive"
+	// ClassBackground requests are made by LOD's own jobs, ex: priming and
+	// warm-up, and yield to interactive requests
+	ClassBackground = "background"
+)
+
+// Class returns the priority class of upstream requests for the given client
+func Class(client string) string {
+	if strings.HasPrefix(client, str.ClientBackground) {
+		return ClassBackground
+	}
+	return ClassInteractive
+}
+
+// Scheduler limits concurrent upstream requests for a proxy. Once the limit
+// is reached, queued requests are granted freed slots round-robin per client
+// so a single bulk-downloading client cannot starve everyone else. Queued
+// interactive requests are granted slots before background requests, which
+// hold at most a share of the slots.
+type Scheduler struct {
+	name            string
+	limit           int
+	backgroundLimit int
+	timeout         time.Duration
+
+	mu         sync.Mutex
+	active     int                  // number of granted slots
+	background int                  // number of granted slots held by background requests
+	queues     map[string][]*waiter // queued requests per client, oldest first
+	order      map[string][]string  // clients with queued requests by class, in round-robin order
+}
+
+// waiter is a single queued request
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// GetScheduler gets a fair queuing scheduler by proxy name, nil if disabled
+func GetScheduler(name string) *Scheduler {
+	return load().schedulers[name]
+}
+
+// newScheduler builds a scheduler from the proxy's fairness configuration
+func newScheduler(proxy *config.Proxy) *Scheduler {
+	return &Scheduler{
+		name:            proxy.Name,
+		limit:           proxy.Upstream.Fairness.MaxConcurrency,
+		backgroundLimit: proxy.Upstream.Fairness.BackgroundMaxConcurrency,
+		timeout:         proxy.Upstream.Fairness.QueueTimeoutDuration,
+		queues:          make(map[string][]*waiter),
+		order:           make(map[string][]string),
+	}
+}
+
+// Wrap returns fn guarded by the scheduler for the given client. A nil
+// scheduler returns fn as is.
+func (s *Scheduler) Wrap(client string, fn func() (interface{}, error)) func() (interface{}, error) {
+	return s.WrapCancel(client, nil, fn)
+}
+
+// WrapCancel returns fn guarded by the scheduler for the given client, giving
+// up its place in the queue once done is closed. A nil scheduler returns fn
+// as is.
+func (s *Scheduler) WrapCancel(client string, done <-chan struct{}, fn func() (interface{}, error)) func() (interface{}, error) {
+	if s == nil {
+		return fn
+	}
+
+	return func() (interface{}, error) {
+		if err := s.AcquireCancel(client, done); err != nil {
+			return nil, err
+		}
+		defer s.Release(client)
+
+		return fn()
+	}
+}
+
+// Acquire waits for an upstream request slot for the given client, returning
+// ErrQueueTimeout if none is granted within the queue timeout
+func (s *Scheduler) Acquire(client string) error {
+	return s.AcquireCancel(client, nil)
+}
+
+// AcquireCancel waits for an upstream request slot for the given client like
+// Acquire, returning ErrClientAborted if done is closed while queued
+func (s *Scheduler) AcquireCancel(client string, done <-chan struct{}) error {
+	class := Class(client)
+	start := time.Now()
+
+	s.mu.Lock()
+	if len(s.order[class]) == 0 && s.available(class) {
+		s.grant(class)
+		s.mu.Unlock()
+		metrics.classWait.WithLabelValues(s.name, class).Observe(0)
+		return nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	if len(s.queues[client]) == 0 {
+		s.order[class] = append(s.order[class], client)
+	}
+	s.queues[client] = append(s.queues[client], w)
+	metrics.queued.WithLabelValues(s.name).Inc()
+	metrics.classQueued.WithLabelValues(s.name, class).Inc()
+	s.mu.Unlock()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		metrics.classWait.WithLabelValues(s.name, class).Observe(time.Since(start).Seconds())
+		return nil
+	case <-timer.C:
+		err = ErrQueueTimeout{
+			ProxyName: s.name,
+			Client:    client,
+		}
+	case <-done:
+		err = ErrClientAborted{
+			ProxyName: s.name,
+			Client:    client,
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the slot may have been granted in the meantime
+	if w.granted {
+		metrics.classWait.WithLabelValues(s.name, class).Observe(time.Since(start).Seconds())
+		return nil
+	}
+
+	s.remove(client, w)
+	metrics.queued.WithLabelValues(s.name).Dec()
+	metrics.classQueued.WithLabelValues(s.name, class).Dec()
+	if _, timedOut := err.(ErrQueueTimeout); timedOut {
+		metrics.queueTimeouts.WithLabelValues(s.name).Inc()
+	}
+
+	return err
+}
+
+// Release frees the upstream request slot held by the given client, handing
+// it to the next queued interactive client in round-robin order if any are
+// waiting, otherwise to the next background client if below its limit
+func (s *Scheduler) Release(client string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if Class(client) == ClassBackground {
+		s.background--
+	}
+	metrics.classActive.WithLabelValues(s.name, Class(client)).Dec()
+
+	for _, class := range []string{ClassInteractive, ClassBackground} {
+		if len(s.order[class]) > 0 && s.available(class) {
+			s.next(class)
+			return
+		}
+	}
+}
+
+// available returns true if a slot is free for a request of the given class,
+// background requests only being granted slots no interactive request is
+// queued for, up to their limit
+func (s *Scheduler) available(class string) bool {
+	if s.active >= s.limit {
+		return false
+	}
+	if class == ClassBackground {
+		return s.background < s.backgroundLimit && len(s.order[ClassInteractive]) == 0
+	}
+	return true
+}
+
+// grant counts a slot granted to a request of the given class
+func (s *Scheduler) grant(class string) {
+	s.active++
+	if class == ClassBackground {
+		s.background++
+	}
+	metrics.classActive.WithLabelValues(s.name, class).Inc()
+}
+
+// next grants a slot to the next queued client of the given class in
+// round-robin order
+func (s *Scheduler) next(class string) {
+	client := s.order[class][0]
+	s.order[class] = s.order[class][1:]
+
+	queue := s.queues[client]
+	w := queue[0]
+	if len(queue) > 1 {
+		s.queues[client] = queue[1:]
+		s.order[class] = append(s.order[class], client)
+	} else {
+		delete(s.queues, client)
+	}
+
+	s.grant(class)
+	w.granted = true
+	close(w.ready)
+	metrics.queued.WithLabelValues(s.name).Dec()
+	metrics.classQueued.WithLabelValues(s.name, class).Dec()
+}
+
+// remove drops a timed out or aborted waiter from its client's queue
+func (s *Scheduler) remove(client string, w *waiter) {
+	queue := s.queues[client]
+	for i := range queue {
+		if queue[i] == w {
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+
+	if len(queue) > 0 {
+		s.queues[client] = queue
+		return
+	}
+
+	delete(s.queues, client)
+	class := Class(client)
+	for i := range s.order[class] {
+		if s.order[class][i] == client {
+			s.order[class] = append(s.order[class][:i], s.order[class][i+1:]...)
+			break
+		}
+	}
+}
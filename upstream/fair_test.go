@@ -0,0 +1,158 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// testScheduler builds a scheduler with the given concurrency limit
+func testScheduler(limit int, timeout time.Duration) *Scheduler {
+	return newScheduler(&config.Proxy{
+		Name: "test",
+		Upstream: config.Upstream{
+			Fairness: config.Fairness{
+				Enabled:                  true,
+				MaxConcurrency:           limit,
+				BackgroundMaxConcurrency: limit,
+				QueueTimeoutDuration:     timeout,
+			},
+		},
+	})
+}
+
+// queued returns the number of requests waiting in the scheduler
+func (s *Scheduler) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, queue := range s.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// enqueue starts a request for the client that reports on granted once it
+// acquires a slot, waiting until it is queued
+func enqueue(t *testing.T, s *Scheduler, client string, granted chan<- string) {
+	before := s.queued()
+	go func() {
+		if err := s.Acquire(client); err != nil {
+			t.Errorf(str.TUpstreamBadAcquire, err)
+			return
+		}
+		granted <- client
+	}()
+
+	for s.queued() == before {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	s := testScheduler(1, time.Minute)
+	granted := make(chan string, 4)
+
+	// the bulk client holds the only slot and queues more requests
+	if err := s.Acquire("bulk"); err != nil {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+	enqueue(t, s, "bulk", granted)
+	enqueue(t, s, "bulk", granted)
+	enqueue(t, s, "bulk", granted)
+	enqueue(t, s, "user", granted)
+
+	// the interactive user is served second despite queueing last
+	expected := []string{"bulk", "user", "bulk", "bulk"}
+	for _, client := range expected {
+		s.Release("bulk")
+		if got := <-granted; got != client {
+			t.Fatalf(str.TUpstreamBadGrant, got, client)
+		}
+	}
+}
+
+func TestFairQueueTimeout(t *testing.T) {
+	s := testScheduler(1, 10*time.Millisecond)
+
+	if err := s.Acquire("bulk"); err != nil {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+
+	var queueErr ErrQueueTimeout
+	if err := s.Acquire("user"); !errors.As(err, &queueErr) {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+
+	if s.queued() != 0 {
+		t.Fatalf(str.TUpstreamBadAcquire, "timed out request still queued")
+	}
+}
+
+func TestFairQueueCancel(t *testing.T) {
+	s := testScheduler(1, time.Minute)
+
+	if err := s.Acquire("bulk"); err != nil {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+
+	done := make(chan struct{})
+	close(done)
+
+	var abortErr ErrClientAborted
+	if err := s.AcquireCancel("user", done); !errors.As(err, &abortErr) {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+
+	if s.queued() != 0 {
+		t.Fatalf(str.TUpstreamBadAcquire, "aborted request still queued")
+	}
+}
+
+func TestFairQueuePriority(t *testing.T) {
+	s := newScheduler(&config.Proxy{
+		Name: "test",
+		Upstream: config.Upstream{
+			Fairness: config.Fairness{
+				Enabled:                  true,
+				MaxConcurrency:           2,
+				BackgroundMaxConcurrency: 1,
+				QueueTimeoutDuration:     time.Minute,
+			},
+		},
+	})
+	granted := make(chan string, 2)
+
+	// background requests hold at most one slot, leaving one to interactive ones
+	if err := s.Acquire(str.ClientAdmin); err != nil {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+	enqueue(t, s, str.ClientAdmin, granted)
+	if err := s.Acquire("user"); err != nil {
+		t.Fatalf(str.TUpstreamBadAcquire, err)
+	}
+
+	// the interactive request is granted the next slot despite queueing last
+	enqueue(t, s, "user", granted)
+	s.Release("user")
+	if got := <-granted; got != "user" {
+		t.Fatalf(str.TUpstreamBadGrant, got, "user")
+	}
+
+	s.Release(str.ClientAdmin)
+	if got := <-granted; got != str.ClientAdmin {
+		t.Fatalf(str.TUpstreamBadGrant, got, str.ClientAdmin)
+	}
+
+	for client, class := range map[string]string{
+		"user": ClassInteractive, str.ClientAdmin: ClassBackground, str.ClientWarmup: ClassBackground,
+	} {
+		if got := Class(client); got != class {
+			t.Errorf(str.TUpstreamBadClass, client, got, class)
+		}
+	}
+}
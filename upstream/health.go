@@ -0,0 +1,178 @@
+package upstream
+
+import (
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// canaryReplacer injects the coordinates of the root tile into a tile URL
+var canaryReplacer = strings.NewReplacer("{z}", "0", "{x}", "0", "{y}", "0")
+
+// Healthy returns true if the target is in rotation
+func (t *Target) Healthy() bool {
+	return !t.unhealthy.Load()
+}
+
+// healthy filters the given targets down to those in rotation, failing open
+// to all targets if every one of them is unhealthy
+func healthy(targets []*Target) []*Target {
+	inRotation := make([]*Target, 0, len(targets))
+	for _, target := range targets {
+		if target.Healthy() {
+			inRotation = append(inRotation, target)
+		}
+	}
+
+	if len(inRotation) == 0 {
+		return targets
+	}
+
+	return inRotation
+}
+
+// rampShare returns the share of traffic a target should receive while
+// slow-starting after recovery, from near zero up to 1 at the end of the window
+func (t *Target) rampShare(window time.Duration, now time.Time) float64 {
+	recovered := atomic.LoadInt64(&t.recovered)
+	if window <= 0 || recovered == 0 {
+		return 1
+	}
+
+	elapsed := now.Sub(time.Unix(0, recovered))
+	if elapsed >= window {
+		return 1
+	}
+
+	return float64(elapsed) / float64(window)
+}
+
+// ramping filters out targets still slow-starting after recovery in proportion
+// to how far into the slow-start window they are, keeping all targets if the
+// filter would leave none
+func ramping(targets []*Target, window time.Duration) []*Target {
+	if window <= 0 {
+		return targets
+	}
+
+	now := time.Now()
+	admitted := make([]*Target, 0, len(targets))
+	for _, target := range targets {
+		if share := target.rampShare(window, now); share >= 1 || rand.Float64() < share {
+			admitted = append(admitted, target)
+		}
+	}
+
+	if len(admitted) == 0 {
+		return targets
+	}
+
+	return admitted
+}
+
+// canaryUrl returns the URL probed to check the health of upstream targets
+func (p *Pool) canaryUrl() string {
+	health := p.Proxy.Upstream.Health
+	if health.Path == "" {
+		return canaryReplacer.Replace(p.Proxy.TileURL)
+	}
+
+	tileUrl, err := url.Parse(p.Proxy.TileURL)
+	if err != nil {
+		return health.Path
+	}
+
+	return tileUrl.Scheme + "://" + tileUrl.Host + health.Path
+}
+
+// probe runs health probes against all targets on the configured interval
+// until the pool is stopped
+func (p *Pool) probe() {
+	ticker := time.NewTicker(p.Proxy.Upstream.Health.IntervalDuration)
+	defer ticker.Stop()
+
+	canary := p.canaryUrl()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			for _, target := range p.Targets() {
+				p.check(target, canary)
+			}
+		}
+	}
+}
+
+// check probes a single target, updating its health once enough consecutive
+// probes have succeeded or failed
+func (p *Pool) check(target *Target, canary string) {
+	health := p.Proxy.Upstream.Health
+
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI(canary)
+	req.Header.SetMethod(health.Method)
+	for _, header := range p.Proxy.AddHeaders {
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	// address the canary host so TLS verification uses the upstream hostname,
+	// while always dialing the target itself
+	isTLS := strings.HasPrefix(canary, "https://")
+	client := &fasthttp.HostClient{
+		Addr:      fasthttp.AddMissingPort(string(req.URI().Host()), isTLS),
+		IsTLS:     isTLS,
+		TLSConfig: p.Proxy.Upstream.TLSConfig,
+		Dial:      target.Dial,
+	}
+
+	err := client.DoTimeout(req, resp, health.TimeoutDuration)
+	ok := err == nil && resp.StatusCode() < fasthttp.StatusInternalServerError
+
+	if ok {
+		target.failures = 0
+		target.successes++
+		if !target.Healthy() && target.successes >= health.HealthyThreshold {
+			p.setHealthy(target, true)
+		}
+		return
+	}
+
+	target.successes = 0
+	target.failures++
+	if target.Healthy() && target.failures >= health.UnhealthyThreshold {
+		p.setHealthy(target, false)
+	}
+
+	if err != nil {
+		util.DebugFlag("upstream", str.CProxy, str.DProbeFail, p.Proxy.Name, target.Addr, err.Error())
+	}
+}
+
+// setHealthy moves a target in or out of rotation
+func (p *Pool) setHealthy(target *Target, healthy bool) {
+	if healthy {
+		atomic.StoreInt64(&target.recovered, time.Now().UnixNano())
+	}
+	target.unhealthy.Store(!healthy)
+
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	metrics.healthy.WithLabelValues(p.Proxy.Name, target.Addr).Set(value)
+
+	util.Info(str.CProxy, str.MUpstreamHealth, p.Proxy.Name, target.Addr, healthy)
+}
@@ -0,0 +1,103 @@
+package upstream
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/dnscache"
+)
+
+// loopResolveTimeout bounds resolving each upstream address while checking
+// for loops, so unreachable nameservers can't stall startup
+const loopResolveTimeout = 2 * time.Second
+
+// CheckLoops returns an error for the first proxy whose upstream resolves
+// back to the address this instance listens on, which would make every cache
+// miss recurse through the proxy until resources are exhausted. Upstreams
+// that can't be resolved, or are reached through an outbound proxy, are left
+// to the loop marker header sent with every upstream request.
+func CheckLoops(c *config.Capabilities) error {
+	local, err := localIPs()
+	if err != nil {
+		return err
+	}
+	port := strconv.Itoa(c.Instance.Port)
+
+	for _, proxy := range c.Proxies {
+		if proxy.Upstream.Type == config.UpstreamDebug || proxy.Upstream.ProxyURL != "" {
+			continue
+		}
+
+		for _, addr := range upstreamAddrs(proxy) {
+			host, addrPort, err := net.SplitHostPort(addr)
+			if err != nil || addrPort != port {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), loopResolveTimeout)
+			ips, err := dnscache.LookupHost(ctx, host)
+			cancel()
+			if err != nil {
+				continue
+			}
+
+			for _, ip := range ips {
+				parsed := net.ParseIP(ip)
+				if parsed != nil && (parsed.IsLoopback() || parsed.IsUnspecified() || local[parsed.String()]) {
+					return ErrUpstreamLoop{ProxyName: proxy.Name, Addr: addr}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// upstreamAddrs returns the host:port addresses a proxy's upstream requests
+// are sent to, its static servers or else the tile URL host
+func upstreamAddrs(proxy config.Proxy) []string {
+	if len(proxy.Upstream.Servers) > 0 {
+		addrs := make([]string, 0, len(proxy.Upstream.Servers))
+		for _, server := range proxy.Upstream.Servers {
+			addrs = append(addrs, server.Addr)
+		}
+		return addrs
+	}
+
+	// templated hostnames, ex: {s}.tile.example.com, fail to parse
+	tileUrl, err := url.Parse(proxy.TileURL)
+	if err != nil || tileUrl.Hostname() == "" {
+		return nil
+	}
+
+	port := tileUrl.Port()
+	if port == "" {
+		port = "80"
+		if tileUrl.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return []string{net.JoinHostPort(tileUrl.Hostname(), port)}
+}
+
+// localIPs returns the set of IP addresses assigned to this host's interfaces
+func localIPs() (map[string]bool, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]bool, len(addrs))
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = true
+		}
+	}
+
+	return local, nil
+}
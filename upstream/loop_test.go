@@ -0,0 +1,34 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestCheckLoops will test that upstreams resolving to this instance's own
+// listening address are refused, while other ports and hosts are allowed
+func TestCheckLoops(t *testing.T) {
+	tests := map[string]bool{
+		"http://127.0.0.1:1337/{z}/{x}/{y}.pbf": true,
+		"http://localhost:1337/{z}/{x}/{y}.pbf": true,
+		"http://127.0.0.1:8080/{z}/{x}/{y}.pbf": false,
+		"https://127.0.0.1/{z}/{x}/{y}.pbf":     false,
+		"http://{s}.tile.invalid/{z}/{x}/{y}":   false,
+	}
+
+	for tileUrl, loops := range tests {
+		c := &config.Capabilities{
+			Instance: config.Instance{Port: 1337},
+			Proxies:  []config.Proxy{{Name: "loop", TileURL: tileUrl}},
+		}
+
+		err := CheckLoops(c)
+		var loopErr ErrUpstreamLoop
+		if errors.As(err, &loopErr) != loops {
+			t.Errorf(str.TUpstreamBadLoop, tileUrl, err, loops)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package upstream
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "upstream"
+
+// metrics for all upstream targets, labeled by proxy and target address so
+// they survive pools being rebuilt on config reloads
+var metrics = struct {
+	requests *prometheus.CounterVec
+	inflight *prometheus.GaugeVec
+	latency  *prometheus.HistogramVec
+	healthy  *prometheus.GaugeVec
+
+	queued        *prometheus.GaugeVec
+	queueTimeouts *prometheus.CounterVec
+
+	classActive *prometheus.GaugeVec
+	classQueued *prometheus.GaugeVec
+	classWait   *prometheus.HistogramVec
+
+	backgroundActive    *prometheus.GaugeVec
+	backgroundThrottled *prometheus.CounterVec
+}{
+	requests: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "requests_total",
+		Help:      "The total number of requests made to each upstream target",
+	}, []string{"proxy", "target", "result"}),
+	inflight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "inflight",
+		Help:      "The number of requests currently in flight to each upstream target",
+	}, []string{"proxy", "target"}),
+	latency: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "latency_seconds",
+		Help:      "The latency of requests made to each upstream target",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"proxy", "target"}),
+	healthy: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "healthy",
+		Help:      "Whether each upstream target is passing health probes and in rotation",
+	}, []string{"proxy", "target"}),
+	queued: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "queued",
+		Help:      "The number of requests waiting in the fair queue for an upstream slot",
+	}, []string{"proxy"}),
+	queueTimeouts: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "queue_timeouts_total",
+		Help:      "The total number of requests that timed out waiting in the fair queue",
+	}, []string{"proxy"}),
+	classActive: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "class_active",
+		Help:      "The number of upstream slots held by each priority class, interactive or background",
+	}, []string{"proxy", "class"}),
+	classQueued: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "class_queued",
+		Help:      "The number of requests of each priority class waiting in the fair queue",
+	}, []string{"proxy", "class"}),
+	classWait: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "class_wait_seconds",
+		Help:      "The time requests of each priority class waited for an upstream slot",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"proxy", "class"}),
+	backgroundActive: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "background_active",
+		Help:      "The number of upstream requests in flight for background jobs",
+	}, []string{"proxy"}),
+	backgroundThrottled: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "background_throttled_seconds_total",
+		Help:      "The total time background job requests waited on the background rate limit",
+	}, []string{"proxy"}),
+}
+
+// connDesc describes the connections held to each upstream host or target
+var connDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(config.Namespace, Subsystem, "connections"),
+	"The number of connections to each upstream host or target by state, open, idle or inflight",
+	[]string{"proxy", "target", "state"}, nil)
+
+// connCollector exports connection pool usage of the shared upstream clients
+// at scrape time, so metrics follow clients across config reloads
+type connCollector struct{}
+
+func init() {
+	prometheus.MustRegister(connCollector{})
+}
+
+// Describe sends the descriptor of the connection pool metrics
+func (connCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- connDesc
+}
+
+// Collect sends the current connection pool usage of every upstream host
+func (connCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, conns := range connStats() {
+		// requests waiting for a free connection count as in flight
+		idle := conns.open - conns.inflight
+		if idle < 0 {
+			idle = 0
+		}
+
+		ch <- prometheus.MustNewConstMetric(connDesc, prometheus.GaugeValue,
+			float64(conns.open), conns.proxy, conns.host, "open")
+		ch <- prometheus.MustNewConstMetric(connDesc, prometheus.GaugeValue,
+			float64(idle), conns.proxy, conns.host, "idle")
+		ch <- prometheus.MustNewConstMetric(connDesc, prometheus.GaugeValue,
+			float64(conns.inflight), conns.proxy, conns.host, "inflight")
+	}
+}
@@ -0,0 +1,28 @@
+package upstream
+
+import "sync/atomic"
+
+// TargetState is a snapshot of an upstream target for the admin API
+type TargetState struct {
+	Addr     string  `json:"addr"`
+	Weight   int     `json:"weight"`
+	Healthy  bool    `json:"healthy"`
+	Inflight int64   `json:"inflight"`
+	Latency  float64 `json:"latency_seconds"`
+}
+
+// State returns a snapshot of all targets in the pool
+func (p *Pool) State() []TargetState {
+	targets := p.Targets()
+	states := make([]TargetState, 0, len(targets))
+	for _, target := range targets {
+		states = append(states, TargetState{
+			Addr:     target.Addr,
+			Weight:   target.Weight,
+			Healthy:  target.Healthy(),
+			Inflight: atomic.LoadInt64(&target.inflight),
+			Latency:  target.latency(),
+		})
+	}
+	return states
+}
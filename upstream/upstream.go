@@ -0,0 +1,378 @@
+package upstream
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/dnscache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// registry holds the upstream pools, fair queuing schedulers and background
+// job pools of the configured proxies by name
+type registry struct {
+	pools       map[string]*Pool
+	schedulers  map[string]*Scheduler
+	backgrounds map[string]*Background
+}
+
+var (
+	// current is the registry read by requests, replaced whole on rebuilds
+	// so lookups never race them
+	current atomic.Pointer[registry]
+	// rebuildMu serializes rebuilds of the registry
+	rebuildMu sync.Mutex
+)
+
+// Pool tracks the upstream servers of a proxy, either statically configured or
+// re-resolved from the upstream hostname on an interval, and balances requests
+// across them using the configured strategy
+type Pool struct {
+	Proxy *config.Proxy // a reference to the proxy's configuration
+
+	host string // upstream hostname from the proxy tile URL
+	port string // upstream port from the proxy tile URL
+
+	mu      sync.RWMutex
+	targets []*Target
+	next    uint64
+
+	dial fasthttp.DialFunc // dialer targets are reached with
+
+	stop chan struct{}
+}
+
+// Target is a single upstream server address
+type Target struct {
+	Addr   string // upstream address, ex: 10.0.0.1:443
+	Weight int    // relative weight of the target, from config or SRV records
+
+	inflight  int64       // number of requests currently in flight to the target
+	ewma      uint64      // float64 bits of the latency moving average in seconds
+	current   int         // smooth weighted round-robin state, guarded by the pool
+	unhealthy atomic.Bool // whether the target failed health probes and is out of rotation
+	successes int         // consecutive successful health probes, owned by the prober
+	failures  int         // consecutive failed health probes, owned by the prober
+	recovered int64       // unix nanoseconds the target last returned to rotation
+
+	dial fasthttp.DialFunc // dialer used to reach the target address
+}
+
+// Init builds upstream pools for all configured proxies with static servers,
+// re-resolution, or health probes enabled, stopping any pools left over from a
+// previous configuration, builds fair queuing schedulers and background job
+// pools, and drops shared upstream clients so they pick up reloaded
+// connection pool settings
+func Init() {
+	rebuildMu.Lock()
+	defer rebuildMu.Unlock()
+
+	resetClients()
+
+	next := newRegistry()
+	proxies := config.Get().Proxies
+	for i := range proxies {
+		next.add(&proxies[i])
+	}
+
+	prev := current.Swap(next)
+	if prev != nil {
+		for _, pool := range prev.pools {
+			close(pool.stop)
+		}
+	}
+}
+
+// Rebuild rebuilds the upstream state of a single proxy by name from its
+// current configuration, or drops it if the proxy is no longer configured,
+// leaving the pools, health and balancing state of other proxies untouched
+func Rebuild(name string) {
+	rebuildMu.Lock()
+	defer rebuildMu.Unlock()
+
+	dropClients(name)
+
+	prev := load()
+	next := newRegistry()
+	for n, pool := range prev.pools {
+		next.pools[n] = pool
+	}
+	for n, scheduler := range prev.schedulers {
+		next.schedulers[n] = scheduler
+	}
+	for n, background := range prev.backgrounds {
+		next.backgrounds[n] = background
+	}
+	stale := next.pools[name]
+	delete(next.pools, name)
+	delete(next.schedulers, name)
+	delete(next.backgrounds, name)
+
+	proxies := config.Get().Proxies
+	for i := range proxies {
+		if proxies[i].Name == name {
+			next.add(&proxies[i])
+		}
+	}
+
+	current.Store(next)
+	if stale != nil {
+		close(stale.stop)
+	}
+}
+
+// Get an upstream pool by proxy name, nil if the proxy has no pool
+func Get(name string) *Pool {
+	return load().pools[name]
+}
+
+// load returns the current registry, empty before the first Init
+func load() *registry {
+	if r := current.Load(); r != nil {
+		return r
+	}
+	return newRegistry()
+}
+
+// newRegistry returns an empty registry
+func newRegistry() *registry {
+	return &registry{
+		pools:       make(map[string]*Pool),
+		schedulers:  make(map[string]*Scheduler),
+		backgrounds: make(map[string]*Background),
+	}
+}
+
+// add builds the scheduler, background pool and upstream pool of the given
+// proxy into the registry, starting the pool's re-resolution and probes
+func (r *registry) add(proxy *config.Proxy) {
+	if proxy.Upstream.Fairness.Enabled {
+		r.schedulers[proxy.Name] = newScheduler(proxy)
+	}
+	r.backgrounds[proxy.Name] = newBackground(proxy)
+
+	if proxy.Upstream.ResolveIntervalDuration == 0 &&
+		len(proxy.Upstream.Servers) == 0 &&
+		!proxy.Upstream.Health.Enabled {
+		return
+	}
+
+	pool, err := newPool(proxy)
+	if err != nil {
+		util.Error(str.CProxy, str.EUpstreamResolve, proxy.Name, err.Error())
+		return
+	}
+
+	r.pools[proxy.Name] = pool
+	if proxy.Upstream.ResolveIntervalDuration > 0 {
+		go pool.run()
+	}
+	if proxy.Upstream.Health.Enabled {
+		go pool.probe()
+	}
+}
+
+// newPool builds a pool for the given proxy, performing the initial resolution
+func newPool(proxy *config.Proxy) (*Pool, error) {
+	tileUrl, err := url.Parse(proxy.TileURL)
+	if err != nil {
+		return nil, err
+	}
+
+	port := tileUrl.Port()
+	if port == "" {
+		port = "80"
+		if tileUrl.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	pool := &Pool{
+		Proxy: proxy,
+		host:  tileUrl.Hostname(),
+		port:  port,
+		dial:  proxy.Upstream.Dial,
+		stop:  make(chan struct{}),
+	}
+	if pool.dial == nil {
+		pool.dial = Dialer(proxy)
+	}
+
+	if len(proxy.Upstream.Servers) > 0 {
+		for _, server := range proxy.Upstream.Servers {
+			pool.targets = append(pool.targets, &Target{
+				Addr:   server.Addr,
+				Weight: server.Weight,
+				dial:   pool.dial,
+			})
+		}
+		return pool, nil
+	}
+
+	// probe the upstream hostname itself when it is not re-resolved
+	if proxy.Upstream.ResolveIntervalDuration == 0 {
+		pool.targets = []*Target{{
+			Addr:   net.JoinHostPort(pool.host, pool.port),
+			Weight: 1,
+			dial:   pool.dial,
+		}}
+		return pool, nil
+	}
+
+	// failed initial resolutions are retried on the next interval, with
+	// requests falling back to the default dialer until then
+	pool.resolve()
+
+	return pool, nil
+}
+
+// run re-resolves the pool's upstream on the configured interval until stopped
+func (p *Pool) run() {
+	ticker := time.NewTicker(p.Proxy.Upstream.ResolveIntervalDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.resolve()
+		}
+	}
+}
+
+// resolve looks up the pool's upstream addresses, keeping the previous
+// addresses if the lookup fails or returns nothing
+func (p *Pool) resolve() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.Proxy.Upstream.ResolveIntervalDuration)
+	defer cancel()
+
+	var targets []*Target
+	var err error
+
+	if p.Proxy.Upstream.SRV != "" {
+		targets, err = p.lookupSRV(ctx)
+	} else {
+		targets, err = p.lookupHost(ctx)
+	}
+
+	if err != nil || len(targets) == 0 {
+		if err != nil {
+			util.Error(str.CProxy, str.EUpstreamResolve, p.Proxy.Name, err.Error())
+		}
+		return
+	}
+
+	p.update(targets)
+
+	util.Debug(str.CProxy, str.DUpstreamResolved, p.Proxy.Name, len(targets))
+}
+
+// update replaces the pool's targets with freshly resolved ones, carrying
+// balancing state over for addresses that are still present and dropping the
+// shared clients and health gauges of addresses that are gone
+func (p *Pool) update(targets []*Target) {
+	// sort for stable ordering so unchanged lookups don't reshuffle rotation
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Addr < targets[j].Addr
+	})
+
+	p.mu.Lock()
+	existing := make(map[string]*Target, len(p.targets))
+	for _, target := range p.targets {
+		existing[target.Addr] = target
+	}
+	for i, target := range targets {
+		if prev, ok := existing[target.Addr]; ok {
+			prev.Weight = target.Weight
+			targets[i] = prev
+			delete(existing, target.Addr)
+		}
+	}
+	p.targets = targets
+	p.mu.Unlock()
+
+	if len(existing) == 0 {
+		return
+	}
+
+	// requests still in flight to removed targets complete normally
+	dropClientsWhere(func(key clientKey) bool {
+		_, removed := existing[key.target]
+		return key.proxy == p.Proxy.Name && removed
+	})
+	for addr := range existing {
+		metrics.healthy.DeleteLabelValues(p.Proxy.Name, addr)
+	}
+}
+
+// lookupHost resolves the upstream hostname to its IP addresses
+func (p *Pool) lookupHost(ctx context.Context) ([]*Target, error) {
+	addrs, err := dnscache.LookupHost(ctx, p.host)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*Target, 0, len(addrs))
+	for _, addr := range addrs {
+		targets = append(targets, &Target{
+			Addr:   net.JoinHostPort(addr, p.port),
+			Weight: 1,
+			dial:   p.dial,
+		})
+	}
+
+	return targets, nil
+}
+
+// lookupSRV resolves the configured SRV record to its targets
+func (p *Pool) lookupSRV(ctx context.Context) ([]*Target, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", p.Proxy.Upstream.SRV)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]*Target, 0, len(records))
+	for _, record := range records {
+		weight := int(record.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+
+		targets = append(targets, &Target{
+			Addr:   net.JoinHostPort(record.Target, strconv.Itoa(int(record.Port))),
+			Weight: weight,
+			dial:   p.dial,
+		})
+	}
+
+	return targets, nil
+}
+
+// Targets returns the currently resolved upstream targets
+func (p *Pool) Targets() []*Target {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.targets
+}
+
+// Dial connects to the target address regardless of the address requested,
+// preserving the original hostname for TLS verification
+func (t *Target) Dial(_ string) (net.Conn, error) {
+	return t.dial(t.Addr)
+}
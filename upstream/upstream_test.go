@@ -0,0 +1,183 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// testPool builds a pool with the given strategy and static servers
+func testPool(strategy string, servers ...config.Server) *Pool {
+	proxy := &config.Proxy{
+		Name: "test",
+		Upstream: config.Upstream{
+			Strategy: strategy,
+			Servers:  servers,
+		},
+	}
+
+	pool := &Pool{Proxy: proxy}
+	for _, server := range servers {
+		pool.targets = append(pool.targets, &Target{
+			Addr:   server.Addr,
+			Weight: server.Weight,
+		})
+	}
+
+	return pool
+}
+
+// pickCounts picks n targets, releasing each, and counts picks per address
+func pickCounts(pool *Pool, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		target := pool.Pick()
+		counts[target.Addr]++
+		pool.Done(target, time.Millisecond, false)
+	}
+	return counts
+}
+
+func TestNilPool(t *testing.T) {
+	var pool *Pool
+	if target := pool.Pick(); target != nil {
+		t.Fatalf(str.TUpstreamBadPick, target.Addr, "none")
+	}
+	pool.Done(nil, 0, false)
+}
+
+func TestRoundRobin(t *testing.T) {
+	pool := testPool(config.StrategyRoundRobin,
+		config.Server{Addr: "a:80", Weight: 1},
+		config.Server{Addr: "b:80", Weight: 1})
+
+	counts := pickCounts(pool, 10)
+	if counts["a:80"] != 5 || counts["b:80"] != 5 {
+		t.Fatalf(str.TUpstreamBadSpread, counts)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	pool := testPool(config.StrategyWeighted,
+		config.Server{Addr: "a:80", Weight: 3},
+		config.Server{Addr: "b:80", Weight: 1})
+
+	counts := pickCounts(pool, 8)
+	if counts["a:80"] != 6 || counts["b:80"] != 2 {
+		t.Fatalf(str.TUpstreamBadSpread, counts)
+	}
+}
+
+func TestLeastInflight(t *testing.T) {
+	pool := testPool(config.StrategyLeastInflight,
+		config.Server{Addr: "a:80", Weight: 1},
+		config.Server{Addr: "b:80", Weight: 1})
+
+	// hold a request open against the first pick
+	busy := pool.Pick()
+	for i := 0; i < 4; i++ {
+		target := pool.Pick()
+		if target == busy {
+			t.Fatalf(str.TUpstreamBadPick, target.Addr, "idle target")
+		}
+		pool.Done(target, time.Millisecond, false)
+	}
+	pool.Done(busy, time.Millisecond, false)
+}
+
+func TestEWMA(t *testing.T) {
+	pool := testPool(config.StrategyEWMA,
+		config.Server{Addr: "a:80", Weight: 1},
+		config.Server{Addr: "b:80", Weight: 1})
+
+	pool.targets[0].observe(500 * time.Millisecond)
+	pool.targets[1].observe(10 * time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		target := pool.Pick()
+		if target.Addr != "b:80" {
+			t.Fatalf(str.TUpstreamBadPick, target.Addr, "b:80")
+		}
+		pool.Done(target, 10*time.Millisecond, false)
+	}
+}
+
+func TestUnhealthySkipped(t *testing.T) {
+	pool := testPool(config.StrategyRoundRobin,
+		config.Server{Addr: "a:80", Weight: 1},
+		config.Server{Addr: "b:80", Weight: 1})
+
+	pool.targets[0].unhealthy.Store(true)
+	counts := pickCounts(pool, 4)
+	if counts["b:80"] != 4 {
+		t.Fatalf(str.TUpstreamBadSpread, counts)
+	}
+
+	// fail open to all targets when none are healthy
+	pool.targets[1].unhealthy.Store(true)
+	counts = pickCounts(pool, 4)
+	if counts["a:80"] != 2 || counts["b:80"] != 2 {
+		t.Fatalf(str.TUpstreamBadSpread, counts)
+	}
+}
+
+func TestRampShare(t *testing.T) {
+	now := time.Now()
+	target := &Target{recovered: now.Add(-15 * time.Second).UnixNano()}
+
+	if share := target.rampShare(30*time.Second, now); share < 0.49 || share > 0.51 {
+		t.Fatalf(str.TUpstreamBadShare, share, 0.5)
+	}
+
+	if share := target.rampShare(10*time.Second, now); share != 1 {
+		t.Fatalf(str.TUpstreamBadShare, share, 1.0)
+	}
+
+	// targets that never recovered receive their full share
+	if share := (&Target{}).rampShare(30*time.Second, now); share != 1 {
+		t.Fatalf(str.TUpstreamBadShare, share, 1.0)
+	}
+}
+
+// TestRebuild will test that rebuilding a single proxy replaces only its own
+// upstream state, and drops it once the proxy is no longer configured
+func TestRebuild(t *testing.T) {
+	proxy := func(name string) string {
+		return `
+[[proxies]]
+name = "` + name + `"
+tile_url = "http://tiles.invalid/{z}/{x}/{y}.pbf"
+[proxies.cache]
+mem_enabled = true
+mem_cap = 10
+mem_ttl = "1h"
+key_template = "{z}/{x}/{y}"
+[[proxies.upstream.servers]]
+addr = "10.0.0.1:80"
+`
+	}
+
+	if err := config.LoadData([]byte(proxy("a") + proxy("b"))); err != nil {
+		t.Fatal(err)
+	}
+	Init()
+	a, b := Get("a"), Get("b")
+
+	Rebuild("b")
+	if got := Get("a"); got != a {
+		t.Errorf(str.TUpstreamBadRebuild, "a", true, false)
+	}
+	if got := Get("b"); got == b || got == nil {
+		t.Errorf(str.TUpstreamBadRebuild, "b", false, true)
+	}
+
+	if err := config.LoadData([]byte(proxy("a"))); err != nil {
+		t.Fatal(err)
+	}
+	Rebuild("b")
+	if Get("b") != nil || GetBackground("b") != nil || Get("a") != a {
+		t.Errorf(str.TUpstreamBadRebuild, "b", true, false)
+	}
+}
@@ -16,17 +16,19 @@ type LogMessage struct {
 	Severity string `json:"severity"`
 	Caller   string `json:"caller"`
 	Message  string `json:"message"`
+
+	Fields map[string]string `json:"fields,omitempty"` // fields of the logger the message was printed with
 }
 
 // Info prints an info message to the standard logger
 func Info(caller, message string, args ...interface{}) {
-	printLog("info", str.InfoFormat, caller, message, args...)
+	printLog("info", str.InfoFormat, caller, message, nil, args...)
 }
 
 // Debug prints a debug message to the standard logger
 func Debug(caller, message string, args ...interface{}) {
 	if !env.IsProd() {
-		printLog("debug", str.DebugFormat, caller, message, args...)
+		printLog("debug", str.DebugFormat, caller, message, nil, args...)
 	}
 }
 
@@ -39,14 +41,18 @@ func DebugFlag(flag, caller, message string, args ...interface{}) {
 
 // Error prints an error message to the standard logger
 func Error(caller, message string, args ...interface{}) {
-	printLog("warn", str.ErrorFormat, caller, message, args...)
+	printLog("warn", str.ErrorFormat, caller, message, nil, args...)
 }
 
-// printLog prints logs to stdout in the proper format
+// printLog prints logs to stdout in the proper format, along with any fields
 // Standard in developer mode and JSON in deploy mode
-func printLog(severity, format, caller, message string, args ...interface{}) {
+func printLog(severity, format, caller, message string, fields []field, args ...interface{}) {
 	if !env.IsProd() {
-		log.Printf(format, caller, fmt.Sprintf(message, args...))
+		line := fmt.Sprintf(message, args...)
+		for _, f := range fields {
+			line += " " + f.key + "=" + f.value
+		}
+		log.Printf(format, caller, line)
 	} else {
 		logMessage := LogMessage{
 			Time:     MilliTime(),
@@ -54,6 +60,12 @@ func printLog(severity, format, caller, message string, args ...interface{}) {
 			Caller:   strings.TrimSpace(caller),
 			Message:  fmt.Sprintf(message, args...),
 		}
+		if len(fields) > 0 {
+			logMessage.Fields = make(map[string]string, len(fields))
+			for _, f := range fields {
+				logMessage.Fields[f.key] = f.value
+			}
+		}
 		if out, err := json.Marshal(logMessage); err != nil {
 			Error(str.CLog, str.ELogFail, err.Error(), logMessage)
 		} else {
@@ -0,0 +1,94 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/str"
+)
+
+// Logger prints log lines carrying a set of fields, such as the proxy, tile
+// and request ID of the request being handled, so related lines can be
+// correlated. A nil Logger prints lines without fields.
+type Logger struct {
+	fields []field
+}
+
+// field is a single key and value attached to log lines
+type field struct {
+	key   string
+	value string
+}
+
+// With returns a copy of the logger with the given field added
+func (l *Logger) With(key string, value interface{}) *Logger {
+	logger := &Logger{}
+	if l != nil {
+		logger.fields = make([]field, len(l.fields), len(l.fields)+1)
+		copy(logger.fields, l.fields)
+	}
+	logger.fields = append(logger.fields, field{key: key, value: fmt.Sprint(value)})
+	return logger
+}
+
+// Info prints an info message with the logger's fields
+func (l *Logger) Info(caller, message string, args ...interface{}) {
+	printLog("info", str.InfoFormat, caller, message, l.all(), args...)
+}
+
+// Debug prints a debug message with the logger's fields
+func (l *Logger) Debug(caller, message string, args ...interface{}) {
+	if !env.IsProd() {
+		printLog("debug", str.DebugFormat, caller, message, l.all(), args...)
+	}
+}
+
+// DebugFlag prints a debug message with the logger's fields if flag is enabled
+func (l *Logger) DebugFlag(flag, caller, message string, args ...interface{}) {
+	if IsDebugFlag(flag) {
+		l.Debug(caller, message, args...)
+	}
+}
+
+// Error prints an error message with the logger's fields
+func (l *Logger) Error(caller, message string, args ...interface{}) {
+	printLog("warn", str.ErrorFormat, caller, message, l.all(), args...)
+}
+
+// all returns the logger's fields, nil for a nil logger
+func (l *Logger) all() []field {
+	if l == nil {
+		return nil
+	}
+	return l.fields
+}
+
+// Log returns the request-scoped logger of the request, creating one carrying
+// the request ID if the request doesn't have one yet. Work done outside of a
+// request, without a context, gets a nil Logger.
+func Log(ctx *fiber.Ctx) *Logger {
+	if ctx == nil {
+		return nil
+	}
+
+	if logger, ok := ctx.Locals(str.LocalLogger).(*Logger); ok {
+		return logger
+	}
+
+	var logger *Logger
+	if id, ok := ctx.Locals(str.LocalRequestID).(string); ok && id != "" {
+		logger = logger.With("request_id", id)
+	} else {
+		logger = &Logger{}
+	}
+
+	ctx.Locals(str.LocalLogger, logger)
+	return logger
+}
+
+// LogWith adds the given field to the request-scoped logger of the request
+func LogWith(ctx *fiber.Ctx, key string, value interface{}) {
+	ctx.Locals(str.LocalLogger, Log(ctx).With(key, value))
+}
@@ -34,3 +34,22 @@ func GetMetricValue(col prometheus.Collector) float64 {
 	_ = (<-c).Write(&m) // read metric value from the channel
 	return *m.Counter.Value
 }
+
+// SumMetricValues sums the current values of every counter in the
+// given collector, such as all label combinations of a CounterVec
+func SumMetricValues(col prometheus.Collector) float64 {
+	c := make(chan prometheus.Metric)
+	go func() {
+		col.Collect(c)
+		close(c)
+	}()
+
+	sum := 0.0
+	for metric := range c {
+		m := dto.Metric{}
+		if metric.Write(&m) == nil && m.Counter != nil {
+			sum += m.Counter.GetValue()
+		}
+	}
+	return sum
+}
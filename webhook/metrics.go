@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/dechristopher/lod/config"
+)
+
+var Subsystem = "webhook"
+
+// metrics of the request summary webhook, which survive its sender being
+// rebuilt on config reloads
+var metrics = struct {
+	summaries *prometheus.CounterVec
+	batches   *prometheus.CounterVec
+	queued    prometheus.Gauge
+}{
+	summaries: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "summaries_total",
+		Help:      "The total number of request summaries by outcome, sent, failed or dropped when the queue is full",
+	}, []string{"result"}),
+	batches: promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "batches_total",
+		Help:      "The total number of batches posted to the collector by result",
+	}, []string{"result"}),
+	queued: promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: config.Namespace,
+		Subsystem: Subsystem,
+		Name:      "queued",
+		Help:      "The number of request summaries waiting to be posted",
+	}),
+}
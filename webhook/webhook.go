@@ -0,0 +1,190 @@
+// Package webhook asynchronously posts summaries of handled requests in
+// batches to an external collector, for custom analytics pipelines
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Summary describes a single handled proxy request
+type Summary struct {
+	Time        time.Time `json:"time"`                 // time the request was received
+	Proxy       string    `json:"proxy"`                // name of the proxy
+	Tile        string    `json:"tile,omitempty"`       // requested tile as z/x/y, if any
+	Key         string    `json:"key,omitempty"`        // name of the API key used, if any
+	CacheStatus string    `json:"cache_status"`         // cache status, ex: hit-m, miss
+	Status      int       `json:"status"`               // response status code
+	LatencyMS   float64   `json:"latency_ms"`           // time taken to handle the request in milliseconds
+	RequestID   string    `json:"request_id,omitempty"` // ID of the request, as logged
+}
+
+// Sender queues summaries and posts them to the collector in batches from a
+// single goroutine. Summaries are dropped rather than blocking requests when
+// the queue is full.
+type Sender struct {
+	config config.Webhook
+	queue  chan Summary
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+var (
+	mu     sync.RWMutex
+	sender *Sender
+)
+
+// Init starts a sender if the webhook is configured, flushing and stopping
+// any sender left over from a previous configuration
+func Init() {
+	var next *Sender
+	if hook := config.Get().Instance.Webhook; hook.URL != "" {
+		next = newSender(hook)
+		go next.run()
+	}
+
+	mu.Lock()
+	prev := sender
+	sender = next
+	mu.Unlock()
+
+	prev.Close()
+}
+
+// Close flushes the summaries queued by the active sender and stops it
+func Close() {
+	mu.Lock()
+	prev := sender
+	sender = nil
+	mu.Unlock()
+
+	prev.Close()
+}
+
+// Emit queues a request summary for the collector, if a webhook is configured
+func Emit(summary Summary) {
+	mu.RLock()
+	defer mu.RUnlock()
+	sender.Emit(summary)
+}
+
+// newSender builds a sender for the given webhook configuration
+func newSender(hook config.Webhook) *Sender {
+	return &Sender{
+		config: hook,
+		queue:  make(chan Summary, hook.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Emit queues a request summary, dropping it if the queue is full
+func (s *Sender) Emit(summary Summary) {
+	if s == nil {
+		return
+	}
+
+	select {
+	case s.queue <- summary:
+		metrics.queued.Inc()
+	default:
+		metrics.summaries.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Close stops the sender after posting all queued summaries
+func (s *Sender) Close() {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
+
+// run batches queued summaries, posting a batch once it is full or the
+// flush interval passes, until stopped
+func (s *Sender) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushIntervalDuration)
+	defer ticker.Stop()
+
+	batch := make([]Summary, 0, s.config.BatchSize)
+	for {
+		select {
+		case summary := <-s.queue:
+			metrics.queued.Dec()
+			batch = append(batch, summary)
+			if len(batch) >= s.config.BatchSize {
+				batch = s.post(batch)
+			}
+		case <-ticker.C:
+			batch = s.post(batch)
+		case <-s.stop:
+			for {
+				select {
+				case summary := <-s.queue:
+					metrics.queued.Dec()
+					batch = append(batch, summary)
+					if len(batch) >= s.config.BatchSize {
+						batch = s.post(batch)
+					}
+				default:
+					s.post(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// post sends a batch of summaries to the collector as a JSON array, returning
+// the emptied batch for reuse
+func (s *Sender) post(batch []Summary) []Summary {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := s.send(batch); err != nil {
+		util.Error(str.CMain, str.EWebhook, len(batch), err.Error())
+		metrics.batches.WithLabelValues("failure").Inc()
+		metrics.summaries.WithLabelValues("failed").Add(float64(len(batch)))
+	} else {
+		metrics.batches.WithLabelValues("success").Inc()
+		metrics.summaries.WithLabelValues("sent").Add(float64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// send posts a batch of summaries, failing on any non-2xx response
+func (s *Sender) send(batch []Summary) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	agent := fiber.Post(s.config.URL)
+	agent.Timeout(s.config.TimeoutDuration)
+	agent.ContentType(fiber.MIMEApplicationJSON)
+	for _, header := range s.config.Headers {
+		agent.Set(header.Name, header.Value)
+	}
+	agent.Body(body)
+
+	code, _, errs := agent.Bytes()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if code < fiber.StatusOK || code >= fiber.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", code)
+	}
+	return nil
+}
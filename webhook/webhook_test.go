@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// TestSender will test that queued summaries are posted in batches of the
+// configured size and flushed when the sender is closed
+func TestSender(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]Summary
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf(str.TWebhookBadHeader, r.Header.Get("Authorization"))
+		}
+		var batch []Summary
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	s := newSender(config.Webhook{
+		URL:                   server.URL,
+		Headers:               []config.Header{{Name: "Authorization", Value: "Bearer secret"}},
+		BatchSize:             2,
+		QueueSize:             10,
+		FlushIntervalDuration: time.Hour,
+		TimeoutDuration:       time.Second,
+	})
+	go s.run()
+
+	for _, name := range []string{"a", "b", "c"} {
+		s.Emit(Summary{Proxy: name, Status: 200})
+	}
+	s.Close()
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 ||
+		batches[0][0].Proxy != "a" || batches[1][0].Proxy != "c" {
+		t.Errorf(str.TWebhookBadBatches, batches)
+	}
+}
+
+// TestSenderDrop will test that summaries are dropped instead of blocking
+// once the queue is full
+func TestSenderDrop(t *testing.T) {
+	s := newSender(config.Webhook{BatchSize: 1, QueueSize: 1})
+
+	done := make(chan struct{})
+	go func() {
+		s.Emit(Summary{Proxy: "a"})
+		s.Emit(Summary{Proxy: "b"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal(str.TWebhookBlocked)
+	}
+	if len(s.queue) != 1 {
+		t.Errorf(str.TWebhookBadQueue, len(s.queue))
+	}
+}
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type budgetResponse struct {
+	Proxy     string               `json:"proxy"`     // name of the proxy
+	Spent     bool                 `json:"spent"`     // whether tiles are served from cache only
+	Resets    *time.Time           `json:"resets"`    // time the spent budget resets, null if not spent
+	Windows   []cache.BudgetWindow `json:"windows"`   // configured budget windows
+	Unlimited bool                 `json:"unlimited"` // whether no budget is configured
+}
+
+// BudgetStatus returns the upstream request budget of a proxy by name, with
+// the requests counted against it in each window
+func BudgetStatus(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	windows, err := c.BudgetStatus(ctx.Context())
+	if err != nil {
+		util.Error(str.CAdmin, str.ECacheBudget, c.Proxy.Name, err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	response := budgetResponse{
+		Proxy:     c.Proxy.Name,
+		Windows:   windows,
+		Unlimited: !c.Proxy.Budget.Enabled(),
+	}
+	now := time.Now()
+	if until, spent := c.BudgetExhausted(now); spent {
+		resets := now.Add(until).Truncate(time.Second)
+		response.Spent = true
+		response.Resets = &resets
+	}
+	return ctx.JSON(response)
+}
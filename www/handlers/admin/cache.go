@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/gofiber/fiber/v2"
@@ -22,22 +23,55 @@ type invalidateAndPrimePayload struct {
 // InvalidateAndPrime is the parent function to all invalidation and priming endpoints
 func InvalidateAndPrime(ctx *fiber.Ctx, payload invalidateAndPrimePayload) error {
 	// get cache by name for this request if one is configured
-	c := cache.Get(ctx.Locals(str.LocalCacheName).(string))
+	c := cache.FromCtx(ctx)
 	if c == nil {
-		util.Error(str.CAdmin, payload.ErrorMessage, "unknown", "invalid proxy name")
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", "invalid proxy name")
 		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
 			"status": "failed",
 			"error":  "invalid proxy name provided",
 		})
 	}
 
+	// priming must never contact the upstream while in maintenance mode
+	if payload.Prime && c.InMaintenance() {
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", str.RMaintenance)
+		return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable,
+			str.RMaintenance, c.Proxy.Maintenance.RetryAfterDuration)
+	}
+
+	// target an explicit cache generation, e.g. to seed the inactive one
+	if !targetGeneration(ctx, c) {
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", "invalid generation")
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "invalid generation provided",
+		})
+	}
+
 	// fill params map to augment param segmentation behavior present in proxy endpoint
 	helpers.FillParamsMap(*c.Proxy, ctx)
+	if !helpers.FillVersion(*c.Proxy, ctx) {
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", "invalid data version")
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "invalid data version provided",
+		})
+	}
+
+	// prime every combination of configured param values if asked to
+	matrix, ok := requestedMatrix(ctx, c)
+	if payload.Prime && !ok {
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", "no param values configured")
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "no param values configured for proxy",
+		})
+	}
 
 	// get requested reqTile from context
 	reqTile, err := tile.Get(ctx)
 	if err != nil {
-		util.Error(str.CAdmin, payload.ErrorMessage, "unknown", err.Error())
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, "unknown", err.Error())
 		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
 			"status":  "failed",
 			"error":   "invalid reqTile requested",
@@ -45,10 +79,15 @@ func InvalidateAndPrime(ctx *fiber.Ctx, payload invalidateAndPrimePayload) error
 		})
 	}
 
+	// fetch tiles in subtrees known to be empty from the upstream again
+	if errEmpty := c.ForgetEmpty(ctx.Context(), *reqTile); errEmpty != nil {
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, reqTile.String(), errEmpty.Error())
+	}
+
 	// determine max zoom based on parameter
 	maxZoom, err := ctx.ParamsInt("maxZoom", payload.MaxZoom)
 	if err != nil {
-		util.Error(str.CAdmin, payload.ErrorMessage, reqTile.String(), err.Error())
+		util.Log(ctx).Error(str.CAdmin, payload.ErrorMessage, reqTile.String(), err.Error())
 		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
 			"status":  "failed",
 			"error":   "internal server error",
@@ -59,85 +98,143 @@ func InvalidateAndPrime(ctx *fiber.Ctx, payload invalidateAndPrimePayload) error
 	// calculate all necessary tiles for this operation
 	tiles := reqTile.DeepChildren(maxZoom)
 
-	util.Debug(str.CAdmin, str.DCalcTiles, c.Proxy.Name,
+	util.Log(ctx).Debug(str.CAdmin, str.DCalcTiles, c.Proxy.Name,
 		len(tiles), reqTile.String(), maxZoom)
 
 	succeeded := 0
+	resumed := 0
+	attempted := len(tiles)
+
+	// tiles primed with each combination of param values, nil otherwise
+	var entries []tile.ListEntry
 
 	if !payload.Prime {
 		// simply invalidate en masse
 		for _, tileToInvalidate := range tiles {
 			key, errKey := helpers.BuildCacheKey(*c.Proxy, ctx, tileToInvalidate)
 			if errKey != nil {
-				util.Debug(str.CAdmin, str.DInvalidateFail, tileToInvalidate.String(), err.Error())
+				util.Log(ctx).Debug(str.CAdmin, str.DInvalidateFail, tileToInvalidate.String(), err.Error())
 				continue
 			}
 			errInv := c.Invalidate(key, ctx.Context())
 			if errInv != nil {
-				util.Debug(str.CAdmin, str.DInvalidateFail, tileToInvalidate.String(), errInv)
+				util.Log(ctx).Debug(str.CAdmin, str.DInvalidateFail, tileToInvalidate.String(), errInv)
 			}
 			succeeded++
 		}
 	} else {
-		// fetch and prime in place for the given tile to avoid invalidating tiles
-		// en masse and having missing tiles in the cache during the priming period
-		wg := &sync.WaitGroup{}
-		wg.Add(c.Proxy.NumWorkers)
-
-		jobs := make(chan tile.Tile, len(tiles))
-		successes := make(chan bool, len(tiles))
-
-		// spin up workers to make agent-proxied requests to the upstream
-		for numWorkers := 0; numWorkers < c.Proxy.NumWorkers; numWorkers++ {
-			go tileWorker(tileWorkerPayload{
-				jobs:      jobs,
-				successes: successes,
-				cache:     c,
-				ctx:       ctx,
-				waitGroup: wg,
-			})
+		jobs := make([]primeJob, 0, len(tiles))
+		if matrix != nil {
+			query := primeQuery(ctx)
+			entries = make([]tile.ListEntry, 0, len(tiles))
+			for _, t := range tiles {
+				entries = append(entries, tile.ListEntry{Tile: t, Query: query})
+			}
+			entries = tile.ExpandMatrix(entries, matrix)
+			for i := range entries {
+				jobs = append(jobs, primeJob{tile: entries[i].Tile, entry: &entries[i]})
+			}
+		} else {
+			for _, t := range tiles {
+				jobs = append(jobs, primeJob{tile: t})
+			}
 		}
-
-		// submit jobs to workers
-		for _, tileJob := range tiles {
-			jobs <- tileJob
+		attempted = len(jobs)
+
+		// deep priming jobs checkpoint their progress to resume where they were
+		// interrupted when repeated, unless asked to restart
+		var checkpoint *cache.Checkpoint
+		var progress *cache.CheckpointProgress
+		if attempted > 1 {
+			subject := fmt.Sprintf("%s|%d", reqTile.String(), maxZoom)
+			checkpoint, progress = loadProgress(ctx, c, seedJobID(ctx, c, subject), attempted)
 		}
 
-		// signal that we're out of tiles to prime
-		close(jobs)
-
-		// wait until workers finish
-		wg.Wait()
+		pending := pendingJobs(jobs, checkpoint)
+		resumed = attempted - len(pending)
 
-		// close successes channel after workers finish
-		close(successes)
+		// count successfully primed tiles, including those primed before resuming
+		succeeded = resumed + primeTiles(ctx, c, pending, progress)
+		progress.Finish(succeeded == attempted)
+	}
 
-		// count successfully primed tiles
-		for range successes {
-			succeeded++
+	// purge invalidated and re-primed tiles from the downstream CDN, unless
+	// they belong to an inactive generation that isn't being served yet
+	if helpers.CacheGeneration(*c.Proxy, ctx) == c.Generation() {
+		if entries != nil {
+			purgeCDNURLs(c, listPublicUrls(ctx, c, entries))
+		} else {
+			purgeCDNTiles(ctx, c, tiles)
 		}
 	}
 
 	status := "ok"
-	if succeeded != len(tiles) {
+	if succeeded != attempted {
 		status = "failed"
 	}
 
-	util.Info(str.CAdmin, payload.InfoMessage, reqTile.String(), maxZoom, len(tiles))
+	util.Log(ctx).Info(str.CAdmin, payload.InfoMessage, reqTile.String(), maxZoom, attempted)
 	return ctx.JSON(map[string]interface{}{
-		"attempted": len(tiles),
+		"attempted": attempted,
 		"primed":    succeeded,
+		"resumed":   resumed,
 		"status":    status,
 	})
 }
 
+// primeTiles fetches and primes the given jobs in place with the proxy's
+// workers, returning the number of tiles primed
+func primeTiles(ctx *fiber.Ctx, c *cache.Cache, pending []primeJob, progress *cache.CheckpointProgress) int {
+	// fetch and prime in place for the given tile to avoid invalidating tiles
+	// en masse and having missing tiles in the cache during the priming period
+	wg := &sync.WaitGroup{}
+	wg.Add(c.Proxy.Background.Workers)
+
+	jobs := make(chan primeJob, len(pending))
+	successes := make(chan bool, len(pending))
+
+	// spin up workers to make agent-proxied requests to the upstream
+	for numWorkers := 0; numWorkers < c.Proxy.Background.Workers; numWorkers++ {
+		go tileWorker(tileWorkerPayload{
+			jobs:      jobs,
+			successes: successes,
+			cache:     c,
+			ctx:       ctx,
+			progress:  progress,
+			waitGroup: wg,
+		})
+	}
+
+	// submit jobs to workers
+	for _, job := range pending {
+		jobs <- job
+	}
+
+	// signal that we're out of tiles to prime
+	close(jobs)
+
+	// wait until workers finish
+	wg.Wait()
+
+	// close successes channel after workers finish
+	close(successes)
+
+	// count successfully primed tiles
+	succeeded := 0
+	for range successes {
+		succeeded++
+	}
+	return succeeded
+}
+
 // tileWorkerPayload is a struct containing all the ingredients
 // needed for a tileWorker to operate on its job queue
 type tileWorkerPayload struct {
-	jobs      <-chan tile.Tile
+	jobs      <-chan primeJob
 	successes chan<- bool
 	cache     *cache.Cache
 	ctx       *fiber.Ctx
+	progress  *cache.CheckpointProgress // checkpointed progress of the job, nil if not checkpointed
 	waitGroup *sync.WaitGroup
 }
 
@@ -146,50 +243,56 @@ type tileWorkerPayload struct {
 func tileWorker(payload tileWorkerPayload) {
 	defer payload.waitGroup.Done()
 
-	for tileJob := range payload.jobs {
-		url, err := helpers.BuildTileUrl(*payload.cache.Proxy, payload.ctx, tileJob)
-		if err != nil {
-			util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
-			continue
-		}
-
-		cacheKey, err := helpers.BuildCacheKey(*payload.cache.Proxy, payload.ctx, tileJob)
-		if err != nil {
-			util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
-			continue
-		}
-
-		response, errProxy := helpers.FetchUpstream(url, *payload.cache.Proxy)()
-		if errProxy != nil {
-			util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
-			continue
+	for job := range payload.jobs {
+		if primeTile(payload, job) {
+			// signal successful tile
+			payload.successes <- true
+			payload.progress.Complete(job.tile.Zoom, job.index)
 		}
+	}
+}
 
-		// cast interface returned from flight group to a proxyResponse
-		proxyResp, ok := response.(helpers.ProxyResponse)
+// primeTile fetches a single tile from the upstream and primes the cache with
+// it, returning true if it was primed
+func primeTile(payload tileWorkerPayload, job primeJob) bool {
+	tileJob := job.tile
 
-		// sanity check to ensure cast worked properly
+	// tile list entries are requested with their own parameters
+	ctx := payload.ctx
+	if job.entry != nil {
+		var ok bool
+		ctx, ok = helpers.AcquireEntryCtx(ctx.App(), *payload.cache.Proxy, payload.cache, *job.entry)
 		if !ok {
-			util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
-			continue
+			util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), "invalid data version")
+			return false
 		}
+		defer ctx.App().ReleaseCtx(ctx)
 
-		// write reqTile data and headers and cache result
-		if err = helpers.ProcessResponse(helpers.ProcessResponsePayload{
-			Ctx:       payload.ctx,
-			Cache:     payload.cache,
-			Proxy:     *payload.cache.Proxy,
-			CacheKey:  cacheKey,
-			Response:  proxyResp,
-			WriteData: true,
-		}); err != nil {
-			util.DebugFlag("primer", str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
-			return
+		if generation, isSet := payload.ctx.Locals(str.LocalGeneration).(string); isSet {
+			ctx.Locals(str.LocalGeneration, generation)
 		}
+	}
+
+	cacheKey, err := helpers.BuildCacheKey(*payload.cache.Proxy, ctx, tileJob)
+	if err != nil {
+		util.Debug(str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
+		return false
+	}
 
-		// signal successful tile
-		payload.successes <- true
+	// priming jobs are fair queued as a single background client
+	if err = helpers.FetchBackground(helpers.BackgroundFetchPayload{
+		Ctx:       ctx,
+		Cache:     payload.cache,
+		Tile:      tileJob,
+		CacheKey:  cacheKey,
+		Client:    str.ClientAdmin,
+		WriteData: true,
+	}); err != nil {
+		util.DebugFlag("primer", str.CAdmin, str.DPrimeFail, tileJob.String(), err.Error())
+		return false
 	}
+
+	return true
 }
 
 // InvalidateTile will invalidate a tile from the caches if it exists
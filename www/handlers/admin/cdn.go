@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+// purgeCDNTiles purges the public URLs of the given tiles from the proxy's
+// downstream CDN in the background, if a CDN is configured
+func purgeCDNTiles(ctx *fiber.Ctx, c *cache.Cache, tiles []tile.Tile) {
+	if c.CDN == nil {
+		return
+	}
+
+	// build URLs up front since the request context is recycled after returning
+	urls := make([]string, 0, len(tiles))
+	for _, t := range tiles {
+		publicUrl, err := helpers.BuildPublicUrl(*c.Proxy, ctx, t)
+		if err != nil {
+			util.Debug(str.CAdmin, str.DCDNPurgeFail, t.String(), err.Error())
+			continue
+		}
+		urls = append(urls, publicUrl)
+	}
+
+	purgeCDNURLs(c, urls)
+}
+
+// purgeCDNURLs purges the given public tile URLs from the proxy's downstream
+// CDN in the background, if one is configured
+func purgeCDNURLs(c *cache.Cache, urls []string) {
+	if c.CDN == nil {
+		return
+	}
+
+	go func() {
+		if err := c.CDN.PurgeURLs(urls); err != nil {
+			util.Error(str.CAdmin, str.ECDNPurge, c.Proxy.Name, err.Error())
+			return
+		}
+		util.Info(str.CAdmin, str.MCDNPurge, c.Proxy.Name, len(urls))
+	}()
+}
+
+// purgeCDNTags purges all objects with the given tags from the proxy's
+// downstream CDN in the background, if a CDN supporting tag purges is configured
+func purgeCDNTags(c *cache.Cache, tags []string) {
+	if c.CDN == nil {
+		return
+	}
+
+	if tagHeader, _ := c.CDN.TagHeader(tags); tagHeader == "" {
+		return
+	}
+
+	go func() {
+		if err := c.CDN.PurgeTags(tags); err != nil {
+			util.Error(str.CAdmin, str.ECDNPurge, c.Proxy.Name, err.Error())
+			return
+		}
+		util.Info(str.CAdmin, str.MCDNPurgeTags, c.Proxy.Name, tags)
+	}()
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+// primeJob is a single tile to prime, along with its position among the
+// tiles of its zoom level for checkpointing
+type primeJob struct {
+	tile  tile.Tile
+	index int
+	entry *tile.ListEntry // tile list entry requesting the tile, nil to use the priming request's parameters
+}
+
+type progressResponse struct {
+	Proxy string             `json:"proxy"` // name of the proxy
+	Jobs  []cache.Checkpoint `json:"jobs"`  // progress of running and interrupted seed jobs
+}
+
+// PrimeProgress returns the progress of the priming and seed jobs of a proxy
+// by name that are running or were interrupted, as checkpointed to Redis
+func PrimeProgress(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	if !c.Proxy.Cache.RedisEnabled {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "redis cache not enabled for proxy",
+		})
+	}
+
+	checkpoints, err := c.Checkpoints(ctx.Context())
+	if err != nil {
+		util.Error(str.CAdmin, str.ECacheCheckpoint, c.Proxy.Name, "*", err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	return ctx.JSON(progressResponse{
+		Proxy: c.Proxy.Name,
+		Jobs:  checkpoints,
+	})
+}
+
+// seedJobID identifies a priming job by the proxy, the subject describing its
+// tiles and the parameters shaping them, so repeating the request resumes it
+func seedJobID(ctx *fiber.Ctx, c *cache.Cache, subject string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s", c.Proxy.Name, subject,
+		helpers.CacheGeneration(*c.Proxy, ctx), primeQuery(ctx))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadProgress returns the checkpoint of the priming job with the given ID
+// and progress tracking resuming from it, both nil if the redis cache is
+// disabled. Checkpoints are dropped instead if asked to restart the job.
+func loadProgress(ctx *fiber.Ctx, c *cache.Cache, id string, total int) (*cache.Checkpoint, *cache.CheckpointProgress) {
+	checkpoint, err := c.ResumeCheckpoint(ctx.Context(), id,
+		string(ctx.Request().URI().RequestURI()), total, ctx.QueryBool("restart", false))
+	if err != nil {
+		util.Log(ctx).Error(str.CAdmin, str.ECacheCheckpoint, c.Proxy.Name, id, err.Error())
+	}
+
+	if checkpoint != nil && checkpoint.Completed() > 0 {
+		util.Log(ctx).Info(str.CAdmin, str.MPrimeResume, c.Proxy.Name, id, checkpoint.Completed(), total)
+	}
+	return checkpoint, cache.NewCheckpointProgress(c, checkpoint)
+}
+
+// pendingJobs numbers the given jobs in order within their zoom levels,
+// returning those not yet completed as of the given checkpoint
+func pendingJobs(jobs []primeJob, cp *cache.Checkpoint) []primeJob {
+	pending := make([]primeJob, 0, len(jobs))
+	indices := make(map[int]int)
+	for _, job := range jobs {
+		job.index = indices[job.tile.Zoom]
+		indices[job.tile.Zoom]++
+		if cp != nil && job.index < cp.Done[job.tile.Zoom] {
+			continue
+		}
+		pending = append(pending, job)
+	}
+	return pending
+}
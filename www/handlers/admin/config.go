@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Config returns the effective configuration of the instance as loaded, after
+// environment interpolation, defaults and reloads, with secrets masked
+func Config(c *fiber.Ctx) error {
+	return c.JSON(config.Get().Redacted())
+}
+
+// ConfigDiff returns the structural difference between the running
+// configuration and a candidate configuration in the request body, in the
+// format given by the format query parameter (toml, yaml or hcl, defaulting
+// to toml), without applying it. Candidates that fail validation are
+// rejected with the validation error.
+func ConfigDiff(c *fiber.Ctx) error {
+	format := c.Query("format", config.FormatTOML)
+	if format != config.FormatTOML && format != config.FormatYAML && format != config.FormatHCL {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "unsupported config format",
+			"format": format,
+		})
+	}
+
+	candidate, err := config.Parse(c.Body(), format)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "invalid config",
+			"error":  err.Error(),
+		})
+	}
+
+	return c.JSON(config.DiffCapabilities(config.Get(), &candidate))
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/corsdebug"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type corsResponse struct {
+	Proxy    string              `json:"proxy"`    // name of the proxy
+	Seen     uint64              `json:"seen"`     // cross-origin requests considered for capture
+	Recorded uint64              `json:"recorded"` // captures recorded, including those no longer kept
+	Captures []corsdebug.Capture `json:"captures"` // kept captures, newest first
+}
+
+// CORSCaptures returns the captured CORS decisions of a proxy by name, newest
+// first, optionally only those of an origin (?origin=) or of disallowed
+// origins (?mismatches=true)
+func CORSCaptures(ctx *fiber.Ctx) error {
+	return corsCaptures(ctx, false)
+}
+
+// ResetCORSCaptures drops the captured CORS decisions of a proxy by name
+func ResetCORSCaptures(ctx *fiber.Ctx) error {
+	return corsCaptures(ctx, true)
+}
+
+// corsCaptures optionally resets the captured CORS decisions of a proxy by
+// name, and responds with the resulting captures
+func corsCaptures(ctx *fiber.Ctx, reset bool) error {
+	name := ctx.Locals(str.LocalCacheName).(string)
+	recorder := corsdebug.Get(name)
+	if recorder == nil {
+		// 404 if the proxy doesn't capture CORS decisions
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no cors debug capture enabled for proxy with given name",
+		})
+	}
+
+	if reset {
+		recorder.Reset()
+		util.Info(str.CAdmin, str.MCORSDebugReset, name)
+	}
+
+	captures, seen, recorded := recorder.Captures(ctx.Query("origin"), ctx.QueryBool("mismatches"))
+	return ctx.JSON(corsResponse{
+		Proxy:    name,
+		Seen:     seen,
+		Recorded: recorded,
+		Captures: captures,
+	})
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type coverageResponse struct {
+	Proxy    string          `json:"proxy"`    // name of the proxy
+	Coverage config.Coverage `json:"coverage"` // zoom levels and bounds tiles are served for
+}
+
+// CoverageStatus returns the zoom levels and bounds a proxy by name serves
+func CoverageStatus(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	return ctx.JSON(coverageResponse{
+		Proxy:    c.Proxy.Name,
+		Coverage: c.Coverage(),
+	})
+}
+
+// DiscoverCoverage populates the unset zoom levels and bounds of a proxy by
+// name from its upstream metadata
+func DiscoverCoverage(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil || c.Proxy.Metadata.URL == "" {
+		// 404 if no proxy with metadata found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy with metadata configured with given name",
+		})
+	}
+
+	coverage, err := helpers.DiscoverCoverage(c)
+	if err != nil {
+		util.Error(str.CAdmin, str.EDiscover, c.Proxy.Name, err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	util.Info(str.CAdmin, str.MDiscover, c.Proxy.Name, coverage)
+	return ctx.JSON(coverageResponse{
+		Proxy:    c.Proxy.Name,
+		Coverage: coverage,
+	})
+}
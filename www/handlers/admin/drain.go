@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/fleet"
+)
+
+type drainResponse struct {
+	Status string  `json:"status"` // draining
+	Waited float64 `json:"waited"` // seconds waited for load balancer deregistration
+}
+
+// Drain takes the instance out of rotation before shutdown, failing readiness
+// checks and marking it draining in the fleet registry, and responds once the
+// configured delay for load balancer deregistration has passed. Meant to be
+// called from preStop hooks, after which the instance can be stopped.
+func Drain(ctx *fiber.Ctx) error {
+	waited := fleet.Drain(ctx.Context())
+	return ctx.JSON(drainResponse{
+		Status: "draining",
+		Waited: waited.Seconds(),
+	})
+}
@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/fleet"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type fleetResponse struct {
+	Self    string         `json:"self"`    // node ID of this instance
+	Members []fleet.Member `json:"members"` // live instances registered in the fleet
+}
+
+// Fleet returns the instances registered in the fleet registry with a recent
+// heartbeat, including this one
+func Fleet(ctx *fiber.Ctx) error {
+	if config.Get().Instance.Fleet.RedisURL == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "fleet registry not configured",
+		})
+	}
+
+	members, err := fleet.List(ctx.Context())
+	if err != nil {
+		util.Error(str.CAdmin, str.EFleetList, err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	return ctx.JSON(fleetResponse{
+		Self:    config.Get().Instance.NodeID,
+		Members: members,
+	})
+}
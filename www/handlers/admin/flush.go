@@ -9,12 +9,20 @@ import (
 	"github.com/dechristopher/lod/util"
 )
 
-// Flush an entire proxy cache by name, or all caches
-func Flush(ctx *fiber.Ctx) error {
-	if ctx.Path() == "/admin/flush" {
+// Flush builds a handler flushing an entire proxy cache by name, or all caches
+// held by the given manager
+func Flush(caches *cache.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return flush(caches, ctx)
+	}
+}
+
+// flush an entire proxy cache by name, or all caches
+func flush(caches *cache.Manager, ctx *fiber.Ctx) error {
+	if ctx.Locals(str.LocalCacheName) == nil {
 		// flush all configured proxies
 		for _, proxy := range config.Get().Proxies {
-			err := cache.Get(proxy.Name).FlushInternal()
+			err := caches.Get(proxy.Name).FlushInternal()
 
 			if err != nil {
 				util.Error(str.CAdmin, str.ECacheFlush, proxy.Name, err.Error())
@@ -42,7 +50,7 @@ func Flush(ctx *fiber.Ctx) error {
 	for _, proxy := range config.Get().Proxies {
 		if proxy.Name == name {
 			// flush the proxy's internal cache
-			err := cache.Get(proxy.Name).FlushInternal()
+			err := caches.Get(proxy.Name).FlushInternal()
 
 			if err != nil {
 				util.Error(str.CAdmin, str.ECacheFlush, name, err.Error())
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type generationResponse struct {
+	Proxy    string `json:"proxy"`    // name of the proxy
+	Active   string `json:"active"`   // cache generation being served
+	Inactive string `json:"inactive"` // cache generation available for seeding
+}
+
+// GenerationStatus returns the active and inactive cache generations of a
+// proxy by name
+func GenerationStatus(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	if !c.Proxy.Generations.Enabled {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "generations not enabled for proxy",
+		})
+	}
+
+	return ctx.JSON(generationResponse{
+		Proxy:    c.Proxy.Name,
+		Active:   c.Generation(),
+		Inactive: c.InactiveGeneration(),
+	})
+}
+
+// SwitchGeneration atomically switches a proxy by name to serve the cache
+// generation given by path parameter, typically the inactive one after
+// seeding it with a new data release. Naming the target rather than toggling
+// keeps repeated or concurrent switches against different instances agreeing.
+func SwitchGeneration(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	generation := ctx.Params("generation")
+	if err := c.SwitchGeneration(ctx.Context(), generation); err != nil {
+		util.Error(str.CAdmin, str.EGenerationSwitch, c.Proxy.Name, err.Error())
+
+		status := fiber.StatusInternalServerError
+		var disabled cache.ErrGenerationsDisabled
+		var invalid cache.ErrInvalidGeneration
+		if errors.As(err, &disabled) || errors.As(err, &invalid) {
+			status = fiber.StatusBadRequest
+		}
+		return ctx.Status(status).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	util.Info(str.CAdmin, str.MGenerationSwitch, c.Proxy.Name, generation)
+	return GenerationStatus(ctx)
+}
+
+// targetGeneration stores the cache generation targeted by an admin request's
+// ?generation= parameter, returning false if it is invalid for the proxy
+func targetGeneration(ctx *fiber.Ctx, c *cache.Cache) bool {
+	generation := ctx.Query("generation")
+	if generation == "" {
+		return true
+	}
+
+	if !c.Proxy.Generations.Enabled ||
+		(generation != config.GenerationBlue && generation != config.GenerationGreen) {
+		return false
+	}
+
+	ctx.Locals(str.LocalGeneration, generation)
+	return true
+}
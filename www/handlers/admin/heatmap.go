@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/heatmap"
+	"github.com/dechristopher/lod/str"
+)
+
+// Heatmap formats of a single requested zoom level
+const (
+	heatmapGeoJSON = "geojson"
+	heatmapPNG     = "png"
+)
+
+// Heatmap returns the request counts of a proxy by name aggregated over its
+// last completed heatmap window, or the current one with ?live=true
+func Heatmap(ctx *fiber.Ctx) error {
+	export, err := heatmapExport(ctx)
+	if export == nil {
+		return err
+	}
+	return ctx.JSON(export)
+}
+
+// HeatmapZoom returns the heatmap of a single requested zoom level of a proxy
+// by name, as GeoJSON (?format=geojson, default) or PNG (?format=png)
+func HeatmapZoom(ctx *fiber.Ctx) error {
+	zoom, err := ctx.ParamsInt("zoom")
+	if err != nil || zoom < 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "invalid zoom level provided",
+		})
+	}
+
+	format := ctx.Query("format", heatmapGeoJSON)
+	if format != heatmapGeoJSON && format != heatmapPNG {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "format must be geojson or png",
+		})
+	}
+
+	export, err := heatmapExport(ctx)
+	if export == nil {
+		return err
+	}
+
+	if format == heatmapGeoJSON {
+		ctx.Set(fiber.HeaderContentType, "application/geo+json")
+		return ctx.JSON(export.GeoJSON(zoom))
+	}
+
+	image, err := export.PNG(zoom)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+	ctx.Set(fiber.HeaderContentType, "image/png")
+	return ctx.Send(image)
+}
+
+// heatmapExport returns the requested heatmap window of a proxy by name, or
+// responds with why there is none
+func heatmapExport(ctx *fiber.Ctx) (*heatmap.Export, error) {
+	tracker := heatmap.Get(ctx.Locals(str.LocalCacheName).(string))
+	if tracker == nil {
+		// 404 if the proxy has no heatmap enabled
+		return nil, ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no heatmap enabled for proxy with given name",
+		})
+	}
+
+	export := tracker.Exported(ctx.QueryBool("live"))
+	if export == nil {
+		// 404 until the first window completes
+		return nil, ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no heatmap window completed yet, try ?live=true",
+		})
+	}
+	return export, nil
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type keyHashResponse struct {
+	Proxy  string `json:"proxy"`  // name of the proxy
+	Hashed string `json:"hashed"` // redis key the tile is stored under
+	Key    string `json:"key"`    // cache key the redis key was hashed from
+}
+
+// KeyHashLookup returns the cache key a hashed redis key of a proxy by name
+// was hashed from, as recorded by its key hashing debug index
+func KeyHashLookup(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	if !c.Proxy.Cache.KeyHash.DebugIndex {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "key hashing debug index not enabled for proxy",
+		})
+	}
+
+	hashed := ctx.Params("hashed")
+	key, err := c.KeyOf(ctx.Context(), hashed)
+	if err != nil {
+		util.Error(str.CAdmin, str.ECacheKeyIndex, c.Proxy.Name, hashed, err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	if key == "" {
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "hashed key not found in debug index",
+		})
+	}
+
+	return ctx.JSON(keyHashResponse{
+		Proxy:  c.Proxy.Name,
+		Hashed: hashed,
+		Key:    key,
+	})
+}
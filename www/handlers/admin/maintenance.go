@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type maintenanceResponse struct {
+	Proxy       string `json:"proxy"`       // name of the proxy
+	Maintenance bool   `json:"maintenance"` // whether the proxy is in maintenance mode
+	Mode        string `json:"mode"`        // configured maintenance behavior
+	RetryAfter  string `json:"retry_after"` // configured Retry-After sent with 503 responses
+}
+
+// MaintenanceStatus returns the maintenance mode state of a proxy by name
+func MaintenanceStatus(ctx *fiber.Ctx) error {
+	return setMaintenance(ctx, nil)
+}
+
+// EnableMaintenance puts a proxy by name into maintenance mode
+func EnableMaintenance(ctx *fiber.Ctx) error {
+	enabled := true
+	return setMaintenance(ctx, &enabled)
+}
+
+// DisableMaintenance takes a proxy by name out of maintenance mode
+func DisableMaintenance(ctx *fiber.Ctx) error {
+	enabled := false
+	return setMaintenance(ctx, &enabled)
+}
+
+// setMaintenance applies the given maintenance state to a proxy by name, if
+// any, and responds with the resulting state
+func setMaintenance(ctx *fiber.Ctx, enabled *bool) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	if enabled != nil {
+		c.SetMaintenance(*enabled)
+		util.Info(str.CAdmin, str.MMaintenance, c.Proxy.Name, *enabled, c.Proxy.Maintenance.Mode)
+	}
+
+	return ctx.JSON(maintenanceResponse{
+		Proxy:       c.Proxy.Name,
+		Maintenance: c.InMaintenance(),
+		Mode:        c.Proxy.Maintenance.Mode,
+		RetryAfter:  c.Proxy.Maintenance.RetryAfter,
+	})
+}
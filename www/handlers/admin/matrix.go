@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/cache"
+)
+
+// requestedMatrix returns the combinations of param values to prime every
+// tile with if the priming request asks for them with ?matrix=true. Returns
+// false if asked to but the proxy has no param values configured.
+func requestedMatrix(ctx *fiber.Ctx, c *cache.Cache) ([]map[string]string, bool) {
+	if !ctx.QueryBool("matrix", false) {
+		return nil, true
+	}
+
+	matrix := c.Proxy.ParamMatrix()
+	return matrix, matrix != nil
+}
+
+// primeQuery returns the query parameters of a priming request, leaving out
+// whether to restart the job so restarting doesn't change it
+func primeQuery(ctx *fiber.Ctx) string {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+	ctx.Request().URI().QueryArgs().CopyTo(args)
+	args.Del("restart")
+	return args.String()
+}
@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// queryParams lists the optional query parameters accepted by endpoints,
+// keyed by operation ID
+var queryParams = map[string][]string{
+	"getProxySavings":           {"window", "cost_per_gb", "cost_per_million"},
+	"invalidateProxyTile":       {"generation"},
+	"invalidateProxyTileDeep":   {"generation"},
+	"invalidateProxyTileDeepTo": {"generation"},
+	"primeProxyTile":            {"generation"},
+	"primeProxyTileDeep":        {"generation"},
+	"primeProxyTileDeepTo":      {"generation"},
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components *openAPIComponents              `json:"components,omitempty"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+}
+
+type operation struct {
+	method      string
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]parameterSchemas `json:"content"`
+}
+
+type parameterSchemas struct {
+	Schema parameterSchema `json:"schema"`
+}
+
+type parameter struct {
+	Name     string          `json:"name"`
+	In       string          `json:"in"`
+	Required bool            `json:"required"`
+	Schema   parameterSchema `json:"schema"`
+}
+
+type parameterSchema struct {
+	Type string   `json:"type"`
+	Enum []string `json:"enum,omitempty"`
+}
+
+type response struct {
+	Description string `json:"description"`
+}
+
+// OpenAPI builds a handler serving an OpenAPI 3 document describing the given
+// global and proxy-specific admin endpoints mounted under basePath
+func OpenAPI(basePath string, global, named []route) fiber.Handler {
+	doc, _ := json.Marshal(buildOpenAPI(basePath, global, named))
+
+	return func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return ctx.Send(doc)
+	}
+}
+
+// buildOpenAPI generates the OpenAPI document for the admin endpoints of the
+// current configuration
+func buildOpenAPI(basePath string, global, named []route) openAPIDoc {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "LOD Admin API",
+			Version: config.Version,
+		},
+		Paths: map[string]map[string]operation{},
+	}
+
+	if config.Get().Instance.AdminToken != "" {
+		doc.Components = &openAPIComponents{SecuritySchemes: map[string]securityScheme{
+			"bearer": {Type: "http", Scheme: "bearer"},
+		}}
+		doc.Security = []map[string][]string{{"bearer": {}}}
+	}
+
+	for _, rt := range global {
+		path, params := openAPIPath(rt.Path)
+		addOperation(doc, basePath+path, newOperation(rt, "instance", params))
+	}
+
+	var names []string
+	endpointParam := false
+	for _, proxy := range config.Get().Proxies {
+		names = append(names, proxy.Name)
+		endpointParam = endpointParam || proxy.HasEndpointParam
+	}
+	if len(names) == 0 && !config.Get().Instance.Dynamic.Enabled {
+		return doc
+	}
+
+	// proxies registered at runtime can't be enumerated ahead of time
+	proxyParam := parameter{Name: "proxy", In: "path", Required: true,
+		Schema: parameterSchema{Type: "string", Enum: names}}
+	if config.Get().Instance.Dynamic.Enabled {
+		proxyParam.Schema.Enum = nil
+		endpointParam = true
+	}
+
+	for _, rt := range named {
+		path, params := openAPIPath(rt.Path)
+		params = append([]parameter{proxyParam}, params...)
+		addOperation(doc, basePath+"/{proxy}"+path, newOperation(rt, "proxy", params))
+
+		// proxies with a dynamic endpoint take it before the endpoint path
+		if endpointParam {
+			withEndpoint := rt
+			withEndpoint.ID += "WithEndpoint"
+			endpoint := parameter{Name: "e", In: "path", Required: true,
+				Schema: parameterSchema{Type: "string"}}
+			addOperation(doc, basePath+"/{proxy}/{e}"+path, newOperation(withEndpoint, "proxy",
+				append([]parameter{proxyParam, endpoint}, params[1:]...)))
+		}
+	}
+
+	return doc
+}
+
+// addOperation adds an operation to the document under the method of its route
+func addOperation(doc openAPIDoc, path string, op operation) {
+	if doc.Paths[path] == nil {
+		doc.Paths[path] = map[string]operation{}
+	}
+	doc.Paths[path][strings.ToLower(op.method)] = op
+}
+
+// newOperation describes an endpoint with the given path parameters
+func newOperation(rt route, tag string, params []parameter) operation {
+	for _, name := range queryParams[rt.ID] {
+		params = append(params, parameter{Name: name, In: "query",
+			Schema: parameterSchema{Type: "string"}})
+	}
+
+	op := operation{
+		method:      rt.Method,
+		OperationID: rt.ID,
+		Summary:     rt.Summary,
+		Tags:        []string{tag},
+		Parameters:  params,
+		Responses: map[string]response{
+			"200": {Description: "OK"},
+		},
+	}
+
+	// endpoints taking a body accept a proxy document using config file keys
+	if rt.Method == fiber.MethodPut {
+		op.RequestBody = &requestBody{Required: true, Content: map[string]parameterSchemas{
+			fiber.MIMEApplicationJSON: {Schema: parameterSchema{Type: "object"}},
+		}}
+	}
+
+	return op
+}
+
+// openAPIPath converts a fiber route path into an OpenAPI templated path
+// and its path parameters
+func openAPIPath(path string) (string, []parameter) {
+	var params []parameter
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := strings.TrimPrefix(segment, ":")
+			segments[i] = "{" + name + "}"
+			params = append(params, parameter{Name: name, In: "path", Required: true,
+				Schema: parameterSchema{Type: "string"}})
+		}
+	}
+
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+// PrimeList invalidates and primes the explicit tiles of a tile list, either
+// uploaded as the request body or the "file" field of a multipart form, or
+// referenced by the "file" query parameter as a path on the instance. Lists
+// hold "z/x/y" paths with optional query parameters or z,x,y CSV rows with
+// optional name=value parameter columns, one tile per line.
+func PrimeList(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	// priming must never contact the upstream while in maintenance mode
+	if c.InMaintenance() {
+		util.Log(ctx).Error(str.CAdmin, str.EPrimeList, "unknown", str.RMaintenance)
+		return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable,
+			str.RMaintenance, c.Proxy.Maintenance.RetryAfterDuration)
+	}
+
+	// target an explicit cache generation, e.g. to seed the inactive one
+	if !targetGeneration(ctx, c) {
+		util.Log(ctx).Error(str.CAdmin, str.EPrimeList, "unknown", "invalid generation")
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "invalid generation provided",
+		})
+	}
+
+	// prime every combination of configured param values if asked to
+	matrix, ok := requestedMatrix(ctx, c)
+	if !ok {
+		util.Log(ctx).Error(str.CAdmin, str.EPrimeList, "unknown", "no param values configured")
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "no param values configured for proxy",
+		})
+	}
+
+	source, lines, err := readPrimeList(ctx)
+	if err != nil {
+		util.Log(ctx).Error(str.CAdmin, str.EPrimeList, source, err.Error())
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "failed to read tile list",
+		})
+	}
+
+	entries := make([]tile.ListEntry, 0, len(lines))
+	for _, line := range lines {
+		entry, errEntry := tile.ParseListEntry(line)
+		if errEntry != nil {
+			util.Log(ctx).Debug(str.CAdmin, str.DPrimeFail, line, errEntry.Error())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	invalid := len(lines) - len(entries)
+	if matrix != nil {
+		entries = tile.ExpandMatrix(entries, matrix)
+	}
+
+	if len(entries) == 0 {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{
+			"status":  "failed",
+			"error":   "no valid tiles in tile list",
+			"invalid": invalid,
+		})
+	}
+
+	// list jobs are identified by their contents, so repeating an interrupted
+	// upload resumes it
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	checkpoint, progress := loadProgress(ctx, c, seedJobID(ctx, c, hex.EncodeToString(sum[:])), len(entries))
+
+	jobs := make([]primeJob, 0, len(entries))
+	for i := range entries {
+		jobs = append(jobs, primeJob{tile: entries[i].Tile, entry: &entries[i]})
+	}
+	pending := pendingJobs(jobs, checkpoint)
+	resumed := len(entries) - len(pending)
+
+	succeeded := resumed + primeTiles(ctx, c, pending, progress)
+	progress.Finish(succeeded == len(entries))
+
+	// purge re-primed tiles from the downstream CDN, unless they belong to
+	// an inactive generation that isn't being served yet
+	if c.CDN != nil && helpers.CacheGeneration(*c.Proxy, ctx) == c.Generation() {
+		purgeCDNURLs(c, listPublicUrls(ctx, c, entries))
+	}
+
+	status := "ok"
+	if succeeded != len(entries) {
+		status = "failed"
+	}
+
+	util.Log(ctx).Info(str.CAdmin, str.MPrimeList, succeeded, len(entries), source, invalid)
+	return ctx.JSON(map[string]interface{}{
+		"attempted": len(entries),
+		"primed":    succeeded,
+		"resumed":   resumed,
+		"invalid":   invalid,
+		"status":    status,
+	})
+}
+
+// readPrimeList returns the entries of the tile list of a priming request,
+// along with a description of where it was read from for logging
+func readPrimeList(ctx *fiber.Ctx) (string, []string, error) {
+	if path := ctx.Query("file"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return path, nil, err
+		}
+		defer file.Close()
+
+		lines, err := tile.ReadList(file)
+		return path, lines, err
+	}
+
+	var list io.Reader = bytes.NewReader(ctx.Body())
+	source := "upload"
+	if header, err := ctx.FormFile("file"); err == nil {
+		file, errOpen := header.Open()
+		if errOpen != nil {
+			return header.Filename, nil, errOpen
+		}
+		defer file.Close()
+		list, source = file, header.Filename
+	}
+
+	lines, err := tile.ReadList(list)
+	return source, lines, err
+}
+
+// listPublicUrls returns the public URLs of the given tile list entries,
+// requested with their own parameters
+func listPublicUrls(ctx *fiber.Ctx, c *cache.Cache, entries []tile.ListEntry) []string {
+	urls := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entryCtx, ok := helpers.AcquireEntryCtx(ctx.App(), *c.Proxy, c, entry)
+		if !ok {
+			continue
+		}
+
+		publicUrl, err := helpers.BuildPublicUrl(*c.Proxy, entryCtx, entry.Tile)
+		ctx.App().ReleaseCtx(entryCtx)
+		if err != nil {
+			util.Debug(str.CAdmin, str.DCDNPurgeFail, entry.Tile.String(), err.Error())
+			continue
+		}
+		urls = append(urls, publicUrl)
+	}
+	return urls
+}
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// ListProxies returns the proxies registered at runtime
+func ListProxies(ctx *fiber.Ctx) error {
+	proxies := make([]config.Proxy, 0)
+	for _, p := range config.Get().Proxies {
+		if p.Dynamic {
+			proxies = append(proxies, p)
+		}
+	}
+	return ctx.JSON(proxies)
+}
+
+// PutProxy builds a handler registering or replacing a proxy by name from a
+// JSON document in the request body, using the config file's proxy keys, and
+// rebuilding the caches held by the given manager
+func PutProxy(caches *cache.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		name := ctx.Params("name")
+		created, err := config.PutProxy(name, ctx.Body())
+		if err != nil {
+			return errorDynamic(ctx, err)
+		}
+
+		// build the proxy's cache anew from its updated configuration
+		caches.Drop(name)
+		if err = rebuild(caches, name); err != nil {
+			return errorReload(ctx, err)
+		}
+
+		util.Log(ctx).Info(str.CAdmin, str.MDynamicPut, name)
+
+		status := fiber.StatusOK
+		if created {
+			status = fiber.StatusCreated
+		}
+		return ctx.Status(status).JSON(map[string]string{
+			"status": "ok",
+			"proxy":  name,
+		})
+	}
+}
+
+// DeleteProxy builds a handler deleting a proxy registered at runtime by name,
+// dropping its cache from the given manager
+func DeleteProxy(caches *cache.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		name := ctx.Params("name")
+		if err := config.DeleteProxy(name); err != nil {
+			return errorDynamic(ctx, err)
+		}
+
+		if err := rebuild(caches, name); err != nil {
+			return errorReload(ctx, err)
+		}
+
+		util.Log(ctx).Info(str.CAdmin, str.MDynamicDelete, name)
+		return ctx.JSON(map[string]string{
+			"status": "ok",
+			"proxy":  name,
+		})
+	}
+}
+
+// dynamicNamed wraps a named endpoint handler to serve proxies registered at
+// runtime by their name path parameter, passing requests for other proxies on
+func dynamicNamed(caches *cache.Manager, endpoint bool, handler fiber.Handler) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		name := ctx.Params("proxy")
+
+		c := caches.Get(name)
+		if c == nil || !c.Proxy.Dynamic || c.Proxy.HasEndpointParam != endpoint {
+			return ctx.Next()
+		}
+
+		ctx.Locals(str.LocalCacheName, name)
+		ctx.Locals(str.LocalCache, c)
+		util.LogWith(ctx, "proxy", name)
+		return handler(ctx)
+	}
+}
+
+// errorDynamic responds to a failed dynamic proxy change
+func errorDynamic(ctx *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+
+	var static config.ErrDynamicProxyStatic
+	var notFound config.ErrDynamicProxyNotFound
+	switch {
+	case errors.As(err, &static):
+		status = fiber.StatusConflict
+	case errors.As(err, &notFound):
+		status = fiber.StatusNotFound
+	}
+
+	util.Log(ctx).Error(str.CAdmin, str.EDynamic, err.Error())
+	return ctx.Status(status).JSON(map[string]string{
+		"status": "failed",
+		"error":  err.Error(),
+	})
+}
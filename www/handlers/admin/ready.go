@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/fleet"
+)
+
+type readyResponse struct {
+	Ready    bool                         `json:"ready"`    // whether every proxy's cache is warm and the instance isn't draining
+	Draining bool                         `json:"draining"` // whether the instance is draining before shutdown
+	Proxies  map[string]cache.WarmupState `json:"proxies"`  // warm-up progress of each proxy
+}
+
+// Ready builds a handler reporting whether the instance should receive
+// traffic, returning 503 until every cache held by the given manager has met
+// its configured warm-up conditions, and once the instance starts draining
+func Ready(caches *cache.Manager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		all := caches.All()
+		resp := readyResponse{
+			Ready:    !fleet.Draining(),
+			Draining: fleet.Draining(),
+			Proxies:  make(map[string]cache.WarmupState, len(all)),
+		}
+
+		for name, ch := range all {
+			state := ch.WarmupState()
+			resp.Ready = resp.Ready && state.Ready
+			resp.Proxies[name] = state
+		}
+
+		if !resp.Ready {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+		return c.JSON(resp)
+	}
+}
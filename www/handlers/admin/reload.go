@@ -3,24 +3,42 @@ package admin
 import (
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/dechristopher/lod/accesslog"
 	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/corsdebug"
+	"github.com/dechristopher/lod/dnscache"
+	"github.com/dechristopher/lod/fleet"
+	"github.com/dechristopher/lod/geoip"
+	"github.com/dechristopher/lod/heatmap"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/probe"
+	"github.com/dechristopher/lod/slo"
+	"github.com/dechristopher/lod/statsd"
 	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/upstream"
 	"github.com/dechristopher/lod/util"
+	"github.com/dechristopher/lod/webhook"
 )
 
-// ReloadCapabilities performs a config reload, picking up any
-// changes to the instance capabilities configuration.
-func ReloadCapabilities(ctx *fiber.Ctx) error {
+// ReloadCapabilities builds a handler performing a config reload, picking up
+// any changes to the instance capabilities configuration and rebuilding the
+// caches held by the given manager
+func ReloadCapabilities(caches *cache.Manager) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return reload(caches, ctx)
+	}
+}
+
+// reload the config and everything built from it
+func reload(caches *cache.Manager, ctx *fiber.Ctx) error {
 	// reload config and update instance capabilities
 	err := config.Load()
 	if err != nil {
 		return errorReload(ctx, err)
 	}
 
-	// reinitialize cache instances
-	err = cache.Init()
-	if err != nil {
+	if err = rebuild(caches); err != nil {
 		return errorReload(ctx, err)
 	}
 
@@ -31,6 +49,70 @@ func ReloadCapabilities(ctx *fiber.Ctx) error {
 	})
 }
 
+// rebuild everything built from the config after it changed. Upstream state
+// is rebuilt only for the given changed proxies if any, so the pools, health
+// and balancing state of the others are kept, and for all proxies otherwise.
+func rebuild(caches *cache.Manager, changed ...string) error {
+	// reinitialize cache instances
+	if err := caches.Init(config.Get()); err != nil {
+		return err
+	}
+
+	// resolve upstream hostnames through the DNS cache, if enabled
+	dnscache.Init()
+
+	// rebuild upstream address pools
+	if len(changed) == 0 {
+		upstream.Init()
+	}
+	for _, name := range changed {
+		upstream.Rebuild(name)
+	}
+
+	// refuse proxies whose upstream leads back to this instance
+	if err := upstream.CheckLoops(config.Get()); err != nil {
+		return err
+	}
+
+	// rediscover proxy coverage from upstream metadata in the background
+	helpers.DiscoverAll(caches)
+
+	// reopen the GeoIP database
+	if err := geoip.Init(); err != nil {
+		return err
+	}
+
+	// track service level objectives
+	slo.Init()
+
+	// aggregate tile request heatmaps
+	heatmap.Init()
+
+	// capture sampled CORS decisions
+	corsdebug.Init()
+
+	// post request summaries to the webhook collector
+	webhook.Init()
+
+	// write request records to the access log sink, if configured
+	if err := accesslog.Init(); err != nil {
+		return err
+	}
+
+	// emit metrics to the statsd agent, if configured
+	if err := statsd.Init(); err != nil {
+		return err
+	}
+
+	// register the instance in the fleet registry, if configured
+	fleet.Init()
+
+	// start synthetic probes
+	probe.Init()
+
+	return nil
+}
+
 func errorReload(ctx *fiber.Ctx, err error) error {
 	util.Error(str.CAdmin, str.EReload, err.Error())
 	return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+)
+
+// oneGB is the number of bytes in a gigabyte, for egress cost estimates
+const oneGB = 1024 * 1024 * 1024
+
+type savingsResponse struct {
+	Proxy         string        `json:"proxy"`                    // name of the proxy
+	Window        string        `json:"window"`                   // window the report covers
+	Savings       cache.Savings `json:"savings"`                  // requests and bytes served from cache and upstream
+	AvoidedRatio  float64       `json:"avoided_ratio"`            // share of requests that never reached the upstream
+	EstimatedCost *float64      `json:"estimated_cost,omitempty"` // estimated upstream cost avoided, if cost rates are provided
+}
+
+// SavingsReport estimates the upstream requests and bytes a proxy's cache
+// avoided over a window (?window=24h, default 1h, max 7 days). Providing
+// ?cost_per_gb= and/or ?cost_per_million= adds an estimated cost avoided.
+func SavingsReport(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	window, err := time.ParseDuration(ctx.Query("window", "1h"))
+	if err != nil || window < time.Minute || window > cache.MaxSavingsWindow {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "window must be a duration between 1m and 168h",
+		})
+	}
+
+	savings := c.Savings(window)
+	response := savingsResponse{
+		Proxy:   c.Proxy.Name,
+		Window:  window.String(),
+		Savings: savings,
+	}
+
+	if total := savings.CacheRequests + savings.UpstreamRequests; total > 0 {
+		response.AvoidedRatio = float64(savings.CacheRequests) / float64(total)
+	}
+
+	perGB, errGB := strconv.ParseFloat(ctx.Query("cost_per_gb"), 64)
+	perMillion, errMillion := strconv.ParseFloat(ctx.Query("cost_per_million"), 64)
+	if errGB == nil || errMillion == nil {
+		cost := 0.0
+		if errGB == nil {
+			cost += float64(savings.CacheBytes) / oneGB * perGB
+		}
+		if errMillion == nil {
+			cost += float64(savings.CacheRequests) / 1e6 * perMillion
+		}
+		response.EstimatedCost = &cost
+	}
+
+	return ctx.JSON(response)
+}
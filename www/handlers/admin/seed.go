@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/seed"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// Seed starts a background job fetching every tile covering the bounding box
+// of the JSON request body at every zoom level of its range into the cache of
+// a proxy by name, responding with the job's progress
+func Seed(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	// seeding must never contact the upstream while in maintenance mode
+	if c.InMaintenance() {
+		return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable,
+			str.RMaintenance, c.Proxy.Maintenance.RetryAfterDuration)
+	}
+
+	// seed jobs outlive the request, so can't be routed by its endpoint
+	if c.Proxy.HasEndpointParam {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "seeding proxies with a dynamic endpoint is not supported",
+		})
+	}
+
+	// target an explicit cache generation, e.g. to seed the inactive one
+	if !targetGeneration(ctx, c) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "invalid generation provided",
+		})
+	}
+	generation, _ := ctx.Locals(str.LocalGeneration).(string)
+
+	var req seed.Request
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "failed to parse seed request",
+		})
+	}
+
+	job, err := seed.Start(ctx.App(), c, req, generation)
+	if err != nil {
+		util.Log(ctx).Error(str.CAdmin, str.ESeed, c.Proxy.Name, err.Error())
+
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.As(err, &seed.ErrJobRunning{}):
+			status = fiber.StatusConflict
+		case errors.As(err, &cache.ErrClosed{}):
+			// the proxy's cache was dropped by a reload while handling
+			status = fiber.StatusServiceUnavailable
+		}
+		return ctx.Status(status).JSON(map[string]string{
+			"status": "failed",
+			"error":  err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(job.Progress())
+}
+
+// SeedProgress returns the progress of the latest seed job of a proxy by name
+func SeedProgress(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	job := seed.Get(c)
+	if job == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no seed job started for proxy",
+		})
+	}
+
+	return ctx.JSON(job.Progress())
+}
+
+// CancelSeed stops the running seed job of a proxy by name, responding with
+// its progress once every tile in flight has been seeded
+func CancelSeed(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	job, cancelled := seed.Cancel(c)
+	if !cancelled {
+		return ctx.Status(fiber.StatusConflict).JSON(map[string]string{
+			"status": "no seed job running for proxy",
+		})
+	}
+	job.Wait()
+
+	return ctx.JSON(job.Progress())
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+type sparseResponse struct {
+	Proxy    string `json:"proxy"`    // name of the proxy
+	Enabled  bool   `json:"enabled"`  // whether empty subtrees are tracked
+	MinZoom  int    `json:"min_zoom"` // shallowest zoom level marking subtrees empty
+	Subtrees int    `json:"subtrees"` // number of subtrees known to be empty upstream
+}
+
+// SparseStatus returns the subtrees known to be empty upstream of a proxy by name
+func SparseStatus(ctx *fiber.Ctx) error {
+	return sparse(ctx, false)
+}
+
+// ResetSparse forgets all subtrees known to be empty upstream of a proxy by
+// name, so their tiles are fetched from the upstream again
+func ResetSparse(ctx *fiber.Ctx) error {
+	return sparse(ctx, true)
+}
+
+// sparse optionally resets the known empty subtrees of a proxy by name, and
+// responds with the resulting state
+func sparse(ctx *fiber.Ctx, reset bool) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	if reset {
+		if err := c.ResetEmpty(ctx.Context()); err != nil {
+			util.Error(str.CAdmin, str.ECacheSparse, c.Proxy.Name, err.Error())
+			return ctx.Status(fiber.StatusInternalServerError).JSON(map[string]string{
+				"status": "failed",
+				"error":  err.Error(),
+			})
+		}
+		util.Info(str.CAdmin, str.MSparseReset, c.Proxy.Name)
+	}
+
+	return ctx.JSON(sparseResponse{
+		Proxy:    c.Proxy.Name,
+		Enabled:  c.Proxy.Sparse.Enabled,
+		MinZoom:  c.Proxy.Sparse.MinZoom,
+		Subtrees: c.EmptySubtrees(),
+	})
+}
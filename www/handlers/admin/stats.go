@@ -16,6 +16,8 @@ type stats struct {
 	TPS      float64 `json:"tps"`      // average tiles per second served over the past minute
 	Cache    fetch   `json:"cache"`    // cache fetch performance stats
 	Upstream fetch   `json:"upstream"` // upstream fetch performance stats
+	// in-memory cache and per-shard stats, omitted if disabled
+	Memory *cache.MemoryStats `json:"memory,omitempty"`
 }
 
 type fetch struct {
@@ -27,7 +29,7 @@ type fetch struct {
 // Stats returns stats for a cache by name, or all caches
 func Stats(ctx *fiber.Ctx) error {
 	// TODO implement global stats
-	if ctx.Path() == "/admin/stats" {
+	if ctx.Locals(str.LocalCacheName) == nil {
 		return ctx.JSON(map[string]string{
 			"stats": "all",
 		})
@@ -41,7 +43,7 @@ func Stats(ctx *fiber.Ctx) error {
 		})
 	}
 
-	c := cache.Get(name)
+	c := cache.FromCtx(ctx)
 	if c == nil {
 		// 404 if no proxy found with given name
 		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
@@ -49,14 +51,19 @@ func Stats(ctx *fiber.Ctx) error {
 		})
 	}
 
-	hits := util.GetMetricValue(c.Metrics.CacheHits)
+	hits := util.SumMetricValues(c.Metrics.CacheHits)
 	misses := util.GetMetricValue(c.Metrics.CacheMisses)
 
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = hits / (hits + misses)
+	}
+
 	return ctx.JSON(stats{
 		Hits:     hits,
 		Misses:   misses,
 		Requests: hits + misses,
-		HitRate:  util.GetMetricValue(c.Metrics.HitRate),
+		HitRate:  hitRate,
 		TPS:      0,
 		Cache: fetch{
 			FetchAvg:  0,
@@ -68,5 +75,6 @@ func Stats(ctx *fiber.Ctx) error {
 			Fetch75th: 0,
 			Fetch99th: 0,
 		},
+		Memory: c.MemoryStats(),
 	})
 }
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// PurgeTag invalidates all tiles of a proxy by name tagged with the given tag
+func PurgeTag(ctx *fiber.Ctx) error {
+	c := cache.FromCtx(ctx)
+	if c == nil {
+		// 404 if no proxy found with given name
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no proxy configured with given name",
+		})
+	}
+
+	tag := ctx.Params("tag")
+	if !c.Proxy.Tags.Enabled() {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "failed",
+			"error":  "tagging not configured for proxy",
+		})
+	}
+
+	purged, err := c.PurgeTag(ctx.Context(), tag)
+	if err != nil {
+		util.Error(str.CAdmin, str.EPurgeTag, tag, err.Error())
+
+		status := fiber.StatusInternalServerError
+		if errors.As(err, &cache.ErrReadOnly{}) {
+			status = fiber.StatusConflict
+		}
+		return ctx.Status(status).JSON(map[string]interface{}{
+			"status": "failed",
+			"purged": purged,
+			"error":  err.Error(),
+		})
+	}
+
+	// purge tagged objects from the downstream CDN
+	purgeCDNTags(c, []string{tag})
+
+	util.Info(str.CAdmin, str.MPurgeTag, tag, c.Proxy.Name, purged)
+	return ctx.JSON(map[string]interface{}{
+		"status": "ok",
+		"tag":    tag,
+		"purged": purged,
+	})
+}
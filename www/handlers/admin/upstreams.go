@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/upstream"
+)
+
+type upstreamsResponse struct {
+	Proxy    string                 `json:"proxy"`    // name of the proxy
+	Strategy string                 `json:"strategy"` // configured balancing strategy
+	Targets  []upstream.TargetState `json:"targets"`  // balancing and health state of each upstream target
+}
+
+// Upstreams returns the balancing and health state of a proxy's upstream
+// targets by name
+func Upstreams(ctx *fiber.Ctx) error {
+	pool := upstream.Get(ctx.Locals(str.LocalCacheName).(string))
+	if pool == nil {
+		// 404 if the proxy has no upstream pool configured
+		return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{
+			"status": "no upstream pool configured for proxy with given name",
+		})
+	}
+
+	return ctx.JSON(upstreamsResponse{
+		Proxy:    pool.Proxy.Name,
+		Strategy: pool.Proxy.Upstream.Strategy,
+		Targets:  pool.State(),
+	})
+}
@@ -0,0 +1,13 @@
+package admin
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+)
+
+// Version returns the build details of the running binary, also exported
+// as labels of the lod_build_info metric
+func Version(ctx *fiber.Ctx) error {
+	return ctx.JSON(config.GetBuild())
+}
@@ -8,12 +8,28 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/valyala/fasthttp/fasthttpadaptor"
 
+	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/www/middleware"
 )
 
-// Wire admin group and endpoint handlers
-func Wire(r *fiber.App) {
+// APIVersion is the current version prefix of the admin API
+const APIVersion = "v1"
+
+// route is a single admin endpoint, used both to mount the handler and to
+// describe it in the generated OpenAPI document
+type route struct {
+	Method  string // HTTP method of the endpoint
+	Path    string // fiber path, relative to the admin or proxy group
+	ID      string // unique OpenAPI operation ID
+	Summary string // short description of the endpoint
+	Handler fiber.Handler
+}
+
+// Wire admin group and endpoint handlers, operating on the caches held by the
+// given manager. Endpoints are served under /admin/v1, and at their legacy
+// unversioned paths under /admin for existing tooling
+func Wire(r *fiber.App, caches *cache.Manager) {
 	// admin handler group
 	adminGroup := r.Group("/admin")
 
@@ -26,72 +42,172 @@ func Wire(r *fiber.App) {
 			middleware.Bearer, true))
 	}
 
-	if config.Get().Instance.MetricsEnabled {
-		// prometheus metrics endpoint
-		p := fasthttpadaptor.NewFastHTTPHandler(promhttp.Handler())
-		adminGroup.Get("/metrics/prometheus", func(c *fiber.Ctx) error {
-			p(c.Context())
-			return nil
-		})
-	}
-
-	// JSON service health / status handler
-	adminGroup.Get("/status", Status)
+	global := globalEndpoints(caches)
+	named := namedEndpoints(caches)
 
-	// Fiber monitor handler
-	adminGroup.Get("/monitor", monitor.New(monitor.Config{
-		Title:   "LOD Instance Monitor",
-		Refresh: time.Second,
-	}))
+	// versioned API and its OpenAPI document
+	v1Group := adminGroup.Group("/" + APIVersion)
+	v1Group.Get("/openapi.json", OpenAPI("/admin/"+APIVersion, global, named))
+	wireRoutes(v1Group, caches, global, named)
 
-	// capabilities endpoint shows configuration summary
-	adminGroup.Get("/capabilities", Capabilities)
-
-	// reload endpoint will reload capabilities configuration from config.File
-	adminGroup.Get("/reload", ReloadCapabilities)
-
-	// return stats for all caches
-	adminGroup.Get("/stats", Stats)
+	// legacy unversioned API
+	wireRoutes(adminGroup, caches, global, named)
+}
 
-	// flush the in-memory caches of all proxies
-	adminGroup.Get("/flush", Flush)
+// wireRoutes mounts global and proxy-specific endpoints onto the given group
+func wireRoutes(group fiber.Router, caches *cache.Manager, global, named []route) {
+	for _, rt := range global {
+		group.Add(rt.Method, rt.Path, rt.Handler)
+	}
 
 	// Wire up named endpoints for each configured proxy
 	for _, proxy := range config.Get().Proxies {
-		namedAdminGroup := adminGroup.Group(proxy.Name)
+		namedAdminGroup := group.Group(proxy.Name)
 
 		// add cache name middleware to named handler group
-		namedAdminGroup.Use(middleware.GenCacheNameMiddleware(proxy.Name))
+		namedAdminGroup.Use(middleware.GenCacheMiddleware(caches, proxy.Name))
 
-		for path, handler := range namedEndpoints {
-			handlerPath := path
+		for _, rt := range named {
+			handlerPath := rt.Path
 			// if dynamic endpoint configured, add endpoint path parameter
 			if proxy.HasEndpointParam {
 				handlerPath = "/:e" + handlerPath
 			}
 
 			// configure proxy endpoint genHandler
-			namedAdminGroup.Get(handlerPath, handler)
+			namedAdminGroup.Add(rt.Method, handlerPath, rt.Handler)
 		}
 	}
+
+	// serve named endpoints of proxies registered at runtime by path parameter
+	if config.Get().Instance.Dynamic.Enabled {
+		for _, rt := range named {
+			group.Add(rt.Method, "/:proxy"+rt.Path, dynamicNamed(caches, false, rt.Handler))
+			group.Add(rt.Method, "/:proxy/:e"+rt.Path, dynamicNamed(caches, true, rt.Handler))
+		}
+	}
+}
+
+// globalEndpoints returns the instance-wide admin endpoints, operating on the
+// caches held by the given manager
+func globalEndpoints(caches *cache.Manager) []route {
+	var routes []route
+
+	if config.Get().Instance.MetricsEnabled {
+		// prometheus metrics endpoint
+		p := fasthttpadaptor.NewFastHTTPHandler(promhttp.Handler())
+		routes = append(routes, route{fiber.MethodGet, "/metrics/prometheus", "getPrometheusMetrics",
+			"Prometheus metrics exposition", func(c *fiber.Ctx) error {
+				p(c.Context())
+				return nil
+			}})
+	}
+
+	routes = append(routes,
+		// JSON service health / status handler
+		route{fiber.MethodGet, "/status", "getStatus", "Service health and status", Status},
+		// Fiber monitor handler
+		route{fiber.MethodGet, "/monitor", "getMonitor", "HTML instance monitor", monitor.New(monitor.Config{
+			Title:   "LOD Instance Monitor",
+			Refresh: time.Second,
+		})},
+		// version endpoint shows the build details of the running binary
+		route{fiber.MethodGet, "/version", "getVersion", "Version, commit, build date and Go version of the build", Version},
+		// capabilities endpoint shows configuration summary
+		route{fiber.MethodGet, "/capabilities", "getCapabilities", "Configuration summary", Capabilities},
+		// config endpoint shows the effective configuration with secrets masked
+		route{fiber.MethodGet, "/config", "getConfig", "Effective configuration with secrets redacted", Config},
+		// config diff endpoint previews the changes a candidate config would make on reload
+		route{fiber.MethodPost, "/config/diff", "diffConfig", "Structural diff of a candidate configuration against the running one", ConfigDiff},
+		// reload endpoint will reload capabilities configuration from config.File
+		route{fiber.MethodGet, "/reload", "reloadCapabilities", "Reload configuration from the config file", ReloadCapabilities(caches)},
+		// take the instance out of rotation ahead of shutdown, ex: from a preStop hook
+		route{fiber.MethodGet, "/drain", "drainInstance", "Fail readiness and wait for load balancer deregistration", Drain},
+		// list the instances registered in the fleet registry
+		route{fiber.MethodGet, "/fleet", "getFleet", "Instances registered in the fleet registry", Fleet},
+		// return stats for all caches
+		route{fiber.MethodGet, "/stats", "getStats", "Stats for all proxies", Stats},
+		// flush the in-memory caches of all proxies
+		route{fiber.MethodGet, "/flush", "flushAll", "Flush the caches of all proxies", Flush(caches)},
+	)
+
+	if config.Get().Instance.Dynamic.Enabled {
+		routes = append(routes,
+			// list, register and delete proxies at runtime
+			route{fiber.MethodGet, "/proxies", "listProxies", "Proxies registered at runtime", ListProxies},
+			route{fiber.MethodPut, "/proxies/:name", "putProxy", "Register or replace a proxy", PutProxy(caches)},
+			route{fiber.MethodDelete, "/proxies/:name", "deleteProxy", "Delete a proxy registered at runtime", DeleteProxy(caches)},
+		)
+	}
+
+	return routes
 }
 
-// namedEndpoints is a map of proxy-specific handler functions and their paths
-var namedEndpoints = map[string]fiber.Handler{
-	// return stats for a cache by name
-	"/stats": Stats,
-	// flush the in-memory cache of a proxy by name
-	"/flush": Flush,
-	// invalidate a given tile without re-priming
-	"/invalidate/:z/:x/:y": InvalidateTile,
-	// invalidate a given tile and all of its children up to a given max
-	// maxZoom defaults to zoom level 12
-	"/invalidate/deep/:z/:x/:y":          InvalidateTileDeep,
-	"/invalidate/deep/:z/:x/:y/:maxZoom": InvalidateTileDeep,
-	// invalidate and prime a given tile
-	"/prime/:z/:x/:y": PrimeTile,
-	// invalidate and prime a given tile and all of its children up to a given max
-	// maxZoom defaults to zoom level 12
-	"/prime/deep/:z/:x/:y":          PrimeTileDeep,
-	"/prime/deep/:z/:x/:y/:maxZoom": PrimeTileDeep,
+// namedEndpoints returns the proxy-specific handler functions and their
+// paths, operating on the caches held by the given manager
+func namedEndpoints(caches *cache.Manager) []route {
+	return []route{
+		// return stats for a cache by name
+		{fiber.MethodGet, "/stats", "getProxyStats", "Stats for a proxy", Stats},
+		// flush the in-memory cache of a proxy by name
+		{fiber.MethodGet, "/flush", "flushProxy", "Flush the cache of a proxy", Flush(caches)},
+		// estimate upstream requests and bytes avoided by the cache of a proxy by name
+		{fiber.MethodGet, "/savings", "getProxySavings", "Upstream requests and bytes avoided by the cache", SavingsReport},
+		// show maintenance mode state of a proxy by name
+		{fiber.MethodGet, "/maintenance", "getProxyMaintenance", "Maintenance mode state", MaintenanceStatus},
+		// put a proxy by name into maintenance mode
+		{fiber.MethodGet, "/maintenance/enable", "enableProxyMaintenance", "Enable maintenance mode", EnableMaintenance},
+		// take a proxy by name out of maintenance mode
+		{fiber.MethodGet, "/maintenance/disable", "disableProxyMaintenance", "Disable maintenance mode", DisableMaintenance},
+		// show the zoom levels and bounds a proxy by name serves
+		{fiber.MethodGet, "/coverage", "getProxyCoverage", "Served zoom levels and bounds", CoverageStatus},
+		// populate unset zoom levels and bounds of a proxy by name from its upstream metadata
+		{fiber.MethodGet, "/coverage/discover", "discoverProxyCoverage", "Discover served zoom levels and bounds from the upstream", DiscoverCoverage},
+		// show the number of subtrees known to be empty upstream of a proxy by name
+		{fiber.MethodGet, "/sparse", "getProxySparse", "Subtrees known to be empty upstream", SparseStatus},
+		// forget all subtrees known to be empty upstream of a proxy by name
+		{fiber.MethodGet, "/sparse/reset", "resetProxySparse", "Forget all subtrees known to be empty upstream", ResetSparse},
+		// show the aggregated tile requests of a proxy by name
+		{fiber.MethodGet, "/heatmap", "getProxyHeatmap", "Aggregated tile requests by zoom level", Heatmap},
+		// show the aggregated tile requests at a zoom level of a proxy by name as GeoJSON or PNG
+		{fiber.MethodGet, "/heatmap/:zoom", "getProxyHeatmapZoom", "Tile request heatmap of a zoom level as GeoJSON or PNG", HeatmapZoom},
+		// show the upstream request budget of a proxy by name
+		{fiber.MethodGet, "/budget", "getProxyBudget", "Upstream requests counted against the budget", BudgetStatus},
+		// show the captured CORS decisions of a proxy by name
+		{fiber.MethodGet, "/cors", "getProxyCORSCaptures", "Captured cross-origin requests and CORS decisions", CORSCaptures},
+		// drop the captured CORS decisions of a proxy by name
+		{fiber.MethodGet, "/cors/reset", "resetProxyCORSCaptures", "Drop captured CORS decisions", ResetCORSCaptures},
+		// show the active and inactive cache generations of a proxy by name
+		{fiber.MethodGet, "/generation", "getProxyGeneration", "Active and inactive cache generations", GenerationStatus},
+		// switch a proxy by name to serve the given cache generation on every instance
+		{fiber.MethodGet, "/generation/switch/:generation", "switchProxyGeneration", "Serve the given cache generation on every instance sharing Redis", SwitchGeneration},
+		// show the cache key a hashed redis key of a proxy by name was hashed from
+		{fiber.MethodGet, "/keyhash/:hashed", "getProxyKeyHash", "Cache key of a hashed redis key", KeyHashLookup},
+		// show balancing and health state of a proxy's upstream targets by name
+		{fiber.MethodGet, "/upstreams", "getProxyUpstreams", "Balancing and health state of upstream targets", Upstreams},
+		// purge all tiles with a given surrogate key tag
+		{fiber.MethodGet, "/purge/tag/:tag", "purgeProxyTag", "Purge all tiles with a surrogate key tag", PurgeTag},
+		// invalidate a given tile without re-priming
+		{fiber.MethodGet, "/invalidate/:z/:x/:y", "invalidateProxyTile", "Invalidate a tile", InvalidateTile},
+		// invalidate a given tile and all of its children up to a given max
+		// maxZoom defaults to zoom level 12
+		{fiber.MethodGet, "/invalidate/deep/:z/:x/:y", "invalidateProxyTileDeep", "Invalidate a tile and its children", InvalidateTileDeep},
+		{fiber.MethodGet, "/invalidate/deep/:z/:x/:y/:maxZoom", "invalidateProxyTileDeepTo", "Invalidate a tile and its children up to maxZoom", InvalidateTileDeep},
+		// show the checkpointed progress of priming jobs of a proxy by name
+		{fiber.MethodGet, "/prime/progress", "getProxyPrimeProgress", "Progress of running and interrupted priming jobs", PrimeProgress},
+		// invalidate and prime the tiles of an uploaded or referenced tile list
+		{fiber.MethodPost, "/prime/list", "primeProxyTileList", "Invalidate and prime the tiles of a z/x/y or CSV tile list", PrimeList},
+		// seed the tiles of a bounding box and zoom range in the background
+		{fiber.MethodPost, "/seed", "seedProxy", "Start seeding the tiles of a bounding box and zoom range", Seed},
+		// show the progress of the latest seed job of a proxy by name
+		{fiber.MethodGet, "/seed", "getProxySeed", "Progress of the latest seed job", SeedProgress},
+		// cancel the running seed job of a proxy by name
+		{fiber.MethodDelete, "/seed", "cancelProxySeed", "Cancel the running seed job", CancelSeed},
+		// invalidate and prime a given tile
+		{fiber.MethodGet, "/prime/:z/:x/:y", "primeProxyTile", "Invalidate and prime a tile", PrimeTile},
+		// invalidate and prime a given tile and all of its children up to a given max
+		// maxZoom defaults to zoom level 12
+		{fiber.MethodGet, "/prime/deep/:z/:x/:y", "primeProxyTileDeep", "Invalidate and prime a tile and its children", PrimeTileDeep},
+		{fiber.MethodGet, "/prime/deep/:z/:x/:y/:maxZoom", "primeProxyTileDeepTo", "Invalidate and prime a tile and its children up to maxZoom", PrimeTileDeep},
+	}
 }
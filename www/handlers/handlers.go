@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
+	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/www/handlers/admin"
 	"github.com/dechristopher/lod/www/handlers/proxy"
@@ -11,18 +12,21 @@ import (
 )
 
 // Wire builds all the websocket and http routes
-// into the fiber app context
-func Wire(r *fiber.App) {
+// into the fiber app context, serving caches held by the given manager
+func Wire(r *fiber.App, caches *cache.Manager) {
 	// recover from panics
 	r.Use(recover.New())
 
+	// unauthenticated readiness probe for load balancers, gated on cache warm-up
+	r.Get("/ready", admin.Ready(caches))
+
 	// wire admin group handlers if not disabled
 	if !config.Get().Instance.AdminDisabled {
-		admin.Wire(r)
+		admin.Wire(r, caches)
 	}
 
 	// wire proxy groups and handlers for each configured proxy
-	proxy.Wire(r)
+	proxy.Wire(r, caches)
 
 	// Custom 404 page
 	middleware.NotFound(r)
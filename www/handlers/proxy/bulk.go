@@ -0,0 +1,306 @@
+package proxy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/upstream"
+	"github.com/dechristopher/lod/util"
+)
+
+// Bulk archive formats
+const (
+	bulkFormatTar = "tar"
+	bulkFormatZip = "zip"
+)
+
+// HeaderTilesMissing reports the number of requested tiles left out of a bulk
+// response because they were missing, invalid, or out of range
+const HeaderTilesMissing = "X-LOD-Tiles-Missing"
+
+// bulkRequest is the JSON body of a bulk tile request
+type bulkRequest struct {
+	Format string     `json:"format"` // archive format, "tar" (default) or "zip"
+	Tiles  []bulkTile `json:"tiles"`  // tiles to include in the archive
+}
+
+// bulkTile is a single tile coordinate of a bulk tile request
+type bulkTile struct {
+	Z int `json:"z"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// bulkEntry is a resolved tile of a bulk request, nil data if missing
+type bulkEntry struct {
+	tile     tile.Tile
+	url      string
+	body     []byte
+	cacheKey string
+	data     []byte
+	headers  map[string]string
+	source   string // where the tile data came from, "cache" or "upstream"
+}
+
+// genBulkHandler builds a bulk tile endpoint handler from configuration
+func genBulkHandler(p config.Proxy, c *cache.Cache) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ctx.Locals(str.LocalCache, c)
+		util.LogWith(ctx, "proxy", p.Name)
+		return handleBulk(p, c, ctx)
+	}
+}
+
+// handleBulk responds to bulk tile requests with an archive of all requested
+// tiles, served from cache where possible and fetched upstream otherwise
+func handleBulk(p config.Proxy, c *cache.Cache, ctx *fiber.Ctx) error {
+	var req bulkRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "invalid bulk tile request body",
+		})
+	}
+
+	if req.Format == "" {
+		req.Format = bulkFormatTar
+	}
+
+	if req.Format != bulkFormatTar && req.Format != bulkFormatZip {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "format must be one of 'tar' or 'zip'",
+		})
+	}
+
+	if len(req.Tiles) == 0 || len(req.Tiles) > p.Bulk.MaxTiles {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": fmt.Sprintf("between 1 and %d tiles must be requested", p.Bulk.MaxTiles),
+		})
+	}
+
+	helpers.FillParamsMap(p, ctx)
+
+	if !helpers.FillVersion(p, ctx) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{
+			"status": "unknown data version requested",
+		})
+	}
+
+	// resolve cache keys and cached tiles up front, since both rely on the
+	// request context which must not be shared across goroutines
+	entries := make([]*bulkEntry, 0, len(req.Tiles))
+	var keyed []*bulkEntry
+	var keys []string
+	for _, requested := range req.Tiles {
+		entry := &bulkEntry{tile: tile.Tile{X: requested.X, Y: requested.Y, Zoom: requested.Z}}
+		entries = append(entries, entry)
+
+		if !entry.tile.InRange() {
+			continue
+		}
+
+		var err error
+		if entry.url, err = helpers.BuildTileUrl(p, ctx, entry.tile); err != nil {
+			continue
+		}
+		if entry.body, err = helpers.BuildTileBody(p, ctx, entry.tile); err != nil {
+			continue
+		}
+		if entry.cacheKey, err = helpers.BuildCacheKey(p, ctx, entry.tile); err != nil {
+			continue
+		}
+
+		keyed = append(keyed, entry)
+		keys = append(keys, entry.cacheKey)
+	}
+
+	// look all tiles up in the cache at once, fetching the rest upstream
+	var misses []*bulkEntry
+	for i, cachedTile := range c.FetchMany(keys, ctx) {
+		entry := keyed[i]
+		if cachedTile != nil {
+			entry.data = cachedTile.TileData()
+			entry.headers = cachedTile.Headers()
+			entry.source = cache.SourceCache
+			continue
+		}
+
+		// never contact the upstream while in maintenance mode, or for tiles of
+		// cache-only zoom levels
+		if c.InMaintenance() {
+			continue
+		}
+		if p.IsCacheOnly(entry.tile.Zoom) {
+			c.Metrics.CacheOnlyMisses.Inc()
+			continue
+		}
+		misses = append(misses, entry)
+	}
+
+	fetchBulk(p, c, util.Log(ctx), helpers.ClientKey(ctx, p), helpers.RequestOrigin(ctx), misses)
+
+	archive, missing, err := writeBulkArchive(req.Format, entries)
+	if err != nil {
+		util.Log(ctx).Error(str.CProxy, str.EProxyWrite, p.Name, "bulk", err.Error())
+		return ctx.Status(fiber.StatusInternalServerError).SendString("")
+	}
+
+	for _, entry := range entries {
+		if len(entry.data) > 0 {
+			c.RecordServed(entry.source, len(entry.data))
+		}
+	}
+
+	ctx.Locals(str.LocalCacheStatus, ":bulk ")
+	ctx.Set(HeaderTilesMissing, strconv.Itoa(missing))
+	ctx.Set(fiber.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=\"%s.%s\"", p.Name, req.Format))
+
+	if req.Format == bulkFormatZip {
+		ctx.Set(fiber.HeaderContentType, "application/zip")
+	} else {
+		ctx.Set(fiber.HeaderContentType, "application/x-tar")
+	}
+
+	return ctx.Send(archive)
+}
+
+// fetchBulk fetches missed tiles from the upstream using the proxy's number of
+// cache workers, caching every tile fetched and logging failures to the logger
+// of the bulk request
+func fetchBulk(p config.Proxy, c *cache.Cache, log *util.Logger, client string, origin helpers.Origin, misses []*bulkEntry) {
+	jobs := make(chan *bulkEntry)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < p.NumWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				fetchBulkTile(p, c, log, client, origin, entry)
+			}
+		}()
+	}
+
+	for _, entry := range misses {
+		jobs <- entry
+	}
+	close(jobs)
+
+	wg.Wait()
+}
+
+// fetchBulkTile fetches and caches a single missed tile of a bulk request
+func fetchBulkTile(p config.Proxy, c *cache.Cache, log *util.Logger, client string, origin helpers.Origin, entry *bulkEntry) {
+	defer flightGroup.Forget(entry.cacheKey)
+
+	fetch := upstream.GetScheduler(p.Name).Wrap(client, c.Budgeted(helpers.FetchUpstream(entry.url, p, origin, entry.body)))
+	response, errProxy, _ := flightGroup.Do(entry.cacheKey, recovered(p, c, entry.cacheKey, fetch))
+	var budgetErr cache.ErrBudgetSpent
+	if errors.As(errProxy, &budgetErr) {
+		// leave the tile out of the archive once the budget is spent
+		c.Metrics.BudgetRejections.Inc()
+		return
+	}
+	if errProxy != nil {
+		log.Error(str.CProxy, str.EProxyAgentError, p.Name, entry.cacheKey, errProxy.Error())
+		return
+	}
+
+	proxyResp, ok := response.(helpers.ProxyResponse)
+	if !ok {
+		log.Error(str.CProxy, str.EProxyBadCast, p.Name, entry.cacheKey)
+		return
+	}
+
+	result := &helpers.ProcessedTile{}
+	if err := helpers.ProcessResponse(helpers.ProcessResponsePayload{
+		Cache:    c,
+		Proxy:    p,
+		Tile:     entry.tile,
+		CacheKey: entry.cacheKey,
+		Response: proxyResp,
+		Result:   result,
+	}); err != nil {
+		return
+	}
+
+	entry.data = result.Data
+	entry.headers = result.Headers
+	entry.source = cache.SourceUpstream
+}
+
+// writeBulkArchive writes all non-empty tiles to an archive of the given
+// format, named {z}/{x}/{y}.{ext}, returning the number of tiles left out
+func writeBulkArchive(format string, entries []*bulkEntry) ([]byte, int, error) {
+	buf := &bytes.Buffer{}
+	missing := 0
+	now := time.Now()
+
+	var tarWriter *tar.Writer
+	var zipWriter *zip.Writer
+	if format == bulkFormatZip {
+		zipWriter = zip.NewWriter(buf)
+	} else {
+		tarWriter = tar.NewWriter(buf)
+	}
+
+	for _, entry := range entries {
+		if len(entry.data) == 0 {
+			missing++
+			continue
+		}
+
+		name := fmt.Sprintf("%d/%d/%d.%s", entry.tile.Zoom, entry.tile.X, entry.tile.Y,
+			helpers.FileExtension(entry.headers[fiber.HeaderContentType]))
+
+		if zipWriter != nil {
+			// tiles are usually compressed already, so store them as is
+			writer, err := zipWriter.CreateHeader(&zip.FileHeader{
+				Name:     name,
+				Method:   zip.Store,
+				Modified: now,
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+			if _, err = writer.Write(entry.data); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(entry.data)),
+			ModTime: now,
+		}); err != nil {
+			return nil, 0, err
+		}
+		if _, err := tarWriter.Write(entry.data); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var err error
+	if zipWriter != nil {
+		err = zipWriter.Close()
+	} else {
+		err = tarWriter.Close()
+	}
+
+	return buf.Bytes(), missing, err
+}
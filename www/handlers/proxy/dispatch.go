@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/www/middleware"
+)
+
+// dispatcher serves proxies configured after the app's routes were built,
+// such as dynamic proxies registered through the admin API, by mounting each
+// on an app of its own that is rebuilt whenever its cache instance changes
+type dispatcher struct {
+	mu      sync.Mutex
+	config  fiber.Config
+	caches  *cache.Manager
+	static  map[string]bool
+	mounted map[string]mountedProxy
+}
+
+// mountedProxy is a proxy mounted on its own app, built from a cache instance
+type mountedProxy struct {
+	cache   *cache.Cache
+	handler fasthttp.RequestHandler
+}
+
+// newDispatcher returns a dispatcher for proxies not in the given set of
+// proxies mounted directly on the given app
+func newDispatcher(r *fiber.App, caches *cache.Manager, static map[string]bool) *dispatcher {
+	return &dispatcher{
+		config:  r.Config(),
+		caches:  caches,
+		static:  static,
+		mounted: make(map[string]mountedProxy),
+	}
+}
+
+// handle dispatches a request to the proxy named by its first path segment,
+// passing it on if no such proxy is served by the dispatcher
+func (d *dispatcher) handle(ctx *fiber.Ctx) error {
+	name, _, _ := strings.Cut(strings.TrimPrefix(ctx.Path(), "/"), "/")
+	if d.static[name] {
+		return ctx.Next()
+	}
+
+	c := d.caches.Get(name)
+	if c == nil {
+		return ctx.Next()
+	}
+
+	d.handler(name, c)(ctx.Context())
+	return nil
+}
+
+// handler returns the request handler of the proxy's app, mounting it anew if
+// its cache instance was rebuilt, and dropping apps of removed proxies
+func (d *dispatcher) handler(name string, c *cache.Cache) fasthttp.RequestHandler {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if m, ok := d.mounted[name]; ok && m.cache == c {
+		return m.handler
+	}
+
+	for mountedName := range d.mounted {
+		if d.caches.Get(mountedName) == nil {
+			delete(d.mounted, mountedName)
+		}
+	}
+
+	app := fiber.New(d.config)
+	Mount(app, *c.Proxy, c)
+	middleware.NotFound(app)
+	d.mounted[name] = mountedProxy{cache: c, handler: app.Handler()}
+
+	return d.mounted[name].handler
+}
@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"fmt"
+)
+
+// ErrWarmupSkipped is an error struct for a warm-up tile that couldn't be
+// fetched from the upstream
+type ErrWarmupSkipped struct {
+	ProxyName string
+	Reason    string
+}
+
+// Error returns the string representation of ErrWarmupSkipped
+func (e ErrWarmupSkipped) Error() string {
+	return fmt.Sprintf("warmup: proxy(%s) skipped tile: %s", e.ProxyName, e.Reason)
+}
+
+// ErrFetchPanic is an error struct for shared upstream fetches that panicked,
+// returned to every request waiting on the fetch
+type ErrFetchPanic struct {
+	ProxyName string
+	Panic     interface{}
+}
+
+// Error returns the string representation of ErrFetchPanic
+func (e ErrFetchPanic) Error() string {
+	return fmt.Sprintf("proxy: fetch of proxy(%s) panicked: %v", e.ProxyName, e.Panic)
+}
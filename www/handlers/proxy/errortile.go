@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// genErrorTileMiddleware builds a middleware replacing the body of failed
+// tile requests with the proxy's error tile for their status, keeping the
+// status itself. Requests that aren't for tiles are left as is.
+func genErrorTileMiddleware(p config.Proxy) fiber.Handler {
+	prefix := "/" + p.Name
+	return func(ctx *fiber.Ctx) error {
+		err := ctx.Next()
+
+		if ctx.Method() != fiber.MethodGet && ctx.Method() != fiber.MethodHead {
+			return err
+		}
+		switch strings.TrimPrefix(ctx.Path(), prefix) {
+		case metadataPath, openAPIPath:
+			return err
+		}
+
+		// errors returned by handlers are answered with a 500
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		errorTile := p.ErrorTile(status)
+		if errorTile == nil {
+			return err
+		}
+		if err != nil {
+			util.Error(str.CMain, str.ERequest, ctx.String(), err.Error())
+		}
+
+		ctx.Response().Header.Del(fiber.HeaderContentEncoding)
+		ctx.Set(fiber.HeaderContentType, errorTile.ContentType)
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+		return ctx.Status(status).Send(errorTile.Data)
+	}
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/metadata"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/timing"
+	"github.com/dechristopher/lod/upstream"
+	"github.com/dechristopher/lod/util"
+)
+
+const metadataPath = "/metadata"
+
+// genMetadataHandler builds a handler serving the proxy's normalized upstream
+// metadata as a TileJSON document pointing at LOD
+func genMetadataHandler(p config.Proxy, c *cache.Cache) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		timing.Start(ctx)
+		ctx.Locals(str.LocalCache, c)
+		util.LogWith(ctx, "proxy", p.Name)
+		err := handleMetadata(p, c, ctx)
+		observeRequest(p, c, ctx, time.Since(start))
+		return err
+	}
+}
+
+// handleMetadata serves the proxy's normalized metadata from the cache,
+// fetching and normalizing it from the upstream on misses
+func handleMetadata(p config.Proxy, c *cache.Cache, ctx *fiber.Ctx) error {
+	cacheKey := "metadata:" + p.Name
+
+	var doc metadata.Document
+	if cached := c.Fetch(cacheKey, ctx); cached != nil && json.Unmarshal(cached.TileData(), &doc) == nil {
+		return sendMetadata(ctx, p, doc)
+	}
+
+	ctx.Locals(str.LocalCacheStatus, ":miss ")
+	if c.InMaintenance() {
+		return sendMaintenance(ctx, p)
+	}
+
+	defer flightGroup.Forget(cacheKey)
+
+	fetch := upstream.GetScheduler(p.Name).Wrap(helpers.ClientKey(ctx, p), func() (interface{}, error) {
+		return helpers.FetchMetadata(p)
+	})
+
+	var result singleflight.Result
+	select {
+	case result = <-flightGroup.DoChan(cacheKey, recovered(p, c, cacheKey, fetch)):
+	case <-helpers.ClientDone(ctx):
+		return sendClientAborted(ctx, c, cache.AbortUpstream)
+	}
+
+	var queueErr upstream.ErrQueueTimeout
+	if errors.As(result.Err, &queueErr) {
+		return sendQueueTimeout(ctx, p)
+	}
+
+	if result.Err != nil {
+		util.Log(ctx).Error(str.CProxy, str.EMetadata, p.Name, p.Metadata.URL, result.Err.Error())
+		ctx.Locals(str.LocalCacheStatus, ":err-a")
+		return ctx.Status(fiber.StatusInternalServerError).SendString("")
+	}
+
+	doc = result.Val.(metadata.Document)
+	if data, err := json.Marshal(doc); err == nil {
+		go c.EncodeSet(cacheKey, data, map[string]string{
+			fiber.HeaderContentType: fiber.MIMEApplicationJSON,
+		})
+	}
+
+	return sendMetadata(ctx, p, doc)
+}
+
+// sendMetadata responds with a normalized metadata document, its tiles
+// pointing at the proxy's tile route on the requested host
+func sendMetadata(ctx *fiber.Ctx, p config.Proxy, doc metadata.Document) error {
+	doc.Tiles = []string{tileTemplate(ctx.BaseURL(), p, doc.Format)}
+	return ctx.JSON(doc)
+}
+
+// routeParamPattern matches named placeholders in a proxy's tile route
+var routeParamPattern = regexp.MustCompile(`:([a-zA-Z0-9_]+)`)
+
+// tileTemplate returns the TileJSON URL template of a proxy's tile route,
+// using the tile format as the extension of wildcard routes
+func tileTemplate(baseURL string, p config.Proxy, format string) string {
+	if format == "" {
+		format = "png"
+	}
+	route := routeParamPattern.ReplaceAllString(p.Route(), "{$1}")
+	return baseURL + "/" + p.Name + strings.ReplaceAll(route, "*", format)
+}
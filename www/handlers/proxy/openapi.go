@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+const openAPIPath = "/openapi.json"
+
+// routePlaceholderPattern matches the named placeholders and the extension
+// wildcard of a fiber route
+var routePlaceholderPattern = regexp.MustCompile(`:([a-zA-Z0-9_]+)|\*`)
+
+type openAPIDoc struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Servers    []openAPIServer                 `json:"servers"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components *openAPIComponents              `json:"components,omitempty"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+type operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Parameters  []parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]response `json:"responses"`
+}
+
+type requestBody struct {
+	Required bool                    `json:"required"`
+	Content  map[string]schemaHolder `json:"content"`
+}
+
+type schemaHolder struct {
+	Schema schema `json:"schema"`
+}
+
+type parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   schema `json:"schema"`
+}
+
+type schema struct {
+	Type    string `json:"type"`
+	Minimum *int   `json:"minimum,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+type response struct {
+	Description string `json:"description"`
+}
+
+// genOpenAPIHandler builds a handler serving an OpenAPI 3 document describing
+// the proxy's public endpoints, their parameters and auth requirements
+func genOpenAPIHandler(p config.Proxy) fiber.Handler {
+	doc, _ := json.Marshal(buildOpenAPI(p))
+
+	return func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return ctx.Send(doc)
+	}
+}
+
+// buildOpenAPI generates the OpenAPI document for the public endpoints of the
+// given proxy, relative to its name
+func buildOpenAPI(p config.Proxy) openAPIDoc {
+	doc := openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "LOD " + p.Name,
+			Version: config.Version,
+		},
+		Servers: []openAPIServer{{URL: "/" + p.Name}},
+		Paths:   map[string]map[string]operation{},
+	}
+
+	// every configured auth mechanism must be satisfied, so all are required
+	// together by a single security requirement
+	schemes := map[string]securityScheme{}
+	if p.AccessToken != "" || len(p.Keys) > 0 {
+		schemes["token"] = securityScheme{Type: "apiKey", Name: "token", In: "query"}
+	}
+	if p.JWT.Enabled {
+		schemes["bearer"] = securityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+	}
+	if len(schemes) > 0 {
+		doc.Components = &openAPIComponents{SecuritySchemes: schemes}
+		requirement := map[string][]string{}
+		for name := range schemes {
+			requirement[name] = []string{}
+		}
+		doc.Security = []map[string][]string{requirement}
+	}
+
+	tilePath, tileParams := openAPIRoute(p.Route())
+	tileParams = append(tileParams, queryParameters(p)...)
+	addOperation(doc, tilePath, fiber.MethodGet, newOperation("getTile", "Get a tile", tileParams))
+	for _, method := range p.Methods {
+		addOperation(doc, tilePath, method, newOperation(strings.ToLower(method)+"Tile",
+			"Get a tile, passing the request body to the upstream", tileParams))
+	}
+
+	if p.Metadata.URL != "" {
+		addOperation(doc, metadataPath, fiber.MethodGet,
+			newOperation("getMetadata", "Get the dataset's TileJSON metadata", nil))
+	}
+
+	if p.Bulk.Enabled {
+		bulkPath := bulkEndpointPath
+		if p.HasEndpointParam {
+			bulkPath = "/:" + str.ParamEndpoint + bulkPath
+		}
+		path, params := openAPIRoute(bulkPath)
+		op := newOperation("getTiles", "Get an archive of the requested tiles",
+			append(params, queryParameters(p)...))
+		op.RequestBody = &requestBody{Required: true, Content: map[string]schemaHolder{
+			fiber.MIMEApplicationJSON: {Schema: schema{Type: "object"}},
+		}}
+		addOperation(doc, path, fiber.MethodPost, op)
+	}
+
+	for _, route := range p.Routes {
+		routeProxy := p.ForRoute(route)
+		path, params := openAPIRoute(routeProxy.Route())
+		params = append(params, queryParameters(routeProxy)...)
+		summary := "Get a tile"
+		if route.Kind == config.RouteResource {
+			summary = "Get a resource"
+		}
+		addOperation(doc, path, fiber.MethodGet, newOperation("get_"+route.Name, summary+" from the "+
+			route.Name+" route", params))
+	}
+
+	return doc
+}
+
+// addOperation adds an operation to the document under the given method
+func addOperation(doc openAPIDoc, path, method string, op operation) {
+	if doc.Paths[path] == nil {
+		doc.Paths[path] = map[string]operation{}
+	}
+	doc.Paths[path][strings.ToLower(method)] = op
+}
+
+// newOperation describes an endpoint with the given parameters
+func newOperation(id, summary string, params []parameter) operation {
+	return operation{
+		OperationID: id,
+		Summary:     summary,
+		Parameters:  params,
+		Responses: map[string]response{
+			"200": {Description: "OK"},
+		},
+	}
+}
+
+// queryParameters describes the proxy's configured query parameters, which
+// are all optional and fall back to their defaults
+func queryParameters(p config.Proxy) []parameter {
+	params := make([]parameter, 0, len(p.Params))
+	for _, param := range p.Params {
+		params = append(params, parameter{Name: param.Name, In: "query",
+			Schema: schema{Type: "string", Default: param.Default}})
+	}
+	return params
+}
+
+// openAPIRoute converts a fiber route into an OpenAPI templated path and its
+// path parameters, describing tile coordinates as non-negative integers and
+// the extension wildcard as the "format" parameter
+func openAPIRoute(route string) (string, []parameter) {
+	var params []parameter
+
+	path := routePlaceholderPattern.ReplaceAllStringFunc(route, func(placeholder string) string {
+		name := strings.TrimPrefix(placeholder, ":")
+		param := parameter{Name: name, In: "path", Required: true, Schema: schema{Type: "string"}}
+		switch name {
+		case str.ParamZ, str.ParamX, str.ParamY:
+			zero := 0
+			param.Schema = schema{Type: "integer", Minimum: &zero}
+		case "*":
+			param.Name = "format"
+		}
+		params = append(params, param)
+		return "{" + param.Name + "}"
+	})
+
+	return path, params
+}
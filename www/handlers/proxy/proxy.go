@@ -1,15 +1,29 @@
 package proxy
 
 import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/sync/singleflight"
 
+	"github.com/dechristopher/lod/accesslog"
 	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/heatmap"
 	"github.com/dechristopher/lod/helpers"
 	"github.com/dechristopher/lod/packet"
+	"github.com/dechristopher/lod/slo"
 	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/timing"
+	"github.com/dechristopher/lod/upstream"
 	"github.com/dechristopher/lod/util"
+	"github.com/dechristopher/lod/webhook"
 )
 
 type tileError struct {
@@ -19,15 +33,96 @@ type tileError struct {
 
 var flightGroup singleflight.Group
 
-// genHandler builds a new proxy endpoint handler from configuration
-func genHandler(p config.Proxy) fiber.Handler {
-	// get cache instance for this proxy
-	c := cache.Get(p.Name)
-
+// NewHandler builds a tile endpoint handler for the given prepared proxy
+// configuration and its cache instance, for mounting on any fiber router
+func NewHandler(p config.Proxy, c *cache.Cache) fiber.Handler {
 	// handler function to wire to endpoint
 	return func(ctx *fiber.Ctx) error {
-		return handle(p, c, ctx)
+		start := time.Now()
+		timing.Start(ctx)
+		ctx.Locals(str.LocalCache, c)
+		util.LogWith(ctx, "proxy", p.Name)
+		err := handle(p, c, ctx)
+		observeRequest(p, c, ctx, time.Since(start))
+		return err
+	}
+}
+
+// observeRequest records the latency of a handled request, measures it
+// against the proxy's service level objectives if configured, logs it if it
+// was slow, and queues its summary for the webhook collector and its record
+// for the access log. The timing breakdown is added to the response as a
+// Server-Timing header if enabled, exposed to the origins CORS allows.
+func observeRequest(p config.Proxy, c *cache.Cache, ctx *fiber.Ctx, latency time.Duration) {
+	status := cacheStatus(ctx)
+	if p.ServerTiming {
+		ctx.Set(fiber.HeaderServerTiming, timing.Get(ctx).ServerTiming(latency))
+		if origin := ctx.GetRespHeader(fiber.HeaderAccessControlAllowOrigin); origin != "" {
+			ctx.Set(fiber.HeaderTimingAllowOrigin, origin)
+		}
+	}
+	if p.ResponseVia {
+		helpers.SetResponseVia(ctx)
+	}
+	if threshold := p.SlowRequests.ThresholdDuration; threshold > 0 && latency > threshold {
+		logSlowRequest(ctx, threshold, latency, status)
+	}
+	c.Metrics.RequestDuration.WithLabelValues(status).Observe(latency.Seconds())
+	slo.Get(p.Name).Observe(latency, ctx.Response().StatusCode() >= fiber.StatusInternalServerError)
+
+	summary := webhook.Summary{
+		Time:        time.Now().Add(-latency),
+		Proxy:       p.Name,
+		CacheStatus: status,
+		Status:      ctx.Response().StatusCode(),
+		LatencyMS:   float64(latency) / float64(time.Millisecond),
+	}
+	if reqTile, err := tile.Get(ctx); err == nil {
+		summary.Tile = fmt.Sprintf("%d/%d/%d", reqTile.Zoom, reqTile.X, reqTile.Y)
 	}
+	if key := p.Key(ctx.Query("token")); key != nil {
+		summary.Key = key.Name
+	}
+	summary.RequestID, _ = ctx.Locals(str.LocalRequestID).(string)
+	webhook.Emit(summary)
+
+	if accesslog.Enabled() {
+		accesslog.Emit(accesslog.Record{
+			Time:        summary.Time,
+			Proxy:       p.Name,
+			Method:      ctx.Method(),
+			Path:        ctx.Path(),
+			Tile:        summary.Tile,
+			Status:      summary.Status,
+			CacheStatus: status,
+			LatencyMS:   summary.LatencyMS,
+			Bytes:       len(ctx.Response().Body()),
+			ClientIP:    ctx.IP(),
+			UserAgent:   ctx.Get(fiber.HeaderUserAgent),
+			Referer:     ctx.Get(fiber.HeaderReferer),
+			Key:         summary.Key,
+			RequestID:   summary.RequestID,
+		})
+	}
+}
+
+// logSlowRequest logs a request that took longer than the slow request
+// threshold with the time it spent in each phase of handling it
+func logSlowRequest(ctx *fiber.Ctx, threshold, latency time.Duration, status string) {
+	b := timing.Get(ctx)
+	util.Log(ctx).Error(str.CProxy, str.ESlowRequest, latency, threshold, status,
+		b.Spent(timing.Cache), b.Spent(timing.Redis), b.Spent(timing.Upstream),
+		b.Spent(timing.Encode), b.Spent(timing.Write), b.Other(latency))
+}
+
+// cacheStatus returns the request's cache status without log padding,
+// for use as a metric label
+func cacheStatus(ctx *fiber.Ctx) string {
+	status, _ := ctx.Locals(str.LocalCacheStatus).(string)
+	if status = strings.Trim(status, ": "); status == "" {
+		return "unknown"
+	}
+	return status
 }
 
 // handle proxy requests for the specified proxy config
@@ -36,113 +131,357 @@ func handle(p config.Proxy, c *cache.Cache, ctx *fiber.Ctx) error {
 	// their values in a map within the request locals
 	helpers.FillParamsMap(p, ctx)
 
-	// build tileUrl and cacheKey from request context and config
-	tileUrl, cacheKey, err := buildKeyAndUrl(p, ctx)
+	// parse the requested tile, correlating the request's log lines with it
+	reqTile, _ := tile.Get(ctx)
+	if reqTile != nil {
+		util.LogWith(ctx, "tile", reqTile.String())
+	}
+
+	// resolve the data version pinned by the client, if any
+	if !helpers.FillVersion(p, ctx) {
+		ctx.Locals(str.LocalCacheStatus, ":err-v")
+		return ctx.Status(fiber.StatusBadRequest).SendString("")
+	}
+
+	// keep the body of passthrough requests to send upstream
+	helpers.FillBody(p, ctx)
+
+	// build tileUrl, body and cacheKey from request context and config
+	tileUrl, body, cacheKey, err := buildKeyAndUrl(p, ctx)
 	if err != nil {
 		// buildKeyAndUrl log their own errors, so no need to here
 		return ctx.Status(fiber.StatusBadRequest).SendString("")
 	}
 
+	// refuse requests that already passed through this instance to break loops
+	// in misconfigured tiered deployments
+	if helpers.IsPeerLoop(ctx) {
+		ctx.Locals(str.LocalCacheStatus, ":loop ")
+		util.Log(ctx).Error(str.CProxy, str.EProxyLoop, p.Name)
+		return ctx.Status(fiber.StatusLoopDetected).SendString("")
+	}
+
+	// answer requests for tiles outside the tile pyramid or the proxy's
+	// coverage without any lookups
+	if !reqTile.InRange() || !c.Coverage().Contains(*reqTile) {
+		ctx.Locals(str.LocalCacheStatus, ":oob  ")
+		return helpers.SendMissingTile(ctx, p, fiber.StatusNotFound)
+	}
+
+	// count the request towards the proxy's heatmap
+	heatmap.Get(p.Name).Record(*reqTile)
+
+	// answer requests for tiles in subtrees known to be empty upstream locally
+	if c.KnownEmpty(*reqTile) {
+		ctx.Locals(str.LocalCacheStatus, ":empty")
+		return helpers.SendMissingTile(ctx, p, fiber.StatusNoContent)
+	}
+
+	// hint the neighboring tiles a panning client is likely to request next
+	helpers.SetHints(ctx, p, *reqTile)
+
+	// reject all requests outright if configured to do so during maintenance
+	if c.InMaintenance() && p.Maintenance.Mode == config.MaintenanceUnavailable {
+		return sendMaintenance(ctx, p)
+	}
+
 	// attempt to fetch the tile from cache before hitting the upstream
 	if cachedTile := c.Fetch(cacheKey, ctx); cachedTile != nil {
 		// IF WE HIT A CACHED TILE
-		if err = returnCachedTile(ctx, p, tileUrl, cachedTile); err != nil {
+		stopWrite := timing.Track(ctx, timing.Write)
+		err = returnCachedTile(ctx, p, c, tileUrl, cachedTile)
+		stopWrite()
+		if err != nil {
 			return ctx.Status(fiber.StatusInternalServerError).SendString("")
 		}
 	} else {
 		// IF WE MISSED A CACHED TILE
 		ctx.Locals(str.LocalCacheStatus, ":miss ")
 
+		// never contact the upstream while in maintenance mode
+		if c.InMaintenance() {
+			return sendMaintenance(ctx, p)
+		}
+
+		// never render tiles of cache-only zoom levels at request time
+		if p.IsCacheOnly(reqTile.Zoom) {
+			return sendCacheOnlyMiss(ctx, p, c)
+		}
+
+		var response interface{}
+		var errProxy error
+		var waited bool
+
+		start := time.Now()
+		stopUpstream := timing.Track(ctx, timing.Upstream)
+		done := helpers.ClientDone(ctx)
+		scheduler := upstream.GetScheduler(p.Name)
+
+		// stream large tiles straight from the upstream if configured,
+		// sharing all others with requests waiting on the same tile
+		fetchUpstream := helpers.FetchUpstream
+		if p.Streaming.Enabled {
+			fetchUpstream = helpers.StreamUpstream
+		}
+
 		// clean up flight group after request is done
 		defer flightGroup.Forget(cacheKey)
 
-		// fetch tile via agent proxy, ensuring only a single request is in flight at a given time
-		response, errProxy, waited := flightGroup.Do(cacheKey, helpers.FetchUpstream(tileUrl, p))
+		// fetch tile via agent proxy, ensuring only a single request is in flight
+		// at a given time and fair queuing upstream requests if configured. The
+		// shared fetch keeps running for other waiters and the cache if this
+		// request's client goes away.
+		fetch := scheduler.Wrap(helpers.ClientKey(ctx, p),
+			c.Budgeted(fetchUpstream(tileUrl, p, helpers.RequestOrigin(ctx), body)))
+		flight := flightGroup.DoChan(cacheKey, recovered(p, c, cacheKey, fetch))
+		select {
+		case result := <-flight:
+			response, errProxy, waited = result.Val, result.Err, result.Shared
+		case <-done:
+			// close the stream of a shared fetch nobody else reads
+			go discardFlight(flight)
+			return sendClientAborted(ctx, c, cache.AbortUpstream)
+		}
+
+		// a streamed tile can only be read once, so requests that shared a
+		// fetch whose stream was claimed by another fetch it on their own
+		if shared, ok := response.(helpers.ProxyResponse); ok && !helpers.ClaimStream(shared) {
+			response, errProxy = scheduler.WrapCancel(helpers.ClientKey(ctx, p), done,
+				c.Budgeted(helpers.StreamUpstream(tileUrl, p, helpers.RequestOrigin(ctx), body)))()
+			waited = false
+		}
+		stopUpstream()
+
+		// shed requests that waited too long for a fair queue slot
+		var queueErr upstream.ErrQueueTimeout
+		if errors.As(errProxy, &queueErr) {
+			return sendQueueTimeout(ctx, p)
+		}
+
+		// give up on requests whose client went away while queued
+		var abortErr upstream.ErrClientAborted
+		if errors.As(errProxy, &abortErr) {
+			return sendClientAborted(ctx, c, cache.AbortQueue)
+		}
+
+		// serve from cache only once the upstream request budget is spent
+		var budgetErr cache.ErrBudgetSpent
+		if errors.As(errProxy, &budgetErr) {
+			return sendBudgetSpent(ctx, c, budgetErr.RetryAfter)
+		}
 
 		if errProxy != nil {
 			// return internal server error status if agent proxy request failed in flight
-			util.Error(str.CProxy, str.EProxyAgentError, p.Name, cacheKey, errProxy.Error())
+			util.Log(ctx).Error(str.CProxy, str.EProxyAgentError, p.Name, cacheKey, errProxy.Error())
 			ctx.Locals(str.LocalCacheStatus, ":err-a")
 			return ctx.Status(fiber.StatusInternalServerError).SendString("")
 		}
 
 		if waited {
 			ctx.Locals(str.LocalCacheStatus, ":hit-w")
+		} else {
+			// track fetch times to refetch hot tiles early enough before expiry
+			c.ObserveFetch(time.Since(start))
 		}
 
 		// cast interface returned from flight group to a proxyResponse
 		proxyResp, ok := response.(helpers.ProxyResponse)
-		
+
 		// sanity check to ensure cast worked properly
 		if !ok {
-			util.Error(str.CProxy, str.EProxyBadCast, p.Name, cacheKey)
+			util.Log(ctx).Error(str.CProxy, str.EProxyBadCast, p.Name, cacheKey)
 			ctx.Locals(str.LocalCacheStatus, ":err-i")
 			return ctx.Status(fiber.StatusInternalServerError).SendString("")
 		}
 
+		// propagate cache hits from the origin LOD instance of edge proxies
+		if helpers.OriginCacheHit(proxyResp) {
+			ctx.Locals(str.LocalCacheStatus, ":hit-o")
+		}
+
 		// write tile data and headers and cache result
-		if err = helpers.ProcessResponse(helpers.ProcessResponsePayload{
+		err = helpers.ProcessResponse(helpers.ProcessResponsePayload{
 			Ctx:       ctx,
 			Cache:     c,
 			Proxy:     p,
+			Tile:      *reqTile,
 			CacheKey:  cacheKey,
 			Response:  proxyResp,
 			WriteData: true,
-		}); err != nil {
-			util.Error(str.CProxy, str.EProxyWrite, p.Name, cacheKey, err.Error())
+		})
+		if err != nil {
+			// respond to tiles missing upstream using configured missing tile behavior
+			var statusErr helpers.ErrInvalidStatusCode
+			if errors.As(err, &statusErr) && statusErr.StatusCode == fiber.StatusNotFound {
+				c.MarkEmpty(*reqTile)
+				return helpers.SendMissingTile(ctx, p, fiber.StatusNotFound)
+			}
+
+			util.Log(ctx).Error(str.CProxy, str.EProxyWrite, p.Name, cacheKey, err.Error())
 			ctx.Locals(str.LocalCacheStatus, ":err-u")
 			// Send internal server error response with empty body if upstream
 			// fails to respond or responds with a non-200 status code
 			return ctx.Status(fiber.StatusInternalServerError).SendString("")
 		}
+
+		// remember the upstream has no data below empty tiles
+		if proxyResp.Code == fiber.StatusNoContent {
+			c.MarkEmpty(*reqTile)
+		}
 	}
 
+	// apply configured browser and CDN cache headers for the tile's zoom level
+	helpers.SetCacheHeaders(ctx, p, *reqTile)
+
+	// report cache status to peer LOD instances
+	helpers.SetPeerCacheStatus(ctx)
+
 	return nil
 }
 
-// buildKeyAndUrl returns the upstream tile URL and cache key using the given
-// proxy configuration and fiber request context
-func buildKeyAndUrl(p config.Proxy, ctx *fiber.Ctx) (string, string, error) {
+// buildKeyAndUrl returns the upstream tile URL and request body, and the cache
+// key using the given proxy configuration and fiber request context
+func buildKeyAndUrl(p config.Proxy, ctx *fiber.Ctx) (string, []byte, string, error) {
 	// calculate url from the configured URL and params
 	tileUrl, err := helpers.BuildTileUrl(p, ctx)
 	if err != nil {
 		ctx.Locals(str.LocalCacheStatus, ":err-t")
-		util.Error(str.CProxy, str.ECacheBuildTileUrl, err.Error())
-		return "", "", err
+		util.Log(ctx).Error(str.CProxy, str.ECacheBuildTileUrl, err.Error())
+		return "", nil, "", err
 	}
 
-	// calculate the cache key for this request using XYZ and URL params
+	// calculate the upstream request body, if any, from the template or client
+	body, err := helpers.BuildTileBody(p, ctx)
+	if err != nil {
+		ctx.Locals(str.LocalCacheStatus, ":err-t")
+		util.Log(ctx).Error(str.CProxy, str.ECacheBuildTileUrl, err.Error())
+		return "", nil, "", err
+	}
+
+	// calculate the cache key for this request using XYZ, URL params and body
 	cacheKey, err := helpers.BuildCacheKey(p, ctx)
 	if err != nil {
 		ctx.Locals(str.LocalCacheStatus, ":err-c")
-		util.Error(str.CProxy, str.ECacheBuildKey, err.Error())
-		return "", "", err
+		util.Log(ctx).Error(str.CProxy, str.ECacheBuildKey, err.Error())
+		return "", nil, "", err
+	}
+
+	return tileUrl, body, cacheKey, nil
+}
+
+// sendMaintenance rejects a request with 503 Service Unavailable and a
+// Retry-After header while the proxy is in maintenance mode
+func sendMaintenance(ctx *fiber.Ctx, p config.Proxy) error {
+	ctx.Locals(str.LocalCacheStatus, ":maint")
+	return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable,
+		str.RMaintenance, p.Maintenance.RetryAfterDuration)
+}
+
+// sendCacheOnlyMiss answers a cache miss of a cache-only zoom level using the
+// configured missing tile behavior, without contacting the upstream
+func sendCacheOnlyMiss(ctx *fiber.Ctx, p config.Proxy, c *cache.Cache) error {
+	ctx.Locals(str.LocalCacheStatus, ":cold ")
+	c.Metrics.CacheOnlyMisses.Inc()
+	return helpers.SendMissingTile(ctx, p, fiber.StatusNotFound)
+}
+
+// sendBudgetSpent answers a cache miss with 503 Service Unavailable while the
+// proxy's upstream request budget is spent, hinting to retry once it resets
+func sendBudgetSpent(ctx *fiber.Ctx, c *cache.Cache, retryAfter time.Duration) error {
+	ctx.Locals(str.LocalCacheStatus, ":quota")
+	c.Metrics.BudgetRejections.Inc()
+	return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable, str.RBudgetSpent, retryAfter)
+}
+
+// sendQueueTimeout sheds a request that waited too long for a fair queue slot
+// with 503 Service Unavailable, hinting to retry after another queue timeout
+func sendQueueTimeout(ctx *fiber.Ctx, p config.Proxy) error {
+	ctx.Locals(str.LocalCacheStatus, ":queue")
+	return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable,
+		str.RQueueTimeout, p.Upstream.Fairness.QueueTimeoutDuration)
+}
+
+// sendClientAborted records a request aborted by its client, logging it with
+// the non-standard 499 status since the client never receives a response
+func sendClientAborted(ctx *fiber.Ctx, c *cache.Cache, phase string) error {
+	ctx.Locals(str.LocalCacheStatus, ":abort")
+	c.Metrics.ClientAborts.WithLabelValues(phase).Inc()
+	return ctx.Status(helpers.StatusClientClosedRequest).SendString("")
+}
+
+// recovered wraps a shared fetch, converting panics into an ErrFetchPanic for
+// all waiting requests. Panics in flight group fetches are otherwise rethrown
+// on their own goroutine, crashing the process.
+func recovered(p config.Proxy, c *cache.Cache, key string, fetch func() (interface{}, error)) func() (interface{}, error) {
+	return func() (val interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				util.Error(str.CProxy, str.EFetchPanic, key, p.Name, r, debug.Stack())
+				c.Metrics.Panics.Inc()
+				val, err = nil, ErrFetchPanic{ProxyName: p.Name, Panic: r}
+			}
+		}()
+
+		return fetch()
 	}
+}
 
-	return tileUrl, cacheKey, nil
+// discardFlight closes the unclaimed stream of the result of a shared fetch
+// abandoned by its request
+func discardFlight(flight <-chan singleflight.Result) {
+	result := <-flight
+	if resp, ok := result.Val.(helpers.ProxyResponse); ok {
+		helpers.DiscardStream(resp)
+	}
 }
 
 // returnCachedTile is called if the cache contains the requested tile
-func returnCachedTile(ctx *fiber.Ctx, p config.Proxy, tileUrl string, cachedTile *packet.TilePacket) error {
+func returnCachedTile(ctx *fiber.Ctx, p config.Proxy, c *cache.Cache, tileUrl string, cachedTile *packet.TilePacket) error {
+	// respond to cached empty tiles using configured missing tile behavior
+	if cachedTile.TileDataSize() == 0 {
+		return helpers.SendMissingTile(ctx, p, fiber.StatusNoContent)
+	}
+
 	// write the tile to the response body
 	_, err := ctx.Write(cachedTile.TileData())
 	if err != nil {
 		ctx.Locals(str.LocalCacheStatus, ":err-w")
-		util.Error(str.CProxy, str.EWrite, err.Error(), tileError{
+		util.Log(ctx).Error(str.CProxy, str.EWrite, err.Error(), tileError{
 			url:   tileUrl,
 			proxy: p,
 		})
 		return err
 	}
+	c.RecordServed(cache.SourceCache, cachedTile.TileDataSize())
 
 	// set stored headers in response
 	for key, val := range cachedTile.Headers() {
+		if key == packet.HeaderCreated || key == packet.HeaderTTL || key == packet.HeaderAdaptiveTTL {
+			continue
+		}
 		ctx.Set(key, val)
 	}
 
+	// report how long ago the tile was fetched from the upstream
+	if created, ok := cachedTile.Created(); ok {
+		age := time.Since(created)
+		if age < 0 {
+			age = 0
+		}
+		ctx.Set(fiber.HeaderAge, strconv.FormatInt(int64(age/time.Second), 10))
+	}
+
+	// infer content type for tiles cached without one
+	if _, ok := cachedTile.Headers()[fiber.HeaderContentType]; !ok {
+		contentType, contentEncoding := helpers.InferContentType(cachedTile.TileData(), "")
+		ctx.Set(fiber.HeaderContentType, contentType)
+		if contentEncoding != "" {
+			ctx.Set(fiber.HeaderContentEncoding, contentEncoding)
+		}
+	}
+
 	// remove delete list headers from final response
 	p.DoDeleteHeaders(ctx)
-	ctx.Set("Content-Encoding", "gzip")
 
 	return nil
 }
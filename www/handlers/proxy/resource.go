@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/timing"
+	"github.com/dechristopher/lod/upstream"
+	"github.com/dechristopher/lod/util"
+)
+
+// genResourceHandler builds a new handler for a proxy's resource route, such
+// as a TileJSON or metadata document, from configuration
+func genResourceHandler(p config.Proxy, c *cache.Cache) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		timing.Start(ctx)
+		ctx.Locals(str.LocalCache, c)
+		util.LogWith(ctx, "proxy", p.Name)
+		err := handleResource(p, c, ctx)
+		observeRequest(p, c, ctx, time.Since(start))
+		return err
+	}
+}
+
+// handleResource proxies and caches resource requests for the specified proxy
+// route config, keyed by their request path
+func handleResource(p config.Proxy, c *cache.Cache, ctx *fiber.Ctx) error {
+	helpers.FillParamsMap(p, ctx)
+
+	if !helpers.FillVersion(p, ctx) {
+		ctx.Locals(str.LocalCacheStatus, ":err-v")
+		return ctx.Status(fiber.StatusBadRequest).SendString("")
+	}
+
+	resourceUrl, err := helpers.BuildResourceUrl(p, ctx)
+	if err != nil {
+		ctx.Locals(str.LocalCacheStatus, ":err-t")
+		util.Log(ctx).Error(str.CProxy, str.ECacheBuildTileUrl, err.Error())
+		return ctx.Status(fiber.StatusBadRequest).SendString("")
+	}
+	cacheKey := helpers.BuildResourceKey(p, ctx)
+
+	if helpers.IsPeerLoop(ctx) {
+		ctx.Locals(str.LocalCacheStatus, ":loop ")
+		util.Log(ctx).Error(str.CProxy, str.EProxyLoop, p.Name)
+		return ctx.Status(fiber.StatusLoopDetected).SendString("")
+	}
+
+	if c.InMaintenance() && p.Maintenance.Mode == config.MaintenanceUnavailable {
+		return sendMaintenance(ctx, p)
+	}
+
+	if cached := c.Fetch(cacheKey, ctx); cached != nil {
+		stopWrite := timing.Track(ctx, timing.Write)
+		err = returnCachedTile(ctx, p, c, resourceUrl, cached)
+		stopWrite()
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).SendString("")
+		}
+		helpers.SetPeerCacheStatus(ctx)
+		return nil
+	}
+
+	ctx.Locals(str.LocalCacheStatus, ":miss ")
+	if c.InMaintenance() {
+		return sendMaintenance(ctx, p)
+	}
+
+	defer flightGroup.Forget(cacheKey)
+
+	fetch := upstream.GetScheduler(p.Name).Wrap(helpers.ClientKey(ctx, p),
+		c.Budgeted(helpers.FetchUpstream(resourceUrl, p, helpers.RequestOrigin(ctx), nil)))
+
+	var result singleflight.Result
+	stopUpstream := timing.Track(ctx, timing.Upstream)
+	select {
+	case result = <-flightGroup.DoChan(cacheKey, recovered(p, c, cacheKey, fetch)):
+	case <-helpers.ClientDone(ctx):
+		return sendClientAborted(ctx, c, cache.AbortUpstream)
+	}
+	stopUpstream()
+	response, errProxy, waited := result.Val, result.Err, result.Shared
+
+	var queueErr upstream.ErrQueueTimeout
+	if errors.As(errProxy, &queueErr) {
+		return sendQueueTimeout(ctx, p)
+	}
+
+	var budgetErr cache.ErrBudgetSpent
+	if errors.As(errProxy, &budgetErr) {
+		return sendBudgetSpent(ctx, c, budgetErr.RetryAfter)
+	}
+
+	if errProxy != nil {
+		util.Log(ctx).Error(str.CProxy, str.EProxyAgentError, p.Name, cacheKey, errProxy.Error())
+		ctx.Locals(str.LocalCacheStatus, ":err-a")
+		return ctx.Status(fiber.StatusInternalServerError).SendString("")
+	}
+
+	if waited {
+		ctx.Locals(str.LocalCacheStatus, ":hit-w")
+	}
+
+	proxyResp, ok := response.(helpers.ProxyResponse)
+	if !ok {
+		util.Log(ctx).Error(str.CProxy, str.EProxyBadCast, p.Name, cacheKey)
+		ctx.Locals(str.LocalCacheStatus, ":err-i")
+		return ctx.Status(fiber.StatusInternalServerError).SendString("")
+	}
+	c.RecordUpstream(len(proxyResp.Body))
+
+	// pass missing resources through, and fail on anything else besides data
+	if proxyResp.Code == fiber.StatusNotFound {
+		return ctx.Status(fiber.StatusNotFound).SendString("")
+	}
+	if proxyResp.Code != fiber.StatusOK || len(proxyResp.Body) == 0 {
+		util.Log(ctx).Error(str.CProxy, str.EProxyWrite, p.Name, cacheKey, helpers.ErrInvalidStatusCode{
+			StatusCode: proxyResp.Code,
+			CacheKey:   cacheKey,
+		}.Error())
+		ctx.Locals(str.LocalCacheStatus, ":err-u")
+		return ctx.Status(fiber.StatusInternalServerError).SendString("")
+	}
+
+	if helpers.OriginCacheHit(proxyResp) {
+		ctx.Locals(str.LocalCacheStatus, ":hit-o")
+	}
+
+	data := make([]byte, len(proxyResp.Body))
+	copy(data, proxyResp.Body)
+
+	headers := map[string]string{}
+	p.DoPullHeaders(proxyResp.Resp, headers)
+
+	stopWrite := timing.Track(ctx, timing.Write)
+	for key, val := range headers {
+		ctx.Set(key, val)
+	}
+	_, err = ctx.Write(data)
+	stopWrite()
+	if err != nil {
+		util.Log(ctx).Error(str.CProxy, str.EProxyWrite, p.Name, cacheKey, err.Error())
+		return err
+	}
+	c.RecordServed(cache.SourceUpstream, len(data))
+
+	// spin off a routine to cache the resource without blocking the response
+	go c.EncodeSet(cacheKey, data, headers, helpers.SkipsMemory(ctx))
+
+	helpers.SetPeerCacheStatus(ctx)
+
+	return nil
+}
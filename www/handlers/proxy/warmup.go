@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/tile"
+	"github.com/dechristopher/lod/util"
+)
+
+// warmup fetches every tile in the proxy's warm-up tile list into its cache,
+// from Redis where present and the upstream otherwise, then marks the list
+// done so the instance can report ready
+func warmup(r *fiber.App, p config.Proxy, c *cache.Cache) {
+	defer c.SetWarmupListDone()
+
+	start := time.Now()
+	lines, err := readTileList(p.Warmup.TileList)
+	if err != nil {
+		util.Error(str.CProxy, str.EWarmupList, p.Name, p.Warmup.TileList, err.Error())
+		return
+	}
+
+	jobs := make(chan string)
+	var warmed atomic.Int64
+	wg := &sync.WaitGroup{}
+	wg.Add(p.Background.Workers)
+
+	for i := 0; i < p.Background.Workers; i++ {
+		go func() {
+			defer wg.Done()
+			for line := range jobs {
+				if err := warmTile(r, p, c, line); err != nil {
+					util.Debug(str.CProxy, str.DWarmupFail, p.Name, line, err.Error())
+					continue
+				}
+				warmed.Add(1)
+			}
+		}()
+	}
+
+	for _, line := range lines {
+		jobs <- line
+	}
+	close(jobs)
+	wg.Wait()
+
+	util.Info(str.CProxy, str.MWarmupDone, p.Name, warmed.Load(), len(lines), time.Since(start))
+}
+
+// readTileList reads the entries of a warm-up tile list file
+func readTileList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return tile.ReadList(file)
+}
+
+// warmTile pulls a single tile list entry, "z/x/y" with optional query
+// parameters or a z,x,y CSV row, into the proxy's cache
+func warmTile(r *fiber.App, p config.Proxy, c *cache.Cache, line string) error {
+	entry, err := tile.ParseListEntry(line)
+	if err != nil {
+		return err
+	}
+	t := entry.Tile
+
+	// build a detached request context so query parameters segment the
+	// cache exactly as they would for a live request
+	ctx, ok := helpers.AcquireEntryCtx(r, p, c, entry)
+	if !ok {
+		return tile.ErrInvalidListEntry{Line: line}
+	}
+	defer r.ReleaseCtx(ctx)
+
+	cacheKey, err := helpers.BuildCacheKey(p, ctx, t)
+	if err != nil {
+		return err
+	}
+
+	// tiles already in Redis only need pulling into memory
+	if c.Fetch(cacheKey, ctx) != nil {
+		return nil
+	}
+
+	if c.InMaintenance() {
+		return ErrWarmupSkipped{ProxyName: p.Name, Reason: "maintenance mode"}
+	}
+
+	return helpers.FetchBackground(helpers.BackgroundFetchPayload{
+		Ctx:       ctx,
+		Cache:     c,
+		Tile:      t,
+		CacheKey:  cacheKey,
+		Client:    str.ClientWarmup,
+		WriteData: true,
+	})
+}
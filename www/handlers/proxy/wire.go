@@ -3,43 +3,126 @@ package proxy
 import (
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/str"
 	"github.com/dechristopher/lod/util"
 	"github.com/dechristopher/lod/www/middleware"
 )
 
-// Wire proxy group and endpoints for each configured proxy
-func Wire(r *fiber.App) {
+// Wire proxy group and endpoints for each configured proxy, served from their
+// cache instances held by the given manager. Proxies configured later, such
+// as dynamic proxies, are dispatched to as their cache instances are built.
+func Wire(r *fiber.App, caches *cache.Manager) {
+	static := make(map[string]bool)
 	for _, p := range config.Get().Proxies {
-		wireProxy(r, p)
+		Mount(r, p, caches.Get(p.Name))
+		static[p.Name] = true
 		util.Info(str.CMain, str.MProxy, p.Cache.MemEnabled, p.Cache.RedisEnabled, p.Name, p.TileURL)
+		if p.Chaos.Enabled() {
+			util.Info(str.CMain, str.MChaos, p.Name, p.Chaos)
+		}
 	}
+
+	r.Use(newDispatcher(r, caches, static).handle)
 }
 
-const handlerEndpointPath = "/:z/:x/:y.*"
+const bulkEndpointPath = "/tiles"
 
-// wireProxy configures a new proxy endpoint from the configuration under
-// a named Router group
-func wireProxy(r *fiber.App, p config.Proxy) {
+// Mount configures a new proxy endpoint from the given prepared configuration
+// and its cache instance under a named Router group
+func Mount(r *fiber.App, p config.Proxy, c *cache.Cache) {
 	// genHandler group for this proxy instance
 	proxyGroup := r.Group(p.Name)
 
 	// wire middleware for proxy group
-	middleware.Wire(r, &p)
+	middleware.Wire(proxyGroup, &p)
+
+	// serve configured error tiles in place of the bodies of failed tile
+	// requests, including those rejected by the middleware below
+	if len(p.ErrorTiles) > 0 {
+		proxyGroup.Use(genErrorTileMiddleware(p))
+	}
+
+	// answer panics in the proxy's handlers with a 500, counting them
+	proxyGroup.Use(middleware.GenRecoverMiddleware(&p, c))
+
+	// reject oversized query strings before any other work
+	proxyGroup.Use(middleware.GenQueryLimitMiddleware(&p))
+
+	// look up client countries and enforce country lists if a database is configured
+	if config.Get().Instance.GeoIP.Database != "" {
+		proxyGroup.Use(middleware.GenGeoMiddleware(&p, c))
+	}
+
+	// origin proxies with a peer secret only serve signed edge requests
+	if p.PeerSecret != "" && p.Tier != config.TierEdge {
+		proxyGroup.Use(middleware.GenPeerSignatureMiddleware(p.PeerSecret))
+	}
+
+	// describe the proxy's public endpoints ahead of auth, so API portals and
+	// client generators can learn how to authenticate
+	proxyGroup.Get(openAPIPath, genOpenAPIHandler(p))
+
+	// enable auth middleware if access token or API keys configured
+	if p.AccessToken != "" || len(p.Keys) > 0 {
+		proxyGroup.Use(middleware.GenKeyAuthMiddleware(&p))
+	}
+
+	// require bearer tokens from the identity provider if configured
+	if p.JWT.Enabled {
+		proxyGroup.Use(middleware.GenJWTMiddleware(&p))
+	}
+
+	// reject requests outside of the proxy's or any API key's serving windows
+	if p.HasSchedule() {
+		proxyGroup.Use(middleware.GenScheduleMiddleware(&p))
+	}
 
-	// enable auth middleware if access token configured
-	if p.AccessToken != "" {
-		proxyGroup.Use(middleware.GenAuthMiddleware(p.AccessToken,
-			middleware.Query, false))
+	// classify bots and apply their policies if configured
+	if p.Bots.Enabled {
+		proxyGroup.Use(middleware.GenBotMiddleware(&p, c))
 	}
 
-	path := handlerEndpointPath
+	path := p.Route()
+	bulkPath := bulkEndpointPath
 	// if dynamic endpoint configured, add endpoint path parameter
 	if p.HasEndpointParam {
-		path = "/:e" + path
+		bulkPath = "/:e" + bulkPath
+	}
+
+	// serve the upstream's normalized metadata ahead of the tile route
+	if p.Metadata.URL != "" {
+		proxyGroup.Get(metadataPath, genMetadataHandler(p, c))
+	}
+
+	// configure additional routes ahead of the tile route, whose placeholders
+	// could otherwise match their paths
+	for _, route := range p.Routes {
+		routeProxy := p.ForRoute(route)
+		if route.Kind == config.RouteResource {
+			proxyGroup.Get(route.Path, genResourceHandler(routeProxy, c))
+		} else {
+			proxyGroup.Get(route.Path, NewHandler(routeProxy, c))
+		}
 	}
 
-	// configure proxy endpoint genHandler
-	proxyGroup.Get(path, genHandler(p))
+	// configure proxy endpoint handler
+	handler := NewHandler(p, c)
+	proxyGroup.Get(path, handler)
+
+	// accept configured passthrough methods, whose bodies are sent upstream
+	for _, method := range p.Methods {
+		proxyGroup.Add(method, path, handler)
+	}
+
+	// fetch the warm-up tile list into the cache in the background
+	if p.Warmup.TileList != "" {
+		go warmup(r, p, c)
+	}
+
+	// configure bulk tile endpoint if enabled
+	if p.Bulk.Enabled {
+		proxyGroup.Post(bulkPath, genBulkHandler(p, c))
+	}
 }
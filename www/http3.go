@@ -0,0 +1,95 @@
+package www
+
+import (
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/valyala/fasthttp"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// altSvcOff disables Alt-Svc advertisement of the HTTP/3 listener
+const altSvcOff = "off"
+
+// wireAltSvc advertises the HTTP/3 listener on all responses
+func wireAltSvc(r *fiber.App) {
+	altSvc := config.Get().Instance.HTTP3.AltSvc
+	if altSvc == altSvcOff {
+		return
+	}
+
+	r.Use(func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderAltSvc, altSvc)
+		return ctx.Next()
+	})
+}
+
+// serveHTTP3 serves the app over HTTP/3 alongside the primary listener
+func serveHTTP3(r *fiber.App) *http3.Server {
+	instance := config.Get().Instance
+
+	server := &http3.Server{
+		Addr:    config.GetHTTP3ListenPort(),
+		Handler: http3Handler(r),
+	}
+
+	go func() {
+		util.Info(str.CMain, str.MHTTP3, instance.HTTP3.Port)
+		if err := server.ListenAndServeTLS(instance.HTTP3.CertFile, instance.HTTP3.KeyFile); err != nil {
+			util.Error(str.CMain, str.EHTTP3, err.Error())
+		}
+	}()
+
+	return server
+}
+
+// http3Handler bridges net/http requests from the HTTP/3 server into the
+// fasthttp based fiber app
+func http3Handler(r *fiber.App) http.HandlerFunc {
+	handler := r.Handler()
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		fastReq := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(fastReq)
+
+		fastReq.Header.SetMethod(req.Method)
+		fastReq.Header.SetProtocol(req.Proto)
+		fastReq.SetRequestURI(req.RequestURI)
+		fastReq.Header.SetHost(req.Host)
+		for key, values := range req.Header {
+			for _, value := range values {
+				fastReq.Header.Add(key, value)
+			}
+		}
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fastReq.SetBody(body)
+
+		remoteAddr, err := net.ResolveUDPAddr("udp", req.RemoteAddr)
+		if err != nil {
+			remoteAddr = &net.UDPAddr{}
+		}
+
+		var ctx fasthttp.RequestCtx
+		ctx.Init(fastReq, remoteAddr, nil)
+		// the request context is canceled once the client resets the stream
+		ctx.SetUserValue(str.LocalClientCtx, req.Context())
+		handler(&ctx)
+
+		ctx.Response.Header.VisitAll(func(key, value []byte) {
+			w.Header().Add(string(key), string(value))
+		})
+		w.WriteHeader(ctx.Response.StatusCode())
+		_, _ = w.Write(ctx.Response.Body())
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/bots"
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// GenBotMiddleware builds a middleware classifying requests as coming from
+// bots or interactive clients, keeping tiles fetched by bots out of the
+// in-memory cache and rate limiting bots if configured
+func GenBotMiddleware(proxy *config.Proxy, c *cache.Cache) fiber.Handler {
+	classifier := bots.NewClassifier(proxy.Bots.UserAgents, proxy.Bots.BehaviorRate)
+	limiter := bots.NewCounter()
+
+	return func(ctx *fiber.Ctx) error {
+		now := time.Now()
+		client := helpers.ClientKey(ctx, *proxy)
+		class := classifier.Classify(ctx.Get(fiber.HeaderUserAgent), client, now)
+
+		ctx.Locals(str.LocalClientClass, class)
+		util.LogWith(ctx, "class", class)
+		c.Metrics.ClientClasses.WithLabelValues(string(class)).Inc()
+
+		if class != bots.Bot {
+			return ctx.Next()
+		}
+
+		if proxy.Bots.RateLimit > 0 && limiter.Add(client, now) > proxy.Bots.RateLimit {
+			ctx.Locals(str.LocalCacheStatus, ":limit")
+			return helpers.SendRejection(ctx, fiber.StatusTooManyRequests,
+				str.RBotRateLimit, limiter.Remaining(now))
+		}
+
+		if proxy.Bots.SkipMemory {
+			ctx.Locals(str.LocalSkipMemory, true)
+		}
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/geoip"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// GenGeoMiddleware builds a middleware looking up the client's country,
+// counting requests by country and rejecting those from countries the
+// proxy's country lists exclude
+func GenGeoMiddleware(proxy *config.Proxy, c *cache.Cache) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		country := geoip.Country(ctx.IP())
+
+		ctx.Locals(str.LocalCountry, country)
+		util.LogWith(ctx, "country", country)
+		c.Metrics.ClientCountries.WithLabelValues(country).Inc()
+
+		if !geoip.Allowed(country, proxy.Geo.AllowCountries, proxy.Geo.DenyCountries) {
+			ctx.Locals(str.LocalCacheStatus, ":geo  ")
+			return ctx.Status(fiber.StatusUnavailableForLegalReasons).JSON(map[string]string{
+				"status": "failed",
+				"error":  str.RGeoBlocked,
+			})
+		}
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/jwt"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// GenJWTMiddleware builds a middleware requiring a bearer token verified
+// against the proxy's identity provider keys, issuer and audience
+func GenJWTMiddleware(proxy *config.Proxy) fiber.Handler {
+	verifier := jwt.Verifier{
+		Keys:          jwt.NewJWKS(proxy.JWT.JWKSURL, proxy.JWT.RefreshDuration),
+		Issuer:        proxy.JWT.Issuer,
+		Audience:      proxy.JWT.Audience,
+		Leeway:        proxy.JWT.LeewayDuration,
+		AllowNoExpiry: proxy.JWT.AllowNoExpiry,
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		authorization := ctx.Get(fiber.HeaderAuthorization)
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token == authorization || token == "" {
+			return rejectJWT(ctx, "bearer token required")
+		}
+
+		claims, err := verifier.Verify(token, time.Now())
+		if err != nil {
+			util.Log(ctx).Debug(str.CProxy, str.DJWTRejected, proxy.Name, err.Error())
+			return rejectJWT(ctx, err.Error())
+		}
+
+		util.LogWith(ctx, "sub", claims.Subject())
+		return ctx.Next()
+	}
+}
+
+// rejectJWT responds to a request without a valid bearer token
+func rejectJWT(ctx *fiber.Ctx, reason string) error {
+	ctx.Locals(str.LocalCacheStatus, ":nauth")
+	ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+
+	if !env.IsProd() {
+		// provide useful error messages when running in dev mode
+		return ctx.Status(fiber.StatusUnauthorized).JSON(map[string]string{
+			"status":  "error",
+			"message": reason,
+		})
+	}
+
+	return ctx.Status(fiber.StatusUnauthorized).SendString("")
+}
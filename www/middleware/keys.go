@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/corsdebug"
+	"github.com/dechristopher/lod/headers"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// genCORSMiddleware builds a middleware applying the proxy's CORS config, or
+// the allowed origins of the request's API key where it has its own, and
+// capturing a sample of the decisions made if enabled
+func genCORSMiddleware(proxy *config.Proxy) fiber.Handler {
+	exposed := strings.Join(headers.Literals(proxy.PullHeaders), ",")
+
+	proxyCORS := func(ctx *fiber.Ctx) error {
+		return ctx.Next()
+	}
+	if proxy.CorsOrigins != "" {
+		proxyCORS = cors.New(cors.Config{
+			AllowOrigins:  proxy.CorsOrigins,
+			ExposeHeaders: exposed,
+		})
+	}
+
+	methods := strings.Join(append([]string{fiber.MethodGet, fiber.MethodHead,
+		fiber.MethodOptions}, proxy.Methods...), ",")
+
+	decide := func(ctx *fiber.Ctx) error {
+		key := proxy.Key(ctx.Query("token"))
+		if key == nil || len(key.Origins) == 0 {
+			return proxyCORS(ctx)
+		}
+
+		origin := ctx.Get(fiber.HeaderOrigin)
+		ctx.Vary(fiber.HeaderOrigin)
+		if origin == "" {
+			return ctx.Next()
+		}
+
+		if !key.AllowsOrigin(origin) {
+			ctx.Locals(str.LocalCacheStatus, ":nauth")
+			return ctx.Status(fiber.StatusForbidden).SendString("")
+		}
+
+		ctx.Set(fiber.HeaderAccessControlAllowOrigin, origin)
+
+		// answer preflight requests for the key's origins directly
+		if ctx.Method() == fiber.MethodOptions && ctx.Get(fiber.HeaderAccessControlRequestMethod) != "" {
+			ctx.Set(fiber.HeaderAccessControlAllowMethods, methods)
+			if requested := ctx.Get(fiber.HeaderAccessControlRequestHeaders); requested != "" {
+				ctx.Set(fiber.HeaderAccessControlAllowHeaders, requested)
+			}
+			return ctx.SendStatus(fiber.StatusNoContent)
+		}
+
+		if exposed != "" {
+			ctx.Set(fiber.HeaderAccessControlExposeHeaders, exposed)
+		}
+		return ctx.Next()
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		recorder := corsdebug.Get(proxy.Name)
+		if ctx.Get(fiber.HeaderOrigin) == "" || !recorder.Sample() {
+			return decide(ctx)
+		}
+
+		err := decide(ctx)
+		recorder.Record(captureCORS(ctx, proxy))
+		return err
+	}
+}
+
+// captureCORS describes the CORS decision made for a cross-origin request
+// from the request and the response headers emitted
+func captureCORS(ctx *fiber.Ctx, proxy *config.Proxy) corsdebug.Capture {
+	capture := corsdebug.Capture{
+		Time:      time.Now(),
+		Method:    ctx.Method(),
+		Path:      ctx.Path(),
+		Origin:    ctx.Get(fiber.HeaderOrigin),
+		Preflight: ctx.Method() == fiber.MethodOptions && ctx.Get(fiber.HeaderAccessControlRequestMethod) != "",
+		Policy:    "none",
+		Status:    ctx.Response().StatusCode(),
+		Headers:   map[string]string{},
+	}
+	if capture.Preflight {
+		capture.RequestMethod = ctx.Get(fiber.HeaderAccessControlRequestMethod)
+		capture.RequestHeaders = ctx.Get(fiber.HeaderAccessControlRequestHeaders)
+	}
+
+	key := proxy.Key(ctx.Query("token"))
+	if key != nil {
+		capture.Key = key.Name
+	}
+	switch {
+	case key != nil && len(key.Origins) > 0:
+		capture.Policy = "key"
+		capture.Rule = key.MatchingOrigin(capture.Origin)
+	case proxy.CorsOrigins != "":
+		capture.Policy = "proxy"
+		capture.Rule = corsdebug.MatchRule(strings.Split(proxy.CorsOrigins, ","), capture.Origin)
+	}
+	capture.Allowed = capture.Rule != ""
+
+	ctx.Response().Header.VisitAll(func(name, value []byte) {
+		if strings.HasPrefix(strings.ToLower(string(name)), "access-control-") {
+			capture.Headers[string(name)] = string(value)
+		}
+	})
+	return capture
+}
+
+// GenKeyAuthMiddleware builds a middleware requiring the proxy's access token
+// or one of its API keys in the token query parameter, enforcing the allowed
+// referers of API keys
+func GenKeyAuthMiddleware(proxy *config.Proxy) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token := ctx.Query("token")
+		if proxy.AccessToken != "" && token == proxy.AccessToken {
+			return ctx.Next()
+		}
+
+		key := proxy.Key(token)
+		if key == nil {
+			return rejectAuth(ctx, false)
+		}
+
+		if !key.AllowsReferer(ctx.Get(fiber.HeaderReferer)) {
+			ctx.Locals(str.LocalCacheStatus, ":nauth")
+			return ctx.Status(fiber.StatusForbidden).SendString("")
+		}
+
+		util.LogWith(ctx, "key", key.Name)
+		return ctx.Next()
+	}
+}
@@ -2,22 +2,36 @@ package middleware
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 
+	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/helpers"
 	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
 )
 
 // Wire attaches all middleware to the given router
 func Wire(r fiber.Router, proxy *config.Proxy) {
 	r.Use(requestid.New())
 
+	// Watch the connections of proxy requests for their clients going away
+	if proxy != nil {
+		r.Use(helpers.WatchClient)
+	}
+
+	// Configure CORS for proxies with allowed origins, exposing pulled headers,
+	// with API keys that may carry their own allowed origins, or capturing the
+	// CORS decisions made for debugging
+	if proxy != nil && (proxy.CorsOrigins != "" || len(proxy.Keys) > 0 || proxy.CORSDebug.Enabled) {
+		r.Use(genCORSMiddleware(proxy))
+	}
+
 	// Compress responses for non-tiles, use tileserver compression and encoding
 	if proxy == nil {
 		r.Use(compress.New(compress.Config{
@@ -36,12 +50,14 @@ const (
 	Query AuthType = "query"
 )
 
-// GenCacheNameMiddleware builds a middleware that adds the proxy name to the
-// request context locals so that named admin endpoints can look up the proxy
-// they handle requests for
-func GenCacheNameMiddleware(cacheName string) fiber.Handler {
+// GenCacheMiddleware builds a middleware that adds the proxy name and its
+// cache instance from the given manager to the request context locals so that
+// named admin endpoints can look up the proxy they handle requests for
+func GenCacheMiddleware(caches *cache.Manager, cacheName string) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		ctx.Locals(str.LocalCacheName, cacheName)
+		ctx.Locals(str.LocalCache, caches.Get(cacheName))
+		util.LogWith(ctx, "proxy", cacheName)
 		return ctx.Next()
 	}
 }
@@ -68,23 +84,39 @@ func GenAuthMiddleware(token string, authType AuthType, notFound bool) fiber.Han
 			return ctx.Next()
 		}
 
-		ctx.Locals(str.LocalCacheStatus, ":nauth")
+		return rejectAuth(ctx, notFound)
+	}
+}
 
-		if !env.IsProd() {
-			// provide useful error messages when running in dev mode
-			return ctx.Status(fiber.StatusUnauthorized).JSON(map[string]string{
-				"status":  "error",
-				"message": "failed to auth, invalid token supplied",
-			})
-		}
+// rejectAuth responds to a request that failed to authenticate
+func rejectAuth(ctx *fiber.Ctx, notFound bool) error {
+	ctx.Locals(str.LocalCacheStatus, ":nauth")
 
-		if notFound {
-			// otherwise, pretend nothing exists if notFound is set
-			return ctx.Status(fiber.StatusNotFound).SendString("")
-		}
+	if !env.IsProd() {
+		// provide useful error messages when running in dev mode
+		return ctx.Status(fiber.StatusUnauthorized).JSON(map[string]string{
+			"status":  "error",
+			"message": "failed to auth, invalid token supplied",
+		})
+	}
+
+	if notFound {
+		// otherwise, pretend nothing exists if notFound is set
+		return ctx.Status(fiber.StatusNotFound).SendString("")
+	}
+
+	// return empty 401 if notFound is not set
+	return ctx.Status(fiber.StatusUnauthorized).SendString("")
+}
 
-		// return empty 401 if notFound is not set
-		return ctx.Status(fiber.StatusUnauthorized).SendString("")
+// GenPeerSignatureMiddleware builds a middleware requiring requests to be
+// signed by an edge LOD instance holding the given shared peer secret
+func GenPeerSignatureMiddleware(secret string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if helpers.VerifyPeerSignature(ctx, secret, time.Now()) {
+			return ctx.Next()
+		}
+		return rejectAuth(ctx, true)
 	}
 }
 
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/str"
+)
+
+// GenQueryLimitMiddleware builds a middleware rejecting requests whose query
+// strings exceed the proxy's limits before any lookups, bounding the size of
+// cache keys built from query parameters
+func GenQueryLimitMiddleware(proxy *config.Proxy) fiber.Handler {
+	limits := proxy.Query
+
+	return func(ctx *fiber.Ctx) error {
+		uri := ctx.Request().URI()
+		if limits.MaxLength >= 0 && len(uri.QueryString()) > limits.MaxLength {
+			return rejectQuery(ctx, fiber.StatusRequestURITooLong, str.RQueryTooLong)
+		}
+
+		args := uri.QueryArgs()
+		if limits.MaxParams >= 0 && args.Len() > limits.MaxParams {
+			return rejectQuery(ctx, fiber.StatusBadRequest, str.RQueryTooManyParams)
+		}
+
+		if limits.MaxValueLength >= 0 {
+			tooLong := false
+			args.VisitAll(func(key, value []byte) {
+				if len(key) > limits.MaxValueLength || len(value) > limits.MaxValueLength {
+					tooLong = true
+				}
+			})
+			if tooLong {
+				return rejectQuery(ctx, fiber.StatusRequestURITooLong, str.RQueryTooLong)
+			}
+		}
+
+		return ctx.Next()
+	}
+}
+
+// rejectQuery responds to a request whose query string exceeds the limits
+func rejectQuery(ctx *fiber.Ctx, status int, reason string) error {
+	ctx.Locals(str.LocalCacheStatus, ":query")
+	return ctx.Status(status).JSON(map[string]string{
+		"status": "failed",
+		"error":  reason,
+	})
+}
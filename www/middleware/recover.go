@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/cache"
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/str"
+	"github.com/dechristopher/lod/util"
+)
+
+// GenRecoverMiddleware builds a middleware that converts panics in the rest of
+// the proxy's handler chain into 500 responses carrying the request ID, logging
+// the stack trace and counting the panic against the proxy
+func GenRecoverMiddleware(proxy *config.Proxy, c *cache.Cache) fiber.Handler {
+	return func(ctx *fiber.Ctx) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			requestID, _ := ctx.Locals(str.LocalRequestID).(string)
+			util.Error(str.CProxy, str.EPanic, proxy.Name, requestID, r, debug.Stack())
+			c.Metrics.Panics.Inc()
+
+			ctx.Locals(str.LocalCacheStatus, ":panic")
+			// drop whatever the panicking handler already wrote
+			ctx.Response().Reset()
+			ctx.Set(fiber.HeaderXRequestID, requestID)
+
+			body := map[string]string{
+				"status":     "internal server error",
+				"request_id": requestID,
+			}
+			// include the panic value when running in dev mode
+			if !env.IsProd() {
+				body["error"] = fmt.Sprint(r)
+			}
+			err = ctx.Status(fiber.StatusInternalServerError).JSON(body)
+		}()
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/dechristopher/lod/config"
+	"github.com/dechristopher/lod/helpers"
+	"github.com/dechristopher/lod/str"
+)
+
+// GenScheduleMiddleware builds a middleware rejecting requests outside of the
+// serving windows of the proxy and of the request's API key, if it has any
+func GenScheduleMiddleware(proxy *config.Proxy) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		now := time.Now()
+
+		if !proxy.Schedule.Window.Open(now) {
+			return rejectSchedule(ctx, proxy, proxy.Schedule, now)
+		}
+
+		if key := proxy.Key(ctx.Query("token")); key != nil && !key.Schedule.Window.Open(now) {
+			return rejectSchedule(ctx, proxy, key.Schedule, now)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// rejectSchedule responds to a request outside of a schedule's serving
+// windows, hinting at the next window in maintenance mode
+func rejectSchedule(ctx *fiber.Ctx, proxy *config.Proxy, s config.Schedule, now time.Time) error {
+	ctx.Locals(str.LocalCacheStatus, ":sched")
+
+	if s.Mode == config.ScheduleMaintenance {
+		retryAfter := proxy.Maintenance.RetryAfterDuration
+		if next, ok := s.Window.Next(now); ok {
+			retryAfter = next.Sub(now)
+		}
+		return helpers.SendRejection(ctx, fiber.StatusServiceUnavailable, str.RScheduleClosed, retryAfter)
+	}
+
+	return ctx.Status(fiber.StatusForbidden).JSON(map[string]string{
+		"status": "failed",
+		"error":  str.RScheduleClosed,
+	})
+}
@@ -1,23 +1,34 @@
 package www
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/quic-go/quic-go/http3"
 
+	"github.com/dechristopher/lod/accesslog"
+	"github.com/dechristopher/lod/cache"
 	"github.com/dechristopher/lod/config"
 	"github.com/dechristopher/lod/env"
+	"github.com/dechristopher/lod/fleet"
+	"github.com/dechristopher/lod/statsd"
 	"github.com/dechristopher/lod/str"
 	"github.com/dechristopher/lod/util"
+	"github.com/dechristopher/lod/webhook"
 	"github.com/dechristopher/lod/www/handlers"
+	"github.com/dechristopher/lod/www/handlers/proxy"
+	"github.com/dechristopher/lod/www/middleware"
 )
 
-// Serve all public endpoints
-func Serve() {
+// newApp builds a fiber app with LOD's server settings and request logger
+func newApp() *fiber.App {
 	r := fiber.New(fiber.Config{
 		CaseSensitive:         true,
 		DisableStartupMessage: true,
@@ -50,16 +61,72 @@ func Serve() {
 		Output:     os.Stdout,
 	}))
 
+	return r
+}
+
+// New builds the fiber app serving all public endpoints for the loaded
+// configuration from the caches held by the given manager, without listening
+// for connections
+func New(caches *cache.Manager) *fiber.App {
+	r := newApp()
+
+	// advertise the HTTP/3 listener if enabled
+	if config.Get().Instance.HTTP3.Enabled {
+		wireAltSvc(r)
+	}
+
 	// wire up all route handlers
-	handlers.Wire(r)
+	handlers.Wire(r, caches)
+
+	return r
+}
+
+// NewServer builds a fiber app serving the proxies of the given prepared
+// configuration with their own cache instances, independent of the loaded
+// configuration. Admin endpoints are not served.
+func NewServer(capabilities config.Capabilities) (*fiber.App, error) {
+	caches := cache.NewManager()
+	if err := caches.Init(&capabilities); err != nil {
+		return nil, err
+	}
+
+	r := newApp()
+	r.Use(recover.New())
+
+	for _, p := range capabilities.Proxies {
+		proxy.Mount(r, p, caches.Get(p.Name))
+	}
 
-	// Graceful shutdown with SIGINT
-	// SIGTERM and others will hard kill
+	middleware.NotFound(r)
+
+	return r, nil
+}
+
+// Serve all public endpoints from the caches held by the given manager
+func Serve(caches *cache.Manager) {
+	r := New(caches)
+
+	// serve over HTTP/3 alongside the primary listener if enabled
+	var h3 *http3.Server
+	if config.Get().Instance.HTTP3.Enabled {
+		h3 = serveHTTP3(r)
+	}
+
+	// Graceful shutdown with SIGINT, and with SIGTERM after draining if enabled
+	// SIGTERM and others will hard kill otherwise
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
+	if config.Get().Instance.Drain.SIGTERM {
+		signal.Notify(c, syscall.SIGTERM)
+	}
 	go func() {
-		<-c
+		if sig := <-c; sig == syscall.SIGTERM {
+			fleet.Drain(context.Background())
+		}
 		util.Info(str.CMain, str.MShutdown)
+		if h3 != nil {
+			_ = h3.Close()
+		}
 		_ = r.Shutdown()
 	}()
 
@@ -71,6 +138,21 @@ func Serve() {
 		log.Fatalln(err)
 	}
 
+	// send the redis writes still pending
+	caches.Close()
+
+	// post the request summaries still queued for the webhook
+	webhook.Close()
+
+	// insert the records still queued for the access log
+	accesslog.Close()
+
+	// emit metrics to the statsd agent a final time
+	statsd.Close()
+
+	// leave the fleet registry
+	fleet.Close()
+
 	// Exit cleanly
 	util.Info(str.CMain, str.MExit)
 	os.Exit(0)